@@ -0,0 +1,305 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package byzantine
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/palantir/stacktrace"
+)
+
+const (
+	// The CLI arg the byzantine image reads to decide which behavior to exhibit
+	behaviorArg = "byzantine-behavior"
+
+	// The CLI args the byzantine image reads the parameters of each behavior from
+	chitsPerSecondArg          = "byzantine-chits-per-second"
+	conflictingVotesArg        = "byzantine-conflicting-votes"
+	withholdRatioArg           = "byzantine-withhold-ratio"
+	malformedRatioArg          = "byzantine-malformed-ratio"
+	gossipMessagesPerSecondArg = "byzantine-gossip-messages-per-second"
+)
+
+// BehaviorID identifies a behavior of the byzantine image, and is the value of its byzantine-behavior CLI arg
+type BehaviorID string
+
+const (
+	ChitSpammer            BehaviorID = "chit-spammer"
+	ConflictingTxsVertex   BehaviorID = "conflicting-txs-vertex"
+	EquivocatingVoter      BehaviorID = "equivocating-voter"
+	ResponseWithholder     BehaviorID = "response-withholder"
+	MalformedMessageSender BehaviorID = "malformed-message-sender"
+	GossipFlooder          BehaviorID = "gossip-flooder"
+)
+
+// HonestNetworkOutcome describes how the honest part of a network is expected to fare while byzantine nodes exhibit a
+// behavior. Whatever the behavior, the honest nodes are expected to stay healthy and a new honest node to be able to join
+// as a validator, as long as the byzantine nodes hold less than a third of the stake; behaviors only differ in how much
+// they slow the honest network down.
+type HonestNetworkOutcome struct {
+	// The fraction of a test's execution timeout that the honest network may take to accept a transaction; behaviors
+	// that slow consensus down get a larger share
+	AcceptanceTimeoutRatio float64
+
+	// The longest an X Chain transfer issued through an honest node may take to be accepted once the network has
+	// settled, which is what the test asserts the slowdown against
+	MaxTransferLatency time.Duration
+}
+
+// Behavior is a typed configuration of one of the behaviors of the byzantine image
+// A parameter left at its zero value isn't passed to the image, which then uses its own default for it
+type Behavior interface {
+	// GetID returns the ID of the behavior
+	GetID() BehaviorID
+
+	// GetCLIArgs returns the CLI args that make a node of the byzantine image exhibit the behavior with these parameters
+	GetCLIArgs() map[string]string
+
+	// GetExpectedOutcome returns how the honest network is expected to fare against the behavior
+	GetExpectedOutcome() HonestNetworkOutcome
+
+	// Validate returns an error if the parameters of the behavior are out of range
+	Validate() error
+}
+
+// ChitSpammerBehavior sends chits that were never requested to its peers
+type ChitSpammerBehavior struct {
+	// The number of unrequested chits to send per second
+	ChitsPerSecond int
+}
+
+func (behavior ChitSpammerBehavior) GetID() BehaviorID {
+	return ChitSpammer
+}
+
+func (behavior ChitSpammerBehavior) GetCLIArgs() map[string]string {
+	return newCLIArgs(behavior, chitsPerSecondArg, strconv.Itoa(behavior.ChitsPerSecond))
+}
+
+func (behavior ChitSpammerBehavior) GetExpectedOutcome() HonestNetworkOutcome {
+	return HonestNetworkOutcome{
+		AcceptanceTimeoutRatio: 0.3,
+		MaxTransferLatency:     30 * time.Second,
+	}
+}
+
+func (behavior ChitSpammerBehavior) Validate() error {
+	return validateNonNegative(chitsPerSecondArg, behavior.ChitsPerSecond)
+}
+
+// ConflictingTxsVertexBehavior issues conflicting transactions inside a single vertex when asked to issue transactions
+// through its API. It doesn't misbehave on its own, so it only has an effect in tests that issue transactions through it.
+type ConflictingTxsVertexBehavior struct{}
+
+func (behavior ConflictingTxsVertexBehavior) GetID() BehaviorID {
+	return ConflictingTxsVertex
+}
+
+func (behavior ConflictingTxsVertexBehavior) GetCLIArgs() map[string]string {
+	return map[string]string{
+		behaviorArg: string(behavior.GetID()),
+	}
+}
+
+func (behavior ConflictingTxsVertexBehavior) GetExpectedOutcome() HonestNetworkOutcome {
+	return HonestNetworkOutcome{
+		AcceptanceTimeoutRatio: 0.3,
+		MaxTransferLatency:     30 * time.Second,
+	}
+}
+
+func (behavior ConflictingTxsVertexBehavior) Validate() error {
+	return nil
+}
+
+// EquivocatingVoterBehavior answers each query with several conflicting votes
+type EquivocatingVoterBehavior struct {
+	// The number of conflicting votes sent in answer to each query
+	ConflictingVotesPerQuery int
+}
+
+func (behavior EquivocatingVoterBehavior) GetID() BehaviorID {
+	return EquivocatingVoter
+}
+
+func (behavior EquivocatingVoterBehavior) GetCLIArgs() map[string]string {
+	return newCLIArgs(behavior, conflictingVotesArg, strconv.Itoa(behavior.ConflictingVotesPerQuery))
+}
+
+func (behavior EquivocatingVoterBehavior) GetExpectedOutcome() HonestNetworkOutcome {
+	return HonestNetworkOutcome{
+		AcceptanceTimeoutRatio: 0.3,
+		MaxTransferLatency:     30 * time.Second,
+	}
+}
+
+func (behavior EquivocatingVoterBehavior) Validate() error {
+	// A single vote isn't equivocating
+	if behavior.ConflictingVotesPerQuery != 0 && behavior.ConflictingVotesPerQuery < 2 {
+		return stacktrace.NewError("%v must be at least 2 but was %v", conflictingVotesArg, behavior.ConflictingVotesPerQuery)
+	}
+	return nil
+}
+
+// ResponseWithholderBehavior never answers a fraction of the requests it receives, so its peers have to wait for those
+// requests to time out
+type ResponseWithholderBehavior struct {
+	// The fraction of requests, in [0, 1], that are never answered
+	WithholdRatio float64
+}
+
+func (behavior ResponseWithholderBehavior) GetID() BehaviorID {
+	return ResponseWithholder
+}
+
+func (behavior ResponseWithholderBehavior) GetCLIArgs() map[string]string {
+	return newCLIArgs(behavior, withholdRatioArg, formatRatio(behavior.WithholdRatio))
+}
+
+func (behavior ResponseWithholderBehavior) GetExpectedOutcome() HonestNetworkOutcome {
+	return HonestNetworkOutcome{
+		// Queries sent to the withholders only complete when they time out
+		AcceptanceTimeoutRatio: 0.5,
+		MaxTransferLatency:     90 * time.Second,
+	}
+}
+
+func (behavior ResponseWithholderBehavior) Validate() error {
+	return validateRatio(withholdRatioArg, behavior.WithholdRatio)
+}
+
+// MalformedMessageSenderBehavior corrupts a fraction of the messages it sends so that they can't be parsed
+type MalformedMessageSenderBehavior struct {
+	// The fraction of messages, in [0, 1], that are corrupted
+	MalformedRatio float64
+}
+
+func (behavior MalformedMessageSenderBehavior) GetID() BehaviorID {
+	return MalformedMessageSender
+}
+
+func (behavior MalformedMessageSenderBehavior) GetCLIArgs() map[string]string {
+	return newCLIArgs(behavior, malformedRatioArg, formatRatio(behavior.MalformedRatio))
+}
+
+func (behavior MalformedMessageSenderBehavior) GetExpectedOutcome() HonestNetworkOutcome {
+	return HonestNetworkOutcome{
+		AcceptanceTimeoutRatio: 0.3,
+		MaxTransferLatency:     30 * time.Second,
+	}
+}
+
+func (behavior MalformedMessageSenderBehavior) Validate() error {
+	return validateRatio(malformedRatioArg, behavior.MalformedRatio)
+}
+
+// GossipFlooderBehavior re-gossips the containers it knows of to its peers as fast as it's allowed to
+type GossipFlooderBehavior struct {
+	// The number of gossip messages to send per second
+	GossipMessagesPerSecond int
+}
+
+func (behavior GossipFlooderBehavior) GetID() BehaviorID {
+	return GossipFlooder
+}
+
+func (behavior GossipFlooderBehavior) GetCLIArgs() map[string]string {
+	return newCLIArgs(behavior, gossipMessagesPerSecondArg, strconv.Itoa(behavior.GossipMessagesPerSecond))
+}
+
+func (behavior GossipFlooderBehavior) GetExpectedOutcome() HonestNetworkOutcome {
+	return HonestNetworkOutcome{
+		// Honest nodes have to work through the flood before they get to legitimate messages
+		AcceptanceTimeoutRatio: 0.5,
+		MaxTransferLatency:     90 * time.Second,
+	}
+}
+
+func (behavior GossipFlooderBehavior) Validate() error {
+	return validateNonNegative(gossipMessagesPerSecondArg, behavior.GossipMessagesPerSecond)
+}
+
+/*
+The behaviors of the byzantine image that misbehave on their own, configured with the parameters tests use by default
+NOTE: These must be kept in sync with the behaviors and CLI args the byzantine image supports
+NOTE: ConflictingTxsVertex isn't registered, as it's a no-op unless transactions are issued through it, which only the
+conflicting txs vertex test does
+*/
+var registeredBehaviors = map[BehaviorID]Behavior{
+	// Left at the image's defaults, which the chit spammer test was written against
+	ChitSpammer: ChitSpammerBehavior{},
+	EquivocatingVoter: EquivocatingVoterBehavior{
+		ConflictingVotesPerQuery: 2,
+	},
+	ResponseWithholder: ResponseWithholderBehavior{
+		WithholdRatio: 0.5,
+	},
+	MalformedMessageSender: MalformedMessageSenderBehavior{
+		MalformedRatio: 0.5,
+	},
+	GossipFlooder: GossipFlooderBehavior{
+		GossipMessagesPerSecond: 1000,
+	},
+}
+
+// GetBehavior returns the registered behavior with the given ID, configured with its default parameters
+func GetBehavior(behaviorID BehaviorID) (Behavior, error) {
+	behavior, found := registeredBehaviors[behaviorID]
+	if !found {
+		return nil, stacktrace.NewError("No byzantine behavior with ID '%v' is registered", behaviorID)
+	}
+	return behavior, nil
+}
+
+// GetRegisteredBehaviors returns every registered behavior, configured with its default parameters and sorted by ID
+func GetRegisteredBehaviors() []Behavior {
+	result := make([]Behavior, 0, len(registeredBehaviors))
+	for _, behavior := range registeredBehaviors {
+		result = append(result, behavior)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].GetID() < result[j].GetID()
+	})
+	return result
+}
+
+// ================ Helper functions =========================
+/*
+Builds the CLI args for a behavior with a single parameter, leaving the parameter out if it's at its zero value
+*/
+func newCLIArgs(behavior Behavior, paramArg string, paramValue string) map[string]string {
+	result := map[string]string{
+		behaviorArg: string(behavior.GetID()),
+	}
+	if paramValue != "0" {
+		result[paramArg] = paramValue
+	}
+	return result
+}
+
+func formatRatio(ratio float64) string {
+	return strconv.FormatFloat(ratio, 'f', -1, 64)
+}
+
+func validateNonNegative(argName string, value int) error {
+	if value < 0 {
+		return stacktrace.NewError("%v must not be negative but was %v", argName, value)
+	}
+	return nil
+}
+
+func validateRatio(argName string, value float64) error {
+	if value < 0 || value > 1 {
+		return stacktrace.NewError("%v must be in [0, 1] but was %v", argName, value)
+	}
+	return nil
+}
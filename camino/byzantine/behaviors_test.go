@@ -0,0 +1,46 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package byzantine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegisteredBehaviorsAreValid(t *testing.T) {
+	for _, behavior := range GetRegisteredBehaviors() {
+		assert.NoError(t, behavior.Validate(), "Registered behavior %v is invalid", behavior.GetID())
+		assert.Equal(t, string(behavior.GetID()), behavior.GetCLIArgs()[behaviorArg])
+
+		outcome := behavior.GetExpectedOutcome()
+		assert.True(t, outcome.AcceptanceTimeoutRatio > 0 && outcome.AcceptanceTimeoutRatio < 1)
+		assert.True(t, outcome.MaxTransferLatency > 0)
+	}
+	_, err := GetBehavior(ConflictingTxsVertex)
+	assert.Error(t, err, "The conflicting txs vertex behavior doesn't misbehave on its own, so it mustn't be registered")
+}
+
+func TestZeroParametersUseImageDefaults(t *testing.T) {
+	assert.Equal(t, map[string]string{behaviorArg: string(ChitSpammer)}, ChitSpammerBehavior{}.GetCLIArgs())
+	assert.Equal(t, map[string]string{behaviorArg: string(ResponseWithholder)}, ResponseWithholderBehavior{}.GetCLIArgs())
+
+	withholder := ResponseWithholderBehavior{WithholdRatio: 0.25}
+	assert.Equal(t, "0.25", withholder.GetCLIArgs()[withholdRatioArg])
+}
+
+func TestInvalidParameters(t *testing.T) {
+	assert.Error(t, ResponseWithholderBehavior{WithholdRatio: 1.5}.Validate())
+	assert.Error(t, EquivocatingVoterBehavior{ConflictingVotesPerQuery: 1}.Validate())
+	assert.Error(t, GossipFlooderBehavior{GossipMessagesPerSecond: -1}.Validate())
+
+	_, err := GetBehavior("unknown-behavior")
+	assert.Error(t, err)
+}
@@ -0,0 +1,403 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package health
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chain4travel/camino-testing/camino_client/apis"
+	"github.com/chain4travel/caminogo/api/health"
+	"github.com/kurtosis-tech/kurtosis-go/lib/networks"
+	"github.com/palantir/stacktrace"
+	"github.com/sirupsen/logrus"
+)
+
+// Endpoint is one of the methods of a node's /ext/health API
+type Endpoint string
+
+const (
+	// Liveness reports whether the node is in need of a restart
+	Liveness Endpoint = "liveness"
+	// Readiness reports whether the node has finished initialization (i.e. bootstrapping)
+	Readiness Endpoint = "readiness"
+
+	// The name under which the endpoint's aggregate result is recorded, as opposed to one of its individual checks
+	OverallCheck = "overall"
+
+	DefaultPollInterval = 2 * time.Second
+)
+
+// MonitoredNetwork is the view of a network that the HealthMonitor polls; it must be safe to call concurrently with
+// changes to the network
+type MonitoredNetwork interface {
+	// GetRunningCaminoClients returns API clients for the nodes currently in the network, keyed by service ID
+	GetRunningCaminoClients() map[networks.ServiceID]*apis.Client
+}
+
+// Transition records a health check on a node being observed in a new state
+type Transition struct {
+	ServiceID networks.ServiceID
+	Endpoint  Endpoint
+
+	// The name of the individual check, or OverallCheck for the endpoint's aggregate result
+	Check string
+
+	Healthy   bool
+	Timestamp time.Time
+
+	// Why the check is unhealthy, if it is
+	Message string
+
+	// Whether the node had reported healthy on the endpoint before, i.e. was held to account, when it was observed
+	armed bool
+}
+
+func (transition Transition) String() string {
+	state := "healthy"
+	if !transition.Healthy {
+		state = fmt.Sprintf("unhealthy (%v)", transition.Message)
+	}
+	return fmt.Sprintf(
+		"%v: service %v %v check '%v' became %v",
+		transition.Timestamp.Format(time.RFC3339Nano),
+		transition.ServiceID,
+		transition.Endpoint,
+		transition.Check,
+		state,
+	)
+}
+
+// HealthMonitor polls the liveness and readiness endpoints of every node in a network in the background, recording each
+// check's transitions. A node that has been healthy on an endpoint and then becomes unhealthy on it is a violation,
+// unless the test opened a permitted window for the node with PermitUnhealthy beforehand. Nodes are only held to account
+// once they've first reported healthy, so nodes that are still bootstrapping don't count as violations.
+type HealthMonitor struct {
+	network      MonitoredNetwork
+	endpoints    []Endpoint
+	pollInterval time.Duration
+
+	mutex *sync.Mutex
+
+	// (service ID, endpoint, check) -> whether the check was healthy the last time it was polled
+	lastStates map[checkKey]bool
+
+	// "Set" of (service ID, endpoint) whose overall result has been healthy at least once since it was last excused
+	armed map[checkKey]bool
+
+	// Number of open permitted windows per service, with the empty service ID covering the whole network
+	openPermits map[networks.ServiceID]int
+
+	transitions []Transition
+	violations  []Transition
+
+	stopChan chan struct{}
+	doneChan chan struct{}
+}
+
+type pollResult struct {
+	serviceID networks.ServiceID
+	endpoint  Endpoint
+	reply     *health.APIHealthReply
+	err       error
+	timestamp time.Time
+}
+
+type checkKey struct {
+	serviceID networks.ServiceID
+	endpoint  Endpoint
+	check     string
+}
+
+// NewHealthMonitor creates a monitor that will poll the liveness and readiness of every node in the given network once
+// it's started
+// Args:
+// 	network: The network whose nodes will be polled
+// 	pollInterval: How long to wait between polls of the whole network
+func NewHealthMonitor(network MonitoredNetwork, pollInterval time.Duration) *HealthMonitor {
+	return &HealthMonitor{
+		network:      network,
+		endpoints:    []Endpoint{Liveness, Readiness},
+		pollInterval: pollInterval,
+		mutex:        &sync.Mutex{},
+		lastStates:   make(map[checkKey]bool),
+		armed:        make(map[checkKey]bool),
+		openPermits:  make(map[networks.ServiceID]int),
+		transitions:  []Transition{},
+		violations:   []Transition{},
+	}
+}
+
+// Start begins polling the network in the background; it's a no-op if the monitor is already running
+func (monitor *HealthMonitor) Start() {
+	monitor.mutex.Lock()
+	defer monitor.mutex.Unlock()
+	if monitor.stopChan != nil {
+		return
+	}
+	monitor.stopChan = make(chan struct{})
+	monitor.doneChan = make(chan struct{})
+	go monitor.pollUntilStopped(monitor.stopChan, monitor.doneChan)
+}
+
+// Stop stops polling, blocking until any in-flight poll has finished; it's a no-op if the monitor isn't running
+func (monitor *HealthMonitor) Stop() {
+	monitor.mutex.Lock()
+	stopChan, doneChan := monitor.stopChan, monitor.doneChan
+	monitor.stopChan, monitor.doneChan = nil, nil
+	monitor.mutex.Unlock()
+	if stopChan == nil {
+		return
+	}
+	close(stopChan)
+	<-doneChan
+}
+
+// PermitUnhealthy opens a window during which the given nodes (or every node, if none are given) may become unhealthy
+// without it being a violation, e.g. while a test deliberately partitions or stops them. The returned function closes
+// the window; a node that is still unhealthy when its window closes is only held to account again once it's recovered.
+func (monitor *HealthMonitor) PermitUnhealthy(serviceIDs ...networks.ServiceID) (closeWindow func()) {
+	if len(serviceIDs) == 0 {
+		serviceIDs = []networks.ServiceID{""}
+	}
+	monitor.mutex.Lock()
+	defer monitor.mutex.Unlock()
+	for _, serviceID := range serviceIDs {
+		monitor.openPermits[serviceID]++
+	}
+
+	closeOnce := &sync.Once{}
+	return func() {
+		closeOnce.Do(func() {
+			monitor.mutex.Lock()
+			defer monitor.mutex.Unlock()
+			for _, serviceID := range serviceIDs {
+				monitor.openPermits[serviceID]--
+				if monitor.openPermits[serviceID] == 0 {
+					delete(monitor.openPermits, serviceID)
+				}
+			}
+			// Make nodes that are unhealthy at the close of their window recover before they count again
+			for key, isHealthy := range monitor.lastStates {
+				if key.check == OverallCheck && !isHealthy && windowCovers(serviceIDs, key.serviceID) {
+					delete(monitor.armed, key)
+				}
+			}
+		})
+	}
+}
+
+// GetTransitions returns every transition observed so far, in the order they were observed
+func (monitor *HealthMonitor) GetTransitions() []Transition {
+	monitor.mutex.Lock()
+	defer monitor.mutex.Unlock()
+	return append([]Transition{}, monitor.transitions...)
+}
+
+// GetViolations returns the transitions to unhealthy that happened outside of a permitted window
+func (monitor *HealthMonitor) GetViolations() []Transition {
+	monitor.mutex.Lock()
+	defer monitor.mutex.Unlock()
+	return append([]Transition{}, monitor.violations...)
+}
+
+// VerifyNoViolations returns an error describing every violation observed so far, if there were any
+func (monitor *HealthMonitor) VerifyNoViolations() error {
+	violations := monitor.GetViolations()
+	if len(violations) == 0 {
+		return nil
+	}
+	return stacktrace.NewError("Nodes became unhealthy outside of a permitted window:\n%v", joinTransitions(violations))
+}
+
+// VerifyStayedHealthy asserts that none of the given nodes were observed becoming unhealthy on any endpoint since the
+// given time, regardless of permitted windows (e.g. to claim that honest nodes weren't affected by Byzantine ones). As
+// with violations, a node is only held to account once it has first reported healthy, so a node that joined after the
+// given time doesn't fail the check for still starting up.
+func (monitor *HealthMonitor) VerifyStayedHealthy(serviceIDs map[networks.ServiceID]bool, since time.Time) error {
+	unhealthyTransitions := []Transition{}
+	for _, transition := range monitor.GetTransitions() {
+		if _, found := serviceIDs[transition.ServiceID]; !found {
+			continue
+		}
+		if transition.Check == OverallCheck && !transition.Healthy && transition.armed && !transition.Timestamp.Before(since) {
+			unhealthyTransitions = append(unhealthyTransitions, transition)
+		}
+	}
+	if len(unhealthyTransitions) > 0 {
+		return stacktrace.NewError("Nodes that were expected to stay healthy became unhealthy:\n%v", joinTransitions(unhealthyTransitions))
+	}
+	return nil
+}
+
+// ================ Helper functions =========================
+func (monitor *HealthMonitor) pollUntilStopped(stopChan chan struct{}, doneChan chan struct{}) {
+	defer close(doneChan)
+	ticker := time.NewTicker(monitor.pollInterval)
+	defer ticker.Stop()
+	for {
+		monitor.pollNetwork()
+		select {
+		case <-stopChan:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (monitor *HealthMonitor) pollNetwork() {
+	clients := monitor.network.GetRunningCaminoClients()
+	resultsChan := make(chan pollResult, len(clients)*len(monitor.endpoints))
+	wg := sync.WaitGroup{}
+	for serviceID, client := range clients {
+		for _, endpoint := range monitor.endpoints {
+			wg.Add(1)
+			go func(serviceID networks.ServiceID, client *apis.Client, endpoint Endpoint) {
+				defer wg.Done()
+				reply, err := callEndpoint(client, endpoint, monitor.pollInterval)
+				resultsChan <- pollResult{
+					serviceID: serviceID,
+					endpoint:  endpoint,
+					reply:     reply,
+					err:       err,
+					timestamp: time.Now(),
+				}
+			}(serviceID, client, endpoint)
+		}
+	}
+	wg.Wait()
+	close(resultsChan)
+
+	// Services are dropped from the network before their containers are stopped, so checking again here discards the
+	//  errors from nodes that were deliberately removed while we were polling them
+	stillRunningClients := monitor.network.GetRunningCaminoClients()
+	monitor.mutex.Lock()
+	defer monitor.mutex.Unlock()
+	for result := range resultsChan {
+		if _, found := stillRunningClients[result.serviceID]; found {
+			monitor.record(result)
+		}
+	}
+	monitor.forgetRemovedServices(stillRunningClients)
+}
+
+func callEndpoint(client *apis.Client, endpoint Endpoint, timeout time.Duration) (*health.APIHealthReply, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	switch endpoint {
+	case Liveness:
+		return client.HealthAPI().Liveness(ctx)
+	case Readiness:
+		return client.HealthAPI().Readiness(ctx)
+	default:
+		return nil, stacktrace.NewError("Unrecognized health endpoint '%v'", endpoint)
+	}
+}
+
+// record must be called with the monitor's mutex held
+func (monitor *HealthMonitor) record(result pollResult) {
+	// A node we can't reach is as unhealthy as it gets
+	if result.err != nil {
+		monitor.recordCheck(result.serviceID, result.endpoint, OverallCheck, false, result.err.Error(), result.timestamp)
+		return
+	}
+	for checkName, checkResult := range result.reply.Checks {
+		message := ""
+		if checkResult.Error != nil {
+			message = *checkResult.Error
+		}
+		monitor.recordCheck(result.serviceID, result.endpoint, checkName, checkResult.Error == nil, message, result.timestamp)
+	}
+	message := ""
+	if !result.reply.Healthy {
+		message = fmt.Sprintf("failing checks: %v", failingCheckNames(result.reply))
+	}
+	monitor.recordCheck(result.serviceID, result.endpoint, OverallCheck, result.reply.Healthy, message, result.timestamp)
+}
+
+// recordCheck must be called with the monitor's mutex held
+func (monitor *HealthMonitor) recordCheck(serviceID networks.ServiceID, endpoint Endpoint, check string, isHealthy bool, message string, timestamp time.Time) {
+	key := checkKey{serviceID: serviceID, endpoint: endpoint, check: check}
+	wasHealthy, seenBefore := monitor.lastStates[key]
+	monitor.lastStates[key] = isHealthy
+	if seenBefore && wasHealthy == isHealthy {
+		return
+	}
+
+	_, isArmed := monitor.armed[key]
+	transition := Transition{
+		ServiceID: serviceID,
+		Endpoint:  endpoint,
+		Check:     check,
+		Healthy:   isHealthy,
+		Timestamp: timestamp,
+		Message:   message,
+		armed:     isArmed,
+	}
+	monitor.transitions = append(monitor.transitions, transition)
+	logrus.Debugf("Health transition: %v", transition)
+
+	if check != OverallCheck {
+		return
+	}
+	if isHealthy {
+		monitor.armed[key] = true
+		return
+	}
+	_, isServicePermitted := monitor.openPermits[serviceID]
+	_, isNetworkPermitted := monitor.openPermits[""]
+	if isArmed && !isServicePermitted && !isNetworkPermitted {
+		monitor.violations = append(monitor.violations, transition)
+		logrus.Warnf("Health violation: %v", transition)
+	}
+}
+
+// forgetRemovedServices drops the state of nodes that have left the network, so a new node reusing a removed node's
+// service ID starts afresh; it must be called with the monitor's mutex held
+func (monitor *HealthMonitor) forgetRemovedServices(runningClients map[networks.ServiceID]*apis.Client) {
+	for key := range monitor.lastStates {
+		if _, found := runningClients[key.serviceID]; !found {
+			delete(monitor.lastStates, key)
+			delete(monitor.armed, key)
+		}
+	}
+}
+
+func windowCovers(windowServiceIDs []networks.ServiceID, serviceID networks.ServiceID) bool {
+	for _, windowServiceID := range windowServiceIDs {
+		if windowServiceID == "" || windowServiceID == serviceID {
+			return true
+		}
+	}
+	return false
+}
+
+func failingCheckNames(reply *health.APIHealthReply) string {
+	names := []string{}
+	for checkName, result := range reply.Checks {
+		if result.Error != nil {
+			names = append(names, checkName)
+		}
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+func joinTransitions(transitions []Transition) string {
+	lines := make([]string, 0, len(transitions))
+	for _, transition := range transitions {
+		lines = append(lines, "\t"+transition.String())
+	}
+	return strings.Join(lines, "\n")
+}
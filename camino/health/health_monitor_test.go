@@ -0,0 +1,129 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package health
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/chain4travel/camino-testing/camino_client/apis"
+	"github.com/chain4travel/caminogo/api/health"
+	"github.com/kurtosis-tech/kurtosis-go/lib/networks"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testServiceID networks.ServiceID = "test-service"
+)
+
+type emptyNetwork struct{}
+
+func (network emptyNetwork) GetRunningCaminoClients() map[networks.ServiceID]*apis.Client {
+	return map[networks.ServiceID]*apis.Client{}
+}
+
+func TestUnhealthyBeforeFirstHealthyIsNotViolation(t *testing.T) {
+	monitor := NewHealthMonitor(emptyNetwork{}, DefaultPollInterval)
+	recordReadiness(monitor, false)
+	recordReadiness(monitor, true)
+	assert.Empty(t, monitor.GetViolations())
+	// Both the 'bootstrapped' and overall checks go unhealthy and back
+	assert.Len(t, monitor.GetTransitions(), 4)
+}
+
+func TestUnhealthyAfterHealthyIsViolation(t *testing.T) {
+	monitor := NewHealthMonitor(emptyNetwork{}, DefaultPollInterval)
+	recordReadiness(monitor, true)
+	recordReadiness(monitor, true)
+	recordReadiness(monitor, false)
+	assert.Len(t, monitor.GetViolations(), 1)
+	assert.Error(t, monitor.VerifyNoViolations())
+}
+
+func TestUnreachableNodeIsViolation(t *testing.T) {
+	monitor := NewHealthMonitor(emptyNetwork{}, DefaultPollInterval)
+	recordReadiness(monitor, true)
+	recordUnreachable(monitor)
+	assert.Len(t, monitor.GetViolations(), 1)
+}
+
+func TestPermittedWindow(t *testing.T) {
+	monitor := NewHealthMonitor(emptyNetwork{}, DefaultPollInterval)
+	recordReadiness(monitor, true)
+	closeWindow := monitor.PermitUnhealthy(testServiceID)
+	recordReadiness(monitor, false)
+	closeWindow()
+	assert.Empty(t, monitor.GetViolations())
+
+	// Still unhealthy at the close of the window, so the node must recover before it counts again
+	recordReadiness(monitor, false)
+	assert.Empty(t, monitor.GetViolations())
+	recordReadiness(monitor, true)
+	recordReadiness(monitor, false)
+	assert.Len(t, monitor.GetViolations(), 1)
+}
+
+func TestVerifyStayedHealthyIgnoresWindows(t *testing.T) {
+	monitor := NewHealthMonitor(emptyNetwork{}, DefaultPollInterval)
+	startTime := time.Now()
+	recordReadiness(monitor, true)
+	closeWindow := monitor.PermitUnhealthy()
+	recordReadiness(monitor, false)
+	closeWindow()
+	assert.NoError(t, monitor.VerifyNoViolations())
+	assert.Error(t, monitor.VerifyStayedHealthy(map[networks.ServiceID]bool{testServiceID: true}, startTime))
+	assert.NoError(t, monitor.VerifyStayedHealthy(map[networks.ServiceID]bool{"other-service": true}, startTime))
+}
+
+func TestVerifyStayedHealthyIgnoresNodeStartingUp(t *testing.T) {
+	monitor := NewHealthMonitor(emptyNetwork{}, DefaultPollInterval)
+	startTime := time.Now()
+	// The node is added after the start time, so it's unreachable and then not ready before it first reports healthy
+	recordUnreachable(monitor)
+	recordReadiness(monitor, false)
+	recordReadiness(monitor, true)
+	assert.NoError(t, monitor.VerifyStayedHealthy(map[networks.ServiceID]bool{testServiceID: true}, startTime))
+
+	recordUnreachable(monitor)
+	assert.Error(t, monitor.VerifyStayedHealthy(map[networks.ServiceID]bool{testServiceID: true}, startTime))
+}
+
+func recordUnreachable(monitor *HealthMonitor) {
+	monitor.mutex.Lock()
+	defer monitor.mutex.Unlock()
+	monitor.record(pollResult{
+		serviceID: testServiceID,
+		endpoint:  Readiness,
+		err:       errors.New("connection refused"),
+		timestamp: time.Now(),
+	})
+}
+
+func recordReadiness(monitor *HealthMonitor, isHealthy bool) {
+	checks := map[string]health.Result{}
+	if !isHealthy {
+		errStr := "not bootstrapped"
+		checks["bootstrapped"] = health.Result{Error: &errStr}
+	} else {
+		checks["bootstrapped"] = health.Result{}
+	}
+	monitor.mutex.Lock()
+	defer monitor.mutex.Unlock()
+	monitor.record(pollResult{
+		serviceID: testServiceID,
+		endpoint:  Readiness,
+		reply: &health.APIHealthReply{
+			Checks:  checks,
+			Healthy: isHealthy,
+		},
+		timestamp: time.Now(),
+	})
+}
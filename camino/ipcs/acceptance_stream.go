@@ -0,0 +1,274 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package ipcs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/chain4travel/camino-testing/camino_client/apis"
+	"github.com/chain4travel/caminogo/ids"
+	"github.com/chain4travel/caminogo/ipcs/socket"
+	"github.com/chain4travel/caminogo/utils/hashing"
+	"github.com/palantir/stacktrace"
+	"github.com/sirupsen/logrus"
+)
+
+// EventType is one of the IPC sockets that a node publishes a chain's accepted containers on
+type EventType string
+
+const (
+	// Consensus events are the containers that consensus runs on: vertices on the X Chain, blocks on the P and C Chains
+	Consensus EventType = "consensus"
+	// Decision events are the containers that are finally accepted: txs on the X Chain, blocks on the P and C Chains
+	Decisions EventType = "decisions"
+)
+
+// AcceptedContainer is a container that a node reported accepting on one of its IPC sockets
+type AcceptedContainer struct {
+	EventType EventType
+
+	// The ID of the container, which is the hash of its bytes for txs, vertices and blocks alike
+	ID ids.ID
+
+	// The container as the chain serializes it, which can be decoded with the chain's codec
+	Bytes []byte
+
+	// When the container was read from the socket; the node writes to its sockets as part of accepting the container,
+	// so this trails the acceptance by no more than the time it takes the message to be delivered
+	AcceptedAt time.Time
+}
+
+func (container AcceptedContainer) String() string {
+	return fmt.Sprintf("%v %v accepted at %v", container.EventType, container.ID, container.AcceptedAt.Format(time.RFC3339Nano))
+}
+
+// AcceptanceStream reads the containers that a node accepts on a chain from the node's IPC sockets, as they're accepted
+// The stream keeps reading from the sockets even while nobody receives from its channel, as the node stalls consensus
+// on the chain while its sockets are full, so containers queue up in memory until they're received.
+type AcceptanceStream struct {
+	chainID string
+
+	clients map[EventType]*socket.Client
+
+	// The directory holding the short links to the sockets, which paths on the test volume are too long to dial directly
+	linksDirpath string
+
+	containers chan AcceptedContainer
+
+	mutex *sync.Mutex
+	cond  *sync.Cond
+
+	// Read from the sockets but not yet received from the channel, in the order they were read
+	pending []AcceptedContainer
+
+	runningReaders int
+	closed         bool
+
+	// The first error hit reading from a socket, if any
+	err error
+
+	stopChan chan struct{}
+}
+
+// NewAcceptanceStream asks the node to publish the chain on its IPC sockets and starts reading from them
+// Args:
+// 	client: The API client of the node
+// 	chainID: The ID or alias of the chain to publish
+// 	ipcsDirpath: The directory the node creates its IPC sockets in, from the perspective of the testsuite container
+func NewAcceptanceStream(client *apis.Client, chainID string, ipcsDirpath string) (*AcceptanceStream, error) {
+	reply, err := client.IpcsAPI().PublishBlockchain(context.Background(), chainID)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "Failed to publish chain %v over IPC", chainID)
+	}
+	linksDirpath, err := os.MkdirTemp("", "ipcs")
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "Failed to create the directory for the links to the IPC sockets")
+	}
+
+	stream := &AcceptanceStream{
+		chainID:      chainID,
+		clients:      make(map[EventType]*socket.Client),
+		linksDirpath: linksDirpath,
+		containers:   make(chan AcceptedContainer),
+		mutex:        &sync.Mutex{},
+		stopChan:     make(chan struct{}),
+	}
+	stream.cond = sync.NewCond(stream.mutex)
+	// The node reports the URLs from the perspective of its own container, where the test volume is mounted elsewhere
+	socketURLs := map[EventType]string{
+		Consensus: reply.ConsensusURL,
+		Decisions: reply.DecisionsURL,
+	}
+	for eventType, socketURL := range socketURLs {
+		socketFilepath := filepath.Join(ipcsDirpath, filepath.Base(socketURL))
+		linkFilepath := filepath.Join(linksDirpath, string(eventType))
+		if err := os.Symlink(socketFilepath, linkFilepath); err != nil {
+			stream.closeClients()
+			return nil, stacktrace.Propagate(err, "Failed to link to the %v socket of chain %v at %v", eventType, chainID, socketFilepath)
+		}
+		socketClient, err := socket.Dial(linkFilepath)
+		if err != nil {
+			stream.closeClients()
+			return nil, stacktrace.Propagate(err, "Failed to connect to the %v socket of chain %v at %v", eventType, chainID, socketFilepath)
+		}
+		stream.clients[eventType] = socketClient
+	}
+
+	stream.runningReaders = len(stream.clients)
+	for eventType, socketClient := range stream.clients {
+		go stream.read(eventType, socketClient)
+	}
+	go stream.forward()
+	return stream, nil
+}
+
+// Containers returns the channel that the accepted containers are delivered on, consensus and decision events alike,
+// in the order they were read. The channel is closed once the stream is closed or a socket can't be read any more.
+func (stream *AcceptanceStream) Containers() <-chan AcceptedContainer {
+	return stream.containers
+}
+
+// Err returns the error that stopped the stream, or nil if it's still running or was closed
+func (stream *AcceptanceStream) Err() error {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+	return stream.err
+}
+
+// WaitForDecisions receives from the stream until all the containers with the given IDs have been decided, skipping
+// consensus events and unrelated decisions
+// Args:
+// 	containerIDs: The IDs of the containers to wait for
+// 	timeout: How long to wait for the next of them to be decided, as in polling for each one's status in turn
+// Returns:
+// 	The containers with the given IDs, in the order they were accepted
+func (stream *AcceptanceStream) WaitForDecisions(containerIDs []ids.ID, timeout time.Duration) ([]AcceptedContainer, error) {
+	remaining := ids.NewSet(len(containerIDs))
+	remaining.Add(containerIDs...)
+	decided := make([]AcceptedContainer, 0, remaining.Len())
+	deadline := time.Now().Add(timeout)
+	for remaining.Len() > 0 {
+		select {
+		case container, open := <-stream.containers:
+			if !open {
+				if err := stream.Err(); err != nil {
+					return nil, stacktrace.Propagate(err, "The stream of chain %v stopped before %v of the containers were decided", stream.chainID, remaining.Len())
+				}
+				return nil, stacktrace.NewError("The stream of chain %v was closed before %v of the containers were decided", stream.chainID, remaining.Len())
+			}
+			if container.EventType != Decisions || !remaining.Contains(container.ID) {
+				continue
+			}
+			remaining.Remove(container.ID)
+			decided = append(decided, container)
+			deadline = time.Now().Add(timeout)
+		case <-time.After(time.Until(deadline)):
+			return nil, stacktrace.NewError("Timed out after %v without another of the containers being decided on chain %v; %v remain, e.g. %v", timeout, stream.chainID, remaining.Len(), remaining.List()[0])
+		}
+	}
+	return decided, nil
+}
+
+// Close stops reading from the node's sockets and closes the stream's channel
+// The node keeps publishing the chain, so that other streams on it aren't affected.
+func (stream *AcceptanceStream) Close() error {
+	stream.mutex.Lock()
+	if stream.closed {
+		stream.mutex.Unlock()
+		return nil
+	}
+	stream.closed = true
+	close(stream.stopChan)
+	stream.mutex.Unlock()
+	return stream.closeClients()
+}
+
+// ================ Helper functions =========================
+/*
+Reads the messages from one of the sockets into the pending containers until the socket can't be read any more
+*/
+func (stream *AcceptanceStream) read(eventType EventType, socketClient *socket.Client) {
+	for {
+		message, err := socketClient.Recv()
+		stream.mutex.Lock()
+		if err != nil {
+			isFirstFailure := !stream.closed && stream.err == nil
+			if isFirstFailure {
+				stream.err = stacktrace.Propagate(err, "Failed to read from the %v socket of chain %v", eventType, stream.chainID)
+			}
+			stream.runningReaders--
+			stream.cond.Broadcast()
+			stream.mutex.Unlock()
+			// A stream that only has one of its sockets would silently miss containers, so the other socket is dropped too
+			if isFirstFailure {
+				for _, otherClient := range stream.clients {
+					otherClient.Close()
+				}
+			}
+			return
+		}
+		stream.pending = append(stream.pending, AcceptedContainer{
+			EventType:  eventType,
+			ID:         hashing.ComputeHash256Array(message),
+			Bytes:      message,
+			AcceptedAt: time.Now(),
+		})
+		stream.cond.Broadcast()
+		stream.mutex.Unlock()
+	}
+}
+
+/*
+Delivers the pending containers on the channel, closing it once all of them have been delivered after the readers have
+	stopped, or as soon as the stream is closed
+*/
+func (stream *AcceptanceStream) forward() {
+	defer close(stream.containers)
+	for {
+		stream.mutex.Lock()
+		for len(stream.pending) == 0 && stream.runningReaders > 0 {
+			stream.cond.Wait()
+		}
+		if len(stream.pending) == 0 {
+			stream.mutex.Unlock()
+			return
+		}
+		next := stream.pending[0]
+		stream.pending = stream.pending[1:]
+		stream.mutex.Unlock()
+
+		select {
+		case stream.containers <- next:
+		case <-stream.stopChan:
+			return
+		}
+	}
+}
+
+func (stream *AcceptanceStream) closeClients() error {
+	var firstErr error
+	for eventType, socketClient := range stream.clients {
+		// A reader that failed has already closed the sockets
+		if err := socketClient.Close(); err != nil && !errors.Is(err, net.ErrClosed) && firstErr == nil {
+			firstErr = stacktrace.Propagate(err, "Failed to close the %v socket of chain %v", eventType, stream.chainID)
+		}
+	}
+	if err := os.RemoveAll(stream.linksDirpath); err != nil {
+		logrus.Warnf("Failed to remove the links to the IPC sockets of chain %v at %v: %v", stream.chainID, stream.linksDirpath, err)
+	}
+	return firstErr
+}
@@ -0,0 +1,187 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package ipcs
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/chain4travel/camino-testing/camino_client/apis"
+	"github.com/chain4travel/camino-testing/camino_client/apis/apistest"
+	"github.com/chain4travel/caminogo/ids"
+	"github.com/chain4travel/caminogo/utils/hashing"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testChainID = "X"
+
+	// Where the node's container has the IPCs directory mounted, which differs from where the test sees it
+	testNodeIPCsDirpath = "/shared/ipcs-node"
+
+	testTimeout = 5 * time.Second
+)
+
+func TestStreamDeliversAcceptedContainers(t *testing.T) {
+	node := newTestIPCNode(t)
+	stream, err := NewAcceptanceStream(node.client, testChainID, node.ipcsDirpath)
+	assert.NoError(t, err)
+	defer stream.Close()
+	consensusConn := node.accept(t, Consensus)
+	decisionsConn := node.accept(t, Decisions)
+
+	vertex := []byte("vertex")
+	tx := []byte("tx")
+	writeTestMessage(t, consensusConn, vertex)
+	writeTestMessage(t, decisionsConn, tx)
+
+	received := map[EventType]AcceptedContainer{}
+	for len(received) < 2 {
+		container := receiveTestContainer(t, stream)
+		received[container.EventType] = container
+	}
+	assert.Equal(t, ids.ID(hashing.ComputeHash256Array(vertex)), received[Consensus].ID)
+	assert.Equal(t, vertex, received[Consensus].Bytes)
+	assert.Equal(t, ids.ID(hashing.ComputeHash256Array(tx)), received[Decisions].ID)
+	assert.WithinDuration(t, time.Now(), received[Decisions].AcceptedAt, testTimeout)
+
+	// The stream stops once it's closed
+	assert.NoError(t, stream.Close())
+	_, open := <-stream.Containers()
+	assert.False(t, open)
+	assert.NoError(t, stream.Err())
+}
+
+func TestWaitForDecisionsReturnsAcceptanceOrder(t *testing.T) {
+	node := newTestIPCNode(t)
+	stream, err := NewAcceptanceStream(node.client, testChainID, node.ipcsDirpath)
+	assert.NoError(t, err)
+	defer stream.Close()
+	consensusConn := node.accept(t, Consensus)
+	decisionsConn := node.accept(t, Decisions)
+
+	first, second := []byte("first"), []byte("second")
+	firstID, secondID := ids.ID(hashing.ComputeHash256Array(first)), ids.ID(hashing.ComputeHash256Array(second))
+	// Consensus events and unrelated decisions are skipped
+	writeTestMessage(t, consensusConn, first)
+	writeTestMessage(t, decisionsConn, []byte("unrelated"))
+	writeTestMessage(t, decisionsConn, first)
+	writeTestMessage(t, decisionsConn, second)
+
+	decided, err := stream.WaitForDecisions([]ids.ID{secondID, firstID}, testTimeout)
+	assert.NoError(t, err)
+	assert.Len(t, decided, 2)
+	assert.Equal(t, firstID, decided[0].ID)
+	assert.Equal(t, secondID, decided[1].ID)
+	assert.False(t, decided[1].AcceptedAt.Before(decided[0].AcceptedAt))
+
+	_, err = stream.WaitForDecisions([]ids.ID{{1}}, 10*time.Millisecond)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Timed out")
+}
+
+func TestStreamReportsNodeHangingUp(t *testing.T) {
+	node := newTestIPCNode(t)
+	stream, err := NewAcceptanceStream(node.client, testChainID, node.ipcsDirpath)
+	assert.NoError(t, err)
+	defer stream.Close()
+	node.accept(t, Consensus).Close()
+	node.accept(t, Decisions)
+
+	_, err = stream.WaitForDecisions([]ids.ID{{1}}, testTimeout)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Failed to read from the consensus socket")
+	assert.Error(t, stream.Err())
+	assert.NoError(t, stream.Close())
+}
+
+// testIPCNode stands in for a node that publishes the X Chain on its IPC sockets
+type testIPCNode struct {
+	client      *apis.Client
+	ipcsDirpath string
+	listeners   map[EventType]net.Listener
+}
+
+/*
+Starts listening on the sockets a node would create for the test chain, in a directory whose path is too long to dial
+	directly, and serves the node's IPCs API
+*/
+func newTestIPCNode(t *testing.T) *testIPCNode {
+	ipcsDirpath := filepath.Join(t.TempDir(), "a-directory-whose-path-is-long-enough-to-exceed-the-unix-socket-path-limit")
+	assert.NoError(t, os.MkdirAll(ipcsDirpath, 0755))
+	node := &testIPCNode{
+		ipcsDirpath: ipcsDirpath,
+		listeners:   map[EventType]net.Listener{},
+	}
+	for _, eventType := range []EventType{Consensus, Decisions} {
+		// Listening on a relative path gets around the length limit
+		listener, err := listenInDir(ipcsDirpath, getTestSocketName(eventType))
+		assert.NoError(t, err)
+		t.Cleanup(func() { listener.Close() })
+		node.listeners[eventType] = listener
+	}
+
+	node.client = apistest.NewFakeNode(t).
+		On("ipcs.publishBlockchain", apistest.Resultf(
+			`{"consensusURL": "%v", "decisionsURL": "%v"}`,
+			filepath.Join(testNodeIPCsDirpath, getTestSocketName(Consensus)),
+			filepath.Join(testNodeIPCsDirpath, getTestSocketName(Decisions)))).
+		GetClient()
+	return node
+}
+
+func (node *testIPCNode) accept(t *testing.T, eventType EventType) net.Conn {
+	conn, err := node.listeners[eventType].Accept()
+	assert.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func listenInDir(dirpath string, name string) (net.Listener, error) {
+	workingDirpath, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chdir(dirpath); err != nil {
+		return nil, err
+	}
+	defer os.Chdir(workingDirpath)
+	return net.Listen("unix", name)
+}
+
+func getTestSocketName(eventType EventType) string {
+	return fmt.Sprintf("12345-%v-%v", testChainID, eventType)
+}
+
+/*
+Writes a message the way the node does, prefixed with its length
+*/
+func writeTestMessage(t *testing.T, conn net.Conn, message []byte) {
+	lengthBytes := make([]byte, 8)
+	binary.BigEndian.PutUint64(lengthBytes, uint64(len(message)))
+	_, err := conn.Write(append(lengthBytes, message...))
+	assert.NoError(t, err)
+}
+
+func receiveTestContainer(t *testing.T, stream *AcceptanceStream) AcceptedContainer {
+	select {
+	case container, open := <-stream.Containers():
+		assert.True(t, open, "The stream should still be open")
+		return container
+	case <-time.After(testTimeout):
+		assert.FailNow(t, "Timed out waiting for a container")
+		return AcceptedContainer{}
+	}
+}
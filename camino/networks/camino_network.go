@@ -12,6 +12,8 @@ package networks
 import (
 	"bytes"
 	"fmt"
+	"path/filepath"
+	"sort"
 	"time"
 
 	"github.com/kurtosis-tech/kurtosis-go/lib/networks"
@@ -20,10 +22,13 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/chain4travel/camino-testing/camino/health"
+	"github.com/chain4travel/camino-testing/camino/ipcs"
 	caminoService "github.com/chain4travel/camino-testing/camino/services"
 	"github.com/chain4travel/camino-testing/camino/services/certs"
 	"github.com/chain4travel/camino-testing/camino_client/apis"
 	"github.com/chain4travel/camino-testing/utils/constants"
+	"github.com/chain4travel/camino-testing/utils/random"
 
 	"github.com/palantir/stacktrace"
 )
@@ -34,6 +39,9 @@ const (
 
 	// The prefix for boot node service IDs, with an integer appended to specify each one
 	bootNodeServiceIDPrefix string = "boot-node-"
+
+	// Marks a service configuration whose services don't take the identity of a boot node
+	noBootNodeIdentity = -1
 )
 
 // ========================================================================================================
@@ -49,6 +57,18 @@ type TestCaminoNetwork struct {
 	networks.Network
 
 	svcNetwork *networks.ServiceNetwork
+
+	// Every service that has been part of the network, including removed ones (whose files remain on the test volume)
+	registry *serviceRegistry
+
+	// Polls the health of the network's nodes in the background while the test runs
+	healthMonitor *health.HealthMonitor
+
+	// The database seeders of the network's configurations
+	dbSeeders map[networks.ConfigurationID]*caminoService.DatabaseSeeder
+
+	// Decides which nodes the services added to the network bootstrap from
+	topology Topology
 }
 
 // GetCaminoClient returns the API Client for the node with the given service ID
@@ -57,22 +77,72 @@ func (network TestCaminoNetwork) GetCaminoClient(serviceID networks.ServiceID) (
 	if err != nil {
 		return nil, stacktrace.Propagate(err, "An error occurred retrieving service node with ID %v", serviceID)
 	}
-	caminoService := node.Service.(caminoService.CaminoService)
-	jsonRPCSocket := caminoService.GetJSONRPCSocket()
-	uri := fmt.Sprintf("http://%s:%d", jsonRPCSocket.GetIpAddr(), jsonRPCSocket.GetPort())
-	return apis.NewClient(uri, constants.DefaultRequestTimeout), nil
+	return newCaminoClient(node.Service.(caminoService.CaminoService)), nil
+}
+
+// GetRunningCaminoClients returns the API Clients for all the nodes currently in the network, keyed by service ID. Unlike
+// GetCaminoClient, it's safe to call concurrently with services being added and removed.
+func (network TestCaminoNetwork) GetRunningCaminoClients() map[networks.ServiceID]*apis.Client {
+	result := make(map[networks.ServiceID]*apis.Client)
+	for serviceID, service := range network.registry.getRunning() {
+		result[serviceID] = newCaminoClient(service)
+	}
+	return result
+}
+
+// GetHealthMonitor returns the monitor that polls the health of the network's nodes while the test runs, which tests
+// can use to permit nodes to become unhealthy or to assert on their health history
+func (network TestCaminoNetwork) GetHealthMonitor() *health.HealthMonitor {
+	return network.healthMonitor
+}
+
+// OpenAcceptanceStream has the running node with the given service ID publish the chain with the given ID or alias on its
+// IPC sockets, and returns a stream of the containers it accepts on the chain from then on
+func (network TestCaminoNetwork) OpenAcceptanceStream(serviceID networks.ServiceID, chainID string) (*ipcs.AcceptanceStream, error) {
+	service, found := network.registry.getRunning()[serviceID]
+	if !found {
+		return nil, stacktrace.NewError("No service with ID %v is running in the network", serviceID)
+	}
+	launchDetails := service.GetLaunchDetails()
+	if launchDetails == nil {
+		return nil, stacktrace.NewError("No launch details were recorded for service with ID %v, so its IPC sockets can't be found", serviceID)
+	}
+	stream, err := ipcs.NewAcceptanceStream(newCaminoClient(service), chainID, launchDetails.GetIPCsDirpath())
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred opening the acceptance stream of chain %v on service with ID %v", chainID, serviceID)
+	}
+	return stream, nil
+}
+
+// GetSuiteExecutionDirpath returns the root of the suite execution volume, where Kurtosis creates the directory of each
+// service and where files that outlive the test (e.g. artifacts and results) can be written
+func (network TestCaminoNetwork) GetSuiteExecutionDirpath() (string, error) {
+	for _, service := range network.registry.getAll() {
+		launchDetails := service.GetLaunchDetails()
+		if launchDetails == nil {
+			continue
+		}
+		// Kurtosis creates the directory for each service at the root of the suite execution volume
+		return filepath.Dir(launchDetails.GetServiceDirpath()), nil
+	}
+	return "", stacktrace.NewError("No launch details were recorded for any service in the network, so the suite execution volume can't be found")
 }
 
 // GetAllBootServiceIDs returns the service IDs of all the boot nodes in the network
 func (network TestCaminoNetwork) GetAllBootServiceIDs() map[networks.ServiceID]bool {
 	result := make(map[networks.ServiceID]bool)
 	for i := 0; i < len(DefaultLocalNetGenesisConfig.Stakers); i++ {
-		bootID := networks.ServiceID(bootNodeServiceIDPrefix + strconv.Itoa(i))
-		result[bootID] = true
+		result[GetBootServiceID(i)] = true
 	}
 	return result
 }
 
+// GetBootServiceID returns the service ID of the boot node with the given index, whose identity is the genesis staker
+// with that index
+func GetBootServiceID(bootNodeIndex int) networks.ServiceID {
+	return networks.ServiceID(bootNodeServiceIDPrefix + strconv.Itoa(bootNodeIndex))
+}
+
 // AddService adds a service to the test Camino network, using the given configuration
 // Args:
 // 		configurationID: The ID of the configuration to use for the service being added
@@ -80,10 +150,17 @@ func (network TestCaminoNetwork) GetAllBootServiceIDs() map[networks.ServiceID]b
 // Returns:
 // 		An availability checker that will return true when teh newly-added service is available
 func (network TestCaminoNetwork) AddService(configurationID networks.ConfigurationID, serviceID networks.ServiceID) (*services.ServiceAvailabilityChecker, error) {
-	availabilityChecker, err := network.svcNetwork.AddService(configurationID, serviceID, network.GetAllBootServiceIDs())
+	bootstrapperIDs, err := network.topology.GetBootstrappers(serviceID, network.registry.getRunningInLaunchOrder())
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred getting the services that service with ID %v should bootstrap from", serviceID)
+	}
+	availabilityChecker, err := network.svcNetwork.AddService(configurationID, serviceID, bootstrapperIDs)
 	if err != nil {
 		return nil, stacktrace.Propagate(err, "An error occurred adding service with service ID %v, configuration ID %v", serviceID, configurationID)
 	}
+	if err := network.trackService(serviceID); err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred tracking newly-added service with ID %v", serviceID)
+	}
 	return availabilityChecker, nil
 }
 
@@ -91,12 +168,135 @@ func (network TestCaminoNetwork) AddService(configurationID networks.Configurati
 // Args:
 // 	serviceID: The ID of the service to remove from the network
 func (network TestCaminoNetwork) RemoveService(serviceID networks.ServiceID) error {
+	// Drop the service from the registry before stopping it, so that monitors don't mistake the removal for a failure
+	network.registry.markRemoved(serviceID)
 	if err := network.svcNetwork.RemoveService(serviceID, containerStopTimeoutSeconds); err != nil {
 		return stacktrace.Propagate(err, "An error occurred removing service with ID %v", serviceID)
 	}
 	return nil
 }
 
+// AddServiceFromDatabase adds a service to the test Camino network like AddService, except that the new service starts
+// with a copy of the database of an earlier service instead of an empty one
+// Args:
+// 		configurationID: The ID of the configuration to use for the service being added
+// 		serviceID: The ID to give the service being added
+// 		sourceServiceID: The ID of the service whose database to copy, which must have been removed from the network already
+// 			so that its database is no longer being written to
+// Returns:
+// 		An availability checker that will return true when the newly-added service is available
+func (network TestCaminoNetwork) AddServiceFromDatabase(
+	configurationID networks.ConfigurationID,
+	serviceID networks.ServiceID,
+	sourceServiceID networks.ServiceID) (*services.ServiceAvailabilityChecker, error) {
+	if _, found := network.registry.getRunning()[sourceServiceID]; found {
+		return nil, stacktrace.NewError("Service with ID %v must be removed from the network before its database can be copied", sourceServiceID)
+	}
+	sourceService, found := network.registry.getAll()[sourceServiceID]
+	if !found {
+		return nil, stacktrace.NewError("No service with ID %v has been part of the network", sourceServiceID)
+	}
+	launchDetails := sourceService.GetLaunchDetails()
+	if launchDetails == nil {
+		return nil, stacktrace.NewError("No launch details were recorded for service with ID %v, so its database can't be found", sourceServiceID)
+	}
+	dbSeeder, found := network.dbSeeders[configurationID]
+	if !found {
+		return nil, stacktrace.NewError("No database seeder exists for configuration ID %v", configurationID)
+	}
+
+	dbSeeder.SeedNextLaunch(launchDetails.GetDBDirpath())
+	availabilityChecker, err := network.AddService(configurationID, serviceID)
+	if err != nil {
+		// Don't let the seed leak into a later launch
+		dbSeeder.CancelPendingSeed()
+		return nil, stacktrace.Propagate(err, "An error occurred adding service with ID %v from the database of service with ID %v", serviceID, sourceServiceID)
+	}
+	return availabilityChecker, nil
+}
+
+// InjectDiskFault makes the disk of the given service fail in the given way until ClearDiskFaults is called; the
+// service's configuration must have disk fault injection enabled
+func (network TestCaminoNetwork) InjectDiskFault(serviceID networks.ServiceID, fault caminoService.DiskFault) error {
+	service, found := network.registry.getAll()[serviceID]
+	if !found {
+		return stacktrace.NewError("No service with ID %v has been part of the network", serviceID)
+	}
+	if err := caminoService.InjectDiskFault(service.GetLaunchDetails(), fault); err != nil {
+		return stacktrace.Propagate(err, "An error occurred injecting disk fault '%v' into service with ID %v", fault, serviceID)
+	}
+	return nil
+}
+
+// ClearDiskFaults undoes the disk faults injected into the given service, which may have stopped running since
+func (network TestCaminoNetwork) ClearDiskFaults(serviceID networks.ServiceID) error {
+	service, found := network.registry.getAll()[serviceID]
+	if !found {
+		return stacktrace.NewError("No service with ID %v has been part of the network", serviceID)
+	}
+	if err := caminoService.ClearDiskFaults(service.GetLaunchDetails()); err != nil {
+		return stacktrace.Propagate(err, "An error occurred clearing the disk faults of service with ID %v", serviceID)
+	}
+	return nil
+}
+
+// GetServiceIPAddress returns the IP address of the service with the given ID, which is also the address that its peers
+// see it connecting from. Services that have been removed from the network can be looked up too.
+func (network TestCaminoNetwork) GetServiceIPAddress(serviceID networks.ServiceID) (string, error) {
+	service, found := network.registry.getAll()[serviceID]
+	if !found {
+		return "", stacktrace.NewError("No service with ID %v has been part of the network", serviceID)
+	}
+	stakingSocket := service.GetStakingSocket()
+	return stakingSocket.GetIpAddr(), nil
+}
+
+// GetServiceURI returns the URI that the APIs of the service with the given ID are served under, e.g. for tools outside
+// the testsuite to connect to. Services that have been removed from the network can be looked up too.
+func (network TestCaminoNetwork) GetServiceURI(serviceID networks.ServiceID) (string, error) {
+	service, found := network.registry.getAll()[serviceID]
+	if !found {
+		return "", stacktrace.NewError("No service with ID %v has been part of the network", serviceID)
+	}
+	return getServiceURI(service), nil
+}
+
+// GetServiceLaunchDetails returns where the files of the service with the given ID live on the test volume and the command
+// it was started with. Services that have been removed from the network can be looked up too.
+func (network TestCaminoNetwork) GetServiceLaunchDetails(serviceID networks.ServiceID) (*caminoService.CaminoServiceLaunchDetails, error) {
+	service, found := network.registry.getAll()[serviceID]
+	if !found {
+		return nil, stacktrace.NewError("No service with ID %v has been part of the network", serviceID)
+	}
+	launchDetails := service.GetLaunchDetails()
+	if launchDetails == nil {
+		return nil, stacktrace.NewError("No launch details were recorded for service with ID %v", serviceID)
+	}
+	return launchDetails, nil
+}
+
+// trackService records the service with the given ID so that it can be reached even after it's removed from the network
+func (network TestCaminoNetwork) trackService(serviceID networks.ServiceID) error {
+	node, err := network.svcNetwork.GetService(serviceID)
+	if err != nil {
+		return stacktrace.Propagate(err, "An error occurred retrieving service node with ID %v", serviceID)
+	}
+	network.registry.add(serviceID, node.Service.(caminoService.CaminoService))
+	return nil
+}
+
+func newCaminoClient(service caminoService.CaminoService) *apis.Client {
+	return apis.NewClient(getServiceURI(service), constants.DefaultRequestTimeout)
+}
+
+/*
+Gets the URI that the APIs of the service are served under
+*/
+func getServiceURI(service caminoService.CaminoService) string {
+	jsonRPCSocket := service.GetJSONRPCSocket()
+	return fmt.Sprintf("http://%s:%d", jsonRPCSocket.GetIpAddr(), jsonRPCSocket.GetPort())
+}
+
 // ========================================================================================================
 //                                    Camino Service Config
 // ========================================================================================================
@@ -126,6 +326,22 @@ type TestCaminoNetworkServiceConfig struct {
 	// TODO Make these named parameters, so we don't have an arbitrary bag of extra CLI args!
 	// A list of extra CLI args that should be passed to the Camino services started with this configuration
 	additionalCLIArgs map[string]string
+
+	// The index of the genesis boot node whose cert the Camino services started with this configuration use, or
+	// noBootNodeIdentity if they get random certs
+	bootNodeIdentity int
+
+	// Provides the certs of the Camino services started with this configuration, if they don't get random certs
+	certProvider certs.CaminoCertProvider
+
+	// How far ahead of the host's clock the clocks of the Camino services started with this configuration run
+	clockOffset time.Duration
+
+	// The kind of database the Camino services started with this configuration use; the node's default if empty
+	dbType caminoService.DatabaseType
+
+	// Whether disk faults can be injected into the Camino services started with this configuration
+	diskFaultInjection bool
 }
 
 // NewTestCaminoNetworkServiceConfig creates a new Camino network service config with the given parameters
@@ -154,9 +370,47 @@ func NewTestCaminoNetworkServiceConfig(
 		snowSampleSize:        snowSampleSize,
 		networkInitialTimeout: networkInitialTimeout,
 		additionalCLIArgs:     additionalCLIArgs,
+		bootNodeIdentity:      noBootNodeIdentity,
 	}
 }
 
+// WithBootNodeIdentity makes the Camino services started with this configuration use the cert of the genesis boot node
+// with the given index, so that they have the same node ID as that boot node (used for testing how the network behaves
+// when a genesis validator's identity is duplicated). The varyCerts setting is ignored for such configurations.
+func (config *TestCaminoNetworkServiceConfig) WithBootNodeIdentity(bootNodeIndex int) *TestCaminoNetworkServiceConfig {
+	config.bootNodeIdentity = bootNodeIndex
+	return config
+}
+
+// WithCertProvider makes the Camino services started with this configuration get their certs from the given provider, for
+// testing how the network behaves with certs of other kinds. The varyCerts setting is ignored for such configurations.
+func (config *TestCaminoNetworkServiceConfig) WithCertProvider(certProvider certs.CaminoCertProvider) *TestCaminoNetworkServiceConfig {
+	config.certProvider = certProvider
+	return config
+}
+
+// WithClockOffset makes the Camino services started with this configuration run their clocks offset from the host's by
+// the given amount (negative to lag behind), for testing how the network behaves when its nodes' clocks disagree. The
+// configuration's image must have been built by scripts/build_clock_skew_image.sh, as stock images ignore the offset.
+func (config *TestCaminoNetworkServiceConfig) WithClockOffset(clockOffset time.Duration) *TestCaminoNetworkServiceConfig {
+	config.clockOffset = clockOffset
+	return config
+}
+
+// WithDatabaseType makes the Camino services started with this configuration keep their state in the given kind of
+// database, rather than the node's default
+func (config *TestCaminoNetworkServiceConfig) WithDatabaseType(dbType caminoService.DatabaseType) *TestCaminoNetworkServiceConfig {
+	config.dbType = dbType
+	return config
+}
+
+// WithDiskFaultInjection makes it possible to inject faults into the disk of the Camino services started with this
+// configuration with TestCaminoNetwork.InjectDiskFault. They must keep their state on disk.
+func (config *TestCaminoNetworkServiceConfig) WithDiskFaultInjection() *TestCaminoNetworkServiceConfig {
+	config.diskFaultInjection = true
+	return config
+}
+
 // ========================================================================================================
 //                                Camino Test Network Loader
 // ========================================================================================================
@@ -190,6 +444,18 @@ type TestCaminoNetworkLoader struct {
 
 	// The initial timeout for the network
 	networkInitialTimeout time.Duration
+
+	// The database seeders of the network's configurations, filled in when the network is configured
+	dbSeeders map[networks.ConfigurationID]*caminoService.DatabaseSeeder
+
+	// Decides which nodes each node of the network bootstraps from
+	topology Topology
+
+	// Extra CLI args that are passed as-is to the boot nodes
+	bootNodeAdditionalCLIArgs map[string]string
+
+	// The snapshot the network is restored from, if it's not set up from scratch
+	snapshot *NetworkSnapshot
 }
 
 // NewTestCaminoNetworkLoader creates a new loader to create a TestCaminoNetwork with the specified parameters, transparently handling the creation
@@ -248,16 +514,40 @@ func NewTestCaminoNetworkLoader(
 		bootstrapperSnowSampleSize: bootstrapperSnowSampleSize,
 		txFee:                      txFee,
 		networkInitialTimeout:      networkInitialTimeout,
+		dbSeeders:                  make(map[networks.ConfigurationID]*caminoService.DatabaseSeeder),
+		topology:                   FullTopology{},
+		bootNodeAdditionalCLIArgs:  make(map[string]string),
 	}, nil
 }
 
+// WithTopology makes the network bootstrap its nodes, including the boot nodes and the nodes added while the test runs,
+// according to the given topology rather than the default FullTopology
+// NOTE: The non-boot services the network initializes with are launched in order of their service IDs
+func (loader *TestCaminoNetworkLoader) WithTopology(topology Topology) *TestCaminoNetworkLoader {
+	loader.topology = topology
+	return loader
+}
+
+// WithBootNodeCLIArgs passes the given extra CLI args as-is to the boot nodes, which otherwise get none (used for tuning
+// consensus parameters that the loader doesn't take explicitly, for the whole network)
+func (loader *TestCaminoNetworkLoader) WithBootNodeCLIArgs(additionalCLIArgs map[string]string) *TestCaminoNetworkLoader {
+	loader.bootNodeAdditionalCLIArgs = make(map[string]string)
+	for param, argument := range additionalCLIArgs {
+		loader.bootNodeAdditionalCLIArgs[param] = argument
+	}
+	return loader
+}
+
+// WithSnapshot makes the network start from the given snapshot, taking it first if no test of the suite run has yet
+// NOTE: The snapshot must be taken from a network loaded with the same parameters, which its name is trusted to tell
+func (loader *TestCaminoNetworkLoader) WithSnapshot(snapshot *NetworkSnapshot) *TestCaminoNetworkLoader {
+	loader.snapshot = snapshot
+	return loader
+}
+
 // ConfigureNetwork defines the netwrok's service configurations to be used
 func (loader TestCaminoNetworkLoader) ConfigureNetwork(builder *networks.ServiceNetworkBuilder) error {
 	localNetGenesisStakers := DefaultLocalNetGenesisConfig.Stakers
-	bootNodeIDs := make([]string, 0, len(localNetGenesisStakers))
-	for _, staker := range DefaultLocalNetGenesisConfig.Stakers {
-		bootNodeIDs = append(bootNodeIDs, staker.NodeID)
-	}
 
 	// Add boot node configs
 	for i := 0; i < len(DefaultLocalNetGenesisConfig.Stakers); i++ {
@@ -275,11 +565,11 @@ func (loader TestCaminoNetworkLoader) ConfigureNetwork(builder *networks.Service
 			loader.txFee,
 			loader.isStaking,
 			loader.networkInitialTimeout,
-			make(map[string]string), // No additional CLI args for the default network
-			bootNodeIDs[0:i],        // Only the node IDs of the already-started nodes
+			loader.bootNodeAdditionalCLIArgs,
 			certs.NewStaticCaminoCertProvider(*keyBytes, *certBytes),
 			loader.bootNodeLogLevel,
 		)
+		loader.dbSeeders[configID] = initializerCore.GetDatabaseSeeder()
 		availabilityCheckerCore := caminoService.CaminoServiceAvailabilityCheckerCore{}
 
 		if err := builder.AddConfiguration(configID, loader.bootNodeImage, initializerCore, availabilityCheckerCore); err != nil {
@@ -289,8 +579,31 @@ func (loader TestCaminoNetworkLoader) ConfigureNetwork(builder *networks.Service
 
 	// Add user-custom configs
 	for configID, configParams := range loader.serviceConfigs {
-		certProvider := certs.NewRandomCaminoCertProvider(configParams.varyCerts)
+		// Scoped by the config ID, so that a config's nodes get the same identities from the same seed
+		var certProvider certs.CaminoCertProvider = certs.NewRandomCaminoCertProvider(
+			configParams.varyCerts,
+			random.NewRand("certs/"+string(configID)))
+		if configParams.certProvider != nil {
+			if configParams.bootNodeIdentity != noBootNodeIdentity {
+				return stacktrace.NewError("Configuration with ID %v can't both take a boot node's identity and use a cert provider", configID)
+			}
+			certProvider = configParams.certProvider
+		}
+		if configParams.bootNodeIdentity != noBootNodeIdentity {
+			if configParams.bootNodeIdentity < 0 || configParams.bootNodeIdentity >= len(localNetGenesisStakers) {
+				return stacktrace.NewError(
+					"Configuration with ID %v takes the identity of boot node %v, but there are only %v boot nodes",
+					configID,
+					configParams.bootNodeIdentity,
+					len(localNetGenesisStakers))
+			}
+			identity := localNetGenesisStakers[configParams.bootNodeIdentity]
+			certProvider = certs.NewStaticCaminoCertProvider(*bytes.NewBufferString(identity.PrivateKey), *bytes.NewBufferString(identity.TLSCert))
+		}
 		imageName := configParams.imageName
+		if configParams.diskFaultInjection && configParams.dbType == caminoService.MemDB {
+			return stacktrace.NewError("Configuration with ID %v can't inject disk faults into nodes that keep their state in memory", configID)
+		}
 
 		initializerCore := caminoService.NewCaminoServiceInitializerCore(
 			configParams.snowSampleSize,
@@ -299,10 +612,13 @@ func (loader TestCaminoNetworkLoader) ConfigureNetwork(builder *networks.Service
 			loader.isStaking,
 			configParams.networkInitialTimeout,
 			configParams.additionalCLIArgs,
-			bootNodeIDs,
 			certProvider,
 			configParams.serviceLogLevel,
-		)
+		).WithClockOffset(configParams.clockOffset).WithDatabaseType(configParams.dbType)
+		if configParams.diskFaultInjection {
+			initializerCore.WithDiskFaultInjection()
+		}
+		loader.dbSeeders[configID] = initializerCore.GetDatabaseSeeder()
 		availabilityCheckerCore := caminoService.CaminoServiceAvailabilityCheckerCore{}
 		if err := builder.AddConfiguration(configID, imageName, initializerCore, availabilityCheckerCore); err != nil {
 			return stacktrace.Propagate(err, "An error occurred adding Camino node configuration with ID %v", configID)
@@ -316,38 +632,92 @@ func (loader TestCaminoNetworkLoader) ConfigureNetwork(builder *networks.Service
 // NOTE: The resulting services.ServiceAvailabilityChecker map will contain more IDs than the user requested as it will
 // 		contain boot nodes. The IDs that these boot nodes are an unspecified implementation detail.
 func (loader TestCaminoNetworkLoader) InitializeNetwork(network *networks.ServiceNetwork) (map[networks.ServiceID]services.ServiceAvailabilityChecker, error) {
-	availabilityCheckers := make(map[networks.ServiceID]services.ServiceAvailabilityChecker)
+	if loader.snapshot != nil {
+		return loader.initializeNetworkFromSnapshot(network)
+	}
+	return loader.launchInitialServices(network, nil)
+}
 
-	// Add the bootstrapper nodes
-	bootstrapperServiceIDs := make(map[networks.ServiceID]bool)
-	for i := 0; i < len(DefaultLocalNetGenesisConfig.Stakers); i++ {
-		configID := networks.ConfigurationID(bootNodeConfigIDPrefix + strconv.Itoa(i))
-		serviceID := networks.ServiceID(bootNodeServiceIDPrefix + strconv.Itoa(i))
-		checker, err := network.AddService(configID, serviceID, bootstrapperServiceIDs)
-		if err != nil {
-			return nil, stacktrace.Propagate(err, "Error occurred when adding boot node with ID %v and config ID %v", serviceID, configID)
+// WrapNetwork implements a networks.NetworkLoader function and wraps the underlying networks.ServiceNetwork with the TestCaminoNetwork
+func (loader TestCaminoNetworkLoader) WrapNetwork(network *networks.ServiceNetwork) (networks.Network, error) {
+	wrappedNetwork := TestCaminoNetwork{
+		svcNetwork: network,
+		registry:   newServiceRegistry(),
+		dbSeeders:  loader.dbSeeders,
+		topology:   loader.topology,
+	}
+	wrappedNetwork.healthMonitor = health.NewHealthMonitor(wrappedNetwork, health.DefaultPollInterval)
+	// Tracked in launch order, so that the topology sees the same order for the services added later
+	for _, initialService := range loader.getInitialServices() {
+		if err := wrappedNetwork.trackService(initialService.serviceID); err != nil {
+			return nil, stacktrace.Propagate(err, "An error occurred tracking node with ID %v", initialService.serviceID)
 		}
-
-		// TODO the first node should have zero dependencies and the rest should
-		// have only the first node as a dependency
-		bootstrapperServiceIDs[serviceID] = true
-		availabilityCheckers[serviceID] = *checker
 	}
+	return wrappedNetwork, nil
+}
 
-	// Additional user defined nodes
-	for serviceID, configID := range loader.desiredServiceConfig {
-		checker, err := network.AddService(configID, serviceID, bootstrapperServiceIDs)
+// initialService is a service that the network initializes with, along with the configuration it's launched from
+type initialService struct {
+	serviceID networks.ServiceID
+	configID  networks.ConfigurationID
+}
+
+/*
+Launches the services the network initializes with, bootstrapping each according to the topology
+
+Args:
+	network: The network to launch the services in
+	beforeLaunch: If non-nil, called right before each service is launched (e.g. to seed its database)
+*/
+func (loader TestCaminoNetworkLoader) launchInitialServices(
+	network *networks.ServiceNetwork,
+	beforeLaunch func(initialService) error) (map[networks.ServiceID]services.ServiceAvailabilityChecker, error) {
+	availabilityCheckers := make(map[networks.ServiceID]services.ServiceAvailabilityChecker)
+	launchedServiceIDs := make([]networks.ServiceID, 0, len(DefaultLocalNetGenesisConfig.Stakers)+len(loader.desiredServiceConfig))
+	for _, service := range loader.getInitialServices() {
+		bootstrapperIDs, err := loader.topology.GetBootstrappers(service.serviceID, launchedServiceIDs)
 		if err != nil {
-			return nil, stacktrace.Propagate(err, "Error occurred when adding non-boot node with ID %v and config ID %v", serviceID, configID)
+			return nil, stacktrace.Propagate(err, "Error occurred getting the services that the node with ID %v should bootstrap from", service.serviceID)
 		}
-		availabilityCheckers[serviceID] = *checker
+		if beforeLaunch != nil {
+			if err := beforeLaunch(service); err != nil {
+				return nil, stacktrace.Propagate(err, "Error occurred preparing the launch of node with ID %v", service.serviceID)
+			}
+		}
+		checker, err := network.AddService(service.configID, service.serviceID, bootstrapperIDs)
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "Error occurred when adding node with ID %v and config ID %v", service.serviceID, service.configID)
+		}
+		launchedServiceIDs = append(launchedServiceIDs, service.serviceID)
+		availabilityCheckers[service.serviceID] = *checker
 	}
 	return availabilityCheckers, nil
 }
 
-// WrapNetwork implements a networks.NetworkLoader function and wraps the underlying networks.ServiceNetwork with the TestCaminoNetwork
-func (loader TestCaminoNetworkLoader) WrapNetwork(network *networks.ServiceNetwork) (networks.Network, error) {
-	return TestCaminoNetwork{
-		svcNetwork: network,
-	}, nil
+/*
+Gets the services the network initializes with, in the order they're launched: the boot nodes, then the additional user
+defined nodes in order of their service IDs
+*/
+func (loader TestCaminoNetworkLoader) getInitialServices() []initialService {
+	result := make([]initialService, 0, len(DefaultLocalNetGenesisConfig.Stakers)+len(loader.desiredServiceConfig))
+	for i := 0; i < len(DefaultLocalNetGenesisConfig.Stakers); i++ {
+		result = append(result, initialService{
+			serviceID: GetBootServiceID(i),
+			configID:  networks.ConfigurationID(bootNodeConfigIDPrefix + strconv.Itoa(i)),
+		})
+	}
+	desiredServiceIDs := make([]networks.ServiceID, 0, len(loader.desiredServiceConfig))
+	for serviceID := range loader.desiredServiceConfig {
+		desiredServiceIDs = append(desiredServiceIDs, serviceID)
+	}
+	sort.Slice(desiredServiceIDs, func(i, j int) bool {
+		return desiredServiceIDs[i] < desiredServiceIDs[j]
+	})
+	for _, serviceID := range desiredServiceIDs {
+		result = append(result, initialService{
+			serviceID: serviceID,
+			configID:  loader.desiredServiceConfig[serviceID],
+		})
+	}
+	return result
 }
@@ -0,0 +1,51 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package networks
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kurtosis-tech/kurtosis-go/lib/services"
+
+	caminoService "github.com/chain4travel/camino-testing/camino/services"
+	"github.com/chain4travel/camino-testing/camino/services/certs"
+	"github.com/stretchr/testify/assert"
+)
+
+// The boot nodes are bootstrapped from by the node IDs derived from their certs, so these must be the genesis node IDs
+func TestBootNodeCertsHaveGenesisNodeIDs(t *testing.T) {
+	for _, staker := range DefaultLocalNetGenesisConfig.Stakers {
+		initializerCore := caminoService.NewCaminoServiceInitializerCore(
+			2,
+			2,
+			0,
+			true,
+			2*time.Second,
+			make(map[string]string),
+			certs.NewStaticCaminoCertProvider(*bytes.NewBufferString(staker.PrivateKey), *bytes.NewBufferString(staker.TLSCert)),
+			caminoService.INFO,
+		)
+		osFiles := map[string]*os.File{}
+		for fileID := range initializerCore.GetFilesToMount() {
+			file, err := os.Create(filepath.Join(t.TempDir(), fileID))
+			assert.NoError(t, err, "An error occurred creating file %v", fileID)
+			defer file.Close()
+			osFiles[fileID] = file
+		}
+		assert.NoError(t, initializerCore.InitializeMountedFiles(osFiles, make([]services.Service, 0)))
+
+		service := initializerCore.GetServiceFromIp("1.2.3.4").(caminoService.CaminoService)
+		assert.Equal(t, staker.NodeID, service.GetLaunchDetails().GetNodeID())
+	}
+}
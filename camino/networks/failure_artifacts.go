@@ -0,0 +1,182 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package networks
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	caminoService "github.com/chain4travel/camino-testing/camino/services"
+	"github.com/chain4travel/camino-testing/utils/random"
+	"github.com/kurtosis-tech/kurtosis-go/lib/networks"
+	"github.com/palantir/stacktrace"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// The directory, at the root of the suite execution volume, where failure artifacts are written
+	failureArtifactsDirname = "failure-artifacts"
+
+	// Names of the entries inside each service's artifact archive
+	configArtifactName       = "node-config.json"
+	startCommandArtifactName = "start-command.txt"
+	seedArtifactName         = "seed.txt"
+	logsArtifactDirname      = "logs"
+	profilesArtifactDirname  = "profiles"
+
+	artifactDirPerms  = 0755
+	artifactFilePerms = 0644
+)
+
+// CollectFailureArtifacts gathers the logs, config, start command, suite seed and any profiles of every service that has been part of the network
+// (including removed ones) into one gzipped tarball per service, so that CI can publish them when a test fails. Archives
+// are written to the failure-artifacts directory at the root of the suite execution volume and are named
+// <testName>_<serviceID>.tar.gz.
+// Collection is best-effort: a service whose artifacts can't be gathered is logged and skipped, and an error listing all
+// such services is returned at the end.
+func (network TestCaminoNetwork) CollectFailureArtifacts(testName string) error {
+	failedServiceIDs := []string{}
+	for serviceID, service := range network.registry.getAll() {
+		launchDetails := service.GetLaunchDetails()
+		if launchDetails == nil {
+			logrus.Warnf("No launch details were recorded for service with ID %v; skipping its artifacts", serviceID)
+			continue
+		}
+		archiveFilepath, err := writeServiceArtifactArchive(
+			failureArtifactsDirname,
+			testName,
+			serviceID,
+			*launchDetails,
+			writeServiceArtifacts)
+		if err != nil {
+			logrus.Errorf("An error occurred collecting the artifacts of service with ID %v:", serviceID)
+			fmt.Fprintln(logrus.StandardLogger().Out, err)
+			failedServiceIDs = append(failedServiceIDs, string(serviceID))
+			continue
+		}
+		logrus.Infof("Wrote failure artifacts for service with ID %v to %v", serviceID, archiveFilepath)
+	}
+	if len(failedServiceIDs) > 0 {
+		return stacktrace.NewError("Failed to collect artifacts for services with IDs %v", strings.Join(failedServiceIDs, ", "))
+	}
+	return nil
+}
+
+// ================ Helper functions =========================
+/*
+Writes an archive for a single service to the given directory at the root of the suite execution volume, filling it using
+	the given function, and returns the filepath of the archive
+*/
+func writeServiceArtifactArchive(
+	artifactsDirname string,
+	testName string,
+	serviceID networks.ServiceID,
+	launchDetails caminoService.CaminoServiceLaunchDetails,
+	writeArtifacts func(*tar.Writer, caminoService.CaminoServiceLaunchDetails) error) (string, error) {
+	// Kurtosis creates the directory for each service at the root of the suite execution volume
+	suiteExecutionDirpath := filepath.Dir(launchDetails.GetServiceDirpath())
+	artifactsDirpath := filepath.Join(suiteExecutionDirpath, artifactsDirname)
+	if err := os.MkdirAll(artifactsDirpath, artifactDirPerms); err != nil {
+		return "", stacktrace.Propagate(err, "An error occurred creating the artifacts directory at %v", artifactsDirpath)
+	}
+
+	archiveFilepath := filepath.Join(artifactsDirpath, fmt.Sprintf("%v_%v.tar.gz", testName, serviceID))
+	archiveFp, err := os.OpenFile(archiveFilepath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, artifactFilePerms)
+	if err != nil {
+		return "", stacktrace.Propagate(err, "An error occurred creating the artifact archive at %v", archiveFilepath)
+	}
+	defer archiveFp.Close()
+	gzipWriter := gzip.NewWriter(archiveFp)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	if err := writeArtifacts(tarWriter, launchDetails); err != nil {
+		return "", stacktrace.Propagate(err, "An error occurred writing the artifacts to archive %v", archiveFilepath)
+	}
+	// The writers only flush their trailers on close, so an error here means the archive is incomplete
+	if err := tarWriter.Close(); err != nil {
+		return "", stacktrace.Propagate(err, "An error occurred finalizing the tar stream of archive %v", archiveFilepath)
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return "", stacktrace.Propagate(err, "An error occurred finalizing the gzip stream of archive %v", archiveFilepath)
+	}
+	return archiveFilepath, nil
+}
+
+func writeServiceArtifacts(tarWriter *tar.Writer, launchDetails caminoService.CaminoServiceLaunchDetails) error {
+	startCommand := strings.Join(launchDetails.GetStartCommand(), " ") + "\n"
+	if err := writeArchiveEntry(tarWriter, startCommandArtifactName, []byte(startCommand)); err != nil {
+		return stacktrace.Propagate(err, "An error occurred archiving the start command")
+	}
+	seed := fmt.Sprintf("%d\n", random.GetSeed())
+	if err := writeArchiveEntry(tarWriter, seedArtifactName, []byte(seed)); err != nil {
+		return stacktrace.Propagate(err, "An error occurred archiving the suite seed")
+	}
+	if err := archiveFile(tarWriter, configArtifactName, launchDetails.GetConfigFilepath()); err != nil {
+		return stacktrace.Propagate(err, "An error occurred archiving the node config")
+	}
+	if err := archiveDirectory(tarWriter, logsArtifactDirname, launchDetails.GetLogDirpath()); err != nil {
+		return stacktrace.Propagate(err, "An error occurred archiving the node logs")
+	}
+	if err := archiveDirectory(tarWriter, profilesArtifactDirname, launchDetails.GetProfileDirpath()); err != nil {
+		return stacktrace.Propagate(err, "An error occurred archiving the node profiles")
+	}
+	return nil
+}
+
+/*
+Adds every regular file under the given directory to the archive, preserving their paths relative to the directory. A
+directory that doesn't exist (e.g. because the node never got far enough to create it) is skipped.
+*/
+func archiveDirectory(tarWriter *tar.Writer, archiveDirname string, dirpath string) error {
+	if _, err := os.Stat(dirpath); os.IsNotExist(err) {
+		logrus.Debugf("Directory %v doesn't exist; skipping it", dirpath)
+		return nil
+	}
+	return filepath.Walk(dirpath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		relativePath, err := filepath.Rel(dirpath, path)
+		if err != nil {
+			return stacktrace.Propagate(err, "An error occurred getting the path of %v relative to %v", path, dirpath)
+		}
+		return archiveFile(tarWriter, filepath.Join(archiveDirname, relativePath), path)
+	})
+}
+
+func archiveFile(tarWriter *tar.Writer, entryName string, fileToArchive string) error {
+	contents, err := os.ReadFile(fileToArchive)
+	if err != nil {
+		return stacktrace.Propagate(err, "An error occurred reading file %v", fileToArchive)
+	}
+	return writeArchiveEntry(tarWriter, entryName, contents)
+}
+
+func writeArchiveEntry(tarWriter *tar.Writer, entryName string, contents []byte) error {
+	header := &tar.Header{
+		Name: entryName,
+		Mode: artifactFilePerms,
+		Size: int64(len(contents)),
+	}
+	if err := tarWriter.WriteHeader(header); err != nil {
+		return stacktrace.Propagate(err, "An error occurred writing the archive header for %v", entryName)
+	}
+	if _, err := tarWriter.Write(contents); err != nil {
+		return stacktrace.Propagate(err, "An error occurred writing the archive entry for %v", entryName)
+	}
+	return nil
+}
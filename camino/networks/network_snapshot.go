@@ -0,0 +1,309 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package networks
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	caminoService "github.com/chain4travel/camino-testing/camino/services"
+	"github.com/kurtosis-tech/kurtosis-go/lib/networks"
+	"github.com/kurtosis-tech/kurtosis-go/lib/services"
+	"github.com/palantir/stacktrace"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// The directory, at the root of the suite execution volume, where snapshots are kept
+	snapshotsDirname = "snapshots"
+
+	// The file, inside a snapshot's directory, that lists the snapshot's nodes; it's written last, so a snapshot without
+	// one is incomplete
+	snapshotManifestFilename = "manifest.json"
+
+	snapshotDirPerms  = 0755
+	snapshotFilePerms = 0644
+)
+
+// NetworkSnapshot is the state of every node of a network, i.e. their databases and staking identities, captured after
+// running some setup on the network. The first test of a suite run that starts from a snapshot runs the setup and takes
+// the snapshot, which then lives on the suite execution volume; every test that starts from it after, including the
+// first, restores its nodes from it instead of rerunning the setup.
+// NOTE: As snapshots only last for the suite run, the network's clock keeps running between taking and restoring them, so
+// the setup must leave enough time until anything it schedules (e.g. the end of a validation period) for the tests to run
+type NetworkSnapshot struct {
+	// Identifies the snapshot, and with it the network parameters and setup it was taken with
+	name string
+
+	// Brings a freshly initialized network to the state that's snapshotted; it must neither add nor remove services
+	setup func(network TestCaminoNetwork) error
+
+	// The directory snapshots are kept in, which is only known once a service has been launched on the suite execution
+	// volume
+	snapshotsDirpath string
+}
+
+// NewNetworkSnapshot creates a snapshot that's taken after running the given setup on a network
+// Args:
+// 	name: The name of the snapshot, which every test that starts from it must use with the same network parameters and
+// 		setup
+// 	setup: Brings a freshly initialized network to the state that's snapshotted; it must neither add nor remove services
+func NewNetworkSnapshot(name string, setup func(network TestCaminoNetwork) error) *NetworkSnapshot {
+	return &NetworkSnapshot{
+		name:  name,
+		setup: setup,
+	}
+}
+
+// snapshotManifest records the nodes a snapshot was taken of, in the order they were launched in
+type snapshotManifest struct {
+	Nodes []snapshotNode `json:"nodes"`
+}
+
+type snapshotNode struct {
+	ServiceID string `json:"serviceId"`
+	ConfigID  string `json:"configId"`
+	NodeID    string `json:"nodeId"`
+}
+
+/*
+Initializes the network from the loader's snapshot, taking the snapshot first if it doesn't exist yet
+*/
+func (loader TestCaminoNetworkLoader) initializeNetworkFromSnapshot(network *networks.ServiceNetwork) (map[networks.ServiceID]services.ServiceAvailabilityChecker, error) {
+	suiteExecutionDirpath, err := loader.findSuiteExecutionDirpath(network)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred finding the suite execution volume to keep snapshot %v on", loader.snapshot.name)
+	}
+	snapshot := *loader.snapshot
+	snapshot.snapshotsDirpath = filepath.Join(suiteExecutionDirpath, snapshotsDirname)
+
+	// Tests run in parallel, so the snapshot is only taken by whichever test gets to it first
+	unlock, err := snapshot.lock()
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred locking snapshot %v", snapshot.name)
+	}
+	defer unlock()
+
+	manifest, err := snapshot.loadManifest()
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred loading the manifest of snapshot %v", snapshot.name)
+	}
+	if manifest == nil {
+		logrus.Infof("Snapshot %v hasn't been taken yet, so the network is set up from scratch to take it...", snapshot.name)
+		if manifest, err = loader.takeSnapshot(network, snapshot); err != nil {
+			return nil, stacktrace.Propagate(err, "An error occurred taking snapshot %v", snapshot.name)
+		}
+		logrus.Infof("Took snapshot %v", snapshot.name)
+	}
+	if err := manifest.verifyMatches(loader.getInitialServices()); err != nil {
+		return nil, stacktrace.Propagate(err, "Snapshot %v wasn't taken of a network like this one", snapshot.name)
+	}
+
+	logrus.Infof("Restoring the network from snapshot %v...", snapshot.name)
+	return loader.launchInitialServices(network, func(service initialService) error {
+		dbSeeder, found := loader.dbSeeders[service.configID]
+		if !found {
+			return stacktrace.NewError("No database seeder exists for configuration ID %v", service.configID)
+		}
+		return dbSeeder.SeedNextLaunchFromNodeState(getSnapshotNodeDirpath(snapshot.getDirpath(), service.serviceID))
+	})
+}
+
+/*
+Launches the first of the network's services and removes it again, to find the suite execution volume from the directory
+Kurtosis created for it, as the rest of the code does from the launch details of the network's services
+
+Returns:
+	The root of the suite execution volume, from the perspective of the testsuite container
+*/
+func (loader TestCaminoNetworkLoader) findSuiteExecutionDirpath(network *networks.ServiceNetwork) (string, error) {
+	firstService := loader.getInitialServices()[0]
+	if _, err := network.AddService(firstService.configID, firstService.serviceID, map[networks.ServiceID]bool{}); err != nil {
+		return "", stacktrace.Propagate(err, "An error occurred launching node with ID %v", firstService.serviceID)
+	}
+	serviceNode, err := network.GetService(firstService.serviceID)
+	if err != nil {
+		return "", stacktrace.Propagate(err, "An error occurred getting node with ID %v", firstService.serviceID)
+	}
+	launchDetails := serviceNode.Service.(caminoService.CaminoService).GetLaunchDetails()
+	if err := network.RemoveService(firstService.serviceID, containerStopTimeoutSeconds); err != nil {
+		return "", stacktrace.Propagate(err, "An error occurred removing node with ID %v", firstService.serviceID)
+	}
+	if launchDetails == nil {
+		return "", stacktrace.NewError("No launch details were recorded for node with ID %v", firstService.serviceID)
+	}
+	// Kurtosis creates the directory for each service at the root of the suite execution volume
+	return filepath.Dir(launchDetails.GetServiceDirpath()), nil
+}
+
+/*
+Sets up a network from scratch, then stops all of its nodes and saves their state as the given snapshot
+
+Returns:
+	The manifest of the snapshot that was taken
+*/
+func (loader TestCaminoNetworkLoader) takeSnapshot(network *networks.ServiceNetwork, snapshot NetworkSnapshot) (*snapshotManifest, error) {
+	availabilityCheckers, err := loader.launchInitialServices(network, nil)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred launching the network to snapshot")
+	}
+	for serviceID, availabilityChecker := range availabilityCheckers {
+		if err := availabilityChecker.WaitForStartup(); err != nil {
+			return nil, stacktrace.Propagate(err, "An error occurred waiting for service with ID %v to start up", serviceID)
+		}
+	}
+	untypedNetwork, err := loader.WrapNetwork(network)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred wrapping the network to snapshot")
+	}
+	wrappedNetwork := untypedNetwork.(TestCaminoNetwork)
+	if err := snapshot.setup(wrappedNetwork); err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred running the setup of snapshot %v", snapshot.name)
+	}
+
+	initialServices := loader.getInitialServices()
+	runningServices := wrappedNetwork.registry.getRunning()
+	for _, service := range initialServices {
+		if _, found := runningServices[service.serviceID]; !found || len(runningServices) != len(initialServices) {
+			return nil, stacktrace.NewError(
+				"The setup of snapshot %v changed which services are running; it must neither add nor remove services",
+				snapshot.name)
+		}
+	}
+	// Every node is stopped before any database is copied, so that the copies are consistent with each other
+	manifest := &snapshotManifest{}
+	launchDetails := make(map[networks.ServiceID]*caminoService.CaminoServiceLaunchDetails, len(initialServices))
+	for _, service := range initialServices {
+		serviceLaunchDetails, err := wrappedNetwork.GetServiceLaunchDetails(service.serviceID)
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "An error occurred getting the launch details of service with ID %v", service.serviceID)
+		}
+		launchDetails[service.serviceID] = serviceLaunchDetails
+		manifest.Nodes = append(manifest.Nodes, snapshotNode{
+			ServiceID: string(service.serviceID),
+			ConfigID:  string(service.configID),
+			NodeID:    serviceLaunchDetails.GetNodeID(),
+		})
+		if err := wrappedNetwork.RemoveService(service.serviceID); err != nil {
+			return nil, stacktrace.Propagate(err, "An error occurred stopping service with ID %v", service.serviceID)
+		}
+	}
+
+	// Saved to a temporary directory first, so that a snapshot that fails halfway is never mistaken for a complete one
+	tempDirpath := snapshot.getDirpath() + ".tmp"
+	if err := os.RemoveAll(tempDirpath); err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred removing leftover snapshot directory %v", tempDirpath)
+	}
+	for _, service := range initialServices {
+		nodeDirpath := getSnapshotNodeDirpath(tempDirpath, service.serviceID)
+		if err := caminoService.SaveNodeState(launchDetails[service.serviceID], nodeDirpath); err != nil {
+			return nil, stacktrace.Propagate(err, "An error occurred saving the state of service with ID %v", service.serviceID)
+		}
+	}
+	if err := manifest.save(tempDirpath); err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred saving the manifest of snapshot %v", snapshot.name)
+	}
+	if err := os.Rename(tempDirpath, snapshot.getDirpath()); err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred moving snapshot %v into place", snapshot.name)
+	}
+	return manifest, nil
+}
+
+// ================ Helper functions =========================
+func (snapshot NetworkSnapshot) getDirpath() string {
+	return filepath.Join(snapshot.snapshotsDirpath, snapshot.name)
+}
+
+func getSnapshotNodeDirpath(snapshotDirpath string, serviceID networks.ServiceID) string {
+	return filepath.Join(snapshotDirpath, string(serviceID))
+}
+
+/*
+Takes the lock of the snapshot, which is shared with the testsuite containers of the other tests through the suite
+execution volume
+
+Returns:
+	A function that releases the lock
+*/
+func (snapshot NetworkSnapshot) lock() (func(), error) {
+	if err := os.MkdirAll(snapshot.snapshotsDirpath, snapshotDirPerms); err != nil {
+		return nil, stacktrace.Propagate(err, "Could not create snapshots directory %v", snapshot.snapshotsDirpath)
+	}
+	lockFilepath := snapshot.getDirpath() + ".lock"
+	lockFile, err := os.OpenFile(lockFilepath, os.O_RDWR|os.O_CREATE, snapshotFilePerms)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "Could not open lock file %v", lockFilepath)
+	}
+	if err := syscall.Flock(int(lockFile.Fd()), syscall.LOCK_EX); err != nil {
+		lockFile.Close()
+		return nil, stacktrace.Propagate(err, "Could not lock lock file %v", lockFilepath)
+	}
+	return func() {
+		// Closing the file releases the lock
+		if err := lockFile.Close(); err != nil {
+			logrus.Warnf("Could not close lock file %v: %v", lockFilepath, err)
+		}
+	}, nil
+}
+
+/*
+Loads the manifest of the snapshot, returning nil if the snapshot hasn't been taken yet
+*/
+func (snapshot NetworkSnapshot) loadManifest() (*snapshotManifest, error) {
+	manifestFilepath := filepath.Join(snapshot.getDirpath(), snapshotManifestFilename)
+	manifestBytes, err := os.ReadFile(manifestFilepath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "Could not read manifest file %v", manifestFilepath)
+	}
+	manifest := &snapshotManifest{}
+	if err := json.Unmarshal(manifestBytes, manifest); err != nil {
+		return nil, stacktrace.Propagate(err, "Could not parse manifest file %v", manifestFilepath)
+	}
+	return manifest, nil
+}
+
+func (manifest snapshotManifest) save(snapshotDirpath string) error {
+	manifestBytes, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return stacktrace.Propagate(err, "Could not serialize the snapshot manifest")
+	}
+	manifestFilepath := filepath.Join(snapshotDirpath, snapshotManifestFilename)
+	if err := os.WriteFile(manifestFilepath, manifestBytes, snapshotFilePerms); err != nil {
+		return stacktrace.Propagate(err, "Could not write manifest file %v", manifestFilepath)
+	}
+	return nil
+}
+
+/*
+Checks that the snapshot was taken of the given services, launched in the same order from the same configurations
+*/
+func (manifest snapshotManifest) verifyMatches(initialServices []initialService) error {
+	if len(manifest.Nodes) != len(initialServices) {
+		return stacktrace.NewError("The snapshot has %v nodes, but the network initializes with %v", len(manifest.Nodes), len(initialServices))
+	}
+	for i, service := range initialServices {
+		node := manifest.Nodes[i]
+		if node.ServiceID != string(service.serviceID) || node.ConfigID != string(service.configID) {
+			return stacktrace.NewError(
+				"Node %v of the snapshot is service %v with configuration %v, but the network initializes with service %v with configuration %v there",
+				i,
+				node.ServiceID,
+				node.ConfigID,
+				service.serviceID,
+				service.configID)
+		}
+	}
+	return nil
+}
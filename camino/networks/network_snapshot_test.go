@@ -0,0 +1,79 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package networks
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	caminoService "github.com/chain4travel/camino-testing/camino/services"
+	"github.com/kurtosis-tech/kurtosis-go/lib/networks"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshotManifestRoundTrip(t *testing.T) {
+	snapshot := NewNetworkSnapshot("funded", nil)
+	snapshot.snapshotsDirpath = t.TempDir()
+	manifest, err := snapshot.loadManifest()
+	assert.NoError(t, err)
+	assert.Nil(t, manifest, "A snapshot that hasn't been taken should have no manifest")
+
+	expected := snapshotManifest{
+		Nodes: []snapshotNode{
+			{ServiceID: "boot-node-0", ConfigID: "boot-node-config-0", NodeID: "NodeID-first"},
+			{ServiceID: "validator-0", ConfigID: "validator-config", NodeID: "NodeID-second"},
+		},
+	}
+	unlock, err := snapshot.lock()
+	assert.NoError(t, err)
+	assert.DirExists(t, snapshot.snapshotsDirpath)
+	assert.NoError(t, os.MkdirAll(snapshot.getDirpath(), snapshotDirPerms))
+	assert.NoError(t, expected.save(snapshot.getDirpath()))
+	unlock()
+
+	manifest, err = snapshot.loadManifest()
+	assert.NoError(t, err)
+	assert.Equal(t, &expected, manifest)
+}
+
+func TestSnapshotManifestMatchesInitialServices(t *testing.T) {
+	loader, err := NewTestCaminoNetworkLoader(
+		true,
+		"caminogo:latest",
+		caminoService.INFO,
+		2,
+		2,
+		0,
+		2*time.Second,
+		map[networks.ConfigurationID]TestCaminoNetworkServiceConfig{},
+		map[networks.ServiceID]networks.ConfigurationID{
+			"validator-1": "validator-config",
+			"validator-0": "validator-config",
+		})
+	assert.NoError(t, err)
+	initialServices := loader.getInitialServices()
+	assert.Len(t, initialServices, len(DefaultLocalNetGenesisConfig.Stakers)+2)
+
+	manifest := snapshotManifest{}
+	for _, service := range initialServices {
+		manifest.Nodes = append(manifest.Nodes, snapshotNode{ServiceID: string(service.serviceID), ConfigID: string(service.configID)})
+	}
+	assert.NoError(t, manifest.verifyMatches(initialServices))
+
+	// The nodes must be launched in the same order, from the same configurations
+	lastIndex := len(manifest.Nodes) - 1
+	manifest.Nodes[lastIndex-1], manifest.Nodes[lastIndex] = manifest.Nodes[lastIndex], manifest.Nodes[lastIndex-1]
+	assert.Error(t, manifest.verifyMatches(initialServices))
+	manifest.Nodes[lastIndex-1], manifest.Nodes[lastIndex] = manifest.Nodes[lastIndex], manifest.Nodes[lastIndex-1]
+	manifest.Nodes[lastIndex].ConfigID = "other-config"
+	assert.Error(t, manifest.verifyMatches(initialServices))
+	assert.Error(t, snapshotManifest{Nodes: manifest.Nodes[:lastIndex]}.verifyMatches(initialServices))
+}
@@ -0,0 +1,64 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package networks
+
+import (
+	"archive/tar"
+	"fmt"
+	"strings"
+
+	caminoService "github.com/chain4travel/camino-testing/camino/services"
+	"github.com/palantir/stacktrace"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// The directory, at the root of the suite execution volume, where collected profiles are written
+	profileArtifactsDirname = "profiles"
+)
+
+// CollectProfiles gathers the profiles that the currently-running services have written through their admin API into
+// one gzipped tarball per service, so that CI can publish them alongside the test results. Archives are written to the
+// profiles directory at the root of the suite execution volume and are named <testName>_<serviceID>.tar.gz.
+// Collection is best-effort, in the same way as CollectFailureArtifacts.
+func (network TestCaminoNetwork) CollectProfiles(testName string) error {
+	failedServiceIDs := []string{}
+	for serviceID, service := range network.registry.getRunning() {
+		launchDetails := service.GetLaunchDetails()
+		if launchDetails == nil {
+			logrus.Warnf("No launch details were recorded for service with ID %v; skipping its profiles", serviceID)
+			continue
+		}
+		archiveFilepath, err := writeServiceArtifactArchive(
+			profileArtifactsDirname,
+			testName,
+			serviceID,
+			*launchDetails,
+			writeServiceProfiles)
+		if err != nil {
+			logrus.Errorf("An error occurred collecting the profiles of service with ID %v:", serviceID)
+			fmt.Fprintln(logrus.StandardLogger().Out, err)
+			failedServiceIDs = append(failedServiceIDs, string(serviceID))
+			continue
+		}
+		logrus.Infof("Wrote profiles for service with ID %v to %v", serviceID, archiveFilepath)
+	}
+	if len(failedServiceIDs) > 0 {
+		return stacktrace.NewError("Failed to collect profiles for services with IDs %v", strings.Join(failedServiceIDs, ", "))
+	}
+	return nil
+}
+
+func writeServiceProfiles(tarWriter *tar.Writer, launchDetails caminoService.CaminoServiceLaunchDetails) error {
+	if err := archiveDirectory(tarWriter, profilesArtifactDirname, launchDetails.GetProfileDirpath()); err != nil {
+		return stacktrace.Propagate(err, "An error occurred archiving the node profiles")
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package networks
+
+import (
+	"sync"
+
+	caminoService "github.com/chain4travel/camino-testing/camino/services"
+	"github.com/kurtosis-tech/kurtosis-go/lib/networks"
+)
+
+// serviceRegistry tracks every service that has been part of a TestCaminoNetwork, including removed ones (whose files
+// remain on the test volume). Unlike Kurtosis' ServiceNetwork it's safe for concurrent use, so that background monitors
+// can read it while the test adds and removes services.
+type serviceRegistry struct {
+	mutex *sync.RWMutex
+
+	allServices map[networks.ServiceID]caminoService.CaminoService
+
+	// "Set" of the service IDs that are currently part of the network
+	runningServiceIDs map[networks.ServiceID]bool
+
+	// The IDs of every service that has been part of the network, in the order they were added
+	launchOrder []networks.ServiceID
+}
+
+func newServiceRegistry() *serviceRegistry {
+	return &serviceRegistry{
+		mutex:             &sync.RWMutex{},
+		allServices:       make(map[networks.ServiceID]caminoService.CaminoService),
+		runningServiceIDs: make(map[networks.ServiceID]bool),
+	}
+}
+
+func (registry *serviceRegistry) add(serviceID networks.ServiceID, service caminoService.CaminoService) {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	registry.allServices[serviceID] = service
+	registry.runningServiceIDs[serviceID] = true
+	// A service ID that gets reused after its service was removed moves to the end of the launch order
+	for i, launchedServiceID := range registry.launchOrder {
+		if launchedServiceID == serviceID {
+			registry.launchOrder = append(registry.launchOrder[:i], registry.launchOrder[i+1:]...)
+			break
+		}
+	}
+	registry.launchOrder = append(registry.launchOrder, serviceID)
+}
+
+func (registry *serviceRegistry) markRemoved(serviceID networks.ServiceID) {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	delete(registry.runningServiceIDs, serviceID)
+}
+
+// getAll returns every service that has been part of the network
+func (registry *serviceRegistry) getAll() map[networks.ServiceID]caminoService.CaminoService {
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+	result := make(map[networks.ServiceID]caminoService.CaminoService, len(registry.allServices))
+	for serviceID, service := range registry.allServices {
+		result[serviceID] = service
+	}
+	return result
+}
+
+// getRunning returns the services that are currently part of the network
+func (registry *serviceRegistry) getRunning() map[networks.ServiceID]caminoService.CaminoService {
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+	result := make(map[networks.ServiceID]caminoService.CaminoService, len(registry.runningServiceIDs))
+	for serviceID := range registry.runningServiceIDs {
+		result[serviceID] = registry.allServices[serviceID]
+	}
+	return result
+}
+
+// getRunningInLaunchOrder returns the IDs of the services that are currently part of the network, in the order they were
+// added
+func (registry *serviceRegistry) getRunningInLaunchOrder() []networks.ServiceID {
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+	result := make([]networks.ServiceID, 0, len(registry.runningServiceIDs))
+	for _, serviceID := range registry.launchOrder {
+		if registry.runningServiceIDs[serviceID] {
+			result = append(result, serviceID)
+		}
+	}
+	return result
+}
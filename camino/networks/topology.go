@@ -0,0 +1,152 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package networks
+
+import (
+	"math/rand"
+	"strconv"
+	"strings"
+
+	"github.com/kurtosis-tech/kurtosis-go/lib/networks"
+	"github.com/palantir/stacktrace"
+)
+
+// Topology decides which of the nodes already in a TestCaminoNetwork a node being added bootstraps from, which is what
+// its --bootstrap-ids and --bootstrap-ips get set to. Nodes that aren't bootstrapped from each other can only find each
+// other through gossip.
+type Topology interface {
+	// GetBootstrappers returns the IDs of the services that the given service bootstraps from
+	// Args:
+	// 	serviceID: The ID of the service being added
+	// 	launchedServiceIDs: The IDs of the services currently in the network, in the order they were launched
+	GetBootstrappers(serviceID networks.ServiceID, launchedServiceIDs []networks.ServiceID) (map[networks.ServiceID]bool, error)
+}
+
+// FullTopology is the default topology: each boot node bootstraps from all the boot nodes started before it, and every
+// other node bootstraps from all the boot nodes
+type FullTopology struct{}
+
+func (topology FullTopology) GetBootstrappers(serviceID networks.ServiceID, launchedServiceIDs []networks.ServiceID) (map[networks.ServiceID]bool, error) {
+	result := map[networks.ServiceID]bool{}
+	for _, launchedServiceID := range launchedServiceIDs {
+		if isBootServiceID(launchedServiceID) {
+			result[launchedServiceID] = true
+		}
+	}
+	return result, nil
+}
+
+// StarTopology has every node bootstrap from the first node of the network, so all other nodes only learn of each other
+// through it
+type StarTopology struct{}
+
+func (topology StarTopology) GetBootstrappers(serviceID networks.ServiceID, launchedServiceIDs []networks.ServiceID) (map[networks.ServiceID]bool, error) {
+	result := map[networks.ServiceID]bool{}
+	if len(launchedServiceIDs) > 0 {
+		result[launchedServiceIDs[0]] = true
+	}
+	return result, nil
+}
+
+// ChainTopology has every node bootstrap from the node launched right before it, so that the nodes form a line
+type ChainTopology struct{}
+
+func (topology ChainTopology) GetBootstrappers(serviceID networks.ServiceID, launchedServiceIDs []networks.ServiceID) (map[networks.ServiceID]bool, error) {
+	result := map[networks.ServiceID]bool{}
+	if len(launchedServiceIDs) > 0 {
+		result[launchedServiceIDs[len(launchedServiceIDs)-1]] = true
+	}
+	return result, nil
+}
+
+// RandomTopology has every node bootstrap from a random sample of the nodes already in the network
+// The sample only depends on the seed and the launch order, so a network built from the same seed is built the same way
+type RandomTopology struct {
+	// The number of nodes each node bootstraps from, or all the nodes in the network if there are fewer
+	NumBootstrappers int
+
+	Seed int64
+}
+
+func NewRandomTopology(numBootstrappers int, seed int64) RandomTopology {
+	return RandomTopology{
+		NumBootstrappers: numBootstrappers,
+		Seed:             seed,
+	}
+}
+
+func (topology RandomTopology) GetBootstrappers(serviceID networks.ServiceID, launchedServiceIDs []networks.ServiceID) (map[networks.ServiceID]bool, error) {
+	if topology.NumBootstrappers < 1 {
+		return nil, stacktrace.NewError("A random topology needs at least 1 bootstrapper per node, but %v were requested", topology.NumBootstrappers)
+	}
+	// Seeded per launch, so that a node's sample doesn't depend on how many samples were taken before it
+	random := rand.New(rand.NewSource(topology.Seed + int64(len(launchedServiceIDs))))
+	result := map[networks.ServiceID]bool{}
+	for _, index := range random.Perm(len(launchedServiceIDs)) {
+		if len(result) == topology.NumBootstrappers {
+			break
+		}
+		result[launchedServiceIDs[index]] = true
+	}
+	return result, nil
+}
+
+// GraphTopology has every node bootstrap from the nodes given for it in an explicit bootstrap graph; a node that isn't in
+// the graph doesn't bootstrap from any node
+type GraphTopology struct {
+	// Mapping of service ID -> IDs of the services it bootstraps from
+	Bootstrappers map[networks.ServiceID][]networks.ServiceID
+}
+
+func NewGraphTopology(bootstrappers map[networks.ServiceID][]networks.ServiceID) GraphTopology {
+	// Defensive copy
+	bootstrappersCopy := make(map[networks.ServiceID][]networks.ServiceID, len(bootstrappers))
+	for serviceID, bootstrapperIDs := range bootstrappers {
+		bootstrapperIDsCopy := make([]networks.ServiceID, len(bootstrapperIDs))
+		copy(bootstrapperIDsCopy, bootstrapperIDs)
+		bootstrappersCopy[serviceID] = bootstrapperIDsCopy
+	}
+	return GraphTopology{
+		Bootstrappers: bootstrappersCopy,
+	}
+}
+
+func (topology GraphTopology) GetBootstrappers(serviceID networks.ServiceID, launchedServiceIDs []networks.ServiceID) (map[networks.ServiceID]bool, error) {
+	launched := map[networks.ServiceID]bool{}
+	for _, launchedServiceID := range launchedServiceIDs {
+		launched[launchedServiceID] = true
+	}
+	result := map[networks.ServiceID]bool{}
+	for _, bootstrapperID := range topology.Bootstrappers[serviceID] {
+		if !launched[bootstrapperID] {
+			return nil, stacktrace.NewError(
+				"Service %v should bootstrap from service %v, which isn't in the network; services must be launched after the services they bootstrap from",
+				serviceID,
+				bootstrapperID)
+		}
+		result[bootstrapperID] = true
+	}
+	return result, nil
+}
+
+// GetNonBootServiceIDs generates the IDs of a number of non-boot services to add to a network, for building networks of
+// any size
+func GetNonBootServiceIDs(prefix string, numServices int) []networks.ServiceID {
+	result := make([]networks.ServiceID, 0, numServices)
+	for i := 0; i < numServices; i++ {
+		result = append(result, networks.ServiceID(prefix+strconv.Itoa(i)))
+	}
+	return result
+}
+
+// ================ Helper functions =========================
+func isBootServiceID(serviceID networks.ServiceID) bool {
+	return strings.HasPrefix(string(serviceID), bootNodeServiceIDPrefix)
+}
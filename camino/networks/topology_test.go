@@ -0,0 +1,83 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package networks
+
+import (
+	"testing"
+
+	"github.com/kurtosis-tech/kurtosis-go/lib/networks"
+	"github.com/stretchr/testify/assert"
+)
+
+var testLaunchedServiceIDs = []networks.ServiceID{
+	"boot-node-0",
+	"boot-node-1",
+	"node-0",
+	"node-1",
+}
+
+func TestFullTopologyBootstrapsFromBootNodes(t *testing.T) {
+	bootstrappers, err := FullTopology{}.GetBootstrappers("node-2", testLaunchedServiceIDs)
+	assert.NoError(t, err)
+	assert.Equal(t, map[networks.ServiceID]bool{"boot-node-0": true, "boot-node-1": true}, bootstrappers)
+
+	firstBootstrappers, err := FullTopology{}.GetBootstrappers("boot-node-0", []networks.ServiceID{})
+	assert.NoError(t, err)
+	assert.Empty(t, firstBootstrappers)
+}
+
+func TestStarAndChainTopologies(t *testing.T) {
+	starBootstrappers, err := StarTopology{}.GetBootstrappers("node-2", testLaunchedServiceIDs)
+	assert.NoError(t, err)
+	assert.Equal(t, map[networks.ServiceID]bool{"boot-node-0": true}, starBootstrappers)
+
+	chainBootstrappers, err := ChainTopology{}.GetBootstrappers("node-2", testLaunchedServiceIDs)
+	assert.NoError(t, err)
+	assert.Equal(t, map[networks.ServiceID]bool{"node-1": true}, chainBootstrappers)
+}
+
+func TestRandomTopologyIsDeterministic(t *testing.T) {
+	topology := NewRandomTopology(2, 42)
+	bootstrappers, err := topology.GetBootstrappers("node-2", testLaunchedServiceIDs)
+	assert.NoError(t, err)
+	assert.Len(t, bootstrappers, 2)
+	for bootstrapperID := range bootstrappers {
+		assert.Contains(t, testLaunchedServiceIDs, bootstrapperID)
+	}
+	sameBootstrappers, err := topology.GetBootstrappers("node-2", testLaunchedServiceIDs)
+	assert.NoError(t, err)
+	assert.Equal(t, bootstrappers, sameBootstrappers)
+
+	// There aren't more bootstrappers than there are nodes to bootstrap from
+	firstBootstrappers, err := topology.GetBootstrappers("boot-node-1", testLaunchedServiceIDs[:1])
+	assert.NoError(t, err)
+	assert.Equal(t, map[networks.ServiceID]bool{"boot-node-0": true}, firstBootstrappers)
+
+	_, err = NewRandomTopology(0, 42).GetBootstrappers("node-2", testLaunchedServiceIDs)
+	assert.Error(t, err)
+}
+
+func TestGraphTopology(t *testing.T) {
+	topology := NewGraphTopology(map[networks.ServiceID][]networks.ServiceID{
+		"node-2": {"boot-node-1", "node-0"},
+		"node-3": {"node-4"},
+	})
+	bootstrappers, err := topology.GetBootstrappers("node-2", testLaunchedServiceIDs)
+	assert.NoError(t, err)
+	assert.Equal(t, map[networks.ServiceID]bool{"boot-node-1": true, "node-0": true}, bootstrappers)
+
+	unlistedBootstrappers, err := topology.GetBootstrappers("node-5", testLaunchedServiceIDs)
+	assert.NoError(t, err)
+	assert.Empty(t, unlistedBootstrappers)
+
+	// A node can't bootstrap from a node that isn't up yet
+	_, err = topology.GetBootstrappers("node-3", testLaunchedServiceIDs)
+	assert.Error(t, err)
+}
@@ -14,6 +14,9 @@ type CaminoService struct {
 	ipAddr      string
 	stakingPort int
 	jsonRPCPort int
+
+	// How the service was launched; nil if it wasn't launched through an CaminoServiceInitializerCore
+	launchDetails *CaminoServiceLaunchDetails
 }
 
 // GetStakingSocket implements CaminoService
@@ -25,3 +28,9 @@ func (service CaminoService) GetStakingSocket() ServiceSocket {
 func (service CaminoService) GetJSONRPCSocket() ServiceSocket {
 	return *NewServiceSocket(service.ipAddr, service.jsonRPCPort)
 }
+
+// GetLaunchDetails returns where the service's files live on the test volume and the command it was started with, or
+// nil if these weren't recorded
+func (service CaminoService) GetLaunchDetails() *CaminoServiceLaunchDetails {
+	return service.launchDetails
+}
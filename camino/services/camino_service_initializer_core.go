@@ -10,14 +10,21 @@
 package services
 
 import (
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
 	"fmt"
+	"hash/fnv"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/kurtosis-tech/kurtosis-go/lib/services"
 
 	"github.com/chain4travel/camino-testing/camino/services/certs"
+	"github.com/chain4travel/caminogo/network/peer"
+	"github.com/chain4travel/caminogo/utils/constants"
 	"github.com/palantir/stacktrace"
 	"github.com/sirupsen/logrus"
 )
@@ -28,9 +35,30 @@ const (
 
 	stakingTLSCertFileID = "staking-tls-cert"
 	stakingTLSKeyFileID  = "staking-tls-key"
+	nodeConfigFileID     = "node-config"
+
+	// The directory, inside the service's directory on the test volume, that the node will write its logs to
+	logsDirname = "logs"
+
+	// The directory, inside the service's directory on the test volume, that the node will write profiles requested
+	// through its admin API to
+	profilesDirname = "profiles"
+
+	// The directory, inside the service's directory on the test volume, that the node will keep its database in
+	dbDirname = "db"
+
+	// The prefix of the directory, at the root of the test volume, that the node will create its IPC sockets in
+	// Unix socket paths are limited to ~100 characters, which a path inside the service's directory would exceed
+	ipcsDirnamePrefix = "ipcs-"
+	ipcsDirPerms      = 0777
 
 	testVolumeMountpoint = "/shared"
 	caminogoBinary       = "/caminogo/build/caminogo"
+
+	// The environment variable, in nanoseconds, that offsets a node's clock in images whose caminogo was built with the
+	// toolchain of images/clockskew; libfaketime can't be used instead, as Go reads the time through the vDSO rather
+	// than libc
+	clockOffsetEnvVar = "CAMINO_CLOCK_OFFSET"
 )
 
 // CaminoLogLevel specifies the log level for an Camino client
@@ -42,6 +70,16 @@ const (
 	INFO    CaminoLogLevel = "info"
 )
 
+// DatabaseType is the kind of database a Camino node keeps its state in
+type DatabaseType string
+
+const (
+	// LevelDB keeps the node's state on disk, in its database directory on the test volume
+	LevelDB DatabaseType = "leveldb"
+	// MemDB keeps the node's state in memory only, so it's lost when the node stops
+	MemDB DatabaseType = "memdb"
+)
+
 // CaminoServiceInitializerCore implements Kurtosis' services.ServiceInitializerCore used to initialize an Camino service
 type CaminoServiceInitializerCore struct {
 	// Snow protocol sample size
@@ -64,14 +102,26 @@ type CaminoServiceInitializerCore struct {
 	// A set of CLI args that will be passed as-is to the Camino service
 	additionalCLIArgs map[string]string
 
-	// The node IDs of the nodes this node should bootstrap from
-	bootstrapperNodeIDs []string
-
 	// Cert provider that should be used when initializing the Camino service
 	certProvider certs.CaminoCertProvider
 
 	// Log level that the Camino service should start with
 	logLevel CaminoLogLevel
+
+	// Tracks the service currently being launched, so its launch details can be attached to it once it's up
+	launchTracker *caminoServiceLaunchTracker
+
+	// Seeds the database of the next service launched from this core, if requested
+	dbSeeder *DatabaseSeeder
+
+	// How far ahead of the host's clock the node's clock runs, negative if it lags behind
+	clockOffset time.Duration
+
+	// The kind of database the node keeps its state in; the node's default if empty
+	dbType DatabaseType
+
+	// Whether the node is run under a supervisor that disk faults can be injected through
+	diskFaultInjection bool
 }
 
 // NewCaminoServiceInitializerCore creates a new Camino service initializer core with the following parameters:
@@ -80,9 +130,6 @@ type CaminoServiceInitializerCore struct {
 // 		snowQuroumSize: Quorum size for Snow consensus protocol
 // 		stakingEnabled: Whether this node will use staking
 // 		cliArgs: A mapping of cli_arg -> cli_arg_value that will be passed as-is to the Camino node
-// 		bootstrapperNodeIDs: The node IDs of the bootstrapper nodes that this node will connect to. While this *seems* unintuitive
-// 			why this would be required, it's because Camino doesn't actually use certs. So, to prevent against man-in-the-middle attacks,
-// 			the user is required to manually specify the node IDs of the nodese it's connecting to.
 // 		certProvider: Provides the certs used by the Camino services generated by this core
 // 		logLevel: The loglevel that the Camino node should output at.
 // Returns:
@@ -94,15 +141,8 @@ func NewCaminoServiceInitializerCore(
 	stakingEnabled bool,
 	networkInitialTimeout time.Duration,
 	additionalCLIArgs map[string]string,
-	bootstrapperNodeIDs []string,
 	certProvider certs.CaminoCertProvider,
 	logLevel CaminoLogLevel) *CaminoServiceInitializerCore {
-	// Defensive copy
-	bootstrapperIDsCopy := make([]string, 0, len(bootstrapperNodeIDs))
-	for _, nodeID := range bootstrapperNodeIDs {
-		bootstrapperIDsCopy = append(bootstrapperIDsCopy, nodeID)
-	}
-
 	return &CaminoServiceInitializerCore{
 		snowSampleSize:        snowSampleSize,
 		snowQuorumSize:        snowQuorumSize,
@@ -110,12 +150,38 @@ func NewCaminoServiceInitializerCore(
 		stakingEnabled:        stakingEnabled,
 		networkInitialTimeout: networkInitialTimeout,
 		additionalCLIArgs:     additionalCLIArgs,
-		bootstrapperNodeIDs:   bootstrapperIDsCopy,
 		certProvider:          certProvider,
 		logLevel:              logLevel,
+		launchTracker:         &caminoServiceLaunchTracker{},
+		dbSeeder:              &DatabaseSeeder{},
 	}
 }
 
+// WithClockOffset makes the nodes launched from this core run their clocks offset from the host's by the given amount,
+// which requires an image built by scripts/build_clock_skew_image.sh; stock images ignore the offset
+func (core *CaminoServiceInitializerCore) WithClockOffset(clockOffset time.Duration) *CaminoServiceInitializerCore {
+	core.clockOffset = clockOffset
+	return core
+}
+
+// WithDatabaseType makes the nodes launched from this core keep their state in the given kind of database
+func (core *CaminoServiceInitializerCore) WithDatabaseType(dbType DatabaseType) *CaminoServiceInitializerCore {
+	core.dbType = dbType
+	return core
+}
+
+// WithDiskFaultInjection makes the nodes launched from this core run under a supervisor that injects the disk faults
+// requested with InjectDiskFault into them
+func (core *CaminoServiceInitializerCore) WithDiskFaultInjection() *CaminoServiceInitializerCore {
+	core.diskFaultInjection = true
+	return core
+}
+
+// GetDatabaseSeeder returns the seeder that can prepare the database of the next service launched from this core
+func (core CaminoServiceInitializerCore) GetDatabaseSeeder() *DatabaseSeeder {
+	return core.dbSeeder
+}
+
 // GetUsedPorts implements services.ServiceInitializerCore to declare the ports used by the node
 func (core CaminoServiceInitializerCore) GetUsedPorts() map[int]bool {
 	return map[int]bool{
@@ -128,21 +194,79 @@ func (core CaminoServiceInitializerCore) GetUsedPorts() map[int]bool {
 func (core CaminoServiceInitializerCore) GetFilesToMount() map[string]bool {
 	if core.stakingEnabled {
 		return map[string]bool{
+			nodeConfigFileID:     true,
 			stakingTLSCertFileID: true,
 			stakingTLSKeyFileID:  true,
 		}
 	}
-	return make(map[string]bool)
+	return map[string]bool{
+		nodeConfigFileID: true,
+	}
 }
 
 // InitializeMountedFiles implementats services.ServiceInitializerCore to initialize the file needed by the node
 func (core CaminoServiceInitializerCore) InitializeMountedFiles(osFiles map[string]*os.File, dependencies []services.Service) error {
+	configFilePointer := osFiles[nodeConfigFileID]
+	configBytes, err := json.MarshalIndent(newCaminoServiceConfig(core), "", "  ")
+	if err != nil {
+		return stacktrace.Propagate(err, "Could not serialize the node config when initializing service")
+	}
+	if _, err := configFilePointer.Write(configBytes); err != nil {
+		return err
+	}
+	// The test volume is mounted on the testsuite container too, so the node's files are reachable from here
+	serviceDirpath := filepath.Dir(configFilePointer.Name())
+	core.launchTracker.pending = &CaminoServiceLaunchDetails{
+		serviceDirpath: serviceDirpath,
+		configFilepath: configFilePointer.Name(),
+		logDirpath:     filepath.Join(serviceDirpath, logsDirname),
+		profileDirpath: filepath.Join(serviceDirpath, profilesDirname),
+		dbDirpath:      filepath.Join(serviceDirpath, dbDirname),
+		ipcsDirpath:    getIPCsDirpath(serviceDirpath),
+	}
+	// The node doesn't create the directory its IPC sockets go in; it's shared with the node's container, so anyone may
+	//  write to it
+	if err := os.MkdirAll(core.launchTracker.pending.ipcsDirpath, ipcsDirPerms); err != nil {
+		return stacktrace.Propagate(err, "Could not create the IPCs directory of the service")
+	}
+	if err := os.Chmod(core.launchTracker.pending.ipcsDirpath, ipcsDirPerms); err != nil {
+		return stacktrace.Propagate(err, "Could not make the IPCs directory of the service writable by the node")
+	}
+	if core.diskFaultInjection {
+		core.launchTracker.pending.diskFaultsDirpath = filepath.Join(serviceDirpath, diskFaultsDirname)
+		if err := os.MkdirAll(core.launchTracker.pending.diskFaultsDirpath, os.ModePerm); err != nil {
+			return stacktrace.Propagate(err, "Could not create the disk faults directory of the service")
+		}
+	}
+	if err := core.dbSeeder.seed(filepath.Join(serviceDirpath, dbDirname)); err != nil {
+		return stacktrace.Propagate(err, "Could not seed the database of the service")
+	}
+	// Taken even if staking is disabled, so that the seed doesn't leak into a later launch
+	seededCertPEM, seededKeyPEM, isIdentitySeeded := core.dbSeeder.takeIdentity()
+
+	if !core.stakingEnabled {
+		return nil
+	}
 	certFilePointer := osFiles[stakingTLSCertFileID]
 	keyFilePointer := osFiles[stakingTLSKeyFileID]
+	// The provider is asked even when the identity is seeded, so that the services launched after get the same identities
+	//  as they would have otherwise
 	certPEM, keyPEM, err := core.certProvider.GetCertAndKey()
 	if err != nil {
 		return stacktrace.Propagate(err, "Could not get cert & key when initializing service")
 	}
+	if isIdentitySeeded {
+		certPEM, keyPEM = seededCertPEM, seededKeyPEM
+	}
+	nodeID, err := getNodeIDFromCert(certPEM.Bytes())
+	if err != nil {
+		// The service is still launched, so that tests can see how a node handles a cert it can't load; it just can't be
+		//  bootstrapped from, as its node ID isn't known
+		logrus.Warnf("Could not get the node ID of the service's cert, so none will be recorded: %v", err)
+	}
+	core.launchTracker.pending.nodeID = nodeID
+	core.launchTracker.pending.stakingCertFilepath = certFilePointer.Name()
+	core.launchTracker.pending.stakingKeyFilepath = keyFilePointer.Name()
 	if _, err := certFilePointer.Write(certPEM.Bytes()); err != nil {
 		return err
 	}
@@ -155,15 +279,15 @@ func (core CaminoServiceInitializerCore) InitializeMountedFiles(osFiles map[stri
 // GetStartCommand implements services.ServiceInitializerCore to build the command line that will be used to launch an Camino node
 // The IP placeholder is a string that can be used in place of the IP, since we don't yet know the IP when we ask to start a new service
 func (core CaminoServiceInitializerCore) GetStartCommand(mountedFileFilepaths map[string]string, ipPlaceholder string, dependencies []services.Service) ([]string, error) {
-	numBootNodeIDs := len(core.bootstrapperNodeIDs)
-	numDependencies := len(dependencies)
-	if numDependencies > numBootNodeIDs {
-		return nil, stacktrace.NewError(
-			"Camino service is being started with %v dependencies but only %v boot node IDs have been configured",
-			numDependencies,
-			numBootNodeIDs,
-		)
+	configFilepath, found := mountedFileFilepaths[nodeConfigFileID]
+	if !found {
+		return nil, stacktrace.NewError("Could not find file key '%v' in the mounted filepaths map; this is likely a code bug", nodeConfigFileID)
 	}
+	serviceDirpath := filepath.Dir(configFilepath)
+	logDirpath := filepath.Join(serviceDirpath, logsDirname)
+	profileDirpath := filepath.Join(serviceDirpath, profilesDirname)
+	dbDirpath := filepath.Join(serviceDirpath, dbDirname)
+	ipcsDirpath := getIPCsDirpath(serviceDirpath)
 
 	publicIPFlag := fmt.Sprintf("--public-ip=%s", ipPlaceholder)
 	commandList := []string{
@@ -174,11 +298,20 @@ func (core CaminoServiceInitializerCore) GetStartCommand(mountedFileFilepaths ma
 		"--http-host=", // Leave empty to make API openly accessible
 		fmt.Sprintf("--staking-port=%d", stakingPort),
 		fmt.Sprintf("--log-level=%s", core.logLevel),
+		fmt.Sprintf("--log-dir=%s", logDirpath),
 		fmt.Sprintf("--snow-sample-size=%d", core.snowSampleSize),
 		fmt.Sprintf("--snow-quorum-size=%d", core.snowQuorumSize),
 		fmt.Sprintf("--staking-enabled=%v", core.stakingEnabled),
 		fmt.Sprintf("--tx-fee=%d", core.txFee),
 		fmt.Sprintf("--network-initial-timeout=%d", int64(core.networkInitialTimeout)),
+		"--api-admin-enabled=true",
+		fmt.Sprintf("--profile-dir=%s", profileDirpath),
+		fmt.Sprintf("--db-dir=%s", dbDirpath),
+		"--api-ipcs-enabled=true",
+		fmt.Sprintf("--ipcs-path=%s", ipcsDirpath),
+	}
+	if core.dbType != "" {
+		commandList = append(commandList, fmt.Sprintf("--db-type=%s", core.dbType))
 	}
 
 	if core.stakingEnabled {
@@ -194,21 +327,25 @@ func (core CaminoServiceInitializerCore) GetStartCommand(mountedFileFilepaths ma
 		commandList = append(commandList, fmt.Sprintf("--staking-tls-key-file=%s", keyFilepath))
 
 		// NOTE: This seems weird, BUT there's a reason for it: An camino node doesn't use certs, and instead relies on
-		//  the user explicitly passing in the node ID of the bootstrapper it wants. This prevents man-in-the-middle
-		//  attacks, just like using a cert would. Us hardcoding this bootstrapper ID here is the equivalent
-		//  of a user knowing the node ID in advance, which provides the same level of protection.
-		commandList = append(commandList, "--bootstrap-ids="+strings.Join(core.bootstrapperNodeIDs, ","))
+		//  the user explicitly passing in the node IDs of the bootstrappers it wants. This prevents man-in-the-middle
+		//  attacks, just like using a cert would. Us passing the node IDs of the dependencies' certs here is the equivalent
+		//  of a user knowing the node IDs in advance, which provides the same level of protection.
+		bootstrapperNodeIDs := make([]string, 0, len(dependencies))
+		for _, dependency := range dependencies {
+			nodeID, err := getDependencyNodeID(dependency)
+			if err != nil {
+				return nil, stacktrace.Propagate(err, "Could not get the node ID of a dependency of the Camino service")
+			}
+			bootstrapperNodeIDs = append(bootstrapperNodeIDs, nodeID)
+		}
+		commandList = append(commandList, "--bootstrap-ids="+strings.Join(bootstrapperNodeIDs, ","))
 	}
 
 	if len(dependencies) > 0 {
-		avaDependencies := make([]NodeService, 0, len(dependencies))
+		// Built from the dependencies in the same order as the bootstrap IDs, so that the two lists match up
+		socketStrs := make([]string, 0, len(dependencies))
 		for _, service := range dependencies {
-			avaDependencies = append(avaDependencies, service.(NodeService))
-		}
-
-		socketStrs := make([]string, 0, len(avaDependencies))
-		for _, service := range avaDependencies {
-			socket := service.GetStakingSocket()
+			socket := service.(NodeService).GetStakingSocket()
 			socketStrs = append(socketStrs, fmt.Sprintf("%s:%d", socket.GetIpAddr(), socket.GetPort()))
 		}
 		joinedSockets := strings.Join(socketStrs, ",")
@@ -220,8 +357,23 @@ func (core CaminoServiceInitializerCore) GetStartCommand(mountedFileFilepaths ma
 	for param, argument := range core.additionalCLIArgs {
 		commandList = append(commandList, fmt.Sprintf("--%s=%s", param, argument))
 	}
+	if core.diskFaultInjection {
+		supervisorCommand := []string{"/bin/sh", "-c", diskFaultSupervisorScript, "disk-fault-supervisor", filepath.Join(serviceDirpath, diskFaultsDirname)}
+		commandList = append(supervisorCommand, commandList...)
+	}
+	// Kurtosis can't set the container's environment, so the offset is set for the node's process by env
+	// Left out when there's no offset, so that the start command of other nodes stays as it was
+	if core.clockOffset != 0 {
+		envCommand := []string{"env", fmt.Sprintf("%s=%d", clockOffsetEnvVar, int64(core.clockOffset))}
+		commandList = append(envCommand, commandList...)
+	}
 
 	logrus.Debugf("Command list: %+v", commandList)
+	if pending := core.launchTracker.pending; pending != nil {
+		pending.startCommand = make([]string, len(commandList))
+		copy(pending.startCommand, commandList)
+		core.launchTracker.pendingIPPlaceholder = ipPlaceholder
+	}
 	return commandList, nil
 }
 
@@ -229,9 +381,10 @@ func (core CaminoServiceInitializerCore) GetStartCommand(mountedFileFilepaths ma
 // launches the Camino node inside and wrap it with our CaminoService implementation of NodeService
 func (core CaminoServiceInitializerCore) GetServiceFromIp(ipAddr string) services.Service {
 	return CaminoService{
-		ipAddr:      ipAddr,
-		stakingPort: stakingPort,
-		jsonRPCPort: httpPort,
+		ipAddr:        ipAddr,
+		stakingPort:   stakingPort,
+		jsonRPCPort:   httpPort,
+		launchDetails: core.launchTracker.claim(ipAddr),
 	}
 }
 
@@ -240,3 +393,44 @@ func (core CaminoServiceInitializerCore) GetServiceFromIp(ipAddr string) service
 func (core CaminoServiceInitializerCore) GetTestVolumeMountpoint() string {
 	return testVolumeMountpoint
 }
+
+// ================ Helper functions =========================
+/*
+Gets the node ID, as the node will report it, that a node started with the given PEM-encoded staking cert has
+*/
+func getNodeIDFromCert(certPEM []byte) (string, error) {
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return "", stacktrace.NewError("Could not decode the cert's PEM")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return "", stacktrace.Propagate(err, "Could not parse the cert")
+	}
+	return peer.CertToID(cert).PrefixedString(constants.NodeIDPrefix), nil
+}
+
+/*
+Gets the directory that the node with the given service directory creates its IPC sockets in, which sits next to the
+	service directory and is named after it
+*/
+func getIPCsDirpath(serviceDirpath string) string {
+	hasher := fnv.New32a()
+	hasher.Write([]byte(filepath.Base(serviceDirpath)))
+	return filepath.Join(filepath.Dir(serviceDirpath), fmt.Sprintf("%v%08x", ipcsDirnamePrefix, hasher.Sum32()))
+}
+
+/*
+Gets the node ID that was recorded when the given dependency was launched
+*/
+func getDependencyNodeID(dependency services.Service) (string, error) {
+	caminoDependency, ok := dependency.(CaminoService)
+	if !ok {
+		return "", stacktrace.NewError("Dependency is a %T rather than a Camino service, so its node ID isn't known", dependency)
+	}
+	launchDetails := caminoDependency.GetLaunchDetails()
+	if launchDetails == nil || launchDetails.GetNodeID() == "" {
+		return "", stacktrace.NewError("No node ID was recorded when the dependency at IP %v was launched", caminoDependency.ipAddr)
+	}
+	return launchDetails.GetNodeID(), nil
+}
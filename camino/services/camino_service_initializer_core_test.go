@@ -12,17 +12,21 @@ package services
 import (
 	"bytes"
 	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/kurtosis-tech/kurtosis-go/lib/services"
 
 	"github.com/chain4travel/camino-testing/camino/services/certs"
+	"github.com/chain4travel/camino-testing/utils/random"
 	"github.com/stretchr/testify/assert"
 )
 
 const (
-	ipPlaceholder = "IP_PLACEHOLDER"
+	ipPlaceholder      = "IP_PLACEHOLDER"
+	testConfigFilepath = "/shared/service-dir/node-config"
 )
 
 func TestNoDepsStartCommand(t *testing.T) {
@@ -33,7 +37,6 @@ func TestNoDepsStartCommand(t *testing.T) {
 		false,
 		2*time.Second,
 		make(map[string]string),
-		[]string{},
 		certs.NewStaticCaminoCertProvider(bytes.Buffer{}, bytes.Buffer{}),
 		INFO,
 	)
@@ -46,24 +49,26 @@ func TestNoDepsStartCommand(t *testing.T) {
 		"--http-host=",
 		"--staking-port=9651",
 		"--log-level=info",
+		"--log-dir=/shared/service-dir/logs",
 		"--snow-sample-size=1",
 		"--snow-quorum-size=1",
 		"--staking-enabled=false",
 		"--tx-fee=0",
 		fmt.Sprintf("--network-initial-timeout=%d", int64(2*time.Second)),
+		"--api-admin-enabled=true",
+		"--profile-dir=/shared/service-dir/profiles",
+		"--db-dir=/shared/service-dir/db",
+		"--api-ipcs-enabled=true",
+		"--ipcs-path=" + getIPCsDirpath("/shared/service-dir"),
 	}
-	actual, err := initializerCore.GetStartCommand(make(map[string]string), ipPlaceholder, make([]services.Service, 0))
+	actual, err := initializerCore.GetStartCommand(testMountedFilepaths(), ipPlaceholder, make([]services.Service, 0))
 	assert.NoError(t, err, "An error occurred getting the start command")
 	assert.Equal(t, expected, actual)
 }
 
 func TestWithDepsStartCommand(t *testing.T) {
-	testNodeID := "node1"
 	testDependencyIP := "1.2.3.4"
 
-	bootstrapperNodeIDs := []string{
-		testNodeID,
-	}
 	initializerCore := NewCaminoServiceInitializerCore(
 		1,
 		1,
@@ -71,7 +76,6 @@ func TestWithDepsStartCommand(t *testing.T) {
 		false,
 		2*time.Second,
 		make(map[string]string),
-		bootstrapperNodeIDs,
 		certs.NewStaticCaminoCertProvider(bytes.Buffer{}, bytes.Buffer{}),
 		INFO,
 	)
@@ -84,11 +88,17 @@ func TestWithDepsStartCommand(t *testing.T) {
 		"--http-host=",
 		"--staking-port=9651",
 		"--log-level=info",
+		"--log-dir=/shared/service-dir/logs",
 		"--snow-sample-size=1",
 		"--snow-quorum-size=1",
 		"--staking-enabled=false",
 		"--tx-fee=0",
 		fmt.Sprintf("--network-initial-timeout=%d", int64(2*time.Second)),
+		"--api-admin-enabled=true",
+		"--profile-dir=/shared/service-dir/profiles",
+		"--db-dir=/shared/service-dir/db",
+		"--api-ipcs-enabled=true",
+		"--ipcs-path=" + getIPCsDirpath("/shared/service-dir"),
 		fmt.Sprintf("--bootstrap-ips=%v:9651", testDependencyIP),
 	}
 
@@ -100,7 +110,328 @@ func TestWithDepsStartCommand(t *testing.T) {
 	testDependencySlice := []services.Service{
 		testDependency,
 	}
-	actual, err := initializerCore.GetStartCommand(make(map[string]string), ipPlaceholder, testDependencySlice)
+	actual, err := initializerCore.GetStartCommand(testMountedFilepaths(), ipPlaceholder, testDependencySlice)
 	assert.NoError(t, err, "An error occurred getting the start command")
 	assert.Equal(t, expected, actual)
 }
+
+func TestLaunchDetailsAttachedToService(t *testing.T) {
+	initializerCore := NewCaminoServiceInitializerCore(
+		1,
+		1,
+		0,
+		false,
+		2*time.Second,
+		make(map[string]string),
+		certs.NewStaticCaminoCertProvider(bytes.Buffer{}, bytes.Buffer{}),
+		INFO,
+	)
+
+	serviceDirpath := t.TempDir()
+	configFile, err := os.Create(filepath.Join(serviceDirpath, "node-config"))
+	assert.NoError(t, err, "An error occurred creating the config file")
+	defer configFile.Close()
+
+	osFiles := map[string]*os.File{
+		nodeConfigFileID: configFile,
+	}
+	err = initializerCore.InitializeMountedFiles(osFiles, make([]services.Service, 0))
+	assert.NoError(t, err, "An error occurred initializing the mounted files")
+	_, err = initializerCore.GetStartCommand(testMountedFilepaths(), ipPlaceholder, make([]services.Service, 0))
+	assert.NoError(t, err, "An error occurred getting the start command")
+
+	service := initializerCore.GetServiceFromIp("1.2.3.4").(CaminoService)
+	launchDetails := service.GetLaunchDetails()
+	assert.NotNil(t, launchDetails)
+	assert.Equal(t, serviceDirpath, launchDetails.GetServiceDirpath())
+	assert.Equal(t, configFile.Name(), launchDetails.GetConfigFilepath())
+	assert.Equal(t, filepath.Join(serviceDirpath, logsDirname), launchDetails.GetLogDirpath())
+	assert.Equal(t, filepath.Join(serviceDirpath, profilesDirname), launchDetails.GetProfileDirpath())
+	assert.Equal(t, filepath.Join(serviceDirpath, dbDirname), launchDetails.GetDBDirpath())
+	// The IPCs directory is outside the service directory, to keep the socket paths short, but created up front
+	assert.Equal(t, filepath.Dir(serviceDirpath), filepath.Dir(launchDetails.GetIPCsDirpath()))
+	assert.DirExists(t, launchDetails.GetIPCsDirpath())
+	assert.Contains(t, launchDetails.GetStartCommand(), "--public-ip=1.2.3.4")
+
+	configContents, err := os.ReadFile(configFile.Name())
+	assert.NoError(t, err, "An error occurred reading the config file")
+	assert.Contains(t, string(configContents), `"snowSampleSize": 1`)
+
+	// The details belong to the service that was just launched, not to the next one
+	nextService := initializerCore.GetServiceFromIp("5.6.7.8").(CaminoService)
+	assert.Nil(t, nextService.GetLaunchDetails())
+}
+
+func testMountedFilepaths() map[string]string {
+	return map[string]string{
+		nodeConfigFileID: testConfigFilepath,
+	}
+}
+
+func TestDatabaseSeededOnce(t *testing.T) {
+	initializerCore := NewCaminoServiceInitializerCore(
+		1,
+		1,
+		0,
+		false,
+		2*time.Second,
+		make(map[string]string),
+		certs.NewStaticCaminoCertProvider(bytes.Buffer{}, bytes.Buffer{}),
+		INFO,
+	)
+
+	sourceDirpath := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(sourceDirpath, "v1.0.0"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(sourceDirpath, "v1.0.0", "000001.log"), []byte("partial"), 0644))
+	initializerCore.GetDatabaseSeeder().SeedNextLaunch(sourceDirpath)
+
+	seededDirpath := initializeMountedFilesInTempDir(t, initializerCore)
+	seededContents, err := os.ReadFile(filepath.Join(seededDirpath, dbDirname, "v1.0.0", "000001.log"))
+	assert.NoError(t, err, "The seeded database should contain the source database's files")
+	assert.Equal(t, "partial", string(seededContents))
+
+	// The seed only applies to the launch it was requested for
+	unseededDirpath := initializeMountedFilesInTempDir(t, initializerCore)
+	_, err = os.Stat(filepath.Join(unseededDirpath, dbDirname))
+	assert.True(t, os.IsNotExist(err), "The next service should start with an empty database")
+}
+
+func initializeMountedFilesInTempDir(t *testing.T, initializerCore *CaminoServiceInitializerCore) string {
+	serviceDirpath := t.TempDir()
+	configFile, err := os.Create(filepath.Join(serviceDirpath, "node-config"))
+	assert.NoError(t, err, "An error occurred creating the config file")
+	defer configFile.Close()
+	osFiles := map[string]*os.File{
+		nodeConfigFileID: configFile,
+	}
+	assert.NoError(t, initializerCore.InitializeMountedFiles(osFiles, make([]services.Service, 0)))
+	return serviceDirpath
+}
+
+func TestNodeStateSeedsDatabaseAndIdentity(t *testing.T) {
+	initializerCore := NewCaminoServiceInitializerCore(
+		1,
+		1,
+		0,
+		true,
+		2*time.Second,
+		make(map[string]string),
+		certs.NewRandomCaminoCertProvider(true, random.NewRand("node-state")),
+		INFO,
+	)
+
+	savedDetails := launchStakingServiceInTempDir(t, initializerCore)
+	assert.NoError(t, os.MkdirAll(savedDetails.GetDBDirpath(), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(savedDetails.GetDBDirpath(), "000001.log"), []byte("state"), 0644))
+	nodeStateDirpath := filepath.Join(t.TempDir(), "node-state")
+	assert.NoError(t, SaveNodeState(savedDetails, nodeStateDirpath))
+	assert.Error(t, SaveNodeState(savedDetails, nodeStateDirpath), "Saving over an existing node state should fail")
+
+	assert.NoError(t, initializerCore.GetDatabaseSeeder().SeedNextLaunchFromNodeState(nodeStateDirpath))
+	restoredDetails := launchStakingServiceInTempDir(t, initializerCore)
+	assert.Equal(t, savedDetails.GetNodeID(), restoredDetails.GetNodeID())
+	restoredContents, err := os.ReadFile(filepath.Join(restoredDetails.GetDBDirpath(), "000001.log"))
+	assert.NoError(t, err, "The restored database should contain the saved database's files")
+	assert.Equal(t, "state", string(restoredContents))
+
+	// The services launched after get the identities the cert provider would have given them anyway
+	expectedCertPEM := bytes.Buffer{}
+	expectedProvider := certs.NewRandomCaminoCertProvider(true, random.NewRand("node-state"))
+	for i := 0; i < 3; i++ {
+		expectedCertPEM, _, err = expectedProvider.GetCertAndKey()
+		assert.NoError(t, err)
+	}
+	nextDetails := launchStakingServiceInTempDir(t, initializerCore)
+	nextCertPEM, err := os.ReadFile(nextDetails.GetStakingCertFilepath())
+	assert.NoError(t, err)
+	assert.Equal(t, expectedCertPEM.Bytes(), nextCertPEM)
+	assert.NotEqual(t, savedDetails.GetNodeID(), nextDetails.GetNodeID())
+}
+
+func launchStakingServiceInTempDir(t *testing.T, initializerCore *CaminoServiceInitializerCore) *CaminoServiceLaunchDetails {
+	serviceDirpath := t.TempDir()
+	osFiles := map[string]*os.File{}
+	for fileID := range initializerCore.GetFilesToMount() {
+		file, err := os.Create(filepath.Join(serviceDirpath, fileID))
+		assert.NoError(t, err, "An error occurred creating file %v", fileID)
+		defer file.Close()
+		osFiles[fileID] = file
+	}
+	assert.NoError(t, initializerCore.InitializeMountedFiles(osFiles, make([]services.Service, 0)))
+	return initializerCore.GetServiceFromIp("1.2.3.4").(CaminoService).GetLaunchDetails()
+}
+
+func TestStakingStartCommandUsesDependencyNodeIDs(t *testing.T) {
+	initializerCore := NewCaminoServiceInitializerCore(
+		1,
+		1,
+		0,
+		true,
+		2*time.Second,
+		make(map[string]string),
+		certs.NewStaticCaminoCertProvider(bytes.Buffer{}, bytes.Buffer{}),
+		INFO,
+	)
+	mountedFilepaths := testMountedFilepaths()
+	mountedFilepaths[stakingTLSCertFileID] = "/shared/service-dir/staking-tls-cert"
+	mountedFilepaths[stakingTLSKeyFileID] = "/shared/service-dir/staking-tls-key"
+
+	dependencies := []services.Service{
+		CaminoService{
+			ipAddr:        "1.2.3.4",
+			stakingPort:   9651,
+			launchDetails: &CaminoServiceLaunchDetails{nodeID: "NodeID-first"},
+		},
+		CaminoService{
+			ipAddr:        "5.6.7.8",
+			stakingPort:   9651,
+			launchDetails: &CaminoServiceLaunchDetails{nodeID: "NodeID-second"},
+		},
+	}
+	actual, err := initializerCore.GetStartCommand(mountedFilepaths, ipPlaceholder, dependencies)
+	assert.NoError(t, err, "An error occurred getting the start command")
+	assert.Contains(t, actual, "--bootstrap-ids=NodeID-first,NodeID-second")
+	assert.Contains(t, actual, "--bootstrap-ips=1.2.3.4:9651,5.6.7.8:9651")
+
+	// A dependency whose node ID wasn't recorded can't be bootstrapped from securely
+	unknownDependencies := []services.Service{
+		CaminoService{
+			ipAddr:      "1.2.3.4",
+			stakingPort: 9651,
+		},
+	}
+	_, err = initializerCore.GetStartCommand(mountedFilepaths, ipPlaceholder, unknownDependencies)
+	assert.Error(t, err)
+}
+
+func TestUndecodableCertStillLaunched(t *testing.T) {
+	initializerCore := NewCaminoServiceInitializerCore(
+		1,
+		1,
+		0,
+		true,
+		2*time.Second,
+		make(map[string]string),
+		certs.NewMalformedCaminoCertProvider(certs.TruncatedPEM, random.NewRand("malformed-cert")),
+		INFO,
+	)
+	osFiles := map[string]*os.File{}
+	for fileID := range initializerCore.GetFilesToMount() {
+		file, err := os.Create(filepath.Join(t.TempDir(), fileID))
+		assert.NoError(t, err, "An error occurred creating file %v", fileID)
+		defer file.Close()
+		osFiles[fileID] = file
+	}
+	assert.NoError(t, initializerCore.InitializeMountedFiles(osFiles, make([]services.Service, 0)))
+
+	// Nodes can't bootstrap from it, as its node ID isn't known
+	service := initializerCore.GetServiceFromIp("1.2.3.4").(CaminoService)
+	assert.Equal(t, "", service.GetLaunchDetails().GetNodeID())
+}
+
+func TestClockOffsetStartCommand(t *testing.T) {
+	initializerCore := NewCaminoServiceInitializerCore(
+		1,
+		1,
+		0,
+		false,
+		2*time.Second,
+		make(map[string]string),
+		certs.NewStaticCaminoCertProvider(bytes.Buffer{}, bytes.Buffer{}),
+		INFO,
+	)
+	command, err := initializerCore.GetStartCommand(testMountedFilepaths(), ipPlaceholder, make([]services.Service, 0))
+	assert.NoError(t, err, "An error occurred getting the start command")
+	assert.Equal(t, caminogoBinary, command[0], "Nodes without an offset should be started as before")
+
+	initializerCore.WithClockOffset(-3 * time.Second)
+	command, err = initializerCore.GetStartCommand(testMountedFilepaths(), ipPlaceholder, make([]services.Service, 0))
+	assert.NoError(t, err, "An error occurred getting the start command")
+	assert.Equal(t, []string{"env", fmt.Sprintf("CAMINO_CLOCK_OFFSET=%d", int64(-3*time.Second)), caminogoBinary}, command[:3])
+}
+
+func TestDatabaseTypeStartCommand(t *testing.T) {
+	initializerCore := NewCaminoServiceInitializerCore(
+		1,
+		1,
+		0,
+		false,
+		2*time.Second,
+		make(map[string]string),
+		certs.NewStaticCaminoCertProvider(bytes.Buffer{}, bytes.Buffer{}),
+		INFO,
+	).WithDatabaseType(MemDB)
+	command, err := initializerCore.GetStartCommand(testMountedFilepaths(), ipPlaceholder, make([]services.Service, 0))
+	assert.NoError(t, err, "An error occurred getting the start command")
+	assert.Contains(t, command, "--db-type=memdb")
+}
+
+func TestDiskFaultInjection(t *testing.T) {
+	initializerCore := NewCaminoServiceInitializerCore(
+		1,
+		1,
+		0,
+		false,
+		2*time.Second,
+		make(map[string]string),
+		certs.NewStaticCaminoCertProvider(bytes.Buffer{}, bytes.Buffer{}),
+		INFO,
+	).WithDiskFaultInjection()
+	serviceDirpath := initializeMountedFilesInTempDir(t, initializerCore)
+	command, err := initializerCore.GetStartCommand(testMountedFilepaths(), ipPlaceholder, make([]services.Service, 0))
+	assert.NoError(t, err, "An error occurred getting the start command")
+	// The node is run by the supervisor, which is told where the faults are marked from the node's perspective
+	assert.Equal(t, []string{"/bin/sh", "-c"}, command[:2])
+	assert.Equal(t, "/shared/service-dir/"+diskFaultsDirname, command[4])
+	assert.Equal(t, caminogoBinary, command[5])
+
+	launchDetails := initializerCore.GetServiceFromIp("1.2.3.4").(CaminoService).GetLaunchDetails()
+	assert.Equal(t, filepath.Join(serviceDirpath, diskFaultsDirname), launchDetails.GetDiskFaultsDirpath())
+	assert.DirExists(t, launchDetails.GetDiskFaultsDirpath())
+	dbFilepath := filepath.Join(launchDetails.GetDBDirpath(), "v1.0.0", "000001.log")
+	assert.NoError(t, os.MkdirAll(filepath.Dir(dbFilepath), 0755))
+	assert.NoError(t, os.WriteFile(dbFilepath, []byte("state"), 0644))
+	markerFilepath := filepath.Join(launchDetails.GetDiskFaultsDirpath(), noFileGrowthMarkerFilename)
+
+	assert.NoError(t, InjectDiskFault(launchDetails, FullDisk))
+	assert.FileExists(t, markerFilepath)
+	assertPerms(t, dbFilepath, 0644)
+
+	assert.NoError(t, InjectDiskFault(launchDetails, ReadOnlyDisk))
+	assertPerms(t, filepath.Dir(dbFilepath), 0555)
+	assertPerms(t, dbFilepath, 0444)
+
+	assert.NoError(t, ClearDiskFaults(launchDetails))
+	assert.NoFileExists(t, markerFilepath)
+	assertPerms(t, filepath.Dir(dbFilepath), 0755)
+	assertPerms(t, dbFilepath, 0644)
+
+	assert.Error(t, InjectDiskFault(launchDetails, DiskFault("slow")))
+}
+
+func TestDiskFaultsNeedInjectionEnabled(t *testing.T) {
+	initializerCore := NewCaminoServiceInitializerCore(
+		1,
+		1,
+		0,
+		false,
+		2*time.Second,
+		make(map[string]string),
+		certs.NewStaticCaminoCertProvider(bytes.Buffer{}, bytes.Buffer{}),
+		INFO,
+	)
+	initializeMountedFilesInTempDir(t, initializerCore)
+	command, err := initializerCore.GetStartCommand(testMountedFilepaths(), ipPlaceholder, make([]services.Service, 0))
+	assert.NoError(t, err, "An error occurred getting the start command")
+	assert.Equal(t, caminogoBinary, command[0])
+
+	launchDetails := initializerCore.GetServiceFromIp("1.2.3.4").(CaminoService).GetLaunchDetails()
+	assert.Error(t, InjectDiskFault(launchDetails, FullDisk))
+	assert.Error(t, ClearDiskFaults(launchDetails))
+}
+
+func assertPerms(t *testing.T, path string, expectedPerms os.FileMode) {
+	info, err := os.Stat(path)
+	assert.NoError(t, err, "An error occurred getting the permissions of %v", path)
+	assert.Equal(t, expectedPerms, info.Mode().Perm(), "%v has the wrong permissions", path)
+}
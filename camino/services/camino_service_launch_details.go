@@ -0,0 +1,161 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package services
+
+import (
+	"strings"
+)
+
+// CaminoServiceLaunchDetails records where a Camino service's files live on the test volume and the command it was
+// launched with, so that they can be inspected after the fact (e.g. when collecting artifacts for a failed test)
+// NOTE: All filepaths are from the perspective of the testsuite container, NOT the Camino node's container
+type CaminoServiceLaunchDetails struct {
+	// The directory Kurtosis created for the service on the test volume
+	serviceDirpath string
+
+	// The file containing the configuration the service was initialized with
+	configFilepath string
+
+	// The directory the service writes its logs to
+	logDirpath string
+
+	// The directory the service writes the profiles requested through its admin API to
+	profileDirpath string
+
+	// The directory the service keeps its database in
+	dbDirpath string
+
+	// The directory the service creates the IPC sockets of the chains it publishes in
+	ipcsDirpath string
+
+	// The directory the faults injected into the service's disk are marked in; empty if disk fault injection is disabled
+	diskFaultsDirpath string
+
+	// The node ID the service's staking cert gives it; empty if staking is disabled
+	nodeID string
+
+	// The files the service's staking cert and key were written to; empty if staking is disabled
+	stakingCertFilepath string
+	stakingKeyFilepath  string
+
+	// The command the service's container was started with
+	startCommand []string
+}
+
+// GetServiceDirpath returns the directory Kurtosis created for the service on the test volume
+func (details CaminoServiceLaunchDetails) GetServiceDirpath() string {
+	return details.serviceDirpath
+}
+
+// GetConfigFilepath returns the file containing the configuration the service was initialized with, serialized as JSON
+func (details CaminoServiceLaunchDetails) GetConfigFilepath() string {
+	return details.configFilepath
+}
+
+// GetLogDirpath returns the directory the service writes its logs to
+func (details CaminoServiceLaunchDetails) GetLogDirpath() string {
+	return details.logDirpath
+}
+
+// GetProfileDirpath returns the directory the service writes the profiles requested through its admin API to
+func (details CaminoServiceLaunchDetails) GetProfileDirpath() string {
+	return details.profileDirpath
+}
+
+// GetDBDirpath returns the directory the service keeps its database in
+func (details CaminoServiceLaunchDetails) GetDBDirpath() string {
+	return details.dbDirpath
+}
+
+// GetIPCsDirpath returns the directory the service creates the IPC sockets of the chains it publishes in
+func (details CaminoServiceLaunchDetails) GetIPCsDirpath() string {
+	return details.ipcsDirpath
+}
+
+// GetDiskFaultsDirpath returns the directory the faults injected into the service's disk are marked in, or the empty
+// string if the service was launched without disk fault injection
+func (details CaminoServiceLaunchDetails) GetDiskFaultsDirpath() string {
+	return details.diskFaultsDirpath
+}
+
+// GetNodeID returns the node ID the service's staking cert gives it, or the empty string if staking is disabled
+func (details CaminoServiceLaunchDetails) GetNodeID() string {
+	return details.nodeID
+}
+
+// GetStakingCertFilepath returns the file the service's staking cert was written to, or the empty string if staking is
+// disabled
+func (details CaminoServiceLaunchDetails) GetStakingCertFilepath() string {
+	return details.stakingCertFilepath
+}
+
+// GetStakingKeyFilepath returns the file the service's staking key was written to, or the empty string if staking is
+// disabled
+func (details CaminoServiceLaunchDetails) GetStakingKeyFilepath() string {
+	return details.stakingKeyFilepath
+}
+
+// GetStartCommand returns the command the service's container was started with
+func (details CaminoServiceLaunchDetails) GetStartCommand() []string {
+	startCommandCopy := make([]string, len(details.startCommand))
+	copy(startCommandCopy, details.startCommand)
+	return startCommandCopy
+}
+
+// caminoServiceConfig is the serializable form of the parameters an CaminoServiceInitializerCore launches nodes with
+type caminoServiceConfig struct {
+	SnowSampleSize        int               `json:"snowSampleSize"`
+	SnowQuorumSize        int               `json:"snowQuorumSize"`
+	StakingEnabled        bool              `json:"stakingEnabled"`
+	TxFee                 uint64            `json:"txFee"`
+	NetworkInitialTimeout string            `json:"networkInitialTimeout"`
+	LogLevel              CaminoLogLevel    `json:"logLevel"`
+	AdditionalCLIArgs     map[string]string `json:"additionalCLIArgs"`
+	DatabaseType          DatabaseType      `json:"databaseType,omitempty"`
+}
+
+// caminoServiceLaunchTracker carries the details of the service an CaminoServiceInitializerCore is in the middle of
+// launching across the separate Kurtosis initialization calls, until they can be attached to the resulting service.
+// Kurtosis launches services one at a time, so there's only ever a single launch in flight.
+type caminoServiceLaunchTracker struct {
+	pending *CaminoServiceLaunchDetails
+
+	// The IP placeholder the pending launch's start command was built with
+	pendingIPPlaceholder string
+}
+
+// claim hands off the details of the pending launch, substituting the service's actual IP into its start command
+func (tracker *caminoServiceLaunchTracker) claim(ipAddr string) *CaminoServiceLaunchDetails {
+	details := tracker.pending
+	if details == nil {
+		return nil
+	}
+	if tracker.pendingIPPlaceholder != "" {
+		for i, arg := range details.startCommand {
+			details.startCommand[i] = strings.ReplaceAll(arg, tracker.pendingIPPlaceholder, ipAddr)
+		}
+	}
+	tracker.pending = nil
+	tracker.pendingIPPlaceholder = ""
+	return details
+}
+
+func newCaminoServiceConfig(core CaminoServiceInitializerCore) caminoServiceConfig {
+	return caminoServiceConfig{
+		SnowSampleSize:        core.snowSampleSize,
+		SnowQuorumSize:        core.snowQuorumSize,
+		StakingEnabled:        core.stakingEnabled,
+		TxFee:                 core.txFee,
+		NetworkInitialTimeout: core.networkInitialTimeout.String(),
+		LogLevel:              core.logLevel,
+		AdditionalCLIArgs:     core.additionalCLIArgs,
+		DatabaseType:          core.dbType,
+	}
+}
@@ -0,0 +1,176 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package certs
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"math/big"
+	mathrand "math/rand"
+	"time"
+
+	"github.com/palantir/stacktrace"
+)
+
+const (
+	// The preamble of PKCS #8 private keys, which is how non-RSA keys are encoded
+	pkcs8PrivateKeyPreamble = "PRIVATE KEY"
+
+	rsaKeyBits = 4096
+
+	// The number of Miller-Rabin rounds that the primes of generated RSA keys are tested with
+	rsaPrimeTestRounds = 20
+	rsaPublicExponent  = 65537
+)
+
+var (
+	// Generated certs are valid over a fixed window rather than one relative to now, so that a cert generated from the
+	// same randomness - and so the node ID derived from it - is the same across runs
+	seededCertNotBefore = time.Date(2022, time.January, 1, 0, 0, 0, 0, time.UTC)
+	seededCertNotAfter  = seededCertNotBefore.AddDate(30, 0, 0)
+)
+
+// KeyType is the type of key that a generated cert is for
+type KeyType string
+
+const (
+	RSA     KeyType = "rsa"
+	ECDSA   KeyType = "ecdsa"
+	Ed25519 KeyType = "ed25519"
+)
+
+// ================= Helper functions ===================
+/*
+Generates a key of the given type from the given randomness and a cert for it, issued by the given parent cert (which is
+	the cert itself for self-signed certs) and signed with the generated key
+RSA and Ed25519 keys, and the certs for them, are fully determined by the randomness. ECDSA keys aren't, because the
+	standard library mixes its own randomness into ECDSA key generation and signing.
+*/
+func generateCertAndKey(
+	template *x509.Certificate,
+	parent *x509.Certificate,
+	keyType KeyType,
+	random *mathrand.Rand) (certPemBytes bytes.Buffer, keyPemBytes bytes.Buffer, err error) {
+	var privateKey crypto.Signer
+	switch keyType {
+	case RSA:
+		privateKey, err = generateRSAKey(random, rsaKeyBits)
+	case ECDSA:
+		privateKey, err = ecdsa.GenerateKey(elliptic.P256(), random)
+	case Ed25519:
+		keySeed := make([]byte, ed25519.SeedSize)
+		// Reads from a math/rand source never fail
+		random.Read(keySeed)
+		privateKey = ed25519.NewKeyFromSeed(keySeed)
+	default:
+		return bytes.Buffer{}, bytes.Buffer{}, stacktrace.NewError("Unrecognized key type %v", keyType)
+	}
+	if err != nil {
+		return bytes.Buffer{}, bytes.Buffer{}, stacktrace.Propagate(err, "Failed to generate random %v private key.", keyType)
+	}
+	// RSA and Ed25519 signatures don't use the reader, so the cert only depends on the key and the template
+	certBytes, err := x509.CreateCertificate(rand.Reader, template, parent, privateKey.Public(), privateKey)
+	if err != nil {
+		return bytes.Buffer{}, bytes.Buffer{}, stacktrace.Propagate(err, "Failed to sign service cert with cert authority.")
+	}
+	certPEM := new(bytes.Buffer)
+	if err := pem.Encode(certPEM, &pem.Block{
+		Type:  certificatePreamble,
+		Bytes: certBytes,
+	}); err != nil {
+		return bytes.Buffer{}, bytes.Buffer{}, err
+	}
+
+	keyBlock := &pem.Block{}
+	if rsaKey, isRSA := privateKey.(*rsa.PrivateKey); isRSA {
+		keyBlock.Type = privateKeyPreamble
+		keyBlock.Bytes = x509.MarshalPKCS1PrivateKey(rsaKey)
+	} else {
+		keyBlock.Type = pkcs8PrivateKeyPreamble
+		if keyBlock.Bytes, err = x509.MarshalPKCS8PrivateKey(privateKey); err != nil {
+			return bytes.Buffer{}, bytes.Buffer{}, stacktrace.Propagate(err, "Failed to encode %v private key.", keyType)
+		}
+	}
+	certPrivKeyPEM := new(bytes.Buffer)
+	if err := pem.Encode(certPrivKeyPEM, keyBlock); err != nil {
+		return bytes.Buffer{}, bytes.Buffer{}, err
+	}
+	return *certPEM, *certPrivKeyPEM, nil
+}
+
+/*
+Generates an RSA key whose primes are drawn from the given randomness
+The standard library's RSA key generation ignores the reader it's given in newer Go versions, so it can't be used for keys
+	that have to be reproducible
+*/
+func generateRSAKey(random *mathrand.Rand, bits int) (*rsa.PrivateKey, error) {
+	one := big.NewInt(1)
+	publicExponent := big.NewInt(rsaPublicExponent)
+	for {
+		p := generatePrime(random, bits/2)
+		q := generatePrime(random, bits-bits/2)
+		if p.Cmp(q) == 0 {
+			continue
+		}
+		modulus := new(big.Int).Mul(p, q)
+		if modulus.BitLen() != bits {
+			continue
+		}
+		totient := new(big.Int).Mul(new(big.Int).Sub(p, one), new(big.Int).Sub(q, one))
+		privateExponent := new(big.Int).ModInverse(publicExponent, totient)
+		if privateExponent == nil {
+			// The public exponent isn't coprime with the totient, so the primes can't be used
+			continue
+		}
+		privateKey := &rsa.PrivateKey{
+			PublicKey: rsa.PublicKey{
+				N: modulus,
+				E: rsaPublicExponent,
+			},
+			D:      privateExponent,
+			Primes: []*big.Int{p, q},
+		}
+		privateKey.Precompute()
+		if err := privateKey.Validate(); err != nil {
+			return nil, stacktrace.Propagate(err, "Generated an invalid RSA key.")
+		}
+		return privateKey, nil
+	}
+}
+
+/*
+Draws random numbers of the given bit length from the given randomness until one is probably prime
+*/
+func generatePrime(random *mathrand.Rand, bits int) *big.Int {
+	candidateBytes := make([]byte, (bits+7)/8)
+	// The bits of the first byte beyond the requested length
+	excessBits := uint(len(candidateBytes)*8 - bits)
+	candidate := new(big.Int)
+	for {
+		// Reads from a math/rand source never fail
+		random.Read(candidateBytes)
+		candidateBytes[0] &= byte(0xFF >> excessBits)
+		// Setting the top two bits makes the product of two such primes have exactly twice their length
+		candidate.SetBytes(candidateBytes)
+		candidate.SetBit(candidate, bits-1, 1)
+		candidate.SetBit(candidate, bits-2, 1)
+		candidate.SetBit(candidate, 0, 1)
+		if candidate.ProbablyPrime(rsaPrimeTestRounds) {
+			return candidate
+		}
+	}
+}
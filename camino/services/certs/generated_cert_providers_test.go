@@ -0,0 +1,90 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	mathrand "math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testSeed = 1234
+
+func TestKeyTypeCertsLoad(t *testing.T) {
+	for keyType, expectedAlgorithm := range map[KeyType]x509.PublicKeyAlgorithm{
+		ECDSA:   x509.ECDSA,
+		Ed25519: x509.Ed25519,
+	} {
+		cert := loadCert(t, NewKeyTypeCaminoCertProvider(keyType, newTestRand()))
+		assert.Equal(t, expectedAlgorithm, cert.PublicKeyAlgorithm, "Unexpected public key algorithm for key type %v", keyType)
+		assert.Equal(t, rootCert.Subject.Organization, cert.Issuer.Organization)
+	}
+}
+
+func TestValidityWindowCerts(t *testing.T) {
+	expiredCert := loadCert(t, NewExpiredCaminoCertProvider(newTestRand()))
+	assert.True(t, expiredCert.NotAfter.Before(time.Now()))
+
+	notYetValidCert := loadCert(t, NewNotYetValidCaminoCertProvider(newTestRand()))
+	assert.True(t, notYetValidCert.NotBefore.After(time.Now()))
+}
+
+func TestSelfSignedCertsAreOwnIssuer(t *testing.T) {
+	cert := loadCert(t, NewSelfSignedCaminoCertProvider(newTestRand()))
+	assert.Equal(t, cert.Subject.String(), cert.Issuer.String())
+	assert.NoError(t, cert.CheckSignature(cert.SignatureAlgorithm, cert.RawTBSCertificate, cert.Signature))
+}
+
+func TestSameRandomnessGivesSameCerts(t *testing.T) {
+	for _, provider := range []func(random *mathrand.Rand) CaminoCertProvider{
+		func(random *mathrand.Rand) CaminoCertProvider { return NewRandomCaminoCertProvider(true, random) },
+		func(random *mathrand.Rand) CaminoCertProvider { return NewKeyTypeCaminoCertProvider(Ed25519, random) },
+		func(random *mathrand.Rand) CaminoCertProvider { return NewSelfSignedCaminoCertProvider(random) },
+	} {
+		firstCert, firstKey, err := provider(newTestRand()).GetCertAndKey()
+		assert.NoError(t, err)
+		secondCert, secondKey, err := provider(newTestRand()).GetCertAndKey()
+		assert.NoError(t, err)
+		assert.Equal(t, firstCert.Bytes(), secondCert.Bytes())
+		assert.Equal(t, firstKey.Bytes(), secondKey.Bytes())
+	}
+}
+
+func TestMalformedCertsDontLoad(t *testing.T) {
+	for _, malformation := range []CertMalformation{TruncatedPEM, CorruptDER, MismatchedKey} {
+		certPEM, keyPEM, err := NewMalformedCaminoCertProvider(malformation, newTestRand()).GetCertAndKey()
+		assert.NoError(t, err)
+		_, err = tls.X509KeyPair(certPEM.Bytes(), keyPEM.Bytes())
+		assert.Error(t, err, "Cert with malformation %v should not load", malformation)
+	}
+}
+
+func newTestRand() *mathrand.Rand {
+	return mathrand.New(mathrand.NewSource(testSeed))
+}
+
+/*
+Loads the provider's cert and key the way a node does, and parses the cert
+*/
+func loadCert(t *testing.T, provider CaminoCertProvider) *x509.Certificate {
+	certPEM, keyPEM, err := provider.GetCertAndKey()
+	assert.NoError(t, err)
+	_, err = tls.X509KeyPair(certPEM.Bytes(), keyPEM.Bytes())
+	assert.NoError(t, err)
+	block, _ := pem.Decode(certPEM.Bytes())
+	cert, err := x509.ParseCertificate(block.Bytes)
+	assert.NoError(t, err)
+	return cert
+}
@@ -0,0 +1,36 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package certs
+
+import (
+	"bytes"
+	mathrand "math/rand"
+)
+
+// KeyTypeCaminoCertProvider implements CaminoCertProvider by providing certs for keys of a given type, signed by the same
+// root CA as the RandomCaminoCertProvider's
+type KeyTypeCaminoCertProvider struct {
+	keyType KeyType
+	random  *mathrand.Rand
+}
+
+// NewKeyTypeCaminoCertProvider creates a new cert provider that produces a different cert, for a key of the given type, on
+// each call, generated from the given randomness
+func NewKeyTypeCaminoCertProvider(keyType KeyType, random *mathrand.Rand) *KeyTypeCaminoCertProvider {
+	return &KeyTypeCaminoCertProvider{
+		keyType: keyType,
+		random:  random,
+	}
+}
+
+// GetCertAndKey implements CaminoCertProvider
+func (k KeyTypeCaminoCertProvider) GetCertAndKey() (certPemBytes bytes.Buffer, keyPemBytes bytes.Buffer, err error) {
+	return generateCertAndKey(getServiceCert(k.random.Int63()), &rootCert, k.keyType, k.random)
+}
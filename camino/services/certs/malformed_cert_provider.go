@@ -0,0 +1,76 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package certs
+
+import (
+	"bytes"
+	"encoding/pem"
+	mathrand "math/rand"
+
+	"github.com/palantir/stacktrace"
+)
+
+// CertMalformation is a way in which a MalformedCaminoCertProvider breaks the certs it provides
+type CertMalformation string
+
+const (
+	// The cert's PEM is cut off halfway through
+	TruncatedPEM CertMalformation = "truncated-pem"
+
+	// The cert's PEM is well-formed, but the DER it holds isn't a cert
+	CorruptDER CertMalformation = "corrupt-der"
+
+	// The cert and the key are well-formed, but don't belong together
+	MismatchedKey CertMalformation = "mismatched-key"
+)
+
+// MalformedCaminoCertProvider implements CaminoCertProvider by providing certs and keys that a node can't load
+type MalformedCaminoCertProvider struct {
+	malformation CertMalformation
+	random       *mathrand.Rand
+}
+
+func NewMalformedCaminoCertProvider(malformation CertMalformation, random *mathrand.Rand) *MalformedCaminoCertProvider {
+	return &MalformedCaminoCertProvider{
+		malformation: malformation,
+		random:       random,
+	}
+}
+
+// GetCertAndKey implements CaminoCertProvider
+func (m MalformedCaminoCertProvider) GetCertAndKey() (certPemBytes bytes.Buffer, keyPemBytes bytes.Buffer, err error) {
+	validProvider := NewKeyTypeCaminoCertProvider(ECDSA, m.random)
+	certPEM, keyPEM, err := validProvider.GetCertAndKey()
+	if err != nil {
+		return bytes.Buffer{}, bytes.Buffer{}, stacktrace.Propagate(err, "Failed to generate the cert to malform.")
+	}
+	switch m.malformation {
+	case TruncatedPEM:
+		certBytes := certPEM.Bytes()
+		return *bytes.NewBuffer(certBytes[:len(certBytes)/2]), keyPEM, nil
+	case CorruptDER:
+		corruptPEM := new(bytes.Buffer)
+		if err := pem.Encode(corruptPEM, &pem.Block{
+			Type:  certificatePreamble,
+			Bytes: []byte("this is not a DER-encoded certificate"),
+		}); err != nil {
+			return bytes.Buffer{}, bytes.Buffer{}, err
+		}
+		return *corruptPEM, keyPEM, nil
+	case MismatchedKey:
+		_, otherKeyPEM, err := validProvider.GetCertAndKey()
+		if err != nil {
+			return bytes.Buffer{}, bytes.Buffer{}, stacktrace.Propagate(err, "Failed to generate the mismatched key.")
+		}
+		return certPEM, otherKeyPEM, nil
+	default:
+		return bytes.Buffer{}, bytes.Buffer{}, stacktrace.NewError("Unrecognized cert malformation %v", m.malformation)
+	}
+}
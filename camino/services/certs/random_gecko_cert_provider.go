@@ -11,16 +11,11 @@ package certs
 
 import (
 	"bytes"
-	"crypto/rand"
-	"crypto/rsa"
 	"crypto/x509"
 	"crypto/x509/pkix"
-	"encoding/pem"
 	"math/big"
 	mathrand "math/rand"
 	"time"
-
-	"github.com/palantir/stacktrace"
 )
 
 const (
@@ -48,17 +43,25 @@ var rootCert = x509.Certificate{
 
 // RandomCaminoCertProvider implements CaminoCertProviders by providing certs signed by the same root CA
 type RandomCaminoCertProvider struct {
+	random           *mathrand.Rand
 	nextSerialNumber int64
 	varyCerts        bool
+
+	// The cert and key handed out by every call when certs don't vary, generated on the first call
+	// The node ID is derived from the whole cert, so reusing the serial number alone wouldn't duplicate it
+	fixedCertPem []byte
+	fixedKeyPem  []byte
 }
 
 // NewRandomCaminoCertProvider creates a new cert provider that can optionally return either the same cert every time, or different ones
 // Args:
 // 	varyCerts: True to produce a different cert on each call to GetCertAndKey, or false to yield the same
 // 		randomly-generated cert each time
-func NewRandomCaminoCertProvider(varyCerts bool) *RandomCaminoCertProvider {
+// 	random: The randomness that the certs and keys are generated from, which fully determines them
+func NewRandomCaminoCertProvider(varyCerts bool, random *mathrand.Rand) *RandomCaminoCertProvider {
 	return &RandomCaminoCertProvider{
-		nextSerialNumber: mathrand.Int63(),
+		random:           random,
+		nextSerialNumber: random.Int63(),
 		varyCerts:        varyCerts,
 	}
 }
@@ -69,36 +72,23 @@ func NewRandomCaminoCertProvider(varyCerts bool) *RandomCaminoCertProvider {
 // 	certPemBytes: The bytes of the generated cert
 // 	keyPemBytes: The bytes of the private key that was generated alongside the cert
 func (r *RandomCaminoCertProvider) GetCertAndKey() (certPemBytes bytes.Buffer, keyPemBytes bytes.Buffer, err error) {
+	if !r.varyCerts && r.fixedCertPem != nil {
+		// Copies, so that callers can't change what later calls hand out
+		return *bytes.NewBuffer(append([]byte{}, r.fixedCertPem...)), *bytes.NewBuffer(append([]byte{}, r.fixedKeyPem...)), nil
+	}
 	serialNum := r.nextSerialNumber
 	if r.varyCerts {
-		r.nextSerialNumber = mathrand.Int63()
+		r.nextSerialNumber = r.random.Int63()
 	}
-	serviceCert := getServiceCert(serialNum)
-
-	certPrivKey, err := rsa.GenerateKey(rand.Reader, 4096)
+	certPEM, certPrivKeyPEM, err := generateCertAndKey(getServiceCert(serialNum), &rootCert, RSA, r.random)
 	if err != nil {
-		return bytes.Buffer{}, bytes.Buffer{}, stacktrace.Propagate(err, "Failed to generate random private key.")
-	}
-	certBytes, err := x509.CreateCertificate(rand.Reader, serviceCert, &rootCert, &(certPrivKey.PublicKey), certPrivKey)
-	if err != nil {
-		return bytes.Buffer{}, bytes.Buffer{}, stacktrace.Propagate(err, "Failed to sign service cert with cert authority.")
-	}
-	certPEM := new(bytes.Buffer)
-	if err := pem.Encode(certPEM, &pem.Block{
-		Type:  certificatePreamble,
-		Bytes: certBytes,
-	}); err != nil {
 		return bytes.Buffer{}, bytes.Buffer{}, err
 	}
-
-	certPrivKeyPEM := new(bytes.Buffer)
-	if err := pem.Encode(certPrivKeyPEM, &pem.Block{
-		Type:  privateKeyPreamble,
-		Bytes: x509.MarshalPKCS1PrivateKey(certPrivKey),
-	}); err != nil {
-		return bytes.Buffer{}, bytes.Buffer{}, err
+	if !r.varyCerts {
+		r.fixedCertPem = append([]byte{}, certPEM.Bytes()...)
+		r.fixedKeyPem = append([]byte{}, certPrivKeyPEM.Bytes()...)
 	}
-	return *certPEM, *certPrivKeyPEM, nil
+	return certPEM, certPrivKeyPEM, nil
 }
 
 // ================= Helper functions ===================
@@ -113,8 +103,8 @@ func getServiceCert(serialNumber int64) *x509.Certificate {
 			StreetAddress: []string{""},
 			PostalCode:    []string{""},
 		},
-		NotBefore:    time.Now(),
-		NotAfter:     time.Now().AddDate(10, 0, 0),
+		NotBefore:    seededCertNotBefore,
+		NotAfter:     seededCertNotAfter,
 		SubjectKeyId: []byte{1, 2, 3, 4, 6},
 		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
 		KeyUsage:     x509.KeyUsageDigitalSignature,
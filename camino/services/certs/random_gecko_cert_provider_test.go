@@ -0,0 +1,41 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package certs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNonVaryingCertsAreIdentical(t *testing.T) {
+	provider := NewRandomCaminoCertProvider(false, newTestRand())
+	firstCert, firstKey, err := provider.GetCertAndKey()
+	assert.NoError(t, err)
+	secondCert, secondKey, err := provider.GetCertAndKey()
+	assert.NoError(t, err)
+	assert.Equal(t, firstCert.Bytes(), secondCert.Bytes())
+	assert.Equal(t, firstKey.Bytes(), secondKey.Bytes())
+
+	// Consuming a handed out cert doesn't affect the next one
+	firstCert.Reset()
+	thirdCert, _, err := provider.GetCertAndKey()
+	assert.NoError(t, err)
+	assert.Equal(t, secondCert.Bytes(), thirdCert.Bytes())
+}
+
+func TestVaryingCertsDiffer(t *testing.T) {
+	provider := NewRandomCaminoCertProvider(true, newTestRand())
+	firstCert, _, err := provider.GetCertAndKey()
+	assert.NoError(t, err)
+	secondCert, _, err := provider.GetCertAndKey()
+	assert.NoError(t, err)
+	assert.NotEqual(t, firstCert.Bytes(), secondCert.Bytes())
+}
@@ -0,0 +1,35 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package certs
+
+import (
+	"bytes"
+	"crypto/x509/pkix"
+	mathrand "math/rand"
+)
+
+// SelfSignedCaminoCertProvider implements CaminoCertProvider by providing self-signed certs, which aren't issued by the
+// root CA that the certs of the other providers share
+type SelfSignedCaminoCertProvider struct {
+	random *mathrand.Rand
+}
+
+func NewSelfSignedCaminoCertProvider(random *mathrand.Rand) *SelfSignedCaminoCertProvider {
+	return &SelfSignedCaminoCertProvider{random: random}
+}
+
+// GetCertAndKey implements CaminoCertProvider
+func (s SelfSignedCaminoCertProvider) GetCertAndKey() (certPemBytes bytes.Buffer, keyPemBytes bytes.Buffer, err error) {
+	serviceCert := getServiceCert(s.random.Int63())
+	serviceCert.Subject = pkix.Name{
+		Organization: []string{"Self-Signed Camino Node"},
+	}
+	return generateCertAndKey(serviceCert, serviceCert, RSA, s.random)
+}
@@ -0,0 +1,60 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package certs
+
+import (
+	"bytes"
+	mathrand "math/rand"
+	"time"
+)
+
+// ValidityWindowCaminoCertProvider implements CaminoCertProvider by providing certs that are only valid within a given
+// window of time, which can be used to produce expired or not-yet-valid certs
+type ValidityWindowCaminoCertProvider struct {
+	notBefore time.Time
+	notAfter  time.Time
+	random    *mathrand.Rand
+}
+
+// NewValidityWindowCaminoCertProvider creates a new cert provider that produces a different cert, valid from notBefore
+// until notAfter, on each call, generated from the given randomness
+func NewValidityWindowCaminoCertProvider(notBefore time.Time, notAfter time.Time, random *mathrand.Rand) *ValidityWindowCaminoCertProvider {
+	return &ValidityWindowCaminoCertProvider{
+		notBefore: notBefore,
+		notAfter:  notAfter,
+		random:    random,
+	}
+}
+
+// NewExpiredCaminoCertProvider creates a new cert provider that produces certs which expired before the suite existed
+// The window is fixed rather than relative to now, so that the certs only depend on the randomness
+func NewExpiredCaminoCertProvider(random *mathrand.Rand) *ValidityWindowCaminoCertProvider {
+	return NewValidityWindowCaminoCertProvider(
+		time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC),
+		random)
+}
+
+// NewNotYetValidCaminoCertProvider creates a new cert provider that produces certs which only become valid in the far future
+// The window is fixed rather than relative to now, so that the certs only depend on the randomness
+func NewNotYetValidCaminoCertProvider(random *mathrand.Rand) *ValidityWindowCaminoCertProvider {
+	return NewValidityWindowCaminoCertProvider(
+		time.Date(2100, time.January, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2101, time.January, 1, 0, 0, 0, 0, time.UTC),
+		random)
+}
+
+// GetCertAndKey implements CaminoCertProvider
+func (v ValidityWindowCaminoCertProvider) GetCertAndKey() (certPemBytes bytes.Buffer, keyPemBytes bytes.Buffer, err error) {
+	serviceCert := getServiceCert(v.random.Int63())
+	serviceCert.NotBefore = v.notBefore
+	serviceCert.NotAfter = v.notAfter
+	return generateCertAndKey(serviceCert, &rootCert, RSA, v.random)
+}
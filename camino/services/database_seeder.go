@@ -0,0 +1,214 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package services
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/palantir/stacktrace"
+	"github.com/sirupsen/logrus"
+)
+
+// DatabaseSeeder copies the database of an earlier service into the database directory of the next service launched
+// from a CaminoServiceInitializerCore, so that the new node starts from the state the earlier one had reached (e.g. to
+// restart a bootstrap from a partially synced database). It can also give the next service the staking identity of the
+// earlier one, so that the new node carries on as the same validator.
+// NOTE: The source database must no longer be in use, as copying a database that a node is writing to won't give a
+// consistent copy
+type DatabaseSeeder struct {
+	mutex sync.Mutex
+
+	// The database directory, on the test volume, that the next launched service will be seeded from; empty if the next
+	// service should start with an empty database
+	pendingSourceDirpath string
+
+	// The staking cert and key that the next launched service will use instead of the ones its core's cert provider gives;
+	// nil if it should use the provider's
+	pendingCertPEM []byte
+	pendingKeyPEM  []byte
+}
+
+// SeedNextLaunch makes the next service launched from the seeder's core start with a copy of the given database directory
+// Args:
+// 	sourceDirpath: The database directory to copy, from the perspective of the testsuite container
+func (seeder *DatabaseSeeder) SeedNextLaunch(sourceDirpath string) {
+	seeder.mutex.Lock()
+	defer seeder.mutex.Unlock()
+	seeder.pendingSourceDirpath = sourceDirpath
+}
+
+// SeedNextLaunchFromNodeState makes the next service launched from the seeder's core start with a copy of the database
+// and the staking identity that SaveNodeState saved to the given directory
+// Args:
+// 	nodeStateDirpath: The directory SaveNodeState saved to, from the perspective of the testsuite container
+func (seeder *DatabaseSeeder) SeedNextLaunchFromNodeState(nodeStateDirpath string) error {
+	certPEM, err := readOptionalFile(filepath.Join(nodeStateDirpath, nodeStateCertFilename))
+	if err != nil {
+		return stacktrace.Propagate(err, "An error occurred reading the staking cert saved in %v", nodeStateDirpath)
+	}
+	keyPEM, err := readOptionalFile(filepath.Join(nodeStateDirpath, nodeStateKeyFilename))
+	if err != nil {
+		return stacktrace.Propagate(err, "An error occurred reading the staking key saved in %v", nodeStateDirpath)
+	}
+	if (certPEM == nil) != (keyPEM == nil) {
+		return stacktrace.NewError("Only one of the staking cert and key was saved in %v", nodeStateDirpath)
+	}
+
+	seeder.mutex.Lock()
+	defer seeder.mutex.Unlock()
+	seeder.pendingSourceDirpath = filepath.Join(nodeStateDirpath, nodeStateDBDirname)
+	seeder.pendingCertPEM = certPEM
+	seeder.pendingKeyPEM = keyPEM
+	return nil
+}
+
+// CancelPendingSeed makes the next service launched from the seeder's core start with an empty database and the identity
+// its core's cert provider gives again
+func (seeder *DatabaseSeeder) CancelPendingSeed() {
+	seeder.mutex.Lock()
+	defer seeder.mutex.Unlock()
+	seeder.pendingSourceDirpath = ""
+	seeder.pendingCertPEM = nil
+	seeder.pendingKeyPEM = nil
+}
+
+// SaveNodeState copies the database and the staking identity of a service into the given directory, from where
+// DatabaseSeeder.SeedNextLaunchFromNodeState can seed a later launch with them
+// NOTE: The service must have been removed from the network already, as copying a database that a node is writing to
+// won't give a consistent copy
+// Args:
+// 	launchDetails: The launch details of the service whose state to save
+// 	destDirpath: The directory to save the state to, which must not exist yet
+func SaveNodeState(launchDetails *CaminoServiceLaunchDetails, destDirpath string) error {
+	if _, err := os.Stat(destDirpath); !os.IsNotExist(err) {
+		return stacktrace.NewError("Node state directory %v already exists", destDirpath)
+	}
+	if err := os.MkdirAll(destDirpath, os.ModePerm); err != nil {
+		return stacktrace.Propagate(err, "Could not create node state directory %v", destDirpath)
+	}
+	dbDestDirpath := filepath.Join(destDirpath, nodeStateDBDirname)
+	if err := copyDirectory(launchDetails.GetDBDirpath(), dbDestDirpath); err != nil {
+		return stacktrace.Propagate(err, "An error occurred copying database directory %v to %v", launchDetails.GetDBDirpath(), dbDestDirpath)
+	}
+	if launchDetails.GetStakingCertFilepath() == "" {
+		return nil
+	}
+	if err := copyFile(launchDetails.GetStakingCertFilepath(), filepath.Join(destDirpath, nodeStateCertFilename), nodeStateFilePerms); err != nil {
+		return stacktrace.Propagate(err, "An error occurred copying the staking cert")
+	}
+	if err := copyFile(launchDetails.GetStakingKeyFilepath(), filepath.Join(destDirpath, nodeStateKeyFilename), nodeStateFilePerms); err != nil {
+		return stacktrace.Propagate(err, "An error occurred copying the staking key")
+	}
+	return nil
+}
+
+// seed copies the pending source database, if there is one, to the given directory and clears it
+func (seeder *DatabaseSeeder) seed(destDirpath string) error {
+	seeder.mutex.Lock()
+	defer seeder.mutex.Unlock()
+	sourceDirpath := seeder.pendingSourceDirpath
+	if sourceDirpath == "" {
+		return nil
+	}
+	seeder.pendingSourceDirpath = ""
+
+	logrus.Debugf("Seeding database directory %v from %v", destDirpath, sourceDirpath)
+	if err := copyDirectory(sourceDirpath, destDirpath); err != nil {
+		return stacktrace.Propagate(err, "An error occurred copying database directory %v to %v", sourceDirpath, destDirpath)
+	}
+	return nil
+}
+
+// takeIdentity hands off the pending staking cert and key, if there are any, and clears them
+func (seeder *DatabaseSeeder) takeIdentity() (bytes.Buffer, bytes.Buffer, bool) {
+	seeder.mutex.Lock()
+	defer seeder.mutex.Unlock()
+	certPEM, keyPEM := seeder.pendingCertPEM, seeder.pendingKeyPEM
+	if certPEM == nil {
+		return bytes.Buffer{}, bytes.Buffer{}, false
+	}
+	seeder.pendingCertPEM = nil
+	seeder.pendingKeyPEM = nil
+	return *bytes.NewBuffer(certPEM), *bytes.NewBuffer(keyPEM), true
+}
+
+// ================ Helper functions =========================
+const (
+	// The layout of the directories SaveNodeState saves to
+	nodeStateDBDirname    = "db"
+	nodeStateCertFilename = "staker.crt"
+	nodeStateKeyFilename  = "staker.key"
+
+	nodeStateFilePerms = 0600
+)
+
+/*
+Reads the file, returning nil if it doesn't exist
+*/
+func readOptionalFile(path string) ([]byte, error) {
+	fileBytes, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "Could not read file %v", path)
+	}
+	return fileBytes, nil
+}
+
+/*
+Recursively copies the contents of the source directory into the destination directory, creating it if needed
+*/
+func copyDirectory(sourceDirpath string, destDirpath string) error {
+	return filepath.Walk(sourceDirpath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return stacktrace.Propagate(err, "An error occurred walking to %v", path)
+		}
+		relativePath, err := filepath.Rel(sourceDirpath, path)
+		if err != nil {
+			return stacktrace.Propagate(err, "Could not get the path of %v relative to %v", path, sourceDirpath)
+		}
+		destPath := filepath.Join(destDirpath, relativePath)
+		if info.IsDir() {
+			if err := os.MkdirAll(destPath, info.Mode().Perm()); err != nil {
+				return stacktrace.Propagate(err, "Could not create directory %v", destPath)
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		return copyFile(path, destPath, info.Mode().Perm())
+	})
+}
+
+func copyFile(sourceFilepath string, destFilepath string, perm os.FileMode) error {
+	sourceFile, err := os.Open(sourceFilepath)
+	if err != nil {
+		return stacktrace.Propagate(err, "Could not open file %v", sourceFilepath)
+	}
+	defer sourceFile.Close()
+	destFile, err := os.OpenFile(destFilepath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return stacktrace.Propagate(err, "Could not create file %v", destFilepath)
+	}
+	if _, err := io.Copy(destFile, sourceFile); err != nil {
+		destFile.Close()
+		return stacktrace.Propagate(err, "Could not copy %v to %v", sourceFilepath, destFilepath)
+	}
+	if err := destFile.Close(); err != nil {
+		return stacktrace.Propagate(err, "Could not close file %v", destFilepath)
+	}
+	return nil
+}
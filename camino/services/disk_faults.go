@@ -0,0 +1,145 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package services
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/palantir/stacktrace"
+)
+
+// DiskFault is a way for the disk that a node keeps its database on to fail
+// Slow I/O isn't among them, as throttling a container's I/O takes access to the Docker host that the testsuite lacks.
+type DiskFault string
+
+const (
+	// FullDisk stops the node's files from growing, as if the disk had no space left on it
+	FullDisk DiskFault = "full"
+	// ReadOnlyDisk also stops the node from creating, renaming and removing files in its database directory, as if the
+	// disk had been remounted read-only
+	ReadOnlyDisk DiskFault = "read-only"
+)
+
+const (
+	// The directory, inside the service's directory on the test volume, where the faults the node's supervisor should
+	// apply are marked
+	diskFaultsDirname = "disk-faults"
+
+	// The marker that makes the node's supervisor stop the node's files from growing
+	noFileGrowthMarkerFilename = "no-file-growth"
+
+	diskFaultMarkerPerms = 0644
+)
+
+/*
+Runs the node with the capabilities that let root ignore file permissions dropped, so that making its files read-only
+	takes effect, and, as long as the node runs, limits the size its files may grow to whenever the no-file-growth marker
+	exists. The limit is checked on every write, so it also affects the files the node already has open.
+Args: The faults directory, then the node's command
+*/
+const diskFaultSupervisorScript = `faults_dirpath="$1"
+shift
+setpriv --bounding-set=-dac_override,-fowner --inh-caps=-dac_override,-fowner -- "$@" &
+node_pid=$!
+trap 'kill -TERM "${node_pid}"' TERM INT
+while kill -0 "${node_pid}" 2>/dev/null; do
+    if [ -e "${faults_dirpath}/` + noFileGrowthMarkerFilename + `" ]; then
+        prlimit --pid "${node_pid}" --fsize=0:unlimited 2>/dev/null
+    else
+        prlimit --pid "${node_pid}" --fsize=unlimited:unlimited 2>/dev/null
+    fi
+    sleep 1
+done
+wait "${node_pid}"`
+
+// GetDiskFaults returns every disk fault that can be injected
+func GetDiskFaults() []DiskFault {
+	return []DiskFault{FullDisk, ReadOnlyDisk}
+}
+
+// InjectDiskFault makes the disk of a node launched with disk fault injection fail in the given way, within a second,
+// until the faults are cleared
+// NOTE: The node's files outside its database directory, e.g. its logs, stop growing too, as they share the disk
+// Args:
+// 	launchDetails: The launch details of the node
+// 	fault: How the disk should fail
+func InjectDiskFault(launchDetails *CaminoServiceLaunchDetails, fault DiskFault) error {
+	faultsDirpath, err := getDiskFaultsDirpath(launchDetails)
+	if err != nil {
+		return stacktrace.Propagate(err, "Can't inject disk faults into the node")
+	}
+	switch fault {
+	case FullDisk:
+	case ReadOnlyDisk:
+		// The testsuite has the test volume mounted too, so the node's files can be made read-only from here
+		if err := setDirectoryWritable(launchDetails.GetDBDirpath(), false); err != nil {
+			return stacktrace.Propagate(err, "An error occurred making the database directory read-only")
+		}
+	default:
+		return stacktrace.NewError("Unrecognized disk fault '%v'", fault)
+	}
+	markerFilepath := filepath.Join(faultsDirpath, noFileGrowthMarkerFilename)
+	if err := os.WriteFile(markerFilepath, []byte(fault), diskFaultMarkerPerms); err != nil {
+		return stacktrace.Propagate(err, "An error occurred marking the node's files to stop growing")
+	}
+	return nil
+}
+
+// ClearDiskFaults undoes all the disk faults injected into a node, within a second; the node's database is made writable
+// again even if the node is no longer running, so that it can be used to seed another node
+func ClearDiskFaults(launchDetails *CaminoServiceLaunchDetails) error {
+	faultsDirpath, err := getDiskFaultsDirpath(launchDetails)
+	if err != nil {
+		return stacktrace.Propagate(err, "Can't clear disk faults from the node")
+	}
+	if err := os.Remove(filepath.Join(faultsDirpath, noFileGrowthMarkerFilename)); err != nil && !os.IsNotExist(err) {
+		return stacktrace.Propagate(err, "An error occurred letting the node's files grow again")
+	}
+	if err := setDirectoryWritable(launchDetails.GetDBDirpath(), true); err != nil {
+		return stacktrace.Propagate(err, "An error occurred making the database directory writable again")
+	}
+	return nil
+}
+
+// ================ Helper functions =========================
+func getDiskFaultsDirpath(launchDetails *CaminoServiceLaunchDetails) (string, error) {
+	if launchDetails == nil {
+		return "", stacktrace.NewError("No launch details were recorded for the node, so its disk can't be found")
+	}
+	if launchDetails.GetDiskFaultsDirpath() == "" {
+		return "", stacktrace.NewError("The node wasn't launched with disk fault injection enabled")
+	}
+	return launchDetails.GetDiskFaultsDirpath(), nil
+}
+
+/*
+Adds or removes the owner's write permission on the directory and everything in it; directories are changed before their
+	contents, so that the node can't create files in them that are missed
+*/
+func setDirectoryWritable(dirpath string, writable bool) error {
+	return filepath.Walk(dirpath, func(path string, info os.FileInfo, err error) error {
+		if os.IsNotExist(err) {
+			// The node removed it while it was being walked
+			return nil
+		}
+		if err != nil {
+			return stacktrace.Propagate(err, "An error occurred walking to %v", path)
+		}
+		perms := info.Mode().Perm() &^ 0222
+		if writable {
+			perms = info.Mode().Perm() | 0200
+		}
+		if err := os.Chmod(path, perms); err != nil && !os.IsNotExist(err) {
+			return stacktrace.Propagate(err, "Could not change the permissions of %v", path)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,67 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package apis
+
+import (
+	"time"
+
+	"github.com/chain4travel/camino-testing/camino_client/utils"
+	"github.com/chain4travel/caminogo/api"
+	"github.com/chain4travel/caminogo/api/admin"
+	"github.com/chain4travel/caminogo/utils/logging"
+	"github.com/palantir/stacktrace"
+)
+
+const (
+	adminEndpoint = "/ext/admin"
+	adminBase     = "admin"
+)
+
+// AdminLoggerClient covers the logger methods of the admin API, which caminogo's admin.Client doesn't expose
+type AdminLoggerClient struct {
+	requester utils.EndpointRequester
+}
+
+func newAdminLoggerClient(uri string, requestTimeout time.Duration) AdminLoggerClient {
+	return AdminLoggerClient{
+		requester: utils.NewEndpointRequester(uri, adminEndpoint, adminBase, requestTimeout),
+	}
+}
+
+// SetLoggerLevel sets the log and display levels of the logger with the given name, or of all loggers if the name is
+// empty. A nil level leaves the corresponding level untouched.
+func (c AdminLoggerClient) SetLoggerLevel(loggerName string, logLevel *logging.Level, displayLevel *logging.Level) error {
+	reply := &api.SuccessResponse{}
+	err := c.requester.SendRequest("setLoggerLevel", &admin.SetLoggerLevelArgs{
+		LoggerName:   loggerName,
+		LogLevel:     logLevel,
+		DisplayLevel: displayLevel,
+	}, reply)
+	if err != nil {
+		return err
+	}
+	if !reply.Success {
+		return stacktrace.NewError("Node reported failure setting the level of logger '%v'", loggerName)
+	}
+	return nil
+}
+
+// GetLoggerLevel returns the log and display levels of the logger with the given name, or of all loggers if the name is
+// empty, keyed by logger name
+func (c AdminLoggerClient) GetLoggerLevel(loggerName string) (map[string]admin.LogAndDisplayLevels, error) {
+	reply := &admin.GetLoggerLevelReply{}
+	err := c.requester.SendRequest("getLoggerLevel", &admin.GetLoggerLevelArgs{
+		LoggerName: loggerName,
+	}, reply)
+	if err != nil {
+		return nil, err
+	}
+	return reply.LoggerLevels, nil
+}
@@ -0,0 +1,175 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+// Package apistest provides a fake node for unit testing code that talks to the node APIs through an apis.Client
+package apistest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/chain4travel/camino-testing/camino_client/apis"
+)
+
+const (
+	// How long the clients of a fake node wait for an answer
+	fakeNodeRequestTimeout = time.Second
+
+	// The JSON-RPC error codes a fake node answers with
+	invalidRequestErrCode = -32600
+	serverErrCode         = -32000
+)
+
+// Response is a fake node's answer to a single call of a method: a JSON result, or a JSON-RPC error if Err is set
+type Response struct {
+	Result string
+
+	Err string
+}
+
+// Result creates a response with the given JSON result
+func Result(result string) Response {
+	return Response{Result: result}
+}
+
+// Resultf creates a response with the JSON result that the format string yields
+func Resultf(format string, args ...interface{}) Response {
+	return Response{Result: fmt.Sprintf(format, args...)}
+}
+
+// Error creates a response that's a JSON-RPC error with the given message
+func Error(message string) Response {
+	return Response{Err: message}
+}
+
+// FakeNode serves the JSON-RPC APIs of a node over HTTP, answering each method the way the test set it up to, whatever
+// endpoint it's called on. Calls of a method that wasn't set up are answered with a 404, or with the fallback response
+// if there is one.
+type FakeNode struct {
+	server *httptest.Server
+
+	mutex sync.Mutex
+
+	// The responses to each method, the next of which answers each call, repeating the last one
+	responses map[string][]Response
+
+	// The number of calls of each method so far
+	numCalls map[string]int
+
+	// Computes the response to each call of a method from the call's params
+	responders map[string]func(params json.RawMessage) Response
+
+	fallback *Response
+}
+
+// NewFakeNode starts a fake node that's stopped when the test finishes
+func NewFakeNode(t *testing.T) *FakeNode {
+	node := &FakeNode{
+		responses:  map[string][]Response{},
+		numCalls:   map[string]int{},
+		responders: map[string]func(params json.RawMessage) Response{},
+	}
+	node.server = httptest.NewServer(http.HandlerFunc(node.serveHTTP))
+	t.Cleanup(node.server.Close)
+	return node
+}
+
+// On makes the node answer each call of [method] with the next of [responses], repeating the last one
+func (node *FakeNode) On(method string, responses ...Response) *FakeNode {
+	node.mutex.Lock()
+	defer node.mutex.Unlock()
+	node.responses[method] = responses
+	node.numCalls[method] = 0
+	return node
+}
+
+// OnParams makes the node answer each call of [method] with the response [respond] computes from the call's params
+func (node *FakeNode) OnParams(method string, respond func(params json.RawMessage) Response) *FakeNode {
+	node.mutex.Lock()
+	defer node.mutex.Unlock()
+	node.responders[method] = respond
+	return node
+}
+
+// OnAnyOther makes the node answer calls of the methods that weren't set up with [response]
+func (node *FakeNode) OnAnyOther(response Response) *FakeNode {
+	node.mutex.Lock()
+	defer node.mutex.Unlock()
+	node.fallback = &response
+	return node
+}
+
+// GetClient returns a client of the node
+func (node *FakeNode) GetClient() *apis.Client {
+	return apis.NewClient(node.server.URL, fakeNodeRequestTimeout)
+}
+
+// ================ Helper functions =========================
+func (node *FakeNode) serveHTTP(writer http.ResponseWriter, request *http.Request) {
+	requestBody := struct {
+		Method string          `json:"method"`
+		Params json.RawMessage `json:"params"`
+	}{}
+	writer.Header().Set("Content-Type", "application/json")
+	// Bodies that don't decode the way the real clients send them are answered with an error
+	if err := json.NewDecoder(request.Body).Decode(&requestBody); err != nil {
+		writeError(writer, invalidRequestErrCode, "invalid request")
+		return
+	}
+	response, found := node.getResponse(requestBody.Method, requestBody.Params)
+	if !found {
+		http.Error(writer, fmt.Sprintf("unexpected method %v", requestBody.Method), http.StatusNotFound)
+		return
+	}
+	if response.Err != "" {
+		writeError(writer, serverErrCode, response.Err)
+		return
+	}
+	fmt.Fprintf(writer, `{"jsonrpc": "2.0", "id": 1, "result": %v}`, response.Result)
+}
+
+/*
+Returns:
+	The response to the next call of the method, and whether the method was set up or there's a fallback response
+*/
+func (node *FakeNode) getResponse(method string, params json.RawMessage) (Response, bool) {
+	node.mutex.Lock()
+	respond, found := node.responders[method]
+	node.mutex.Unlock()
+	// The responder is called without the lock held, so that it can read state the test guards with its own lock
+	if found {
+		return respond(params), true
+	}
+
+	node.mutex.Lock()
+	defer node.mutex.Unlock()
+	responses, found := node.responses[method]
+	if !found || len(responses) == 0 {
+		if node.fallback == nil {
+			return Response{}, false
+		}
+		return *node.fallback, true
+	}
+	response := responses[len(responses)-1]
+	if numCalls := node.numCalls[method]; numCalls < len(responses) {
+		response = responses[numCalls]
+	}
+	node.numCalls[method]++
+	return response, true
+}
+
+func writeError(writer http.ResponseWriter, code int, message string) {
+	messageBytes, _ := json.Marshal(message)
+	fmt.Fprintf(writer, `{"jsonrpc": "2.0", "id": 1, "error": {"code": %d, "message": %s}}`, code, messageBytes)
+}
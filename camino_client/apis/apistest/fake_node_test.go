@@ -0,0 +1,66 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package apistest
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFakeNodeRepeatsLastResponse(t *testing.T) {
+	client := NewFakeNode(t).
+		On("info.getNetworkID", Result(`{"networkID": "1"}`), Error("not yet"), Result(`{"networkID": "3"}`)).
+		GetClient()
+
+	networkID, err := client.InfoAPI().GetNetworkID(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(1), networkID)
+	_, err = client.InfoAPI().GetNetworkID(context.Background())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "not yet")
+	for i := 0; i < 2; i++ {
+		networkID, err = client.InfoAPI().GetNetworkID(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(3), networkID)
+	}
+}
+
+func TestFakeNodeAnswersFromParams(t *testing.T) {
+	client := NewFakeNode(t).
+		OnParams("info.isBootstrapped", func(params json.RawMessage) Response {
+			bootstrappedParams := struct {
+				Chain string `json:"chain"`
+			}{}
+			assert.NoError(t, json.Unmarshal(params, &bootstrappedParams))
+			return Resultf(`{"isBootstrapped": %v}`, bootstrappedParams.Chain == "P")
+		}).
+		GetClient()
+
+	isBootstrapped, err := client.InfoAPI().IsBootstrapped(context.Background(), "P")
+	assert.NoError(t, err)
+	assert.True(t, isBootstrapped)
+	isBootstrapped, err = client.InfoAPI().IsBootstrapped(context.Background(), "X")
+	assert.NoError(t, err)
+	assert.False(t, isBootstrapped)
+}
+
+func TestFakeNodeRejectsMethodsNotSetUp(t *testing.T) {
+	node := NewFakeNode(t)
+	_, err := node.GetClient().InfoAPI().GetNodeID(context.Background())
+	assert.Error(t, err)
+
+	node.OnAnyOther(Error("invalid params"))
+	_, err = node.GetClient().InfoAPI().GetNodeID(context.Background())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid params")
+}
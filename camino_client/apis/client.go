@@ -12,6 +12,7 @@ package apis
 import (
 	"time"
 
+	"github.com/chain4travel/camino-testing/camino_client/utils"
 	"github.com/chain4travel/caminogo/api/admin"
 	"github.com/chain4travel/caminogo/api/health"
 	"github.com/chain4travel/caminogo/api/info"
@@ -26,25 +27,29 @@ const (
 )
 
 type Client struct {
-	admin    admin.Client
-	xChain   avm.Client
-	health   health.Client
-	info     info.Client
-	ipcs     ipcs.Client
-	keystore keystore.Client
-	platform platformvm.Client
+	admin       admin.Client
+	adminLogger AdminLoggerClient
+	xChain      avm.Client
+	health      health.Client
+	info        info.Client
+	ipcs        ipcs.Client
+	keystore    keystore.Client
+	platform    platformvm.Client
+	requester   utils.CaminoRPCRequester
 }
 
 // Returns a Client for interacting with the P Chain endpoint
 func NewClient(uri string, requestTimeout time.Duration) *Client {
 	return &Client{
-		admin:    admin.NewClient(uri),
-		xChain:   avm.NewClient(uri, XChain),
-		health:   health.NewClient(uri),
-		info:     info.NewClient(uri),
-		ipcs:     ipcs.NewClient(uri),
-		keystore: keystore.NewClient(uri),
-		platform: platformvm.NewClient(uri),
+		admin:       admin.NewClient(uri),
+		adminLogger: newAdminLoggerClient(uri, requestTimeout),
+		xChain:      avm.NewClient(uri, XChain),
+		health:      health.NewClient(uri),
+		info:        info.NewClient(uri),
+		ipcs:        ipcs.NewClient(uri),
+		keystore:    keystore.NewClient(uri),
+		platform:    platformvm.NewClient(uri),
+		requester:   utils.NewCaminoRPCRequester(uri, requestTimeout),
 	}
 }
 
@@ -75,3 +80,13 @@ func (c *Client) KeystoreAPI() keystore.Client {
 func (c *Client) AdminAPI() admin.Client {
 	return c.admin
 }
+
+func (c *Client) AdminLoggerAPI() AdminLoggerClient {
+	return c.adminLogger
+}
+
+// RPCRequester returns a requester that sends requests to any of the node's endpoints, including raw ones that aren't
+// valid JSON-RPC
+func (c *Client) RPCRequester() utils.CaminoRPCRequester {
+	return c.requester
+}
@@ -12,6 +12,7 @@ package utils
 import (
 	"bytes"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"strings"
 	"time"
@@ -30,6 +31,10 @@ const (
 // CaminoRPCRequester ...
 type CaminoRPCRequester interface {
 	SendJSONRPCRequest(endpoint string, method string, params interface{}, reply interface{}) error
+
+	// SendRawJSONRPCRequest posts the body to the endpoint as is, without checking that it's a valid JSON-RPC request,
+	// and returns the status code and body of the response without decoding them
+	SendRawJSONRPCRequest(endpoint string, body []byte) (int, []byte, error)
 }
 
 type jsonRPCRequester struct {
@@ -49,20 +54,14 @@ func NewCaminoRPCRequester(uri string, requestTimeout time.Duration) CaminoRPCRe
 
 // SendJSONRPCRequest ...
 func (requester jsonRPCRequester) SendJSONRPCRequest(endpoint string, method string, params interface{}, reply interface{}) error {
-	// Golang has a nasty & subtle behaviour where duplicated '//' in the URL is treated as GET, even if it's POST
-	// https://stackoverflow.com/questions/23463601/why-golang-treats-my-post-request-as-a-get-one
-	endpoint = strings.TrimLeft(endpoint, "/")
-
 	requestBodyBytes, err := rpc.EncodeClientRequest(method, params)
 	if err != nil {
 		return fmt.Errorf("problem marshaling request to endpoint '%v' with method '%v' and params '%v': %w", endpoint, method, params, err)
 	}
 
-	url := fmt.Sprintf("%v/%v", requester.uri, endpoint)
-	logrus.Tracef("Sending request to %s:\n%s\n", url, requestBodyBytes)
-	resp, err := requester.client.Post(url, "application/json", bytes.NewBuffer(requestBodyBytes))
+	resp, err := requester.post(endpoint, requestBodyBytes)
 	if err != nil {
-		return fmt.Errorf("problem while making JSON RPC POST request to %s: %s", url, err)
+		return err
 	}
 	defer resp.Body.Close()
 	statusCode := resp.StatusCode
@@ -75,6 +74,35 @@ func (requester jsonRPCRequester) SendJSONRPCRequest(endpoint string, method str
 	return rpc.DecodeClientResponse(resp.Body, reply)
 }
 
+// SendRawJSONRPCRequest ...
+func (requester jsonRPCRequester) SendRawJSONRPCRequest(endpoint string, body []byte) (int, []byte, error) {
+	resp, err := requester.post(endpoint, body)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+
+	responseBodyBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, nil, fmt.Errorf("problem reading the response from endpoint '%v': %w", endpoint, err)
+	}
+	return resp.StatusCode, responseBodyBytes, nil
+}
+
+func (requester jsonRPCRequester) post(endpoint string, requestBodyBytes []byte) (*http.Response, error) {
+	// Golang has a nasty & subtle behaviour where duplicated '//' in the URL is treated as GET, even if it's POST
+	// https://stackoverflow.com/questions/23463601/why-golang-treats-my-post-request-as-a-get-one
+	endpoint = strings.TrimLeft(endpoint, "/")
+
+	url := fmt.Sprintf("%v/%v", requester.uri, endpoint)
+	logrus.Tracef("Sending request to %s:\n%s\n", url, requestBodyBytes)
+	resp, err := requester.client.Post(url, "application/json", bytes.NewBuffer(requestBodyBytes))
+	if err != nil {
+		return nil, fmt.Errorf("problem while making JSON RPC POST request to %s: %s", url, err)
+	}
+	return resp, nil
+}
+
 // EndpointRequester ...
 type EndpointRequester interface {
 	SendRequest(method string, params interface{}, reply interface{}) error
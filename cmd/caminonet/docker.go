@@ -0,0 +1,123 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package main
+
+import (
+	"bytes"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/palantir/stacktrace"
+)
+
+const (
+	dockerBinary = "docker"
+
+	// The label that every container and network of a caminonet network carries, with the network's name as its value, so
+	// that they can be cleaned up even if the state file no longer lists them
+	networkNameLabel = "caminonet.network"
+)
+
+/*
+Runs the Docker CLI with the given args and returns what it printed, trimmed
+*/
+func runDocker(args ...string) (string, error) {
+	cmd := exec.Command(dockerBinary, args...)
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return "", stacktrace.Propagate(err, "'docker %v' failed: %v", strings.Join(args, " "), strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+func createDockerNetwork(networkName string, subnet string) error {
+	if _, err := runDocker("network", "create", "--subnet", subnet, "--label", networkNameLabel+"="+networkName, networkName); err != nil {
+		return stacktrace.Propagate(err, "An error occurred creating Docker network %v with subnet %v", networkName, subnet)
+	}
+	return nil
+}
+
+/*
+Starts a detached container on the given Docker network with the given IP
+Args:
+	networkName: The Docker network to attach the container to, which is also the value of its network name label
+	ipAddr: The IP the container gets on the network
+	volumeMounts: host path -> container path of the directories to bind-mount into the container
+	envVars: The environment variables of the container
+	imageName: The image to start the container from
+	cmd: The command to start the container with, replacing the image's default command
+Returns:
+	The ID of the container
+*/
+func runContainer(
+	networkName string,
+	ipAddr string,
+	volumeMounts map[string]string,
+	envVars map[string]string,
+	imageName string,
+	cmd []string) (string, error) {
+	args := []string{"run", "--detach", "--network", networkName, "--ip", ipAddr, "--label", networkNameLabel + "=" + networkName}
+	for hostPath, containerPath := range volumeMounts {
+		args = append(args, "--volume", hostPath+":"+containerPath)
+	}
+	for key, value := range envVars {
+		args = append(args, "--env", key+"="+value)
+	}
+	args = append(args, imageName)
+	args = append(args, cmd...)
+	containerID, err := runDocker(args...)
+	if err != nil {
+		return "", stacktrace.Propagate(err, "An error occurred starting a container of image %v at %v", imageName, ipAddr)
+	}
+	return containerID, nil
+}
+
+/*
+Stops the container, giving it the given number of seconds to stop before it's killed, and removes it
+*/
+func removeContainer(containerID string, stopTimeoutSeconds int) error {
+	if _, err := runDocker("stop", "--time", strconv.Itoa(stopTimeoutSeconds), containerID); err != nil {
+		return stacktrace.Propagate(err, "An error occurred stopping container %v", containerID)
+	}
+	if _, err := runDocker("rm", containerID); err != nil {
+		return stacktrace.Propagate(err, "An error occurred removing container %v", containerID)
+	}
+	return nil
+}
+
+/*
+Force-removes every container and network carrying the network name label of the given caminonet network
+*/
+func removeLabeledDockerResources(networkName string) error {
+	labelFilter := "label=" + networkNameLabel + "=" + networkName
+	containerIDs, err := runDocker("ps", "--all", "--quiet", "--filter", labelFilter)
+	if err != nil {
+		return stacktrace.Propagate(err, "An error occurred listing the containers of network %v", networkName)
+	}
+	if containerIDs != "" {
+		if _, err := runDocker(append([]string{"rm", "--force"}, strings.Fields(containerIDs)...)...); err != nil {
+			return stacktrace.Propagate(err, "An error occurred removing the containers of network %v", networkName)
+		}
+	}
+	networkIDs, err := runDocker("network", "ls", "--quiet", "--filter", labelFilter)
+	if err != nil {
+		return stacktrace.Propagate(err, "An error occurred listing the Docker networks of network %v", networkName)
+	}
+	if networkIDs != "" {
+		if _, err := runDocker(append([]string{"network", "rm"}, strings.Fields(networkIDs)...)...); err != nil {
+			return stacktrace.Propagate(err, "An error occurred removing the Docker network of network %v", networkName)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package main
+
+import (
+	"encoding/binary"
+	"net"
+	"net/http"
+	"net/rpc"
+	"strings"
+	"sync"
+
+	"github.com/kurtosis-tech/kurtosis-go/lib/kurtosis_service"
+	"github.com/palantir/stacktrace"
+	"github.com/powerman/rpc-codec/jsonrpc2"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// Kurtosis' client always calls its API on this port
+	kurtosisAPIPort = "7443"
+
+	// The name the Kurtosis client calls the API's methods under
+	kurtosisAPIServiceName = "KurtosisService"
+
+	// The first host index of a subnet that's handed out to containers, as Docker gives the one before it to the gateway
+	firstContainerHostIndex = 2
+)
+
+// localKurtosisAPI stands in for the API container that Kurtosis runs for every test, so that Kurtosis' ServiceNetwork,
+// and the Camino networks built on it, can be used outside of Kurtosis. It serves Kurtosis' JSON-RPC API, starting the
+// services in containers on a Docker network of its own through the Docker CLI.
+type localKurtosisAPI struct {
+	mutex *sync.Mutex
+
+	dockerNetworkName string
+
+	subnet *net.IPNet
+
+	// The host index, within the subnet, of the IP the next container gets
+	nextHostIndex uint32
+
+	// The host directory that's mounted into every container as its test volume
+	volumeDirpath string
+
+	// IP -> ID of the containers that have been started and not removed
+	containerIDs map[string]string
+}
+
+func newLocalKurtosisAPI(dockerNetworkName string, subnet string, volumeDirpath string) (*localKurtosisAPI, error) {
+	_, parsedSubnet, err := net.ParseCIDR(subnet)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "Could not parse subnet '%v'", subnet)
+	}
+	if parsedSubnet.IP.To4() == nil {
+		return nil, stacktrace.NewError("Subnet '%v' isn't an IPv4 subnet", subnet)
+	}
+	return &localKurtosisAPI{
+		mutex:             &sync.Mutex{},
+		dockerNetworkName: dockerNetworkName,
+		subnet:            parsedSubnet,
+		nextHostIndex:     firstContainerHostIndex,
+		volumeDirpath:     volumeDirpath,
+		containerIDs:      make(map[string]string),
+	}, nil
+}
+
+// AddService implements the Kurtosis API method of the same name
+func (api *localKurtosisAPI) AddService(args kurtosis_service.AddServiceArgs, reply *kurtosis_service.AddServiceResponse) error {
+	api.mutex.Lock()
+	defer api.mutex.Unlock()
+	ipAddr, err := getSubnetIP(api.subnet, api.nextHostIndex)
+	if err != nil {
+		return stacktrace.Propagate(err, "No IP is left for the service")
+	}
+	api.nextHostIndex++
+
+	startCmd := make([]string, 0, len(args.StartCmd))
+	for _, arg := range args.StartCmd {
+		startCmd = append(startCmd, strings.ReplaceAll(arg, args.IPPlaceholder, ipAddr))
+	}
+	envVars := make(map[string]string, len(args.DockerEnvironmentVars))
+	for key, value := range args.DockerEnvironmentVars {
+		envVars[key] = strings.ReplaceAll(value, args.IPPlaceholder, ipAddr)
+	}
+	containerID, err := runContainer(
+		api.dockerNetworkName,
+		ipAddr,
+		map[string]string{api.volumeDirpath: args.TestVolumeMountFilepath},
+		envVars,
+		args.ImageName,
+		startCmd)
+	if err != nil {
+		return stacktrace.Propagate(err, "An error occurred starting the container of the service")
+	}
+	logrus.Debugf("Started container %v of image %v at %v", containerID, args.ImageName, ipAddr)
+	api.containerIDs[ipAddr] = containerID
+	reply.IPAddress = ipAddr
+	reply.ContainerID = containerID
+	return nil
+}
+
+// RemoveService implements the Kurtosis API method of the same name
+func (api *localKurtosisAPI) RemoveService(args kurtosis_service.RemoveServiceArgs, reply *struct{}) error {
+	if err := removeContainer(args.ContainerID, args.ContainerStopTimeoutSeconds); err != nil {
+		return stacktrace.Propagate(err, "An error occurred removing the container of the service")
+	}
+	api.mutex.Lock()
+	defer api.mutex.Unlock()
+	for ipAddr, containerID := range api.containerIDs {
+		if containerID == args.ContainerID {
+			delete(api.containerIDs, ipAddr)
+		}
+	}
+	return nil
+}
+
+// RegisterTestExecution implements the Kurtosis API method of the same name; there's no test whose timeout to enforce
+func (api *localKurtosisAPI) RegisterTestExecution(args kurtosis_service.RegisterTestExecutionArgs, reply *struct{}) error {
+	return nil
+}
+
+// getContainerID returns the ID of the running container with the given IP
+func (api *localKurtosisAPI) getContainerID(ipAddr string) (string, bool) {
+	api.mutex.Lock()
+	defer api.mutex.Unlock()
+	containerID, found := api.containerIDs[ipAddr]
+	return containerID, found
+}
+
+/*
+Serves the API on the port the Kurtosis client calls it on, on the given IP, until the returned server is closed
+*/
+func serveLocalKurtosisAPI(api *localKurtosisAPI, ipAddr string) (*http.Server, error) {
+	rpcServer := rpc.NewServer()
+	if err := rpcServer.RegisterName(kurtosisAPIServiceName, api); err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred registering the Kurtosis API")
+	}
+	listener, err := net.Listen("tcp", net.JoinHostPort(ipAddr, kurtosisAPIPort))
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "Could not listen on port %v of %v for Kurtosis API calls; is another caminonet network up?", kurtosisAPIPort, ipAddr)
+	}
+	httpServer := &http.Server{Handler: jsonrpc2.HTTPHandler(rpcServer)}
+	go func() {
+		if err := httpServer.Serve(listener); err != nil && err != http.ErrServerClosed {
+			logrus.Errorf("The Kurtosis API stopped serving: %v", err)
+		}
+	}()
+	return httpServer, nil
+}
+
+/*
+Gets the IP with the given host index within the subnet, which must be neither the subnet's own address nor its
+	broadcast address
+*/
+func getSubnetIP(subnet *net.IPNet, hostIndex uint32) (string, error) {
+	ones, bits := subnet.Mask.Size()
+	numAddresses := uint64(1) << uint(bits-ones)
+	if hostIndex == 0 || uint64(hostIndex) >= numAddresses-1 {
+		return "", stacktrace.NewError("Host index %v is out of the usable range of subnet %v", hostIndex, subnet)
+	}
+	ip := make(net.IP, net.IPv4len)
+	binary.BigEndian.PutUint32(ip, binary.BigEndian.Uint32(subnet.IP.To4())+hostIndex)
+	return ip.String(), nil
+}
@@ -0,0 +1,42 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package main
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetSubnetIP(t *testing.T) {
+	_, subnet, err := net.ParseCIDR("172.30.0.0/24")
+	assert.NoError(t, err)
+
+	ip, err := getSubnetIP(subnet, firstContainerHostIndex)
+	assert.NoError(t, err)
+	assert.Equal(t, "172.30.0.2", ip)
+	ip, err = getSubnetIP(subnet, 254)
+	assert.NoError(t, err)
+	assert.Equal(t, "172.30.0.254", ip)
+
+	// The subnet's own address and its broadcast address can't be handed out
+	_, err = getSubnetIP(subnet, 0)
+	assert.Error(t, err)
+	_, err = getSubnetIP(subnet, 255)
+	assert.Error(t, err)
+}
+
+func TestNewLocalKurtosisAPIRejectsBadSubnets(t *testing.T) {
+	_, err := newLocalKurtosisAPI("caminonet", "172.30.0.0", t.TempDir())
+	assert.Error(t, err)
+	_, err = newLocalKurtosisAPI("caminonet", "fd00::/64", t.TempDir())
+	assert.Error(t, err)
+}
@@ -0,0 +1,346 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+// caminonet brings up a local Camino network that outlives the command, for development against, using the same network
+// loader as the testsuite
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	caminoService "github.com/chain4travel/camino-testing/camino/services"
+	"github.com/chain4travel/camino-testing/camino_client/apis"
+	"github.com/chain4travel/camino-testing/utils/constants"
+	"github.com/chain4travel/caminogo/utils/units"
+	"github.com/palantir/stacktrace"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultNetworkName = "caminonet"
+
+	defaultSubnet = "172.30.0.0/24"
+
+	defaultStateDirname = ".caminonet"
+
+	usage = `Usage: caminonet [--state-dir=DIR] <command> [flags]
+
+Commands:
+  up          Bring a network up and hold it until it's brought down
+  status      Print the nodes of the network and whether they're live
+  add-node    Add a node that isn't a validator to the network
+  stop-node   Stop a node of the network
+  down        Bring the network down
+
+Run 'caminonet <command> --help' for the flags of a command.
+`
+)
+
+func main() {
+	logrus.SetFormatter(&logrus.TextFormatter{
+		ForceColors:   true,
+		FullTimestamp: true,
+	})
+
+	stateDirpathArg := flag.String(
+		"state-dir",
+		getDefaultStateDirpath(),
+		"Directory where the state file of the network, its control socket and the files of its nodes are kept")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	command, commandArgs := flag.Arg(0), flag.Args()[1:]
+	var err error
+	switch command {
+	case "up":
+		err = runUp(*stateDirpathArg, commandArgs)
+	case "status":
+		err = runStatus(*stateDirpathArg, commandArgs)
+	case "add-node":
+		err = runAddNode(*stateDirpathArg, commandArgs)
+	case "stop-node":
+		err = runStopNode(*stateDirpathArg, commandArgs)
+	case "down":
+		err = runDown(*stateDirpathArg, commandArgs)
+	default:
+		fmt.Fprintf(os.Stderr, "Unrecognized command '%v'\n\n", command)
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		logrus.Errorf("An error occurred running command '%v':", command)
+		fmt.Fprintln(logrus.StandardLogger().Out, err)
+		os.Exit(1)
+	}
+}
+
+func runUp(stateDirpath string, args []string) error {
+	flags := flag.NewFlagSet("up", flag.ExitOnError)
+	nameArg := flags.String(
+		"name",
+		defaultNetworkName,
+		"Name of the network, which is also the name of its Docker network and labels its containers")
+	imageArg := flags.String(
+		"image",
+		"",
+		"Name of the Camino Go Docker image that the nodes are launched from")
+	numNodesArg := flags.Int(
+		"nodes",
+		5,
+		"Number of nodes to start with, of which the first 5 are the genesis validators")
+	subnetArg := flags.String(
+		"subnet",
+		defaultSubnet,
+		"IPv4 subnet of the network's Docker network, which must not overlap with any other network of the host")
+	logLevelArg := flags.String(
+		"log-level",
+		string(caminoService.INFO),
+		"Log level of the nodes")
+	snowSampleSizeArg := flags.Int(
+		"snow-sample-size",
+		2,
+		"Snow sample size of the nodes")
+	snowQuorumSizeArg := flags.Int(
+		"snow-quorum-size",
+		2,
+		"Snow quorum size of the nodes")
+	txFeeArg := flags.Uint64(
+		"tx-fee",
+		units.MilliAvax,
+		"Tx fee of the network, in nAVAX")
+	seedArg := flags.Int64(
+		"seed",
+		time.Now().UnixNano(),
+		"Integer that the identities of the nodes that aren't genesis validators derive from")
+	flags.Parse(args)
+	if *imageArg == "" {
+		return stacktrace.NewError("The image the nodes are launched from must be given with --image")
+	}
+
+	return runNetworkDaemon(stateDirpath, networkParams{
+		name:           *nameArg,
+		imageName:      *imageArg,
+		subnet:         *subnetArg,
+		numNodes:       *numNodesArg,
+		logLevel:       caminoService.CaminoLogLevel(*logLevelArg),
+		snowSampleSize: *snowSampleSizeArg,
+		snowQuorumSize: *snowQuorumSizeArg,
+		txFee:          *txFeeArg,
+		seed:           *seedArg,
+	})
+}
+
+func runStatus(stateDirpath string, args []string) error {
+	flags := flag.NewFlagSet("status", flag.ExitOnError)
+	jsonArg := flags.Bool(
+		"json",
+		false,
+		"Print the state file of the network as is, instead of a summary with the liveness of each node")
+	flags.Parse(args)
+
+	state, err := loadUpNetworkState(stateDirpath)
+	if err != nil {
+		return stacktrace.Propagate(err, "An error occurred loading the network state")
+	}
+	if *jsonArg {
+		stateBytes, err := os.ReadFile(getStateFilepath(stateDirpath))
+		if err != nil {
+			return stacktrace.Propagate(err, "An error occurred reading the state file")
+		}
+		fmt.Println(string(stateBytes))
+		return nil
+	}
+	printNetwork(state)
+	fmt.Println("Liveness:")
+	for _, node := range state.Nodes {
+		status := "live"
+		if isLive, reason := getNodeLiveness(node.BaseURL); !isLive {
+			status = "not live: " + reason
+		}
+		fmt.Printf("  %v: %v\n", node.ServiceID, status)
+	}
+	return nil
+}
+
+func runAddNode(stateDirpath string, args []string) error {
+	flags := flag.NewFlagSet("add-node", flag.ExitOnError)
+	idArg := flags.String(
+		"id",
+		"",
+		"Service ID of the node; the next free node-<index> if not given")
+	flags.Parse(args)
+
+	state, err := loadUpNetworkState(stateDirpath)
+	if err != nil {
+		return stacktrace.Propagate(err, "An error occurred loading the network state")
+	}
+	node := NodeState{}
+	if err := callNetworkDaemon(state, "AddNode", AddNodeArgs{ServiceID: *idArg}, &node); err != nil {
+		return stacktrace.Propagate(err, "An error occurred adding the node")
+	}
+	printNode(node)
+	return nil
+}
+
+func runStopNode(stateDirpath string, args []string) error {
+	flags := flag.NewFlagSet("stop-node", flag.ExitOnError)
+	idArg := flags.String(
+		"id",
+		"",
+		"Service ID of the node to stop, as printed by 'caminonet status'")
+	flags.Parse(args)
+	if *idArg == "" {
+		return stacktrace.NewError("The node to stop must be given with --id")
+	}
+
+	state, err := loadUpNetworkState(stateDirpath)
+	if err != nil {
+		return stacktrace.Propagate(err, "An error occurred loading the network state")
+	}
+	if err := callNetworkDaemon(state, "StopNode", StopNodeArgs{ServiceID: *idArg}, &struct{}{}); err != nil {
+		return stacktrace.Propagate(err, "An error occurred stopping node %v", *idArg)
+	}
+	fmt.Printf("Stopped node %v\n", *idArg)
+	return nil
+}
+
+func runDown(stateDirpath string, args []string) error {
+	flags := flag.NewFlagSet("down", flag.ExitOnError)
+	flags.Parse(args)
+
+	state, err := loadUpNetworkState(stateDirpath)
+	if err != nil {
+		return stacktrace.Propagate(err, "An error occurred loading the network state")
+	}
+	if err := callNetworkDaemon(state, "Down", DownArgs{}, &struct{}{}); err != nil {
+		// The `up` process is gone, e.g. because it was killed, so what it left behind is cleaned up from here
+		logrus.Warnf("Could not ask the `up` process to bring the network down, so it's brought down from here: %v", err)
+		if err := tearDownNetwork(stateDirpath, state); err != nil {
+			return stacktrace.Propagate(err, "An error occurred bringing network %v down", state.Name)
+		}
+		fmt.Printf("Brought network %v down\n", state.Name)
+		return nil
+	}
+	if err := waitForStateFileRemoval(stateDirpath, downTimeout); err != nil {
+		return stacktrace.Propagate(err, "The `up` process didn't bring network %v down", state.Name)
+	}
+	fmt.Printf("Brought network %v down\n", state.Name)
+	return nil
+}
+
+// ================ Helper functions =========================
+const (
+	nodeCallTimeout = 5 * time.Second
+
+	// How long the `up` process gets to stop every container of the network
+	downTimeout = 2 * time.Minute
+
+	stateFilePollInterval = 500 * time.Millisecond
+)
+
+func getDefaultStateDirpath() string {
+	homeDirpath, err := os.UserHomeDir()
+	if err != nil {
+		return defaultStateDirname
+	}
+	return filepath.Join(homeDirpath, defaultStateDirname)
+}
+
+/*
+Loads the state of the network that's up, failing if there's none
+*/
+func loadUpNetworkState(stateDirpath string) (*networkState, error) {
+	state, err := loadNetworkState(stateDirpath)
+	if err != nil {
+		return nil, err
+	}
+	if state == nil {
+		return nil, stacktrace.NewError("No network is up; there's no state file in %v", stateDirpath)
+	}
+	return state, nil
+}
+
+func waitForStateFileRemoval(stateDirpath string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		if _, err := os.Stat(getStateFilepath(stateDirpath)); os.IsNotExist(err) {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return stacktrace.NewError("The state file still exists after %v", timeout)
+		}
+		time.Sleep(stateFilePollInterval)
+	}
+}
+
+/*
+Asks the node with the given base URL whether it's live, returning why not if it isn't
+*/
+func getNodeLiveness(baseURL string) (bool, string) {
+	client := apis.NewClient(baseURL, constants.DefaultRequestTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), nodeCallTimeout)
+	defer cancel()
+	reply, err := client.HealthAPI().Liveness(ctx)
+	if err != nil {
+		return false, strings.TrimSpace(err.Error())
+	}
+	if !reply.Healthy {
+		failingChecks := []string{}
+		for checkName, check := range reply.Checks {
+			if check.Error != nil {
+				failingChecks = append(failingChecks, checkName)
+			}
+		}
+		sort.Strings(failingChecks)
+		return false, "failing health checks " + strings.Join(failingChecks, ", ")
+	}
+	return true, ""
+}
+
+func printNetwork(state *networkState) {
+	fmt.Printf("Network %v (image %v, subnet %v, seed %v)\n", state.Name, state.ImageName, state.Subnet, state.Seed)
+	fmt.Println("Funded keys:")
+	for _, key := range state.FundedKeys {
+		fmt.Printf("  %v\n", key.PrivateKey)
+		fmt.Printf("    X Chain address: %v\n", key.XAddress)
+		fmt.Printf("    P Chain address: %v\n", key.PAddress)
+	}
+	fmt.Println("Nodes:")
+	for _, node := range state.Nodes {
+		printNode(node)
+	}
+}
+
+func printNode(node NodeState) {
+	fmt.Printf("  %v: %v at %v\n", node.ServiceID, node.NodeID, node.IPAddress)
+	fmt.Printf("    %v\n", node.BaseURL)
+	chainAliases := make([]string, 0, len(node.ChainURLs))
+	for chainAlias := range node.ChainURLs {
+		chainAliases = append(chainAliases, chainAlias)
+	}
+	sort.Strings(chainAliases)
+	for _, chainAlias := range chainAliases {
+		fmt.Printf("    %v Chain: %v\n", chainAlias, node.ChainURLs[chainAlias])
+	}
+	fmt.Printf("    Logs: %v\n", node.LogDirpath)
+}
@@ -0,0 +1,452 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package main
+
+import (
+	"net"
+	"net/rpc"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	caminoNetwork "github.com/chain4travel/camino-testing/camino/networks"
+	caminoService "github.com/chain4travel/camino-testing/camino/services"
+	"github.com/chain4travel/camino-testing/utils/random"
+	"github.com/chain4travel/caminogo/ids"
+	"github.com/chain4travel/caminogo/utils/constants"
+	"github.com/chain4travel/caminogo/utils/formatting"
+	"github.com/kurtosis-tech/kurtosis-go/lib/kurtosis_service"
+	"github.com/kurtosis-tech/kurtosis-go/lib/networks"
+	"github.com/palantir/stacktrace"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// The name the `up` process takes commands under on its control socket
+	controlServiceName = "Caminonet"
+
+	// The IP that the stand-in for the Kurtosis API is served on
+	localKurtosisAPIIP = "127.0.0.1"
+
+	// The configuration of the nodes that aren't boot nodes
+	nodeConfigID networks.ConfigurationID = "node-config"
+
+	// The prefix of the service IDs of the nodes that aren't boot nodes, with the index of the node in the network appended
+	nodeServiceIDPrefix = "node-"
+
+	networkInitialTimeout = 2 * time.Second
+)
+
+// networkParams are the parameters a network is brought up with
+type networkParams struct {
+	name           string
+	imageName      string
+	subnet         string
+	numNodes       int
+	logLevel       caminoService.CaminoLogLevel
+	snowSampleSize int
+	snowQuorumSize int
+	txFee          uint64
+	seed           int64
+}
+
+// networkDaemon is the `up` process of a network, which holds the network for as long as it's up and takes the commands
+// that change it on its control socket
+type networkDaemon struct {
+	mutex *sync.Mutex
+
+	stateDirpath string
+
+	state *networkState
+
+	network caminoNetwork.TestCaminoNetwork
+
+	kurtosisAPI *localKurtosisAPI
+
+	// The index in the network of the next node that gets added without a service ID
+	nextNodeIndex int
+
+	// Closed when the network should be brought down
+	downRequested chan struct{}
+
+	downOnce *sync.Once
+}
+
+// AddNodeArgs are the args of the command to add a node
+type AddNodeArgs struct {
+	// The service ID of the node, or empty to get the next free node-<index>
+	ServiceID string
+}
+
+// StopNodeArgs are the args of the command to stop a node
+type StopNodeArgs struct {
+	ServiceID string
+}
+
+// DownArgs are the args of the command to bring the network down
+type DownArgs struct{}
+
+// AddNode starts a node that isn't a validator and waits for it to bootstrap
+func (daemon *networkDaemon) AddNode(args AddNodeArgs, reply *NodeState) error {
+	daemon.mutex.Lock()
+	defer daemon.mutex.Unlock()
+	serviceID := networks.ServiceID(args.ServiceID)
+	if serviceID == "" {
+		serviceID = networks.ServiceID(nodeServiceIDPrefix + strconv.Itoa(daemon.nextNodeIndex))
+		daemon.nextNodeIndex++
+	}
+	if _, found := daemon.network.GetRunningCaminoClients()[serviceID]; found {
+		return stacktrace.NewError("Node %v is already running", serviceID)
+	}
+	logrus.Infof("Adding node %v...", serviceID)
+	availabilityChecker, err := daemon.network.AddService(nodeConfigID, serviceID)
+	if err != nil {
+		return stacktrace.Propagate(err, "An error occurred adding node %v", serviceID)
+	}
+	if err := availabilityChecker.WaitForStartup(); err != nil {
+		return stacktrace.Propagate(err, "Node %v didn't bootstrap", serviceID)
+	}
+	node, err := daemon.recordNode(serviceID)
+	if err != nil {
+		return stacktrace.Propagate(err, "An error occurred recording node %v", serviceID)
+	}
+	logrus.Infof("Node %v is up", serviceID)
+	*reply = node
+	return nil
+}
+
+// StopNode stops the node and removes it from the network; its files stay on the volume until the network is brought down
+func (daemon *networkDaemon) StopNode(args StopNodeArgs, reply *struct{}) error {
+	daemon.mutex.Lock()
+	defer daemon.mutex.Unlock()
+	serviceID := networks.ServiceID(args.ServiceID)
+	if _, found := daemon.network.GetRunningCaminoClients()[serviceID]; !found {
+		return stacktrace.NewError("No node %v is running", serviceID)
+	}
+	logrus.Infof("Stopping node %v...", serviceID)
+	if err := daemon.network.RemoveService(serviceID); err != nil {
+		return stacktrace.Propagate(err, "An error occurred stopping node %v", serviceID)
+	}
+	daemon.state.removeNode(args.ServiceID)
+	if err := daemon.state.save(daemon.stateDirpath); err != nil {
+		return stacktrace.Propagate(err, "An error occurred saving the network state")
+	}
+	logrus.Infof("Node %v is stopped", serviceID)
+	return nil
+}
+
+// Down makes the `up` process bring the network down and exit; it returns before the network is down
+func (daemon *networkDaemon) Down(args DownArgs, reply *struct{}) error {
+	daemon.requestDown()
+	return nil
+}
+
+/*
+Brings a network up and holds it until it's brought down, by `caminonet down` or an interrupt
+*/
+func runNetworkDaemon(stateDirpath string, params networkParams) (resultErr error) {
+	existingState, err := loadNetworkState(stateDirpath)
+	if err != nil {
+		return stacktrace.Propagate(err, "An error occurred checking for a network that's already up")
+	}
+	if existingState != nil {
+		return stacktrace.NewError("Network %v is already up; bring it down with `caminonet down` first", existingState.Name)
+	}
+	// Kurtosis creates the directory of each service without any permissions, as it expects to run as root
+	if os.Geteuid() != 0 {
+		return stacktrace.NewError("caminonet must be run as root, as the nodes' files are created with root-only permissions")
+	}
+	if _, err := runDocker("version"); err != nil {
+		return stacktrace.Propagate(err, "The Docker CLI can't reach a Docker engine")
+	}
+	volumeDirpath := filepath.Join(stateDirpath, volumeDirname, params.name)
+	if err := os.MkdirAll(volumeDirpath, stateDirPerms); err != nil {
+		return stacktrace.Propagate(err, "An error occurred creating volume directory %v", volumeDirpath)
+	}
+	fundedKeys, err := getFundedKeys()
+	if err != nil {
+		return stacktrace.Propagate(err, "An error occurred getting the funded keys of the network")
+	}
+	state := &networkState{
+		Name:                  params.name,
+		ImageName:             params.imageName,
+		Subnet:                params.subnet,
+		Seed:                  params.seed,
+		VolumeDirpath:         volumeDirpath,
+		ControlSocketFilepath: filepath.Join(stateDirpath, controlSocketFilename),
+		PID:                   os.Getpid(),
+		FundedKeys:            fundedKeys,
+		Nodes:                 []NodeState{},
+	}
+	// Saved before anything is started, so that `caminonet down` can clean up after an `up` that dies midway
+	if err := state.save(stateDirpath); err != nil {
+		return stacktrace.Propagate(err, "An error occurred saving the network state")
+	}
+	defer func() {
+		if err := tearDownNetwork(stateDirpath, state); err != nil {
+			logrus.Errorf("An error occurred bringing the network down: %v", err)
+			if resultErr == nil {
+				resultErr = err
+			}
+		}
+	}()
+
+	if err := createDockerNetwork(params.name, params.subnet); err != nil {
+		return stacktrace.Propagate(err, "An error occurred creating the Docker network")
+	}
+	kurtosisAPI, err := newLocalKurtosisAPI(params.name, params.subnet, volumeDirpath)
+	if err != nil {
+		return stacktrace.Propagate(err, "An error occurred creating the stand-in for the Kurtosis API")
+	}
+	kurtosisAPIServer, err := serveLocalKurtosisAPI(kurtosisAPI, localKurtosisAPIIP)
+	if err != nil {
+		return stacktrace.Propagate(err, "An error occurred serving the stand-in for the Kurtosis API")
+	}
+	defer kurtosisAPIServer.Close()
+
+	random.SetSeed(params.seed)
+	network, err := initializeNetwork(params, volumeDirpath)
+	if err != nil {
+		return stacktrace.Propagate(err, "An error occurred bringing up the nodes")
+	}
+	daemon := &networkDaemon{
+		mutex:         &sync.Mutex{},
+		stateDirpath:  stateDirpath,
+		state:         state,
+		network:       network,
+		kurtosisAPI:   kurtosisAPI,
+		nextNodeIndex: params.numNodes,
+		downRequested: make(chan struct{}),
+		downOnce:      &sync.Once{},
+	}
+	for serviceID := range network.GetRunningCaminoClients() {
+		if _, err := daemon.recordNode(serviceID); err != nil {
+			return stacktrace.Propagate(err, "An error occurred recording node %v", serviceID)
+		}
+	}
+
+	controlListener, err := daemon.serveControlSocket()
+	if err != nil {
+		return stacktrace.Propagate(err, "An error occurred serving the control socket")
+	}
+	defer controlListener.Close()
+
+	printNetwork(state)
+	logrus.Infof("Network %v is up; bring it down with `caminonet down` or by interrupting this process", params.name)
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt, syscall.SIGTERM)
+	select {
+	case <-interrupts:
+	case <-daemon.downRequested:
+	}
+	// Waits for a command that's changing the network to finish
+	daemon.mutex.Lock()
+	defer daemon.mutex.Unlock()
+	logrus.Infof("Bringing network %v down...", params.name)
+	return nil
+}
+
+/*
+Removes every container and the Docker network of the network, then its state file; the files of its nodes stay on the
+	volume, for their logs to be looked at
+*/
+func tearDownNetwork(stateDirpath string, state *networkState) error {
+	if err := removeLabeledDockerResources(state.Name); err != nil {
+		return stacktrace.Propagate(err, "An error occurred removing the containers and Docker network of network %v", state.Name)
+	}
+	if err := os.Remove(state.ControlSocketFilepath); err != nil && !os.IsNotExist(err) {
+		return stacktrace.Propagate(err, "An error occurred removing the control socket")
+	}
+	if err := removeStateFile(stateDirpath); err != nil {
+		return stacktrace.Propagate(err, "An error occurred removing the state file")
+	}
+	return nil
+}
+
+/*
+Brings up the boot nodes and the other initial nodes the same way the testsuite does, with Kurtosis' ServiceNetwork
+	calling the stand-in for the Kurtosis API
+*/
+func initializeNetwork(params networkParams, volumeDirpath string) (caminoNetwork.TestCaminoNetwork, error) {
+	numBootNodes := len(caminoNetwork.DefaultLocalNetGenesisConfig.Stakers)
+	if params.numNodes < numBootNodes {
+		return caminoNetwork.TestCaminoNetwork{}, stacktrace.NewError("A network needs at least the %v boot nodes, but %v nodes were requested", numBootNodes, params.numNodes)
+	}
+	serviceConfigs := map[networks.ConfigurationID]caminoNetwork.TestCaminoNetworkServiceConfig{
+		nodeConfigID: *caminoNetwork.NewTestCaminoNetworkServiceConfig(
+			true,
+			params.logLevel,
+			params.imageName,
+			params.snowQuorumSize,
+			params.snowSampleSize,
+			networkInitialTimeout,
+			make(map[string]string),
+		),
+	}
+	desiredServices := make(map[networks.ServiceID]networks.ConfigurationID)
+	for i := numBootNodes; i < params.numNodes; i++ {
+		desiredServices[networks.ServiceID(nodeServiceIDPrefix+strconv.Itoa(i))] = nodeConfigID
+	}
+	loader, err := caminoNetwork.NewTestCaminoNetworkLoader(
+		true,
+		params.imageName,
+		params.logLevel,
+		params.snowQuorumSize,
+		params.snowSampleSize,
+		params.txFee,
+		networkInitialTimeout,
+		serviceConfigs,
+		desiredServices,
+	)
+	if err != nil {
+		return caminoNetwork.TestCaminoNetwork{}, stacktrace.Propagate(err, "An error occurred creating the network loader")
+	}
+
+	builder := networks.NewServiceNetworkBuilder(kurtosis_service.NewKurtosisService(localKurtosisAPIIP), volumeDirpath)
+	if err := loader.ConfigureNetwork(builder); err != nil {
+		return caminoNetwork.TestCaminoNetwork{}, stacktrace.Propagate(err, "An error occurred configuring the network")
+	}
+	serviceNetwork := builder.Build()
+	logrus.Infof("Starting %v nodes...", params.numNodes)
+	availabilityCheckers, err := loader.InitializeNetwork(serviceNetwork)
+	if err != nil {
+		return caminoNetwork.TestCaminoNetwork{}, stacktrace.Propagate(err, "An error occurred starting the nodes")
+	}
+	for serviceID, availabilityChecker := range availabilityCheckers {
+		if err := availabilityChecker.WaitForStartup(); err != nil {
+			return caminoNetwork.TestCaminoNetwork{}, stacktrace.Propagate(err, "Node %v didn't bootstrap", serviceID)
+		}
+	}
+	untypedNetwork, err := loader.WrapNetwork(serviceNetwork)
+	if err != nil {
+		return caminoNetwork.TestCaminoNetwork{}, stacktrace.Propagate(err, "An error occurred wrapping the network")
+	}
+	return untypedNetwork.(caminoNetwork.TestCaminoNetwork), nil
+}
+
+/*
+Records the running node with the given service ID in the state file, and returns what was recorded
+*/
+func (daemon *networkDaemon) recordNode(serviceID networks.ServiceID) (NodeState, error) {
+	ipAddr, err := daemon.network.GetServiceIPAddress(serviceID)
+	if err != nil {
+		return NodeState{}, stacktrace.Propagate(err, "An error occurred getting the IP of node %v", serviceID)
+	}
+	containerID, found := daemon.kurtosisAPI.getContainerID(ipAddr)
+	if !found {
+		return NodeState{}, stacktrace.NewError("No container is running at the IP of node %v, %v", serviceID, ipAddr)
+	}
+	baseURL, err := daemon.network.GetServiceURI(serviceID)
+	if err != nil {
+		return NodeState{}, stacktrace.Propagate(err, "An error occurred getting the URI of node %v", serviceID)
+	}
+	launchDetails, err := daemon.network.GetServiceLaunchDetails(serviceID)
+	if err != nil {
+		return NodeState{}, stacktrace.Propagate(err, "An error occurred getting the launch details of node %v", serviceID)
+	}
+	node := NodeState{
+		ServiceID:   string(serviceID),
+		NodeID:      launchDetails.GetNodeID(),
+		IPAddress:   ipAddr,
+		ContainerID: containerID,
+		BaseURL:     baseURL,
+		ChainURLs: map[string]string{
+			"X": baseURL + "/ext/bc/X",
+			"P": baseURL + "/ext/bc/P",
+			"C": baseURL + "/ext/bc/C/rpc",
+		},
+		LogDirpath: launchDetails.GetLogDirpath(),
+	}
+	daemon.state.setNode(node)
+	if err := daemon.state.save(daemon.stateDirpath); err != nil {
+		return NodeState{}, stacktrace.Propagate(err, "An error occurred saving the network state")
+	}
+	return node, nil
+}
+
+/*
+Takes the commands of the other caminonet processes on the control socket, until the returned listener is closed
+*/
+func (daemon *networkDaemon) serveControlSocket() (net.Listener, error) {
+	rpcServer := rpc.NewServer()
+	if err := rpcServer.RegisterName(controlServiceName, daemon); err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred registering the control commands")
+	}
+	// A socket left behind by an `up` process that was killed would make listening fail
+	if err := os.Remove(daemon.state.ControlSocketFilepath); err != nil && !os.IsNotExist(err) {
+		return nil, stacktrace.Propagate(err, "An error occurred removing the old control socket")
+	}
+	listener, err := net.Listen("unix", daemon.state.ControlSocketFilepath)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred listening on control socket %v", daemon.state.ControlSocketFilepath)
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				// The listener was closed, as the network is being brought down
+				return
+			}
+			go rpcServer.ServeConn(conn)
+		}
+	}()
+	return listener, nil
+}
+
+func (daemon *networkDaemon) requestDown() {
+	daemon.downOnce.Do(func() {
+		close(daemon.downRequested)
+	})
+}
+
+/*
+Calls the given command of the `up` process of the network
+*/
+func callNetworkDaemon(state *networkState, command string, args interface{}, reply interface{}) error {
+	client, err := rpc.Dial("unix", state.ControlSocketFilepath)
+	if err != nil {
+		return stacktrace.Propagate(err, "Could not reach the `up` process of network %v at %v; is it still running?", state.Name, state.ControlSocketFilepath)
+	}
+	defer client.Close()
+	if err := client.Call(controlServiceName+"."+command, args, reply); err != nil {
+		return stacktrace.Propagate(err, "Command %v of the `up` process of network %v failed", command, state.Name)
+	}
+	return nil
+}
+
+/*
+Gets the keys that hold the genesis funds of the local network, with their X and P Chain addresses
+*/
+func getFundedKeys() ([]fundedKey, error) {
+	fundedAddress := caminoNetwork.DefaultLocalNetGenesisConfig.FundedAddresses
+	shortID, err := ids.ShortFromString(fundedAddress.Address)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "Could not parse funded address %v", fundedAddress.Address)
+	}
+	addresses := make(map[string]string)
+	for _, chainAlias := range []string{"X", "P"} {
+		address, err := formatting.FormatAddress(chainAlias, constants.LocalHRP, shortID.Bytes())
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "Could not format funded address %v for the %v Chain", fundedAddress.Address, chainAlias)
+		}
+		addresses[chainAlias] = address
+	}
+	return []fundedKey{
+		{
+			PrivateKey: fundedAddress.PrivateKey,
+			XAddress:   addresses["X"],
+			PAddress:   addresses["P"],
+		},
+	}, nil
+}
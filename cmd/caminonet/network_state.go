@@ -0,0 +1,145 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/palantir/stacktrace"
+)
+
+const (
+	stateFilename         = "state.json"
+	controlSocketFilename = "control.sock"
+	volumeDirname         = "volume"
+
+	stateFilePerms = 0644
+	stateDirPerms  = 0755
+)
+
+// networkState is what the state file records about a caminonet network, so that the commands run after `up` can find
+// its nodes, and clean up after it even if the `up` process is gone
+type networkState struct {
+	// The name of the network, which is also the name of its Docker network
+	Name string `json:"name"`
+
+	ImageName string `json:"imageName"`
+
+	Subnet string `json:"subnet"`
+
+	// The seed that the identities of the network's non-boot nodes derive from
+	Seed int64 `json:"seed"`
+
+	// The host directory that's mounted into every node as its test volume
+	VolumeDirpath string `json:"volumeDirpath"`
+
+	// The socket that the `up` process takes commands on
+	ControlSocketFilepath string `json:"controlSocketFilepath"`
+
+	// The PID of the `up` process
+	PID int `json:"pid"`
+
+	FundedKeys []fundedKey `json:"fundedKeys"`
+
+	// The nodes that are currently part of the network
+	Nodes []NodeState `json:"nodes"`
+}
+
+type fundedKey struct {
+	PrivateKey string `json:"privateKey"`
+	XAddress   string `json:"xAddress"`
+	PAddress   string `json:"pAddress"`
+}
+
+// NodeState is what the state file records about a node; it's also what the `up` process replies with when it adds one
+type NodeState struct {
+	ServiceID   string `json:"serviceId"`
+	NodeID      string `json:"nodeId"`
+	IPAddress   string `json:"ipAddress"`
+	ContainerID string `json:"containerId"`
+
+	// The URL that all of the node's APIs are served under
+	BaseURL string `json:"baseUrl"`
+
+	// Chain alias -> URL of the chain's API
+	ChainURLs map[string]string `json:"chainUrls"`
+
+	// The node's log directory on the host
+	LogDirpath string `json:"logDirpath"`
+}
+
+func getStateFilepath(stateDirpath string) string {
+	return filepath.Join(stateDirpath, stateFilename)
+}
+
+// loadNetworkState reads the state file in the given directory; it returns nil if there's none, as no network is up
+func loadNetworkState(stateDirpath string) (*networkState, error) {
+	stateFilepath := getStateFilepath(stateDirpath)
+	stateBytes, err := os.ReadFile(stateFilepath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred reading state file %v", stateFilepath)
+	}
+	state := &networkState{}
+	if err := json.Unmarshal(stateBytes, state); err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred parsing state file %v", stateFilepath)
+	}
+	return state, nil
+}
+
+// save writes the state file in the given directory, replacing it in one step so that readers never see a partial state
+func (state *networkState) save(stateDirpath string) error {
+	sort.Slice(state.Nodes, func(i, j int) bool {
+		return state.Nodes[i].ServiceID < state.Nodes[j].ServiceID
+	})
+	stateBytes, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return stacktrace.Propagate(err, "An error occurred serializing the network state")
+	}
+	stateFilepath := getStateFilepath(stateDirpath)
+	tempFilepath := stateFilepath + ".tmp"
+	if err := os.WriteFile(tempFilepath, stateBytes, stateFilePerms); err != nil {
+		return stacktrace.Propagate(err, "An error occurred writing the network state to %v", tempFilepath)
+	}
+	if err := os.Rename(tempFilepath, stateFilepath); err != nil {
+		return stacktrace.Propagate(err, "An error occurred moving the network state to %v", stateFilepath)
+	}
+	return nil
+}
+
+// removeStateFile removes the state file in the given directory, if there's one
+func removeStateFile(stateDirpath string) error {
+	if err := os.Remove(getStateFilepath(stateDirpath)); err != nil && !os.IsNotExist(err) {
+		return stacktrace.Propagate(err, "An error occurred removing the state file")
+	}
+	return nil
+}
+
+// setNode records the node, replacing the node with the same service ID if there's one
+func (state *networkState) setNode(node NodeState) {
+	state.removeNode(node.ServiceID)
+	state.Nodes = append(state.Nodes, node)
+}
+
+// removeNode forgets the node with the given service ID, returning whether there was one
+func (state *networkState) removeNode(serviceID string) bool {
+	for i, node := range state.Nodes {
+		if node.ServiceID == serviceID {
+			state.Nodes = append(state.Nodes[:i], state.Nodes[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
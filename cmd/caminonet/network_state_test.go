@@ -0,0 +1,62 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNetworkStateRoundTrip(t *testing.T) {
+	stateDirpath := t.TempDir()
+	state, err := loadNetworkState(stateDirpath)
+	assert.NoError(t, err)
+	assert.Nil(t, state, "No network should be up without a state file")
+
+	fundedKeys, err := getFundedKeys()
+	assert.NoError(t, err)
+	state = &networkState{
+		Name:       "caminonet",
+		ImageName:  "caminogo:latest",
+		Subnet:     defaultSubnet,
+		Seed:       42,
+		FundedKeys: fundedKeys,
+	}
+	state.setNode(NodeState{ServiceID: "node-5", IPAddress: "172.30.0.7"})
+	state.setNode(NodeState{ServiceID: "boot-node-0", IPAddress: "172.30.0.2"})
+	// Recording a node again replaces it
+	state.setNode(NodeState{ServiceID: "node-5", IPAddress: "172.30.0.8"})
+	assert.NoError(t, state.save(stateDirpath))
+
+	loadedState, err := loadNetworkState(stateDirpath)
+	assert.NoError(t, err)
+	assert.Equal(t, state, loadedState)
+	assert.Equal(t, []NodeState{
+		{ServiceID: "boot-node-0", IPAddress: "172.30.0.2"},
+		{ServiceID: "node-5", IPAddress: "172.30.0.8"},
+	}, loadedState.Nodes)
+
+	assert.True(t, loadedState.removeNode("node-5"))
+	assert.False(t, loadedState.removeNode("node-5"))
+
+	assert.NoError(t, removeStateFile(stateDirpath))
+	state, err = loadNetworkState(stateDirpath)
+	assert.NoError(t, err)
+	assert.Nil(t, state)
+}
+
+func TestGetFundedKeys(t *testing.T) {
+	fundedKeys, err := getFundedKeys()
+	assert.NoError(t, err)
+	assert.Len(t, fundedKeys, 1)
+	assert.Equal(t, "X-local18jma8ppw3nhx5r4ap8clazz0dps7rv5u00z96u", fundedKeys[0].XAddress)
+	assert.Equal(t, "P-local18jma8ppw3nhx5r4ap8clazz0dps7rv5u00z96u", fundedKeys[0].PAddress)
+}
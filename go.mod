@@ -7,6 +7,7 @@ require (
 	github.com/gorilla/rpc v1.2.0
 	github.com/kurtosis-tech/kurtosis-go v0.0.0-20200912210009-15301ba2fcb4
 	github.com/palantir/stacktrace v0.0.0-20161112013806-78658fd2d177
+	github.com/powerman/rpc-codec v1.2.2
 	github.com/sirupsen/logrus v1.8.1
 	github.com/stretchr/testify v1.7.0
 )
@@ -44,7 +45,6 @@ require (
 	github.com/oklog/run v1.1.0 // indirect
 	github.com/onsi/ginkgo v1.16.4 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	github.com/powerman/rpc-codec v1.2.2 // indirect
 	github.com/prometheus/client_golang v1.11.0 // indirect
 	github.com/prometheus/client_model v0.2.0 // indirect
 	github.com/prometheus/common v0.26.0 // indirect
@@ -0,0 +1,58 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+// This file is added to the time package of the Go toolchain that the clock skew image builds caminogo with, and is
+// ignored everywhere else; the Dockerfile strips the build constraint when copying it in
+//go:build ignore
+
+package time
+
+import "syscall"
+
+// How far the wall clock runs ahead of the host's, in nanoseconds, taken from CAMINO_CLOCK_OFFSET (negative if behind)
+var clockOffset = loadClockOffset()
+
+func loadClockOffset() int64 {
+	value, found := syscall.Getenv("CAMINO_CLOCK_OFFSET")
+	if !found || value == "" {
+		return 0
+	}
+	isNegative := value[0] == '-'
+	if isNegative || value[0] == '+' {
+		value = value[1:]
+	}
+	offset := int64(0)
+	for i := 0; i < len(value); i++ {
+		if value[i] < '0' || value[i] > '9' {
+			panic("time: CAMINO_CLOCK_OFFSET must be an integer number of nanoseconds")
+		}
+		offset = offset*10 + int64(value[i]-'0')
+	}
+	if isNegative {
+		return -offset
+	}
+	return offset
+}
+
+// Moves a wall clock reading by the clock offset; monotonic readings are left alone, so timers and durations aren't affected
+func offsetWallClock(sec int64, nsec int32) (int64, int32) {
+	if clockOffset == 0 {
+		return sec, nsec
+	}
+	sec += clockOffset / 1e9
+	totalNsec := int64(nsec) + clockOffset%1e9
+	if totalNsec < 0 {
+		totalNsec += 1e9
+		sec--
+	} else if totalNsec >= 1e9 {
+		totalNsec -= 1e9
+		sec++
+	}
+	return sec, int32(totalNsec)
+}
@@ -5,6 +5,25 @@ script_dirpath="$(cd "$(dirname "${BASH_SOURCE[0]}")"; pwd)"
 SUITE_IMAGE="c4tplatform/camino-testing"
 CAMINO_IMAGE="c4tplatform/caminogo:v1.0.0"
 BYZANTINE_IMAGE="c4tplatform/caminogo-byzantine:v0.0.0"
+# Comma-separated list of caminogo images to run the compatibility matrix tests across (none by default)
+CAMINO_IMAGES="${CAMINO_IMAGES:-}"
+# caminogo image built by scripts/build_clock_skew_image.sh, to run the clock skew test with (skipped by default, as stock
+#  caminogo can't offset its clock)
+CLOCK_SKEW_IMAGE="${CLOCK_SKEW_IMAGE:-}"
+# Comma-separated list of test tags (smoke, byzantine, load, long) to restrict the run to (all tests by default)
+TEST_TAGS="${TEST_TAGS:-}"
+# Regex the names of the tests to run must match (all tests by default)
+TEST_NAME_REGEX="${TEST_NAME_REGEX:-}"
+# JSON object of test name -> object of param name -> value to override test params with, e.g.
+#  '{"stakingNetworkBombardXChainTest": {"NumTxs": 10000, "AcceptanceTimeout": "30s"}}'
+TEST_PARAMS="${TEST_PARAMS:-}"
+# Grid of consensus params to sweep, one test per combination: 'default' or a JSON object of param -> list of values,
+#  e.g. '{"NumNodes": [5], "SnowSampleSizes": [4], "SnowQuorumSizes": [3, 4], "BetaVirtuous": [15], "BetaRogue": [20]}'
+#  (no sweep by default)
+CONSENSUS_SWEEP="${CONSENSUS_SWEEP:-}"
+# Integer that all the suite's randomness derives from, shared by every test so that a run can be reproduced (the current
+#  time by default)
+SEED="${SEED:-$(date +%s)}"
 KURTOSIS_CORE_CHANNEL="master"
 INITIALIZER_IMAGE="kurtosistech/kurtosis-core_initializer:${KURTOSIS_CORE_CHANNEL}"
 API_IMAGE="kurtosistech/kurtosis-core_api:${KURTOSIS_CORE_CHANNEL}"
@@ -86,9 +105,17 @@ if "${do_run}"; then
     suite_execution_volume="camino-test-suite_${docker_tag}_$(date +%s)"
     docker volume create "${suite_execution_volume}"
 
+    # The test params are JSON themselves, so their quotes and backslashes need escaping to nest them in a JSON string
+    escaped_test_params="${TEST_PARAMS//\\/\\\\}"
+    escaped_test_params="${escaped_test_params//\"/\\\"}"
+    escaped_consensus_sweep="${CONSENSUS_SWEEP//\\/\\\\}"
+    escaped_consensus_sweep="${escaped_consensus_sweep//\"/\\\"}"
+    escaped_test_name_regex="${TEST_NAME_REGEX//\\/\\\\}"
+
     # Docker only allows you to have spaces in the variable if you escape them or use a Docker env file
-    custom_env_vars_json_flag="CUSTOM_ENV_VARS_JSON={\"CAMINO_IMAGE\":\"${CAMINO_IMAGE}\",\"BYZANTINE_IMAGE\":\"${BYZANTINE_IMAGE}\"}"
+    custom_env_vars_json_flag="CUSTOM_ENV_VARS_JSON={\"CAMINO_IMAGE\":\"${CAMINO_IMAGE}\",\"BYZANTINE_IMAGE\":\"${BYZANTINE_IMAGE}\",\"CAMINO_IMAGES\":\"${CAMINO_IMAGES}\",\"CLOCK_SKEW_IMAGE\":\"${CLOCK_SKEW_IMAGE}\",\"TEST_TAGS\":\"${TEST_TAGS}\",\"TEST_NAME_REGEX\":\"${escaped_test_name_regex}\",\"TEST_PARAMS\":\"${escaped_test_params}\",\"CONSENSUS_SWEEP\":\"${escaped_consensus_sweep}\",\"SEED\":\"${SEED}\"}"
 
+    echo "Running with seed ${SEED}; set SEED=${SEED} to reproduce this run"
     echo "${custom_env_vars_json_flag}"
     docker run \
         --mount "type=bind,source=/var/run/docker.sock,target=/var/run/docker.sock" \
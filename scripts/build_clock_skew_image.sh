@@ -0,0 +1,23 @@
+#!/bin/bash
+
+set -euo pipefail
+
+# Builds a caminogo image whose nodes honor the CAMINO_CLOCK_OFFSET environment variable, which the testsuite sets on the
+# nodes of a TestCaminoNetworkServiceConfig with a clock offset; pass the resulting image as CLOCK_SKEW_IMAGE to run the
+# clock skew test
+SCRIPT_DIRPATH=$(cd $(dirname "${BASH_SOURCE[0]}") && pwd)
+ROOT_DIRPATH="$(dirname "${SCRIPT_DIRPATH}")"
+DOCKER="${DOCKER:-docker}"
+
+# The stock image to add the offsettable caminogo to, and the caminogo ref to build, which should match it
+CAMINO_IMAGE="${CAMINO_IMAGE:-c4tplatform/caminogo:v1.0.0}"
+CAMINOGO_REF="${CAMINOGO_REF:-v1.0.0}"
+CLOCK_SKEW_IMAGE="${CLOCK_SKEW_IMAGE:-c4tplatform/caminogo-clock-skew:${CAMINOGO_REF}}"
+
+"${DOCKER}" build \
+    --build-arg "CAMINO_IMAGE=${CAMINO_IMAGE}" \
+    --build-arg "CAMINOGO_REF=${CAMINOGO_REF}" \
+    -t "${CLOCK_SKEW_IMAGE}" \
+    -f "${ROOT_DIRPATH}/images/clockskew/Dockerfile" \
+    "${ROOT_DIRPATH}"
+echo "Built ${CLOCK_SKEW_IMAGE}"
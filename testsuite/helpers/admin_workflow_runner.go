@@ -0,0 +1,122 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package helpers
+
+import (
+	"context"
+
+	"github.com/chain4travel/camino-testing/camino_client/apis"
+	"github.com/chain4travel/caminogo/utils/logging"
+	"github.com/palantir/stacktrace"
+	"github.com/sirupsen/logrus"
+)
+
+// AdminWorkFlowRunner executes workflows against the admin API of a single node, like changing its log levels
+// mid-test, profiling it or aliasing its chains.
+// Note: Profiles are written by the node itself, to the profiles directory in its service directory on the test volume
+// (see CaminoServiceLaunchDetails.GetProfileDirpath)
+type AdminWorkFlowRunner struct {
+	client *apis.Client
+	ctx    context.Context
+}
+
+// NewAdminWorkFlowRunner ...
+func NewAdminWorkFlowRunner(client *apis.Client) *AdminWorkFlowRunner {
+	return &AdminWorkFlowRunner{
+		client: client,
+		ctx:    context.Background(),
+	}
+}
+
+// SetLogLevel sets both the log and display level of the node's logger named [loggerName] (or of all of its loggers if
+// [loggerName] is empty) to [level], and verifies that the node picked the new level up
+func (runner AdminWorkFlowRunner) SetLogLevel(loggerName string, level logging.Level) error {
+	adminLogger := runner.client.AdminLoggerAPI()
+	if err := adminLogger.SetLoggerLevel(loggerName, &level, &level); err != nil {
+		return stacktrace.Propagate(err, "Failed to set the level of logger '%v' to %v", loggerName, level)
+	}
+	loggerLevels, err := adminLogger.GetLoggerLevel(loggerName)
+	if err != nil {
+		return stacktrace.Propagate(err, "Failed to get the level of logger '%v'", loggerName)
+	}
+	if len(loggerLevels) == 0 {
+		return stacktrace.NewError("Node reported no loggers matching '%v'", loggerName)
+	}
+	for name, levels := range loggerLevels {
+		if levels.LogLevel != level || levels.DisplayLevel != level {
+			return stacktrace.NewError(
+				"Expected logger '%v' to have log and display level %v but it has log level %v and display level %v",
+				name,
+				level,
+				levels.LogLevel,
+				levels.DisplayLevel)
+		}
+	}
+	logrus.Debugf("Set the level of logger '%v' to %v", loggerName, level)
+	return nil
+}
+
+// StartCPUProfiler starts profiling the node's CPU usage, until StopCPUProfiler is called
+func (runner AdminWorkFlowRunner) StartCPUProfiler() error {
+	success, err := runner.client.AdminAPI().StartCPUProfiler(runner.ctx)
+	return checkAdminCall("start the CPU profiler", success, err)
+}
+
+// StopCPUProfiler stops the running CPU profile, writing it to the node's profiles directory
+func (runner AdminWorkFlowRunner) StopCPUProfiler() error {
+	success, err := runner.client.AdminAPI().StopCPUProfiler(runner.ctx)
+	return checkAdminCall("stop the CPU profiler", success, err)
+}
+
+// WriteMemoryProfile writes a snapshot of the node's heap to its profiles directory
+func (runner AdminWorkFlowRunner) WriteMemoryProfile() error {
+	success, err := runner.client.AdminAPI().MemoryProfile(runner.ctx)
+	return checkAdminCall("write a memory profile", success, err)
+}
+
+// WriteLockProfile writes a snapshot of the node's lock contention to its profiles directory
+func (runner AdminWorkFlowRunner) WriteLockProfile() error {
+	success, err := runner.client.AdminAPI().LockProfile(runner.ctx)
+	return checkAdminCall("write a lock profile", success, err)
+}
+
+// AliasChain gives the chain with ID [chainID] the alias [alias] on the node, and verifies that the node now reports it
+// among the chain's aliases
+func (runner AdminWorkFlowRunner) AliasChain(chainID string, alias string) error {
+	admin := runner.client.AdminAPI()
+	success, err := admin.AliasChain(runner.ctx, chainID, alias)
+	if err := checkAdminCall("alias a chain", success, err); err != nil {
+		return stacktrace.Propagate(err, "Failed to alias chain %v as '%v'", chainID, alias)
+	}
+	aliases, err := admin.GetChainAliases(runner.ctx, chainID)
+	if err != nil {
+		return stacktrace.Propagate(err, "Failed to get the aliases of chain %v", chainID)
+	}
+	for _, existingAlias := range aliases {
+		if existingAlias == alias {
+			return nil
+		}
+	}
+	return stacktrace.NewError("Chain %v was aliased as '%v' but its aliases are %v", chainID, alias, aliases)
+}
+
+// ================ Helper functions =========================
+/*
+Checks both the error and the success flag of an admin API call, which are reported separately
+*/
+func checkAdminCall(description string, success bool, err error) error {
+	if err != nil {
+		return stacktrace.Propagate(err, "Failed to %v", description)
+	}
+	if !success {
+		return stacktrace.NewError("Node reported failure when asked to %v", description)
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package helpers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/chain4travel/camino-testing/camino_client/apis"
+	"github.com/chain4travel/camino-testing/camino_client/utils/constants"
+	"github.com/chain4travel/caminogo/api"
+	"github.com/chain4travel/caminogo/codec"
+	"github.com/chain4travel/caminogo/ids"
+	caminoConstants "github.com/chain4travel/caminogo/utils/constants"
+	"github.com/chain4travel/caminogo/utils/formatting"
+	"github.com/chain4travel/caminogo/vms/avm"
+	"github.com/chain4travel/caminogo/vms/components/avax"
+	"github.com/chain4travel/caminogo/vms/platformvm"
+	"github.com/chain4travel/caminogo/vms/secp256k1fx"
+	"github.com/palantir/stacktrace"
+)
+
+const (
+	// The most atomic UTXOs a node returns per call
+	atomicUTXOsPageSize = 1024
+)
+
+// VerifyAtomicUTXOs checks the AVAX waiting in the node's atomic memory to be imported against the exports recorded in
+// the ledger that haven't been imported yet, for every tracked address and every address with a pending import
+// An address whose exports have all been imported must have nothing left in atomic memory, so this catches both exports
+// that never arrived and imports that didn't consume what they imported. All mismatches are reported at once.
+func (ledger *BalanceLedger) VerifyAtomicUTXOs(client *apis.Client) error {
+	ledger.mutex.Lock()
+	defer ledger.mutex.Unlock()
+	ctx := context.Background()
+
+	avaxAssetID, err := client.PChainAPI().GetStakingAssetID(ctx, caminoConstants.PrimaryNetworkID)
+	if err != nil {
+		return stacktrace.Propagate(err, "Failed to get the ID of the AVAX asset.")
+	}
+	_, xChainCodec, err := avm.NewCodecs([]avm.Fx{&secp256k1fx.Fx{}})
+	if err != nil {
+		return stacktrace.Propagate(err, "Failed to create the X Chain codec.")
+	}
+	problems := []string{}
+	for _, destinationChain := range []LedgerChain{LedgerXChain, LedgerPChain} {
+		addresses := ledger.getAtomicAddresses(destinationChain)
+		if len(addresses) == 0 {
+			continue
+		}
+		atomicAmounts, err := getAtomicAmounts(ctx, client, xChainCodec, avaxAssetID, destinationChain, addresses)
+		if err != nil {
+			return stacktrace.Propagate(err, "Failed to get the atomic UTXOs waiting to be imported to the %v Chain", destinationChain)
+		}
+		for _, address := range addresses {
+			shortID, err := parseShortID(address)
+			if err != nil {
+				return stacktrace.Propagate(err, "Failed to parse %v Chain address %v", destinationChain, address)
+			}
+			expectedAmount := ledger.pendingImports[destinationChain][address]
+			actualAmount := atomicAmounts[shortID]
+			if actualAmount != expectedAmount {
+				problems = append(problems, fmt.Sprintf(
+					"%v Chain address %v: expected %v AVAX waiting to be imported, but found %v",
+					destinationChain,
+					address,
+					expectedAmount,
+					actualAmount))
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return stacktrace.NewError("The atomic memory doesn't match the ledger:\n%v", strings.Join(problems, "\n"))
+	}
+	return nil
+}
+
+// ================ Helper functions =========================
+/*
+Returns, sorted, the tracked addresses on the destination chain and the addresses with an import pending to it; the
+	caller must hold the lock
+*/
+func (ledger *BalanceLedger) getAtomicAddresses(destinationChain LedgerChain) []string {
+	addressSet := map[string]bool{}
+	for address := range ledger.expectedBalances[destinationChain] {
+		addressSet[address] = true
+	}
+	for address := range ledger.pendingImports[destinationChain] {
+		addressSet[address] = true
+	}
+	addresses := make([]string, 0, len(addressSet))
+	for address := range addressSet {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+	return addresses
+}
+
+/*
+Gets the atomic UTXOs exported to the addresses on the destination chain and sums up the AVAX in them per owner
+
+Args:
+	xChainCodec: Decodes the UTXOs exported from the X Chain; the ones exported from the P Chain are decoded with the P
+		Chain's codec
+*/
+func getAtomicAmounts(
+	ctx context.Context,
+	client *apis.Client,
+	xChainCodec codec.Manager,
+	avaxAssetID ids.ID,
+	destinationChain LedgerChain,
+	addresses []string) (map[ids.ShortID]uint64, error) {
+	getPage := client.PChainAPI().GetAtomicUTXOs
+	sourceChainID := constants.XChainID.String()
+	sourceCodec := xChainCodec
+	if destinationChain == LedgerXChain {
+		getPage = client.XChainAPI().GetAtomicUTXOs
+		sourceChainID = constants.PlatformChainID.String()
+		sourceCodec = platformvm.Codec
+	}
+
+	amounts := map[ids.ShortID]uint64{}
+	startIndex := api.Index{}
+	for {
+		utxosBytes, endIndex, err := getPage(ctx, addresses, sourceChainID, atomicUTXOsPageSize, startIndex.Address, startIndex.UTXO)
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "Failed to get the atomic UTXOs.")
+		}
+		for _, utxoBytes := range utxosBytes {
+			utxo := &avax.UTXO{}
+			if _, err := sourceCodec.Unmarshal(utxoBytes, utxo); err != nil {
+				return nil, stacktrace.Propagate(err, "Failed to decode an atomic UTXO.")
+			}
+			if utxo.AssetID() != avaxAssetID {
+				continue
+			}
+			out, ok := utxo.Out.(*secp256k1fx.TransferOutput)
+			if !ok {
+				return nil, stacktrace.NewError("Unexpected atomic UTXO output type %T", utxo.Out)
+			}
+			// The exports the ledger records each pay to a single address
+			for _, owner := range out.Addrs {
+				amounts[owner] += out.Amount()
+			}
+		}
+		if len(utxosBytes) < atomicUTXOsPageSize {
+			return amounts, nil
+		}
+		startIndex = endIndex
+	}
+}
+
+func parseShortID(address string) (ids.ShortID, error) {
+	_, _, addressBytes, err := formatting.ParseAddress(address)
+	if err != nil {
+		return ids.ShortID{}, stacktrace.Propagate(err, "Failed to parse the address.")
+	}
+	return ids.ToShortID(addressBytes)
+}
@@ -0,0 +1,80 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package helpers
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/chain4travel/caminogo/ids"
+	"github.com/chain4travel/caminogo/utils/formatting"
+	"github.com/chain4travel/caminogo/vms/avm"
+	"github.com/chain4travel/caminogo/vms/components/avax"
+	"github.com/chain4travel/caminogo/vms/secp256k1fx"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	testExporterShortID = ids.ShortID{20}
+	testImporterShortID = ids.ShortID{21}
+)
+
+func TestVerifyAtomicUTXOs(t *testing.T) {
+	exporter := newTestAddress(t, "X", testExporterShortID)
+	importer := newTestAddress(t, "P", testImporterShortID)
+	_, xChainCodec, err := avm.NewCodecs([]avm.Fx{&secp256k1fx.Fx{}})
+	assert.NoError(t, err)
+	// Two exports of 30 AVAX to the importer, and one of another asset that isn't counted
+	utxos := []string{}
+	for i, output := range []*avax.TransferableOutput{
+		newTestOwnedOutput(testAvaxAssetID, 30, testImporterShortID),
+		newTestOwnedOutput(testAvaxAssetID, 30, testImporterShortID),
+		newTestOwnedOutput(testOtherAssetID, 5, testImporterShortID),
+	} {
+		utxoBytes, err := xChainCodec.Marshal(0, &avax.UTXO{
+			UTXOID: avax.UTXOID{TxID: testExportTxID, OutputIndex: uint32(i)},
+			Asset:  output.Asset,
+			Out:    output.Out,
+		})
+		assert.NoError(t, err)
+		encodedUTXO, err := formatting.EncodeWithChecksum(formatting.Hex, utxoBytes)
+		assert.NoError(t, err)
+		utxos = append(utxos, fmt.Sprintf(`"%v"`, encodedUTXO))
+	}
+	client := newMethodResultsClient(t, map[string]string{
+		"platform.getStakingAssetID": fmt.Sprintf(`{"assetID": "%v"}`, testAvaxAssetID),
+		"avm.getUTXOs":               `{"numFetched": "0", "utxos": [], "endIndex": {"address": "", "utxo": ""}, "encoding": "hex"}`,
+		"platform.getUTXOs":          fmt.Sprintf(`{"numFetched": "3", "utxos": [%v, %v, %v], "endIndex": {"address": "", "utxo": ""}, "encoding": "hex"}`, utxos[0], utxos[1], utxos[2]),
+	})
+
+	ledger := NewBalanceLedger(LedgerFees{TxFee: 1})
+	ledger.Track(LedgerXChain, exporter, 100)
+	ledger.RecordExport(LedgerXChain, exporter, importer, 30)
+	ledger.RecordExport(LedgerXChain, exporter, importer, 30)
+	assert.NoError(t, ledger.VerifyAtomicUTXOs(client))
+
+	// An import that the node didn't apply leaves the exported AVAX in atomic memory
+	ledger.RecordImport(LedgerPChain, importer)
+	err = ledger.VerifyAtomicUTXOs(client)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), fmt.Sprintf("P Chain address %v: expected 0 AVAX waiting to be imported, but found 60", importer))
+}
+
+func newTestOwnedOutput(assetID ids.ID, amount uint64, owner ids.ShortID) *avax.TransferableOutput {
+	output := newTestOutput(assetID, amount)
+	output.Out.(*secp256k1fx.TransferOutput).Addrs = []ids.ShortID{owner}
+	return output
+}
+
+func newTestAddress(t *testing.T, chainAlias string, shortID ids.ShortID) string {
+	address, err := formatting.FormatAddress(chainAlias, "local", shortID.Bytes())
+	assert.NoError(t, err)
+	return address
+}
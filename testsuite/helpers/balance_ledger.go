@@ -0,0 +1,293 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package helpers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/chain4travel/camino-testing/camino_client/apis"
+	"github.com/palantir/stacktrace"
+)
+
+// LedgerChain is a chain whose AVAX balances a BalanceLedger keeps track of
+type LedgerChain string
+
+const (
+	LedgerXChain LedgerChain = "X"
+	LedgerPChain LedgerChain = "P"
+)
+
+// LedgerFees are the fees that a BalanceLedger charges for the operations it records
+type LedgerFees struct {
+	// Burned by every X Chain transfer, and by every export and import on either chain
+	TxFee uint64
+
+	// Burned by every validator or delegator addition
+	AddStakerTxFee uint64
+}
+
+// BalanceLedger keeps track of the AVAX balance that each tracked address is expected to have on the X and P Chains,
+// given the transfers, stakes and exports/imports recorded in it, and can check all of them against a node at once
+// Only tracked addresses are checked; recording an operation that involves an untracked address (e.g. funds sent to an
+// address owned by another test) only changes the balances of the tracked addresses involved.
+type BalanceLedger struct {
+	mutex *sync.Mutex
+
+	fees LedgerFees
+
+	// Mapping of chain -> address -> expected balance
+	expectedBalances map[LedgerChain]map[string]uint64
+
+	// Mapping of destination chain -> address -> amount exported to the address but not imported yet; addresses stay in
+	// it after their exports are imported, so that VerifyAtomicUTXOs keeps checking them
+	pendingImports map[LedgerChain]map[string]uint64
+
+	// Every change made to a tracked balance, in the order they were recorded
+	postings []ledgerPosting
+
+	// The txs whose burned fees are checked, in the order they were recorded
+	txs []ledgerTx
+
+	// The operations that would have taken a tracked balance below zero, which mean either the node or the test's own
+	// arithmetic is wrong
+	overdrafts []string
+}
+
+// NewBalanceLedger creates a ledger that charges the given fees and doesn't track any address yet
+func NewBalanceLedger(fees LedgerFees) *BalanceLedger {
+	return &BalanceLedger{
+		mutex: &sync.Mutex{},
+		fees:  fees,
+		expectedBalances: map[LedgerChain]map[string]uint64{
+			LedgerXChain: {},
+			LedgerPChain: {},
+		},
+		pendingImports: map[LedgerChain]map[string]uint64{
+			LedgerXChain: {},
+			LedgerPChain: {},
+		},
+	}
+}
+
+// GetFees returns the fees that the ledger charges
+func (ledger *BalanceLedger) GetFees() LedgerFees {
+	return ledger.fees
+}
+
+// Track starts tracking the address on the chain, expecting it to currently have the given balance
+func (ledger *BalanceLedger) Track(chain LedgerChain, address string, balance uint64) {
+	ledger.mutex.Lock()
+	defer ledger.mutex.Unlock()
+	ledger.expectedBalances[chain][address] = balance
+	ledger.postings = append(ledger.postings, ledgerPosting{
+		chain:       chain,
+		address:     address,
+		isCredit:    true,
+		amount:      balance,
+		description: "started tracking",
+	})
+}
+
+// IsTracked returns whether the address is tracked on the chain
+func (ledger *BalanceLedger) IsTracked(chain LedgerChain, address string) bool {
+	ledger.mutex.Lock()
+	defer ledger.mutex.Unlock()
+	_, found := ledger.expectedBalances[chain][address]
+	return found
+}
+
+// GetExpectedBalance returns the balance that a tracked address is expected to have on the chain, and false if the
+// address isn't tracked
+func (ledger *BalanceLedger) GetExpectedBalance(chain LedgerChain, address string) (uint64, bool) {
+	ledger.mutex.Lock()
+	defer ledger.mutex.Unlock()
+	balance, found := ledger.expectedBalances[chain][address]
+	return balance, found
+}
+
+// RecordTransfer records an X Chain transfer of the given amount, which the sender also pays the fee for
+func (ledger *BalanceLedger) RecordTransfer(from string, to string, amount uint64) {
+	ledger.mutex.Lock()
+	defer ledger.mutex.Unlock()
+	description := fmt.Sprintf("transfer of %v to %v", amount, to)
+	ledger.debit(LedgerXChain, from, amount+ledger.fees.TxFee, description)
+	ledger.credit(LedgerXChain, to, amount, fmt.Sprintf("transfer of %v from %v", amount, from))
+}
+
+// RecordStake records a validator or delegator addition that locks the given amount of the staker's P Chain funds until
+// the staking period ends; the staker also pays the fee
+func (ledger *BalanceLedger) RecordStake(staker string, amount uint64) {
+	ledger.mutex.Lock()
+	defer ledger.mutex.Unlock()
+	ledger.debit(LedgerPChain, staker, amount+ledger.fees.AddStakerTxFee, fmt.Sprintf("stake lock of %v", amount))
+}
+
+// RecordExport records an export of the given amount from the source chain to an address on the other chain, which the
+// sender also pays the fee for. The amount only arrives once RecordImport is called for the recipient.
+func (ledger *BalanceLedger) RecordExport(sourceChain LedgerChain, from string, to string, amount uint64) {
+	ledger.mutex.Lock()
+	defer ledger.mutex.Unlock()
+	ledger.debit(sourceChain, from, amount+ledger.fees.TxFee, fmt.Sprintf("export of %v to %v", amount, to))
+	ledger.pendingImports[getOtherChain(sourceChain)][to] += amount
+}
+
+// RecordImport records the import of everything exported to the address on the destination chain, which pays the fee
+// out of the imported amount
+func (ledger *BalanceLedger) RecordImport(destinationChain LedgerChain, to string) {
+	ledger.mutex.Lock()
+	defer ledger.mutex.Unlock()
+	importedAmount := ledger.pendingImports[destinationChain][to]
+	ledger.pendingImports[destinationChain][to] = 0
+	description := fmt.Sprintf("import of %v", importedAmount)
+	if importedAmount >= ledger.fees.TxFee {
+		ledger.credit(destinationChain, to, importedAmount-ledger.fees.TxFee, description)
+	} else {
+		// The fee that the imported funds don't cover is paid out of the funds that were already there
+		ledger.debit(destinationChain, to, ledger.fees.TxFee-importedAmount, description)
+	}
+}
+
+// Verify checks the balance of every tracked address, on both chains, against the balances the node reports
+// All mismatches are reported at once, along with the history of each mismatched address.
+func (ledger *BalanceLedger) Verify(client *apis.Client) error {
+	ledger.mutex.Lock()
+	defer ledger.mutex.Unlock()
+	ctx := context.Background()
+
+	problems := append([]string{}, ledger.overdrafts...)
+	for _, chain := range []LedgerChain{LedgerXChain, LedgerPChain} {
+		addresses := make([]string, 0, len(ledger.expectedBalances[chain]))
+		for address := range ledger.expectedBalances[chain] {
+			addresses = append(addresses, address)
+		}
+		sort.Strings(addresses)
+		for _, address := range addresses {
+			expectedBalance := ledger.expectedBalances[chain][address]
+			actualBalance, err := getBalance(ctx, client, chain, address)
+			if err != nil {
+				return stacktrace.Propagate(err, "Failed to get the %v Chain balance of address %v", chain, address)
+			}
+			if actualBalance != expectedBalance {
+				problems = append(problems, fmt.Sprintf(
+					"%v Chain address %v: expected balance %v, but found %v; history:\n%v",
+					chain,
+					address,
+					expectedBalance,
+					actualBalance,
+					ledger.getHistory(chain, address)))
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return stacktrace.NewError("The tracked balances don't match the ledger:\n%v", strings.Join(problems, "\n"))
+	}
+	return nil
+}
+
+// ================ Helper functions =========================
+// ledgerPosting is a change to the balance of a tracked address
+type ledgerPosting struct {
+	chain       LedgerChain
+	address     string
+	isCredit    bool
+	amount      uint64
+	description string
+}
+
+/*
+Adds to the balance of the address, if it's tracked; the caller must hold the lock
+*/
+func (ledger *BalanceLedger) credit(chain LedgerChain, address string, amount uint64, description string) {
+	balance, found := ledger.expectedBalances[chain][address]
+	if !found {
+		return
+	}
+	ledger.expectedBalances[chain][address] = balance + amount
+	ledger.postings = append(ledger.postings, ledgerPosting{
+		chain:       chain,
+		address:     address,
+		isCredit:    true,
+		amount:      amount,
+		description: description,
+	})
+}
+
+/*
+Takes from the balance of the address, if it's tracked, recording an overdraft if the balance doesn't cover the amount;
+	the caller must hold the lock
+*/
+func (ledger *BalanceLedger) debit(chain LedgerChain, address string, amount uint64, description string) {
+	balance, found := ledger.expectedBalances[chain][address]
+	if !found {
+		return
+	}
+	if amount > balance {
+		ledger.overdrafts = append(ledger.overdrafts, fmt.Sprintf(
+			"%v Chain address %v: %v needs %v, but the address only has %v",
+			chain,
+			address,
+			description,
+			amount,
+			balance))
+		amount = balance
+	}
+	ledger.expectedBalances[chain][address] = balance - amount
+	ledger.postings = append(ledger.postings, ledgerPosting{
+		chain:       chain,
+		address:     address,
+		isCredit:    false,
+		amount:      amount,
+		description: description,
+	})
+}
+
+/*
+Describes every change made to the balance of the address, one per line; the caller must hold the lock
+*/
+func (ledger *BalanceLedger) getHistory(chain LedgerChain, address string) string {
+	lines := []string{}
+	for _, posting := range ledger.postings {
+		if posting.chain != chain || posting.address != address {
+			continue
+		}
+		sign := "-"
+		if posting.isCredit {
+			sign = "+"
+		}
+		lines = append(lines, fmt.Sprintf("\t%v%v (%v)", sign, posting.amount, posting.description))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func getOtherChain(chain LedgerChain) LedgerChain {
+	if chain == LedgerXChain {
+		return LedgerPChain
+	}
+	return LedgerXChain
+}
+
+func getBalance(ctx context.Context, client *apis.Client, chain LedgerChain, address string) (uint64, error) {
+	if chain == LedgerXChain {
+		balance, err := client.XChainAPI().GetBalance(ctx, address, AvaxAssetID, false)
+		if err != nil {
+			return 0, stacktrace.Propagate(err, "Failed to retrieve X Chain balance.")
+		}
+		return uint64(balance.Balance), nil
+	}
+	balance, err := client.PChainAPI().GetBalance(ctx, []string{address})
+	if err != nil {
+		return 0, stacktrace.Propagate(err, "Failed to retrieve P Chain balance.")
+	}
+	return uint64(balance.Balance), nil
+}
@@ -0,0 +1,109 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package helpers
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/chain4travel/camino-testing/camino_client/apis"
+	"github.com/chain4travel/camino-testing/camino_client/apis/apistest"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testTxFee   = 10
+	testXSender = "X-sender"
+	testXOther  = "X-other"
+	testPStaker = "P-staker"
+)
+
+func TestLedgerChargesFees(t *testing.T) {
+	ledger := NewBalanceLedger(LedgerFees{TxFee: testTxFee})
+	ledger.Track(LedgerXChain, testXSender, 1000)
+	ledger.Track(LedgerPChain, testPStaker, 0)
+
+	ledger.RecordTransfer(testXSender, testXOther, 100)
+	assertExpectedBalance(t, ledger, LedgerXChain, testXSender, 890)
+	// Untracked addresses are ignored
+	assert.False(t, ledger.IsTracked(LedgerXChain, testXOther))
+
+	ledger.RecordExport(LedgerXChain, testXSender, testPStaker, 500)
+	assertExpectedBalance(t, ledger, LedgerXChain, testXSender, 380)
+	assertExpectedBalance(t, ledger, LedgerPChain, testPStaker, 0)
+	ledger.RecordImport(LedgerPChain, testPStaker)
+	assertExpectedBalance(t, ledger, LedgerPChain, testPStaker, 490)
+
+	ledger.RecordStake(testPStaker, 300)
+	assertExpectedBalance(t, ledger, LedgerPChain, testPStaker, 190)
+
+	// A second import of the same export doesn't import anything, so only costs the fee
+	ledger.RecordImport(LedgerPChain, testPStaker)
+	assertExpectedBalance(t, ledger, LedgerPChain, testPStaker, 180)
+}
+
+func TestLedgerVerify(t *testing.T) {
+	ledger := NewBalanceLedger(LedgerFees{TxFee: testTxFee})
+	ledger.Track(LedgerXChain, testXSender, 1000)
+	ledger.Track(LedgerPChain, testPStaker, 0)
+	ledger.RecordExport(LedgerXChain, testXSender, testPStaker, 500)
+	ledger.RecordImport(LedgerPChain, testPStaker)
+
+	client := newBalancesClient(t, map[string]uint64{testXSender: 490, testPStaker: 490})
+	assert.NoError(t, ledger.Verify(client))
+
+	// The node forgetting the import fee is caught, along with the history of the address
+	client = newBalancesClient(t, map[string]uint64{testXSender: 490, testPStaker: 500})
+	err := ledger.Verify(client)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "P Chain address P-staker: expected balance 490, but found 500")
+	assert.Contains(t, err.Error(), "+490 (import of 500)")
+}
+
+func TestLedgerReportsOverdrafts(t *testing.T) {
+	ledger := NewBalanceLedger(LedgerFees{TxFee: testTxFee})
+	ledger.Track(LedgerXChain, testXSender, 100)
+	ledger.RecordTransfer(testXSender, testXOther, 100)
+	assertExpectedBalance(t, ledger, LedgerXChain, testXSender, 0)
+
+	err := ledger.Verify(newBalancesClient(t, map[string]uint64{testXSender: 0}))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "transfer of 100 to X-other needs 110, but the address only has 100")
+}
+
+func assertExpectedBalance(t *testing.T, ledger *BalanceLedger, chain LedgerChain, address string, expected uint64) {
+	balance, found := ledger.GetExpectedBalance(chain, address)
+	assert.True(t, found, "%v Chain address %v should be tracked", chain, address)
+	assert.Equal(t, expected, balance, "Unexpected balance for %v Chain address %v", chain, address)
+}
+
+/*
+Creates a client for a node whose X and P Chain APIs report the given balances, by address
+*/
+func newBalancesClient(t *testing.T, balances map[string]uint64) *apis.Client {
+	respond := func(params json.RawMessage) apistest.Response {
+		balanceParams := struct {
+			Address   string   `json:"address"`
+			Addresses []string `json:"addresses"`
+		}{}
+		if err := json.Unmarshal(params, &balanceParams); err != nil {
+			return apistest.Error(err.Error())
+		}
+		address := balanceParams.Address
+		if len(balanceParams.Addresses) > 0 {
+			address = balanceParams.Addresses[0]
+		}
+		return apistest.Resultf(`{"balance": "%v", "utxoIDs": []}`, balances[address])
+	}
+	return apistest.NewFakeNode(t).
+		OnParams("avm.getBalance", respond).
+		OnParams("platform.getBalance", respond).
+		GetClient()
+}
@@ -0,0 +1,108 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package helpers
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	caminoNetwork "github.com/chain4travel/camino-testing/camino/networks"
+	"github.com/chain4travel/caminogo/api"
+	"github.com/kurtosis-tech/kurtosis-go/lib/networks"
+	"github.com/palantir/stacktrace"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// The configuration that the byzantine nodes of the snapshot must be launched with, whatever behavior it configures
+	ByzantineValidatorsConfigID networks.ConfigurationID = "byzantine-config"
+
+	// The number of byzantine nodes in the snapshot, which hold less than a third of the stake
+	NumByzantineValidators = 4
+
+	// The snapshot that the byzantine tests share, which every one of them must use with the same boot nodes, byzantine
+	// service IDs and configuration ID
+	byzantineValidatorsSnapshotName = "byzantineValidators"
+
+	byzantineValidatorServiceIDPrefix = "byzantine-node-"
+
+	byzantineFunderUsername = "byzantine_funder_camino"
+	byzantineFunderPassword = "fund3r!Byzant1ne"
+
+	// What each byzantine node is funded with on the P Chain, and how much of it is staked
+	byzantineValidatorSeedAmount  = uint64(50000000000000)
+	byzantineValidatorStakeAmount = uint64(30000000000000)
+
+	// How long the snapshot's setup waits for the network to accept each tx
+	byzantineValidatorsAcceptanceTimeout = 3 * time.Minute
+)
+
+// NewByzantineValidatorsSnapshot creates the snapshot of a network whose byzantine nodes, with the service IDs of
+// GetByzantineValidatorServiceIDs, have all been added as validators. The byzantine nodes are staked through the first
+// boot node, because a byzantine node can't be relied on to get its own transactions accepted.
+func NewByzantineValidatorsSnapshot() *caminoNetwork.NetworkSnapshot {
+	return caminoNetwork.NewNetworkSnapshot(byzantineValidatorsSnapshotName, func(network caminoNetwork.TestCaminoNetwork) error {
+		return addByzantineValidators(network, GetByzantineValidatorServiceIDs())
+	})
+}
+
+// GetByzantineValidatorServiceIDs returns the service IDs of the byzantine nodes of the snapshot, which a test must launch
+// with ByzantineValidatorsConfigID
+func GetByzantineValidatorServiceIDs() []networks.ServiceID {
+	result := make([]networks.ServiceID, 0, NumByzantineValidators)
+	for i := 0; i < NumByzantineValidators; i++ {
+		result = append(result, networks.ServiceID(byzantineValidatorServiceIDPrefix+strconv.Itoa(i)))
+	}
+	return result
+}
+
+/*
+Funds the byzantine nodes' stakes from the genesis funds on the first boot node and adds the byzantine nodes as validators
+*/
+func addByzantineValidators(network caminoNetwork.TestCaminoNetwork, byzantineServiceIDs []networks.ServiceID) error {
+	ctx := context.Background()
+	funderServiceID := caminoNetwork.GetBootServiceID(0)
+	funderClient, err := network.GetCaminoClient(funderServiceID)
+	if err != nil {
+		return stacktrace.Propagate(err, "Failed to get client for boot node %v.", funderServiceID)
+	}
+	funderRunner := NewRPCWorkFlowRunner(
+		funderClient,
+		api.UserPass{Username: byzantineFunderUsername, Password: byzantineFunderPassword},
+		byzantineValidatorsAcceptanceTimeout)
+	if _, err := funderRunner.ImportGenesisFunds(); err != nil {
+		return stacktrace.Propagate(err, "Failed to import genesis funds on boot node %v.", funderServiceID)
+	}
+	funderPChainAddress, err := funderClient.PChainAPI().CreateAddress(ctx, funderRunner.User())
+	if err != nil {
+		return stacktrace.Propagate(err, "Failed to create funder P Chain address.")
+	}
+	if err := funderRunner.TransferAvaXChainToPChain(
+		funderPChainAddress,
+		uint64(len(byzantineServiceIDs))*byzantineValidatorSeedAmount); err != nil {
+		return stacktrace.Propagate(err, "Failed to fund the byzantine stakes.")
+	}
+	for _, byzantineServiceID := range byzantineServiceIDs {
+		byzClient, err := network.GetCaminoClient(byzantineServiceID)
+		if err != nil {
+			return stacktrace.Propagate(err, "Failed to get client for byzantine node %v.", byzantineServiceID)
+		}
+		byzNodeID, err := byzClient.InfoAPI().GetNodeID(ctx)
+		if err != nil {
+			return stacktrace.Propagate(err, "Could not get node ID of byzantine node %v.", byzantineServiceID)
+		}
+		if err := funderRunner.AddValidatorToPrimaryNetwork(byzNodeID, funderPChainAddress, byzantineValidatorStakeAmount); err != nil {
+			return stacktrace.Propagate(err, "Failed to add byzantine node %v as a validator.", byzantineServiceID)
+		}
+		logrus.Infof("Added byzantine node %v as a validator.", byzantineServiceID)
+	}
+	return nil
+}
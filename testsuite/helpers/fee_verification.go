@@ -0,0 +1,187 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package helpers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/chain4travel/camino-testing/camino_client/apis"
+	"github.com/chain4travel/caminogo/codec"
+	"github.com/chain4travel/caminogo/ids"
+	"github.com/chain4travel/caminogo/utils/constants"
+	"github.com/chain4travel/caminogo/vms/avm"
+	"github.com/chain4travel/caminogo/vms/components/avax"
+	"github.com/chain4travel/caminogo/vms/platformvm"
+	"github.com/chain4travel/caminogo/vms/secp256k1fx"
+	"github.com/palantir/stacktrace"
+)
+
+// LedgerTxType is a kind of tx that a BalanceLedger knows the fee of
+type LedgerTxType string
+
+const (
+	LedgerTransferTx     LedgerTxType = "transfer"
+	LedgerExportTx       LedgerTxType = "export"
+	LedgerImportTx       LedgerTxType = "import"
+	LedgerAddValidatorTx LedgerTxType = "add-validator"
+	LedgerAddDelegatorTx LedgerTxType = "add-delegator"
+)
+
+// GetLedgerFees reads the fees that the node charges from its info API
+// The node doesn't report the fee for adding validators and delegators, which it never charges, so that fee is 0
+func GetLedgerFees(client *apis.Client) (LedgerFees, error) {
+	txFees, err := client.InfoAPI().GetTxFee(context.Background())
+	if err != nil {
+		return LedgerFees{}, stacktrace.Propagate(err, "Failed to get the tx fees from the node.")
+	}
+	return LedgerFees{
+		TxFee: uint64(txFees.TxFee),
+	}, nil
+}
+
+// RecordTx records a tx that was issued on the chain, so that VerifyBurnedFees checks the fee it burned
+func (ledger *BalanceLedger) RecordTx(chain LedgerChain, txType LedgerTxType, txID ids.ID) {
+	ledger.mutex.Lock()
+	defer ledger.mutex.Unlock()
+	ledger.txs = append(ledger.txs, ledgerTx{
+		id:     txID,
+		chain:  chain,
+		txType: txType,
+	})
+}
+
+// VerifyBurnedFees checks that every recorded tx burned exactly the fee for its type, by subtracting the AVAX that the
+// tx produced (including exported and staked AVAX) from the AVAX it consumed (including imported AVAX)
+// The txs must have been accepted by the node. All txs that burned the wrong fee are reported at once.
+func (ledger *BalanceLedger) VerifyBurnedFees(client *apis.Client) error {
+	ledger.mutex.Lock()
+	txs := append([]ledgerTx{}, ledger.txs...)
+	ledger.mutex.Unlock()
+
+	ctx := context.Background()
+	avaxAssetID, err := client.PChainAPI().GetStakingAssetID(ctx, constants.PrimaryNetworkID)
+	if err != nil {
+		return stacktrace.Propagate(err, "Failed to get the ID of the AVAX asset.")
+	}
+	_, xChainCodec, err := avm.NewCodecs([]avm.Fx{&secp256k1fx.Fx{}})
+	if err != nil {
+		return stacktrace.Propagate(err, "Failed to create the X Chain codec.")
+	}
+
+	problems := []string{}
+	for _, tx := range txs {
+		burned, err := getBurnedAmount(ctx, client, xChainCodec, avaxAssetID, tx)
+		if err != nil {
+			return stacktrace.Propagate(err, "Failed to get the amount burned by %v Chain %v tx %v", tx.chain, tx.txType, tx.id)
+		}
+		expectedFee := ledger.getFee(tx.txType)
+		if burned != expectedFee {
+			problems = append(problems, fmt.Sprintf(
+				"%v Chain %v tx %v: expected it to burn %v, but it burned %v",
+				tx.chain,
+				tx.txType,
+				tx.id,
+				expectedFee,
+				burned))
+		}
+	}
+	if len(problems) > 0 {
+		return stacktrace.NewError("These txs didn't burn the expected fee:\n%v", strings.Join(problems, "\n"))
+	}
+	return nil
+}
+
+// ================ Helper functions =========================
+// ledgerTx is a tx that a BalanceLedger checks the burned fee of
+type ledgerTx struct {
+	id     ids.ID
+	chain  LedgerChain
+	txType LedgerTxType
+}
+
+func (ledger *BalanceLedger) getFee(txType LedgerTxType) uint64 {
+	if txType == LedgerAddValidatorTx || txType == LedgerAddDelegatorTx {
+		return ledger.fees.AddStakerTxFee
+	}
+	return ledger.fees.TxFee
+}
+
+/*
+Gets the tx from the node and works out how much AVAX it burned
+*/
+func getBurnedAmount(
+	ctx context.Context,
+	client *apis.Client,
+	xChainCodec codec.Manager,
+	avaxAssetID ids.ID,
+	tx ledgerTx) (uint64, error) {
+	var ins []*avax.TransferableInput
+	var outs []*avax.TransferableOutput
+	if tx.chain == LedgerXChain {
+		txBytes, err := client.XChainAPI().GetTx(ctx, tx.id)
+		if err != nil {
+			return 0, stacktrace.Propagate(err, "Failed to get the tx.")
+		}
+		decodedTx := &avm.Tx{}
+		if _, err := xChainCodec.Unmarshal(txBytes, decodedTx); err != nil {
+			return 0, stacktrace.Propagate(err, "Failed to decode the tx.")
+		}
+		switch unsignedTx := decodedTx.UnsignedTx.(type) {
+		case *avm.BaseTx:
+			ins, outs = unsignedTx.Ins, unsignedTx.Outs
+		case *avm.ExportTx:
+			ins, outs = unsignedTx.Ins, append(unsignedTx.Outs, unsignedTx.ExportedOuts...)
+		case *avm.ImportTx:
+			ins, outs = append(unsignedTx.Ins, unsignedTx.ImportedIns...), unsignedTx.Outs
+		default:
+			return 0, stacktrace.NewError("Unexpected X Chain tx type %T", unsignedTx)
+		}
+	} else {
+		txBytes, err := client.PChainAPI().GetTx(ctx, tx.id)
+		if err != nil {
+			return 0, stacktrace.Propagate(err, "Failed to get the tx.")
+		}
+		decodedTx := &platformvm.Tx{}
+		if _, err := platformvm.Codec.Unmarshal(txBytes, decodedTx); err != nil {
+			return 0, stacktrace.Propagate(err, "Failed to decode the tx.")
+		}
+		switch unsignedTx := decodedTx.UnsignedTx.(type) {
+		case *platformvm.UnsignedExportTx:
+			ins, outs = unsignedTx.Ins, append(unsignedTx.Outs, unsignedTx.ExportedOutputs...)
+		case *platformvm.UnsignedImportTx:
+			ins, outs = append(unsignedTx.Ins, unsignedTx.ImportedInputs...), unsignedTx.Outs
+		case *platformvm.UnsignedAddValidatorTx:
+			ins, outs = unsignedTx.Ins, append(unsignedTx.Outs, unsignedTx.Stake...)
+		case *platformvm.UnsignedAddDelegatorTx:
+			ins, outs = unsignedTx.Ins, append(unsignedTx.Outs, unsignedTx.Stake...)
+		default:
+			return 0, stacktrace.NewError("Unexpected P Chain tx type %T", unsignedTx)
+		}
+	}
+
+	consumed := uint64(0)
+	for _, in := range ins {
+		if in.AssetID() == avaxAssetID {
+			consumed += in.In.Amount()
+		}
+	}
+	produced := uint64(0)
+	for _, out := range outs {
+		if out.AssetID() == avaxAssetID {
+			produced += out.Out.Amount()
+		}
+	}
+	if produced > consumed {
+		return 0, stacktrace.NewError("The tx produced %v AVAX, which is more than the %v it consumed", produced, consumed)
+	}
+	return consumed - produced, nil
+}
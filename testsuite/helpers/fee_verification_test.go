@@ -0,0 +1,118 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package helpers
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/chain4travel/camino-testing/camino_client/apis"
+	"github.com/chain4travel/camino-testing/camino_client/apis/apistest"
+	"github.com/chain4travel/caminogo/ids"
+	"github.com/chain4travel/caminogo/utils/formatting"
+	"github.com/chain4travel/caminogo/vms/avm"
+	"github.com/chain4travel/caminogo/vms/components/avax"
+	"github.com/chain4travel/caminogo/vms/platformvm"
+	"github.com/chain4travel/caminogo/vms/secp256k1fx"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	testAvaxAssetID  = ids.ID{1}
+	testOtherAssetID = ids.ID{2}
+
+	testTransferTxID = ids.ID{10}
+	testExportTxID   = ids.ID{11}
+)
+
+func TestGetLedgerFees(t *testing.T) {
+	client := newMethodResultsClient(t, map[string]string{
+		"info.getTxFee": `{"txFee": "1000000", "creationTxFee": "1000000", "createAssetTxFee": "1000000", "createSubnetTxFee": "100000000", "createBlockchainTxFee": "100000000"}`,
+	})
+	fees, err := GetLedgerFees(client)
+	assert.NoError(t, err)
+	assert.Equal(t, LedgerFees{TxFee: 1000000}, fees)
+}
+
+func TestVerifyBurnedFees(t *testing.T) {
+	_, xChainCodec, err := avm.NewCodecs([]avm.Fx{&secp256k1fx.Fx{}})
+	assert.NoError(t, err)
+	// Burns 10 AVAX; the other asset isn't counted
+	transferTxBytes, err := xChainCodec.Marshal(0, &avm.Tx{UnsignedTx: &avm.BaseTx{BaseTx: avax.BaseTx{
+		Ins:  []*avax.TransferableInput{newTestInput(testAvaxAssetID, 100), newTestInput(testOtherAssetID, 50)},
+		Outs: []*avax.TransferableOutput{newTestOutput(testAvaxAssetID, 90)},
+	}}})
+	assert.NoError(t, err)
+	// Burns 10 AVAX, counting the exported AVAX as produced
+	exportTxBytes, err := platformvm.Codec.Marshal(platformvm.CodecVersion, &platformvm.Tx{UnsignedTx: &platformvm.UnsignedExportTx{
+		BaseTx: platformvm.BaseTx{BaseTx: avax.BaseTx{
+			Ins:  []*avax.TransferableInput{newTestInput(testAvaxAssetID, 100)},
+			Outs: []*avax.TransferableOutput{newTestOutput(testAvaxAssetID, 20)},
+		}},
+		ExportedOutputs: []*avax.TransferableOutput{newTestOutput(testAvaxAssetID, 70)},
+	}})
+	assert.NoError(t, err)
+	client := newMethodResultsClient(t, map[string]string{
+		"platform.getStakingAssetID": fmt.Sprintf(`{"assetID": "%v"}`, testAvaxAssetID),
+		"avm.getTx":                  newTestFormattedTx(t, transferTxBytes),
+		"platform.getTx":             newTestFormattedTx(t, exportTxBytes),
+	})
+
+	ledger := NewBalanceLedger(LedgerFees{TxFee: 10})
+	ledger.RecordTx(LedgerXChain, LedgerTransferTx, testTransferTxID)
+	ledger.RecordTx(LedgerPChain, LedgerExportTx, testExportTxID)
+	assert.NoError(t, ledger.VerifyBurnedFees(client))
+
+	ledger = NewBalanceLedger(LedgerFees{TxFee: 5})
+	ledger.RecordTx(LedgerPChain, LedgerExportTx, testExportTxID)
+	err = ledger.VerifyBurnedFees(client)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), fmt.Sprintf("P Chain export tx %v: expected it to burn 5, but it burned 10", testExportTxID))
+}
+
+func newTestInput(assetID ids.ID, amount uint64) *avax.TransferableInput {
+	return &avax.TransferableInput{
+		Asset: avax.Asset{ID: assetID},
+		In: &secp256k1fx.TransferInput{
+			Amt:   amount,
+			Input: secp256k1fx.Input{SigIndices: []uint32{0}},
+		},
+	}
+}
+
+func newTestOutput(assetID ids.ID, amount uint64) *avax.TransferableOutput {
+	return &avax.TransferableOutput{
+		Asset: avax.Asset{ID: assetID},
+		Out: &secp256k1fx.TransferOutput{
+			Amt: amount,
+			OutputOwners: secp256k1fx.OutputOwners{
+				Threshold: 1,
+				Addrs:     []ids.ShortID{ids.ShortEmpty},
+			},
+		},
+	}
+}
+
+func newTestFormattedTx(t *testing.T, txBytes []byte) string {
+	encodedTx, err := formatting.EncodeWithChecksum(formatting.Hex, txBytes)
+	assert.NoError(t, err)
+	return fmt.Sprintf(`{"tx": "%v", "encoding": "hex"}`, encodedTx)
+}
+
+/*
+Creates a client for a node that replies to each of the given API methods with the given result
+*/
+func newMethodResultsClient(t *testing.T, results map[string]string) *apis.Client {
+	node := apistest.NewFakeNode(t)
+	for method, result := range results {
+		node.On(method, apistest.Result(result))
+	}
+	return node.GetClient()
+}
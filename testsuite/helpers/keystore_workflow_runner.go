@@ -0,0 +1,137 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package helpers
+
+import (
+	"context"
+
+	"github.com/chain4travel/camino-testing/camino_client/apis"
+	"github.com/chain4travel/caminogo/api"
+	"github.com/palantir/stacktrace"
+	"github.com/sirupsen/logrus"
+)
+
+// KeystoreWorkFlowRunner executes workflows against the keystore API of a single node, like creating users, moving them
+// between nodes and deleting them.
+// Note: Tests often use constant credentials (e.g. "staker"), so the runner refuses to create or import a user whose
+// username the node already has, rather than letting a test silently act on a user that some other workflow created.
+type KeystoreWorkFlowRunner struct {
+	client *apis.Client
+	ctx    context.Context
+}
+
+// NewKeystoreWorkFlowRunner ...
+func NewKeystoreWorkFlowRunner(client *apis.Client) *KeystoreWorkFlowRunner {
+	return &KeystoreWorkFlowRunner{
+		client: client,
+		ctx:    context.Background(),
+	}
+}
+
+// CreateUser creates [user] in the node's keystore, failing if the node already has a user with the same username
+func (runner KeystoreWorkFlowRunner) CreateUser(user api.UserPass) error {
+	if err := runner.verifyUsernameFree(user.Username); err != nil {
+		return err
+	}
+	success, err := runner.client.KeystoreAPI().CreateUser(runner.ctx, user)
+	if err := checkKeystoreCall("create user "+user.Username, success, err); err != nil {
+		return err
+	}
+	logrus.Debugf("Created keystore user %v", user.Username)
+	return nil
+}
+
+// ListUsers returns the usernames of all the users in the node's keystore
+func (runner KeystoreWorkFlowRunner) ListUsers() ([]string, error) {
+	usernames, err := runner.client.KeystoreAPI().ListUsers(runner.ctx)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "Failed to list the keystore users")
+	}
+	return usernames, nil
+}
+
+// HasUser returns whether the node's keystore has a user with [username]
+func (runner KeystoreWorkFlowRunner) HasUser(username string) (bool, error) {
+	usernames, err := runner.ListUsers()
+	if err != nil {
+		return false, err
+	}
+	for _, existingUsername := range usernames {
+		if existingUsername == username {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ExportUser returns the serialized form of [user], including its password hash and keys
+func (runner KeystoreWorkFlowRunner) ExportUser(user api.UserPass) ([]byte, error) {
+	exportedUser, err := runner.client.KeystoreAPI().ExportUser(runner.ctx, user)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "Failed to export keystore user %v", user.Username)
+	}
+	return exportedUser, nil
+}
+
+// ImportUser imports [exportedUser] into the node's keystore as [user], failing if the node already has a user with the
+// same username. The password must be the one the user was exported with.
+func (runner KeystoreWorkFlowRunner) ImportUser(user api.UserPass, exportedUser []byte) error {
+	if err := runner.verifyUsernameFree(user.Username); err != nil {
+		return err
+	}
+	success, err := runner.client.KeystoreAPI().ImportUser(runner.ctx, user, exportedUser)
+	return checkKeystoreCall("import user "+user.Username, success, err)
+}
+
+// DeleteUser deletes [user] from the node's keystore, and verifies that the node no longer lists it
+func (runner KeystoreWorkFlowRunner) DeleteUser(user api.UserPass) error {
+	success, err := runner.client.KeystoreAPI().DeleteUser(runner.ctx, user)
+	if err := checkKeystoreCall("delete user "+user.Username, success, err); err != nil {
+		return err
+	}
+	hasUser, err := runner.HasUser(user.Username)
+	if err != nil {
+		return err
+	}
+	if hasUser {
+		return stacktrace.NewError("Keystore user %v was deleted but the node still lists it", user.Username)
+	}
+	return nil
+}
+
+// ================ Helper functions =========================
+/*
+Fails if the node already has a user with the username
+*/
+func (runner KeystoreWorkFlowRunner) verifyUsernameFree(username string) error {
+	hasUser, err := runner.HasUser(username)
+	if err != nil {
+		return err
+	}
+	if hasUser {
+		return stacktrace.NewError(
+			"Keystore user %v already exists on the node; workflows that share a node must use distinct usernames",
+			username)
+	}
+	return nil
+}
+
+/*
+Checks both the error and the success flag of a keystore API call, which are reported separately
+*/
+func checkKeystoreCall(description string, success bool, err error) error {
+	if err != nil {
+		return stacktrace.Propagate(err, "Failed to %v", description)
+	}
+	if !success {
+		return stacktrace.NewError("Node reported failure when asked to %v", description)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package helpers
+
+import (
+	"testing"
+
+	"github.com/chain4travel/caminogo/api"
+	"github.com/stretchr/testify/assert"
+)
+
+var testStakerUser = api.UserPass{Username: "staker", Password: "test34test!23"}
+
+func TestKeystoreCreateUserRejectsTakenUsername(t *testing.T) {
+	// The fake node has no createUser method, so the username must be found taken before the user would be created
+	runner := NewKeystoreWorkFlowRunner(newMethodResultsClient(t, map[string]string{
+		"keystore.listUsers": `{"users": ["staker"]}`,
+	}))
+	err := runner.CreateUser(testStakerUser)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "already exists")
+	assert.Error(t, runner.ImportUser(testStakerUser, []byte{0}))
+}
+
+func TestKeystoreCreateUserChecksSuccess(t *testing.T) {
+	runner := NewKeystoreWorkFlowRunner(newMethodResultsClient(t, map[string]string{
+		"keystore.listUsers":  `{"users": []}`,
+		"keystore.createUser": `{"success": true}`,
+	}))
+	assert.NoError(t, runner.CreateUser(testStakerUser))
+
+	failingRunner := NewKeystoreWorkFlowRunner(newMethodResultsClient(t, map[string]string{
+		"keystore.listUsers":  `{"users": []}`,
+		"keystore.createUser": `{"success": false}`,
+	}))
+	assert.Error(t, failingRunner.CreateUser(testStakerUser))
+}
+
+func TestKeystoreDeleteUserVerifiesRemoval(t *testing.T) {
+	// The fake node still lists the user after reporting it deleted
+	runner := NewKeystoreWorkFlowRunner(newMethodResultsClient(t, map[string]string{
+		"keystore.deleteUser": `{"success": true}`,
+		"keystore.listUsers":  `{"users": ["staker"]}`,
+	}))
+	assert.Error(t, runner.DeleteUser(testStakerUser))
+
+	hasUser, err := runner.HasUser("staker")
+	assert.NoError(t, err)
+	assert.True(t, hasUser)
+	hasUser, err = runner.HasUser("other")
+	assert.NoError(t, err)
+	assert.False(t, hasUser)
+}
@@ -0,0 +1,384 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package helpers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strings"
+
+	"github.com/chain4travel/camino-testing/camino_client/apis"
+	"github.com/chain4travel/caminogo/utils/formatting"
+	"github.com/chain4travel/caminogo/utils/units"
+	"github.com/palantir/stacktrace"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// How large the oversized payloads are unless the fuzzer is configured otherwise
+	defaultOversizedPayloadBytes = 4 * units.MiB
+
+	// How deep the params of the deeply nested request are nested, which is beyond the depth the JSON decoder accepts
+	fuzzNestingDepth = 20000
+
+	// How many random bytes go into the random body and the random txs
+	fuzzRandomBytes = 512
+
+	// The malformed bodies that the Ethereum JSON-RPC server accepts
+	batchRequestDescription = "batch request"
+	wrongVersionDescription = "wrong JSON-RPC version"
+)
+
+// RPCFuzzEndpoint is a node API endpoint and the methods on it that an RPCFuzzer sends requests to
+type RPCFuzzEndpoint struct {
+	Path string
+
+	Methods []string
+
+	// The methods that take a serialized tx, which also get requests with invalid tx bytes
+	IssueTxMethods []string
+
+	// Whether the endpoint is served by the Ethereum JSON-RPC server, which answers batch requests as JSON-RPC 2.0 allows
+	// and doesn't check the JSON-RPC version, so neither is sent to it as a malformed body
+	IsEthereumRPC bool
+}
+
+// DefaultRPCFuzzEndpoints are the endpoints every node serves, with the methods that don't change the node's state
+// when called with valid params, besides issuing txs and the keystore methods, whose params the fuzzer never makes valid.
+// Of the admin API, only the methods that read the node's config are fuzzed, as the others start profilers, write files
+// or change aliases and log levels; of the IPC API, only the listing of published chains is, as the others open sockets.
+var DefaultRPCFuzzEndpoints = []RPCFuzzEndpoint{
+	{
+		Path:    "/ext/health",
+		Methods: []string{"health.health", "health.liveness", "health.readiness"},
+	},
+	{
+		Path:    "/ext/info",
+		Methods: []string{"info.getNodeID", "info.getNetworkID", "info.getBlockchainID", "info.isBootstrapped", "info.peers", "info.getTxFee"},
+	},
+	{
+		Path:    "/ext/keystore",
+		Methods: []string{"keystore.createUser", "keystore.importUser", "keystore.exportUser", "keystore.deleteUser"},
+	},
+	{
+		Path:           "/ext/bc/X",
+		Methods:        []string{"avm.getTxStatus", "avm.getTx", "avm.getBalance", "avm.getUTXOs", "avm.getAssetDescription", "avm.send"},
+		IssueTxMethods: []string{"avm.issueTx"},
+	},
+	{
+		Path:           "/ext/P",
+		Methods:        []string{"platform.getTxStatus", "platform.getTx", "platform.getBalance", "platform.getUTXOs", "platform.getCurrentValidators"},
+		IssueTxMethods: []string{"platform.issueTx"},
+	},
+	{
+		Path:           "/ext/bc/C/avax",
+		Methods:        []string{"avax.getAtomicTxStatus", "avax.getAtomicTx", "avax.getUTXOs"},
+		IssueTxMethods: []string{"avax.issueTx"},
+	},
+	{
+		Path:          "/ext/bc/C/rpc",
+		Methods:       []string{"eth_chainId", "eth_blockNumber", "eth_getBalance", "eth_getBlockByNumber", "eth_getTransactionReceipt", "eth_sendRawTransaction"},
+		IsEthereumRPC: true,
+	},
+	{
+		Path:    "/ext/admin",
+		Methods: []string{"admin.getChainAliases", "admin.getLoggerLevel", "admin.getConfig"},
+	},
+	{
+		Path:    "/ext/ipcs",
+		Methods: []string{"ipcs.getPublishedBlockchains"},
+	},
+}
+
+// RPCFuzzCase is a single malformed or adversarial request
+type RPCFuzzCase struct {
+	Endpoint string
+
+	Description string
+
+	Body []byte
+
+	// Whether the node must answer with an error. Requests that are merely odd, like params a method ignores, may succeed,
+	// but the node must still answer them.
+	MustFail bool
+}
+
+// RPCFuzzReport records how a node answered the cases an RPCFuzzer sent it
+type RPCFuzzReport struct {
+	NumCases int
+
+	// The number of cases the node answered with an error, either a non-2xx status or a JSON-RPC error
+	NumRejected int
+
+	// Descriptions of the cases the node answered wrongly, i.e. not at all or with success when it must have failed
+	Failures []string
+}
+
+// RPCFuzzer sends malformed JSON-RPC bodies, params of the wrong type, oversized payloads and invalid tx bytes to the
+// endpoints of a single node, checking that the node answers each with an error and stays healthy and bootstrapped.
+type RPCFuzzer struct {
+	client                *apis.Client
+	ctx                   context.Context
+	random                *rand.Rand
+	oversizedPayloadBytes int
+}
+
+// NewRPCFuzzer creates a fuzzer that draws the random parts of its cases from [random]
+func NewRPCFuzzer(client *apis.Client, random *rand.Rand) *RPCFuzzer {
+	return &RPCFuzzer{
+		client:                client,
+		ctx:                   context.Background(),
+		random:                random,
+		oversizedPayloadBytes: defaultOversizedPayloadBytes,
+	}
+}
+
+// WithOversizedPayloadBytes sets how large the oversized payloads are
+func (fuzzer *RPCFuzzer) WithOversizedPayloadBytes(oversizedPayloadBytes int) *RPCFuzzer {
+	fuzzer.oversizedPayloadBytes = oversizedPayloadBytes
+	return fuzzer
+}
+
+// CreateCases builds the cases for every method of the endpoints: malformed bodies for each endpoint, wrong param types
+// and oversized payloads for each method, and invalid tx bytes for each method that issues txs
+func (fuzzer *RPCFuzzer) CreateCases(endpoints []RPCFuzzEndpoint) ([]RPCFuzzCase, error) {
+	result := []RPCFuzzCase{}
+	for _, endpoint := range endpoints {
+		allMethods := append(append([]string{}, endpoint.Methods...), endpoint.IssueTxMethods...)
+		if len(allMethods) == 0 {
+			return nil, stacktrace.NewError("Endpoint %v has no methods to fuzz", endpoint.Path)
+		}
+		result = append(result, fuzzer.createMalformedBodyCases(endpoint, allMethods[0])...)
+		for _, method := range endpoint.Methods {
+			result = append(result, fuzzer.createWrongParamCases(endpoint.Path, method, false)...)
+		}
+		for _, method := range endpoint.IssueTxMethods {
+			// No params but a valid tx may be issued
+			result = append(result, fuzzer.createWrongParamCases(endpoint.Path, method, true)...)
+			cases, err := fuzzer.createInvalidTxCases(endpoint.Path, method)
+			if err != nil {
+				return nil, stacktrace.Propagate(err, "Failed to create the invalid tx cases for %v", method)
+			}
+			result = append(result, cases...)
+		}
+	}
+	return result, nil
+}
+
+// Run sends the cases to the node in order, and checks after each that the node is still live and bootstrapped on the X
+// and P Chains. A node that degrades fails the run right away, blaming the case that preceded it; cases that the node
+// merely answered wrongly are collected, and fail the run once every case was sent.
+func (fuzzer *RPCFuzzer) Run(cases []RPCFuzzCase) (RPCFuzzReport, error) {
+	report := RPCFuzzReport{}
+	requester := fuzzer.client.RPCRequester()
+	for _, fuzzCase := range cases {
+		report.NumCases++
+		caseName := fmt.Sprintf("%v %v", fuzzCase.Endpoint, fuzzCase.Description)
+		statusCode, responseBody, err := requester.SendRawJSONRPCRequest(fuzzCase.Endpoint, fuzzCase.Body)
+		if err != nil {
+			report.Failures = append(report.Failures, fmt.Sprintf("%v: got no answer: %v", caseName, err))
+		} else if isRejected, isJSONRPC := classifyFuzzResponse(statusCode, responseBody); isRejected {
+			report.NumRejected++
+		} else if !isJSONRPC {
+			report.Failures = append(report.Failures, fmt.Sprintf("%v: answered with status %v and a body that isn't JSON-RPC", caseName, statusCode))
+		} else if fuzzCase.MustFail {
+			report.Failures = append(report.Failures, fmt.Sprintf("%v: succeeded", caseName))
+		}
+		if err := fuzzer.VerifyNotDegraded(); err != nil {
+			return report, stacktrace.Propagate(err, "The node degraded after case %v", caseName)
+		}
+	}
+	logrus.Debugf("The node rejected %v of %v fuzz cases", report.NumRejected, report.NumCases)
+	if len(report.Failures) > 0 {
+		return report, stacktrace.NewError(
+			"The node answered %v of %v fuzz cases wrongly:\n\t%v",
+			len(report.Failures),
+			report.NumCases,
+			strings.Join(report.Failures, "\n\t"))
+	}
+	return report, nil
+}
+
+// VerifyNotDegraded checks that the node reports itself live, and bootstrapped on the X and P Chains
+func (fuzzer *RPCFuzzer) VerifyNotDegraded() error {
+	liveness, err := fuzzer.client.HealthAPI().Liveness(fuzzer.ctx)
+	if err != nil {
+		return stacktrace.Propagate(err, "Failed to get the node's liveness")
+	}
+	if !liveness.Healthy {
+		return stacktrace.NewError("The node isn't live: %v", liveness.Checks)
+	}
+	for _, chainAlias := range []string{"X", "P"} {
+		isBootstrapped, err := fuzzer.client.InfoAPI().IsBootstrapped(fuzzer.ctx, chainAlias)
+		if err != nil {
+			return stacktrace.Propagate(err, "Failed to get whether the node is bootstrapped on the %v Chain", chainAlias)
+		}
+		if !isBootstrapped {
+			return stacktrace.NewError("The node isn't bootstrapped on the %v Chain anymore", chainAlias)
+		}
+	}
+	return nil
+}
+
+// ================ Helper functions =========================
+/*
+Creates bodies that aren't valid JSON-RPC requests, which the endpoint must refuse whatever method they name
+*/
+func (fuzzer *RPCFuzzer) createMalformedBodyCases(endpoint RPCFuzzEndpoint, method string) []RPCFuzzCase {
+	service := strings.Split(method, ".")[0]
+	randomBody := make([]byte, fuzzRandomBytes)
+	fuzzer.random.Read(randomBody)
+	nestedParams := strings.Repeat("[", fuzzNestingDepth) + strings.Repeat("]", fuzzNestingDepth)
+	bodies := []struct {
+		description string
+		body        string
+	}{
+		{"empty body", ""},
+		{"random bytes", string(randomBody)},
+		{"truncated JSON", fmt.Sprintf(`{"jsonrpc": "2.0", "id": 1, "method": "%v", "params": {`, method)},
+		{"JSON that isn't an object", `"jsonrpc"`},
+		{batchRequestDescription, fmt.Sprintf(`[{"jsonrpc": "2.0", "id": 1, "method": "%v", "params": {}}]`, method)},
+		{"missing method", `{"jsonrpc": "2.0", "id": 1, "params": {}}`},
+		{"unknown method", fmt.Sprintf(`{"jsonrpc": "2.0", "id": 1, "method": "%v.noSuchMethod", "params": {}}`, service)},
+		{wrongVersionDescription, fmt.Sprintf(`{"jsonrpc": "1.0", "id": 1, "method": "%v", "params": {}}`, method)},
+		{"deeply nested params", fmt.Sprintf(`{"jsonrpc": "2.0", "id": 1, "method": "%v", "params": %v}`, method, nestedParams)},
+	}
+	result := make([]RPCFuzzCase, 0, len(bodies))
+	for _, body := range bodies {
+		if endpoint.IsEthereumRPC && (body.description == batchRequestDescription || body.description == wrongVersionDescription) {
+			continue
+		}
+		result = append(result, RPCFuzzCase{
+			Endpoint:    endpoint.Path,
+			Description: body.description,
+			Body:        []byte(body.body),
+			MustFail:    true,
+		})
+	}
+	return result
+}
+
+/*
+Creates requests to the method whose params have the wrong types or are oversized, which a method that ignores its params
+	may answer successfully unless [mustFail] is set
+*/
+func (fuzzer *RPCFuzzer) createWrongParamCases(endpoint string, method string, mustFail bool) []RPCFuzzCase {
+	oversizedString := strings.Repeat("a", fuzzer.oversizedPayloadBytes)
+	paramsList := []struct {
+		description string
+		params      interface{}
+	}{
+		{"wrongly typed params", map[string]interface{}{
+			"address":   12345,
+			"addresses": "not a list",
+			"txID":      true,
+			"assetID":   []int{1, 2, 3},
+			"chain":     1.5,
+			"limit":     "many",
+			"encoding":  7,
+			"tx":        map[string]int{"bytes": 1},
+			"username":  []string{},
+			"password":  false,
+		}},
+		{"params that are a list of scalars", []interface{}{1, "two", nil}},
+		{"null params", nil},
+		{"oversized params", map[string]interface{}{"address": oversizedString}},
+	}
+	result := make([]RPCFuzzCase, 0, len(paramsList))
+	for _, params := range paramsList {
+		result = append(result, RPCFuzzCase{
+			Endpoint:    endpoint,
+			Description: fmt.Sprintf("%v with %v", method, params.description),
+			Body:        encodeFuzzRequest(method, params.params),
+			MustFail:    mustFail,
+		})
+	}
+	return result
+}
+
+/*
+Creates requests to the tx issuing method with tx bytes that don't decode, or that decode to bytes that aren't a tx,
+	all of which the method must refuse
+*/
+func (fuzzer *RPCFuzzer) createInvalidTxCases(endpoint string, method string) ([]RPCFuzzCase, error) {
+	randomTx := make([]byte, fuzzRandomBytes)
+	fuzzer.random.Read(randomTx)
+	oversizedTx := make([]byte, fuzzer.oversizedPayloadBytes)
+	fuzzer.random.Read(oversizedTx)
+	randomHexTx, err := formatting.EncodeWithChecksum(formatting.Hex, randomTx)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "Failed to encode the random tx as hex")
+	}
+	randomCB58Tx, err := formatting.EncodeWithChecksum(formatting.CB58, randomTx)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "Failed to encode the random tx as CB58")
+	}
+	oversizedHexTx, err := formatting.EncodeWithChecksum(formatting.Hex, oversizedTx)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "Failed to encode the oversized tx as hex")
+	}
+	// Cutting off the checksum leaves hex that decodes, but whose last bytes are taken for a checksum that doesn't match
+	hexWithoutChecksum := randomHexTx[:len(randomHexTx)-2*4]
+	txs := []struct {
+		description string
+		tx          string
+		encoding    string
+	}{
+		{"empty tx", "", "hex"},
+		{"tx that isn't hex", "0xZZZZ", "hex"},
+		{"hex tx without a checksum", hexWithoutChecksum, "hex"},
+		{"random hex tx", randomHexTx, "hex"},
+		{"tx that isn't CB58", "0OIl", "cb58"},
+		{"random CB58 tx", randomCB58Tx, "cb58"},
+		{"tx in an unknown encoding", randomHexTx, "base64"},
+		{"oversized random hex tx", oversizedHexTx, "hex"},
+	}
+	result := make([]RPCFuzzCase, 0, len(txs))
+	for _, tx := range txs {
+		result = append(result, RPCFuzzCase{
+			Endpoint:    endpoint,
+			Description: fmt.Sprintf("%v with %v", method, tx.description),
+			Body:        encodeFuzzRequest(method, map[string]string{"tx": tx.tx, "encoding": tx.encoding}),
+			MustFail:    true,
+		})
+	}
+	return result, nil
+}
+
+/*
+Encodes a JSON-RPC request with the given params, which are built from maps, lists and scalars and so always marshal
+*/
+func encodeFuzzRequest(method string, params interface{}) []byte {
+	paramsBytes, _ := json.Marshal(params)
+	return []byte(fmt.Sprintf(`{"jsonrpc": "2.0", "id": 1, "method": "%v", "params": %s}`, method, paramsBytes))
+}
+
+/*
+Returns:
+	Whether the response is an error, i.e. has a non-2xx status or a JSON-RPC error, and whether it's a JSON-RPC response
+		at all
+*/
+func classifyFuzzResponse(statusCode int, responseBody []byte) (bool, bool) {
+	if statusCode < 200 || statusCode > 299 {
+		return true, true
+	}
+	response := struct {
+		Result json.RawMessage `json:"result"`
+		Error  json.RawMessage `json:"error"`
+	}{}
+	if err := json.Unmarshal(responseBody, &response); err != nil {
+		return false, false
+	}
+	hasError := len(response.Error) > 0 && string(response.Error) != "null"
+	hasResult := len(response.Result) > 0
+	return hasError, hasError || hasResult
+}
@@ -0,0 +1,154 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package helpers
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/chain4travel/camino-testing/camino_client/apis"
+	"github.com/chain4travel/camino-testing/camino_client/apis/apistest"
+	"github.com/chain4travel/camino-testing/utils/random"
+	"github.com/stretchr/testify/assert"
+)
+
+const testOversizedPayloadBytes = 1024
+
+func TestRPCFuzzCasesCoverEveryMethod(t *testing.T) {
+	fuzzer := NewRPCFuzzer(nil, random.NewRand("rpc-fuzzer-test")).WithOversizedPayloadBytes(testOversizedPayloadBytes)
+	cases, err := fuzzer.CreateCases(DefaultRPCFuzzEndpoints)
+	assert.NoError(t, err)
+
+	for _, endpoint := range DefaultRPCFuzzEndpoints {
+		for _, method := range append(append([]string{}, endpoint.Methods...), endpoint.IssueTxMethods...) {
+			numCases := 0
+			for _, fuzzCase := range cases {
+				if strings.HasPrefix(fuzzCase.Description, method+" ") {
+					assert.Equal(t, endpoint.Path, fuzzCase.Endpoint)
+					numCases++
+				}
+			}
+			assert.NotZero(t, numCases, "method %v should get fuzz cases", method)
+		}
+	}
+	for _, fuzzCase := range cases {
+		if strings.Contains(fuzzCase.Description, "issueTx with") {
+			assert.True(t, fuzzCase.MustFail, "issue case %v should have to fail", fuzzCase.Description)
+		}
+		if strings.HasSuffix(fuzzCase.Description, "oversized params") {
+			assert.True(t, len(fuzzCase.Body) > testOversizedPayloadBytes)
+		}
+		if fuzzCase.Endpoint == "/ext/bc/C/rpc" {
+			assert.NotEqual(t, batchRequestDescription, fuzzCase.Description)
+			assert.NotEqual(t, wrongVersionDescription, fuzzCase.Description)
+		}
+	}
+}
+
+func TestRPCFuzzerPassesOnRejectingNode(t *testing.T) {
+	client, _ := newFuzzedNodeClient(t, nil)
+	cases := createTestFuzzCases(t, client)
+
+	report, err := NewRPCFuzzer(client, random.NewRand("rpc-fuzzer-test")).Run(cases)
+	assert.NoError(t, err)
+	assert.Equal(t, len(cases), report.NumCases)
+	numHealthCheckCases := 0
+	for _, fuzzCase := range cases {
+		if strings.HasPrefix(fuzzCase.Description, "health.liveness ") || strings.HasPrefix(fuzzCase.Description, "info.isBootstrapped ") {
+			numHealthCheckCases++
+		}
+	}
+	// Only the requests to the methods the fake node always answers may succeed
+	assert.True(t, report.NumRejected >= len(cases)-numHealthCheckCases)
+}
+
+func TestRPCFuzzerReportsAcceptedGarbage(t *testing.T) {
+	client, _ := newFuzzedNodeClient(t, map[string]string{
+		"avm.issueTx":    `{"txID": "11111111111111111111111111111111LpoYY"}`,
+		"avm.getTx":      `{"tx": "0x"}`,
+		"info.getNodeID": `{"nodeID": "NodeID-111111111111111111116DBWJs"}`,
+	})
+	cases := createTestFuzzCases(t, client)
+
+	report, err := NewRPCFuzzer(client, random.NewRand("rpc-fuzzer-test")).Run(cases)
+	assert.Error(t, err)
+	assert.Equal(t, len(cases), report.NumCases)
+	assert.Contains(t, err.Error(), "/ext/bc/X avm.issueTx with random hex tx: succeeded")
+	// Methods other than issuing txs may ignore params they don't understand
+	assert.NotContains(t, err.Error(), "avm.getTx with")
+	assert.NotContains(t, err.Error(), "info.getNodeID with")
+}
+
+func TestRPCFuzzerStopsOnDegradation(t *testing.T) {
+	client, state := newFuzzedNodeClient(t, nil)
+	cases := createTestFuzzCases(t, client)
+	state.setXChainBootstrapped(false)
+
+	report, err := NewRPCFuzzer(client, random.NewRand("rpc-fuzzer-test")).Run(cases)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "isn't bootstrapped on the X Chain anymore")
+	assert.Contains(t, err.Error(), "after case "+cases[0].Endpoint+" "+cases[0].Description)
+	assert.Equal(t, 1, report.NumCases)
+}
+
+func createTestFuzzCases(t *testing.T, client *apis.Client) []RPCFuzzCase {
+	fuzzer := NewRPCFuzzer(client, random.NewRand("rpc-fuzzer-test")).WithOversizedPayloadBytes(testOversizedPayloadBytes)
+	cases, err := fuzzer.CreateCases(DefaultRPCFuzzEndpoints)
+	assert.NoError(t, err)
+	return cases
+}
+
+/*
+Creates a client for a node that's live and bootstrapped, and answers every request with a JSON-RPC error, except the
+	health and bootstrap checks and the methods with the given results, which it answers successfully whatever their params
+
+Returns:
+	The client, and the node's state, which the test may degrade
+*/
+func newFuzzedNodeClient(t *testing.T, successfulMethods map[string]string) (*apis.Client, *fuzzedNodeState) {
+	state := &fuzzedNodeState{xChainBootstrapped: true}
+	node := apistest.NewFakeNode(t).
+		On("health.liveness", apistest.Result(`{"checks": {}, "healthy": true}`)).
+		OnParams("info.isBootstrapped", func(params json.RawMessage) apistest.Response {
+			bootstrappedParams := struct {
+				Chain string `json:"chain"`
+			}{}
+			if err := json.Unmarshal(params, &bootstrappedParams); err != nil {
+				return apistest.Error("invalid params")
+			}
+			isBootstrapped := bootstrappedParams.Chain != "X" || state.isXChainBootstrapped()
+			return apistest.Resultf(`{"isBootstrapped": %v}`, isBootstrapped)
+		}).
+		OnAnyOther(apistest.Error("invalid params"))
+	for method, result := range successfulMethods {
+		node.On(method, apistest.Result(result))
+	}
+	return node.GetClient(), state
+}
+
+// fuzzedNodeState is the part of a fake node's state that a test may change while the fuzzer runs
+type fuzzedNodeState struct {
+	mutex              sync.Mutex
+	xChainBootstrapped bool
+}
+
+func (state *fuzzedNodeState) setXChainBootstrapped(xChainBootstrapped bool) {
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+	state.xChainBootstrapped = xChainBootstrapped
+}
+
+func (state *fuzzedNodeState) isXChainBootstrapped() bool {
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+	return state.xChainBootstrapped
+}
@@ -11,6 +11,8 @@ package helpers
 
 import (
 	"context"
+	"fmt"
+	"math/rand"
 	"time"
 
 	caminoNetwork "github.com/chain4travel/camino-testing/camino/networks"
@@ -48,6 +50,13 @@ type RPCWorkFlowRunner struct {
 	// This timeout represents the time the RPCWorkFlowRunner will wait for some state change to be accepted
 	// and implemented by the underlying client.
 	networkAcceptanceTimeout time.Duration
+
+	// Where the transfers, stakes and exports/imports of the workflows are recorded, if anywhere
+	ledger *BalanceLedger
+
+	// How far ahead of the testsuite's clock the clock of the runner's node runs, which the node checks staking start
+	// times against
+	clockOffset time.Duration
 }
 
 // NewRPCWorkFlowRunner ...
@@ -63,6 +72,47 @@ func NewRPCWorkFlowRunner(
 	}
 }
 
+// CreateRandomUser creates keystore credentials drawn from the given random, so that a test's users don't collide with
+// those of the other tests on the same node while staying reproducible from the suite's seed
+func CreateRandomUser(userRandom *rand.Rand) api.UserPass {
+	return api.UserPass{
+		Username: fmt.Sprintf("rand:%d", userRandom.Int()),
+		Password: fmt.Sprintf("rand:%d", userRandom.Int()),
+	}
+}
+
+// WithLedger makes the runner record the transfers, stakes and exports/imports of its workflows, and the txs they issue,
+// in the given ledger, and track the addresses it creates
+// The funds the runner's user spends are attributed to the user's only address on the chain they're spent on, so workflows
+// that spend funds fail if the user has several addresses on that chain. Raw txs issued with IssueTxList aren't recorded.
+func (runner *RPCWorkFlowRunner) WithLedger(ledger *BalanceLedger) *RPCWorkFlowRunner {
+	runner.ledger = ledger
+	return runner
+}
+
+// WithClockOffset tells the runner that the clock of its node runs ahead of the testsuite's by the given amount (negative
+// if it lags behind), so that the staking start times it picks are far enough in the future for the node to accept them
+func (runner *RPCWorkFlowRunner) WithClockOffset(clockOffset time.Duration) *RPCWorkFlowRunner {
+	runner.clockOffset = clockOffset
+	return runner
+}
+
+// VerifyLedgerBalances checks the balances of all the addresses tracked by the runner's ledger
+func (runner RPCWorkFlowRunner) VerifyLedgerBalances() error {
+	if runner.ledger == nil {
+		return stacktrace.NewError("Can't verify the ledger balances of a runner without a ledger")
+	}
+	return runner.ledger.Verify(runner.client)
+}
+
+// VerifyLedgerFees checks the fees burned by all the txs recorded in the runner's ledger
+func (runner RPCWorkFlowRunner) VerifyLedgerFees() error {
+	if runner.ledger == nil {
+		return stacktrace.NewError("Can't verify the ledger fees of a runner without a ledger")
+	}
+	return runner.ledger.VerifyBurnedFees(runner.client)
+}
+
 // User returns the user credentials for this worker
 func (runner RPCWorkFlowRunner) User() api.UserPass {
 	return runner.userPass
@@ -71,8 +121,7 @@ func (runner RPCWorkFlowRunner) User() api.UserPass {
 // ImportGenesisFunds imports the genesis private key to this user's keystore
 func (runner RPCWorkFlowRunner) ImportGenesisFunds() (string, error) {
 	client := runner.client
-	keystore := client.KeystoreAPI()
-	if _, err := keystore.CreateUser(runner.ctx, runner.userPass); err != nil {
+	if err := NewKeystoreWorkFlowRunner(client).CreateUser(runner.userPass); err != nil {
 		return "", err
 	}
 
@@ -84,6 +133,14 @@ func (runner RPCWorkFlowRunner) ImportGenesisFunds() (string, error) {
 		return "", stacktrace.Propagate(err, "Failed to take control of genesis account.")
 	}
 	logrus.Debugf("Genesis Address: %s.", genesisAccountAddress)
+	if runner.ledger != nil {
+		// The genesis funds aren't known up front, so the address is tracked from its current balance
+		balance, err := getBalance(runner.ctx, client, LedgerXChain, genesisAccountAddress)
+		if err != nil {
+			return "", stacktrace.Propagate(err, "Failed to get the balance of the genesis account to track it.")
+		}
+		runner.ledger.Track(LedgerXChain, genesisAccountAddress, balance)
+	}
 	return genesisAccountAddress, nil
 }
 
@@ -104,6 +161,7 @@ func (runner RPCWorkFlowRunner) ImportGenesisFundsAndStartValidating(
 	if err != nil {
 		return "", stacktrace.Propagate(err, "Failed to create new address on PChain")
 	}
+	runner.trackNewAddress(LedgerPChain, pChainAddress)
 	err = runner.TransferAvaXChainToPChain(pChainAddress, seedAmount)
 	if err != nil {
 		return "", stacktrace.Propagate(err, "Could not transfer AVAX from XChain to PChain account information")
@@ -124,7 +182,7 @@ func (runner RPCWorkFlowRunner) AddDelegatorToPrimaryNetwork(
 	stakeAmount uint64,
 ) error {
 	client := runner.client
-	delegatorStartTime := time.Now().Add(DefaultDelegationDelay)
+	delegatorStartTime := time.Now().Add(runner.clockOffset).Add(DefaultDelegationDelay)
 	startTime := uint64(delegatorStartTime.Unix())
 	endTime := uint64(delegatorStartTime.Add(DefaultDelegationPeriod).Unix())
 	addDelegatorTxID, err := client.PChainAPI().AddDelegator(
@@ -144,6 +202,9 @@ func (runner RPCWorkFlowRunner) AddDelegatorToPrimaryNetwork(
 	if err := runner.waitForPChainTransactionAcceptance(addDelegatorTxID); err != nil {
 		return stacktrace.Propagate(err, "Failed to accept AddDelegator tx: %s", addDelegatorTxID)
 	}
+	if err := runner.recordStake(LedgerAddDelegatorTx, addDelegatorTxID, stakeAmount); err != nil {
+		return stacktrace.Propagate(err, "Failed to record the stake of delegator %s", pChainAddress)
+	}
 
 	// Sleep until delegator starts validating
 	time.Sleep(time.Until(delegatorStartTime) + stakingPeriodSynchronyDelay)
@@ -157,9 +218,27 @@ func (runner RPCWorkFlowRunner) AddValidatorToPrimaryNetwork(
 	pchainAddress string,
 	stakeAmount uint64,
 ) error {
+	// The node rejects start times that aren't far enough in the future by its own clock
+	stakingStartTime := time.Now().Add(runner.clockOffset).Add(DefaultStakingDelay)
+	if _, err := runner.AddValidatorToPrimaryNetworkWithStartTime(nodeID, pchainAddress, stakeAmount, stakingStartTime); err != nil {
+		return stacktrace.Propagate(err, "Failed to stake validator %s", nodeID)
+	}
+
+	time.Sleep(time.Until(stakingStartTime) + stakingPeriodSynchronyDelay)
+
+	return nil
+}
+
+// AddValidatorToPrimaryNetworkWithStartTime adds [nodeID] as a validator from [stakingStartTime] and blocks until the
+// transaction is confirmed, but not until the validation period begins
+func (runner RPCWorkFlowRunner) AddValidatorToPrimaryNetworkWithStartTime(
+	nodeID string,
+	pchainAddress string,
+	stakeAmount uint64,
+	stakingStartTime time.Time,
+) (ids.ID, error) {
 	// Replace with simple call to AddValidator
 	client := runner.client
-	stakingStartTime := time.Now().Add(DefaultStakingDelay)
 	startTime := uint64(stakingStartTime.Unix())
 	endTime := uint64(stakingStartTime.Add(DefaultStakingPeriod).Unix())
 	addStakerTxID, err := client.PChainAPI().AddValidator(
@@ -175,16 +254,16 @@ func (runner RPCWorkFlowRunner) AddValidatorToPrimaryNetwork(
 		DefaultDelegationFeeRate,
 	)
 	if err != nil {
-		return stacktrace.Propagate(err, "Failed to add validator to primrary network %s", nodeID)
+		return ids.Empty, stacktrace.Propagate(err, "Failed to add validator to primrary network %s", nodeID)
 	}
 
 	if err := runner.waitForPChainTransactionAcceptance(addStakerTxID); err != nil {
-		return stacktrace.Propagate(err, "Failed to confirm AddValidator Tx: %s", addStakerTxID)
+		return ids.Empty, stacktrace.Propagate(err, "Failed to confirm AddValidator Tx: %s", addStakerTxID)
 	}
-
-	time.Sleep(time.Until(stakingStartTime) + stakingPeriodSynchronyDelay)
-
-	return nil
+	if err := runner.recordStake(LedgerAddValidatorTx, addStakerTxID, stakeAmount); err != nil {
+		return ids.Empty, stacktrace.Propagate(err, "Failed to record the stake of validator %s", nodeID)
+	}
+	return addStakerTxID, nil
 }
 
 // FundXChainAddresses sends [amount] AVAX to each address in [addresses] and returns the created txIDs
@@ -207,14 +286,18 @@ func (runner RPCWorkFlowRunner) FundXChainAddresses(addresses []string, amount u
 		if err := runner.waitForXchainTransactionAcceptance(txID); err != nil {
 			return err
 		}
+		if err := runner.recordTransfer(txID, address, amount); err != nil {
+			return stacktrace.Propagate(err, "Failed to record the funding of address %s", address)
+		}
 	}
 
 	return nil
 }
 
 // SendAVAX attempts to send [amount] AVAX to address [to] using [runner]'s userPass
+// The transfer is recorded in the ledger as soon as it's issued, so the caller is expected to wait for its acceptance
 func (runner RPCWorkFlowRunner) SendAVAX(to string, amount uint64) (ids.ID, error) {
-	return runner.client.XChainAPI().Send(
+	txID, err := runner.client.XChainAPI().Send(
 		runner.ctx,
 		runner.userPass,
 		nil, // from addrs
@@ -224,14 +307,20 @@ func (runner RPCWorkFlowRunner) SendAVAX(to string, amount uint64) (ids.ID, erro
 		to,
 		"",
 	)
+	if err != nil {
+		return ids.ID{}, err
+	}
+	if err := runner.recordTransfer(txID, to, amount); err != nil {
+		return ids.ID{}, stacktrace.Propagate(err, "Failed to record the transfer to %s", to)
+	}
+	return txID, nil
 }
 
 // CreateDefaultAddresses creates the keystore user for this workflow runner and
 // creates an X and P Chain address for that keystore user
 func (runner RPCWorkFlowRunner) CreateDefaultAddresses() (string, string, error) {
 	client := runner.client
-	keystore := client.KeystoreAPI()
-	if _, err := keystore.CreateUser(runner.ctx, runner.userPass); err != nil {
+	if err := NewKeystoreWorkFlowRunner(client).CreateUser(runner.userPass); err != nil {
 		return "", "", err
 	}
 
@@ -239,9 +328,14 @@ func (runner RPCWorkFlowRunner) CreateDefaultAddresses() (string, string, error)
 	if err != nil {
 		return "", "", err
 	}
+	runner.trackNewAddress(LedgerXChain, xAddress)
 
 	pAddress, err := client.PChainAPI().CreateAddress(runner.ctx, runner.userPass)
-	return xAddress, pAddress, err
+	if err != nil {
+		return "", "", err
+	}
+	runner.trackNewAddress(LedgerPChain, pAddress)
+	return xAddress, pAddress, nil
 }
 
 // SendAVAXBackAndForth sends [amount] AVAX to address [to] using funds from [runner.userPass], [numTxs] times
@@ -261,9 +355,15 @@ func (runner RPCWorkFlowRunner) SendAVAXBackAndForth(to string, amount, txFee, n
 		)
 		if err != nil {
 			errs <- stacktrace.Propagate(err, "Failed to send transaction.")
+			return
 		}
 		if err := runner.waitForXchainTransactionAcceptance(txID); err != nil {
 			errs <- stacktrace.Propagate(err, "Failed to await transaction acceptance.")
+			return
+		}
+		if err := runner.recordTransfer(txID, to, amount-txFee*uint64(i)); err != nil {
+			errs <- stacktrace.Propagate(err, "Failed to record transaction.")
+			return
 		}
 		logrus.Infof("Confirmed Tx: %s", txID)
 	}
@@ -273,8 +373,16 @@ func (runner RPCWorkFlowRunner) SendAVAXBackAndForth(to string, amount, txFee, n
 // TransferAvaXChainToPChain exports AVAX from the X Chain and then imports it to the P Chain
 // and blocks until both transactions have been accepted
 func (runner RPCWorkFlowRunner) TransferAvaXChainToPChain(pChainAddress string, amount uint64) error {
-	client := runner.client
-	txID, err := client.XChainAPI().Export(
+	if err := runner.ExportAvaXChainToPChain(pChainAddress, amount); err != nil {
+		return err
+	}
+	return runner.ImportAvaToPChain(pChainAddress)
+}
+
+// ExportAvaXChainToPChain exports AVAX from the X Chain to [pChainAddress] and blocks until the export has been
+// accepted, leaving the AVAX in atomic memory until it's imported to the P Chain
+func (runner RPCWorkFlowRunner) ExportAvaXChainToPChain(pChainAddress string, amount uint64) error {
+	txID, err := runner.client.XChainAPI().Export(
 		runner.ctx,
 		runner.userPass,
 		nil,
@@ -290,8 +398,16 @@ func (runner RPCWorkFlowRunner) TransferAvaXChainToPChain(pChainAddress string,
 	if err != nil {
 		return stacktrace.Propagate(err, "")
 	}
+	if err := runner.recordExport(LedgerXChain, txID, pChainAddress, amount); err != nil {
+		return stacktrace.Propagate(err, "Failed to record the export to pchainAddress %s", pChainAddress)
+	}
+	return nil
+}
 
-	importTxID, err := client.PChainAPI().ImportAVAX(
+// ImportAvaToPChain imports all the AVAX exported from the X Chain to [pChainAddress] in one tx, and blocks until the
+// import has been accepted
+func (runner RPCWorkFlowRunner) ImportAvaToPChain(pChainAddress string) error {
+	importTxID, err := runner.client.PChainAPI().ImportAVAX(
 		runner.ctx,
 		runner.userPass,
 		nil,
@@ -305,7 +421,7 @@ func (runner RPCWorkFlowRunner) TransferAvaXChainToPChain(pChainAddress string,
 	if err := runner.waitForPChainTransactionAcceptance(importTxID); err != nil {
 		return stacktrace.Propagate(err, "Failed to Accept ImportTx: %s", importTxID)
 	}
-
+	runner.recordImport(LedgerPChain, importTxID, pChainAddress)
 	return nil
 }
 
@@ -314,9 +430,16 @@ func (runner RPCWorkFlowRunner) TransferAvaXChainToPChain(pChainAddress string,
 func (runner RPCWorkFlowRunner) TransferAvaPChainToXChain(
 	xChainAddress string,
 	amount uint64) error {
-	client := runner.client
+	if err := runner.ExportAvaPChainToXChain(xChainAddress, amount); err != nil {
+		return err
+	}
+	return runner.ImportAvaToXChain(xChainAddress)
+}
 
-	exportTxID, err := client.PChainAPI().ExportAVAX(
+// ExportAvaPChainToXChain exports AVAX from the P Chain to [xChainAddress] and blocks until the export has been
+// accepted, leaving the AVAX in atomic memory until it's imported to the X Chain
+func (runner RPCWorkFlowRunner) ExportAvaPChainToXChain(xChainAddress string, amount uint64) error {
+	exportTxID, err := runner.client.PChainAPI().ExportAVAX(
 		runner.ctx,
 		runner.userPass,
 		nil,
@@ -330,17 +453,29 @@ func (runner RPCWorkFlowRunner) TransferAvaPChainToXChain(
 	if err := runner.waitForPChainTransactionAcceptance(exportTxID); err != nil {
 		return stacktrace.Propagate(err, "Failed to accept ExportTx: %s", exportTxID)
 	}
+	if err := runner.recordExport(LedgerPChain, exportTxID, xChainAddress, amount); err != nil {
+		return stacktrace.Propagate(err, "Failed to record the export to xChainAddress %s", xChainAddress)
+	}
+	return nil
+}
 
-	txID, err := client.XChainAPI().Import(
+// ImportAvaToXChain imports all the AVAX exported from the P Chain to [xChainAddress] in one tx, and blocks until the
+// import has been accepted
+func (runner RPCWorkFlowRunner) ImportAvaToXChain(xChainAddress string) error {
+	txID, err := runner.client.XChainAPI().Import(
 		runner.ctx,
 		runner.userPass,
 		xChainAddress,
 		constants.PlatformChainID.String(),
 	)
+	if err != nil {
+		return stacktrace.Propagate(err, "Failed to import AVAX to xChainAddress %s", xChainAddress)
+	}
 	err = runner.waitForXchainTransactionAcceptance(txID)
 	if err != nil {
 		return stacktrace.Propagate(err, "Failed to wait for acceptance of transaction on XChain.")
 	}
+	runner.recordImport(LedgerXChain, txID, xChainAddress)
 	return nil
 }
 
@@ -460,3 +595,81 @@ func (runner RPCWorkFlowRunner) VerifyXChainAVABalance(address string, expectedB
 
 	return nil
 }
+
+// ================ Helper functions =========================
+/*
+Gets the address that the runner's user spends funds from on the chain, which is only known if the user has a single
+	address there
+*/
+func (runner RPCWorkFlowRunner) getSpendingAddress(chain LedgerChain) (string, error) {
+	var addresses []string
+	var err error
+	if chain == LedgerXChain {
+		addresses, err = runner.client.XChainAPI().ListAddresses(runner.ctx, runner.userPass)
+	} else {
+		addresses, err = runner.client.PChainAPI().ListAddresses(runner.ctx, runner.userPass)
+	}
+	if err != nil {
+		return "", stacktrace.Propagate(err, "Failed to list the %v Chain addresses of user %s", chain, runner.userPass.Username)
+	}
+	if len(addresses) != 1 {
+		return "", stacktrace.NewError(
+			"User %s has %v %v Chain addresses, so it can't be known which of them funds were spent from",
+			runner.userPass.Username,
+			len(addresses),
+			chain)
+	}
+	return addresses[0], nil
+}
+
+func (runner RPCWorkFlowRunner) trackNewAddress(chain LedgerChain, address string) {
+	if runner.ledger != nil {
+		runner.ledger.Track(chain, address, 0)
+	}
+}
+
+func (runner RPCWorkFlowRunner) recordTransfer(txID ids.ID, to string, amount uint64) error {
+	if runner.ledger == nil {
+		return nil
+	}
+	from, err := runner.getSpendingAddress(LedgerXChain)
+	if err != nil {
+		return stacktrace.Propagate(err, "Failed to get the address the transfer was sent from")
+	}
+	runner.ledger.RecordTransfer(from, to, amount)
+	runner.ledger.RecordTx(LedgerXChain, LedgerTransferTx, txID)
+	return nil
+}
+
+func (runner RPCWorkFlowRunner) recordStake(txType LedgerTxType, txID ids.ID, amount uint64) error {
+	if runner.ledger == nil {
+		return nil
+	}
+	staker, err := runner.getSpendingAddress(LedgerPChain)
+	if err != nil {
+		return stacktrace.Propagate(err, "Failed to get the address the stake was locked from")
+	}
+	runner.ledger.RecordStake(staker, amount)
+	runner.ledger.RecordTx(LedgerPChain, txType, txID)
+	return nil
+}
+
+func (runner RPCWorkFlowRunner) recordExport(sourceChain LedgerChain, txID ids.ID, to string, amount uint64) error {
+	if runner.ledger == nil {
+		return nil
+	}
+	from, err := runner.getSpendingAddress(sourceChain)
+	if err != nil {
+		return stacktrace.Propagate(err, "Failed to get the address the export was sent from")
+	}
+	runner.ledger.RecordExport(sourceChain, from, to, amount)
+	runner.ledger.RecordTx(sourceChain, LedgerExportTx, txID)
+	return nil
+}
+
+func (runner RPCWorkFlowRunner) recordImport(destinationChain LedgerChain, txID ids.ID, to string) {
+	if runner.ledger != nil {
+		runner.ledger.RecordImport(destinationChain, to)
+		runner.ledger.RecordTx(destinationChain, LedgerImportTx, txID)
+	}
+}
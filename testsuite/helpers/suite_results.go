@@ -0,0 +1,111 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package helpers
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/palantir/stacktrace"
+)
+
+const (
+	// Held while a result is added, as the tests recording into a results directory run in parallel
+	resultsLockFilename = ".lock"
+
+	resultFileSuffix = ".json"
+	resultsDirPerms  = 0755
+	resultFilePerms  = 0644
+)
+
+// RecordSuiteResult writes a test's result, as JSON, to the given results directory at the root of the suite execution
+// volume, and rewrites the table there from every result recorded so far, so that it includes it; returns the filepath
+// of the table
+// Args:
+// 	suiteExecutionDirpath: The root of the suite execution volume
+// 	resultsDirname: The directory that the results of all the tests like this one are recorded in
+// 	resultName: The name of the result's file, unique among the tests recording into the directory
+// 	result: The result, which must be serializable to JSON
+// 	tableFilename: The file, in the results directory, that the table is written to
+// 	formatTable: Formats the table from the JSON of every result recorded, ordered by result name
+func RecordSuiteResult(
+	suiteExecutionDirpath string,
+	resultsDirname string,
+	resultName string,
+	result interface{},
+	tableFilename string,
+	formatTable func(resultsJSON [][]byte) (string, error)) (string, error) {
+	resultsDirpath := filepath.Join(suiteExecutionDirpath, resultsDirname)
+	if err := os.MkdirAll(resultsDirpath, resultsDirPerms); err != nil {
+		return "", stacktrace.Propagate(err, "An error occurred creating the results directory at %v", resultsDirpath)
+	}
+	lockFp, err := os.OpenFile(filepath.Join(resultsDirpath, resultsLockFilename), os.O_CREATE|os.O_RDWR, resultFilePerms)
+	if err != nil {
+		return "", stacktrace.Propagate(err, "An error occurred opening the lock of the results directory")
+	}
+	defer lockFp.Close()
+	// Every test recording into the directory mounts the same volume, so another one may be adding its result at the same time
+	if err := syscall.Flock(int(lockFp.Fd()), syscall.LOCK_EX); err != nil {
+		return "", stacktrace.Propagate(err, "An error occurred locking the results directory")
+	}
+	defer syscall.Flock(int(lockFp.Fd()), syscall.LOCK_UN)
+
+	resultBytes, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return "", stacktrace.Propagate(err, "An error occurred serializing the result")
+	}
+	resultFilepath := filepath.Join(resultsDirpath, resultName+resultFileSuffix)
+	if err := os.WriteFile(resultFilepath, resultBytes, resultFilePerms); err != nil {
+		return "", stacktrace.Propagate(err, "An error occurred writing the result to %v", resultFilepath)
+	}
+
+	resultsJSON, err := readSuiteResults(resultsDirpath)
+	if err != nil {
+		return "", stacktrace.Propagate(err, "An error occurred reading the results recorded so far")
+	}
+	table, err := formatTable(resultsJSON)
+	if err != nil {
+		return "", stacktrace.Propagate(err, "An error occurred formatting the table of the results recorded so far")
+	}
+	tableFilepath := filepath.Join(resultsDirpath, tableFilename)
+	if err := os.WriteFile(tableFilepath, []byte(table), resultFilePerms); err != nil {
+		return "", stacktrace.Propagate(err, "An error occurred writing the table to %v", tableFilepath)
+	}
+	return tableFilepath, nil
+}
+
+// ================ Helper functions =========================
+func readSuiteResults(resultsDirpath string) ([][]byte, error) {
+	entries, err := os.ReadDir(resultsDirpath)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred listing the results directory")
+	}
+	resultFilenames := []string{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), resultFileSuffix) {
+			continue
+		}
+		resultFilenames = append(resultFilenames, entry.Name())
+	}
+	sort.Strings(resultFilenames)
+	resultsJSON := make([][]byte, 0, len(resultFilenames))
+	for _, resultFilename := range resultFilenames {
+		resultBytes, err := os.ReadFile(filepath.Join(resultsDirpath, resultFilename))
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "An error occurred reading result %v", resultFilename)
+		}
+		resultsJSON = append(resultsJSON, resultBytes)
+	}
+	return resultsJSON, nil
+}
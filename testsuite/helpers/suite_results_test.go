@@ -0,0 +1,40 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package helpers
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordSuiteResultRewritesTableFromEveryResult(t *testing.T) {
+	suiteExecutionDirpath := t.TempDir()
+	formatTable := func(resultsJSON [][]byte) (string, error) {
+		rows := make([]string, 0, len(resultsJSON))
+		for _, resultJSON := range resultsJSON {
+			rows = append(rows, string(resultJSON))
+		}
+		return strings.Join(rows, "\n"), nil
+	}
+
+	_, err := RecordSuiteResult(suiteExecutionDirpath, "results", "b", "second", "table.md", formatTable)
+	assert.NoError(t, err)
+	tableFilepath, err := RecordSuiteResult(suiteExecutionDirpath, "results", "a", "first", "table.md", formatTable)
+	assert.NoError(t, err)
+
+	assert.Equal(t, filepath.Join(suiteExecutionDirpath, "results", "table.md"), tableFilepath)
+	table, err := os.ReadFile(tableFilepath)
+	assert.NoError(t, err)
+	assert.Equal(t, "\"first\"\n\"second\"", string(table))
+}
@@ -0,0 +1,40 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package helpers
+
+import (
+	"context"
+	"sort"
+
+	"github.com/chain4travel/camino-testing/camino_client/apis"
+	"github.com/chain4travel/camino-testing/testsuite/verifier"
+	"github.com/chain4travel/caminogo/ids"
+	"github.com/chain4travel/caminogo/utils/constants"
+	"github.com/palantir/stacktrace"
+)
+
+// GetCurrentValidatorIDs returns the sorted node IDs of the current validators of the primary network, as the given node
+// sees them
+func GetCurrentValidatorIDs(client *apis.Client) ([]string, error) {
+	currentValidators, err := client.PChainAPI().GetCurrentValidators(context.Background(), constants.PrimaryNetworkID, []ids.ShortID{})
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "Could not get the current validators")
+	}
+	result := make([]string, 0, len(currentValidators))
+	for _, iValidator := range currentValidators {
+		validator, err := verifier.ToPrimaryValidator(iValidator)
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "An error occurred reading a current validator")
+		}
+		result = append(result, validator.NodeID)
+	}
+	sort.Strings(result)
+	return result, nil
+}
@@ -0,0 +1,40 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package helpers
+
+import (
+	"time"
+)
+
+const (
+	// How long nodes get to agree with each other, since a node that just bootstrapped, restarted or runs another version
+	// may take a moment to catch up with the others
+	AgreementTimeout = 30 * time.Second
+
+	waitPollInterval = 2 * time.Second
+)
+
+// WaitFor retries the given check until it passes or the timeout runs out, returning the check's last error
+func WaitFor(timeout time.Duration, check func() error) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		err := check()
+		if err == nil || time.Now().After(deadline) {
+			return err
+		}
+		time.Sleep(waitPollInterval)
+	}
+}
+
+// WaitForAgreement retries the given check of whether nodes agree with each other until it passes or AgreementTimeout
+// runs out, returning the check's last error
+func WaitForAgreement(check func() error) error {
+	return WaitFor(AgreementTimeout, check)
+}
@@ -0,0 +1,40 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package helpers
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWaitForReturnsOncePassing(t *testing.T) {
+	numChecks := 0
+	err := WaitFor(AgreementTimeout, func() error {
+		numChecks++
+		if numChecks < 2 {
+			return errors.New("not yet")
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, numChecks)
+}
+
+func TestWaitForReturnsLastError(t *testing.T) {
+	numChecks := 0
+	err := WaitFor(0, func() error {
+		numChecks++
+		return errors.New("never")
+	})
+	assert.EqualError(t, err, "never")
+	assert.Equal(t, 1, numChecks)
+}
@@ -0,0 +1,57 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package kurtosis
+
+import (
+	"fmt"
+
+	"github.com/kurtosis-tech/kurtosis-go/lib/networks"
+	"github.com/kurtosis-tech/kurtosis-go/lib/testsuite"
+	"github.com/sirupsen/logrus"
+
+	caminoNetwork "github.com/chain4travel/camino-testing/camino/networks"
+	"github.com/chain4travel/camino-testing/utils/random"
+)
+
+// artifactCollectingTest wraps a test so that, when the test fails, the logs, config and start command of every node in
+// its network are archived on the suite execution volume before the failure is reported to Kurtosis
+// NOTE: Tests that hit their execution timeout are abandoned by Kurtosis rather than failed, so they don't get artifacts
+type artifactCollectingTest struct {
+	testsuite.Test
+
+	testName string
+}
+
+func newArtifactCollectingTest(testName string, test testsuite.Test) artifactCollectingTest {
+	return artifactCollectingTest{
+		Test:     test,
+		testName: testName,
+	}
+}
+
+// Run implements the Kurtosis Test interface
+func (test artifactCollectingTest) Run(network networks.Network, context testsuite.TestContext) {
+	defer func() {
+		// Kurtosis tests report failure by panicking, so a panic is our failure hook; we re-panic afterwards so the
+		//  failure still reaches Kurtosis
+		if recoverResult := recover(); recoverResult != nil {
+			logrus.Errorf("Test '%v' failed with seed %v; pass --seed=%v to reproduce it", test.testName, random.GetSeed(), random.GetSeed())
+			if castedNetwork, ok := network.(caminoNetwork.TestCaminoNetwork); ok {
+				logrus.Infof("Test '%v' failed; collecting node artifacts...", test.testName)
+				if err := castedNetwork.CollectFailureArtifacts(test.testName); err != nil {
+					logrus.Errorf("An error occurred collecting node artifacts for failed test '%v':", test.testName)
+					fmt.Fprintln(logrus.StandardLogger().Out, err)
+				}
+			}
+			panic(recoverResult)
+		}
+	}()
+	test.Test.Run(network, context)
+}
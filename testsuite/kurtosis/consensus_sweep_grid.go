@@ -0,0 +1,48 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package kurtosis
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/chain4travel/camino-testing/testsuite/tests/sweep"
+	"github.com/palantir/stacktrace"
+)
+
+const (
+	// Selects sweep.DefaultConsensusSweepGrid
+	defaultConsensusSweepGridName = "default"
+)
+
+// ParseConsensusSweepGrid parses the grid of consensus params to sweep, which is either "default" for
+// sweep.DefaultConsensusSweepGrid or a JSON object of the grid's fields; e.g. {"NumNodes": [5], "SnowSampleSizes": [4],
+// "SnowQuorumSizes": [3, 4], "BetaVirtuous": [15], "BetaRogue": [20]}. An empty string means no sweep, returning nil.
+func ParseConsensusSweepGrid(gridStr string) (*sweep.ConsensusSweepGrid, error) {
+	trimmedGridStr := strings.TrimSpace(gridStr)
+	if trimmedGridStr == "" {
+		return nil, nil
+	}
+	if trimmedGridStr == defaultConsensusSweepGridName {
+		grid := sweep.DefaultConsensusSweepGrid
+		return &grid, nil
+	}
+	grid := &sweep.ConsensusSweepGrid{}
+	decoder := json.NewDecoder(bytes.NewBufferString(trimmedGridStr))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(grid); err != nil {
+		return nil, stacktrace.Propagate(err, "Could not parse consensus sweep grid JSON; it should be '%v' or an object of param -> list of values", defaultConsensusSweepGridName)
+	}
+	if len(grid.GetParams()) == 0 {
+		return nil, stacktrace.NewError("No combination of the values in consensus sweep grid %v can start a network", trimmedGridStr)
+	}
+	return grid, nil
+}
@@ -0,0 +1,61 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package kurtosis
+
+import (
+	"testing"
+
+	"github.com/chain4travel/camino-testing/testsuite/tests/sweep"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseConsensusSweepGrid(t *testing.T) {
+	grid, err := ParseConsensusSweepGrid("")
+	assert.NoError(t, err)
+	assert.Nil(t, grid)
+
+	grid, err = ParseConsensusSweepGrid("default")
+	assert.NoError(t, err)
+	assert.Equal(t, sweep.DefaultConsensusSweepGrid, *grid)
+
+	// Only the combinations a network can be started with are swept
+	grid, err = ParseConsensusSweepGrid(`{"NumNodes": [5], "SnowSampleSizes": [4], "SnowQuorumSizes": [2, 3, 4], "BetaVirtuous": [15], "BetaRogue": [10, 20]}`)
+	assert.NoError(t, err)
+	assert.Equal(t, []sweep.ConsensusParams{
+		{NumNodes: 5, SnowSampleSize: 4, SnowQuorumSize: 3, BetaVirtuous: 15, BetaRogue: 20},
+		{NumNodes: 5, SnowSampleSize: 4, SnowQuorumSize: 4, BetaVirtuous: 15, BetaRogue: 20},
+	}, grid.GetParams())
+
+	_, err = ParseConsensusSweepGrid(`{"NumNodes": [5], "SnowSampleSizes": [4], "SnowQuorumSizes": [2], "BetaVirtuous": [15], "BetaRogue": [20]}`)
+	assert.Error(t, err)
+	_, err = ParseConsensusSweepGrid(`{"NumNodes": [5], "SampleSizes": [4]}`)
+	assert.Error(t, err)
+}
+
+func TestConsensusSweepTestsRegistered(t *testing.T) {
+	suite := CaminoTestSuite{NormalImageName: "normal-image"}
+	for testName := range suite.getAllTests() {
+		assert.NotContains(t, testName, consensusSweepTestNamePrefix, "No sweep should be run unless a grid is given")
+	}
+
+	suite.ConsensusSweepGrid = &sweep.ConsensusSweepGrid{
+		NumNodes:        []int{5, 8},
+		SnowSampleSizes: []int{2},
+		SnowQuorumSizes: []int{2},
+		BetaVirtuous:    []int{15},
+		BetaRogue:       []int{20},
+	}
+	allTests := suite.getAllTests()
+	for _, testName := range []string{"stakingNetworkConsensusSweepTest-n5-k2-alpha2-beta15-20", "stakingNetworkConsensusSweepTest-n8-k2-alpha2-beta15-20"} {
+		registration, found := allTests[testName]
+		assert.True(t, found, "Test %v should be registered", testName)
+		assert.Contains(t, registration.tags, Load)
+	}
+}
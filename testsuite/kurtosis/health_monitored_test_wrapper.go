@@ -0,0 +1,52 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package kurtosis
+
+import (
+	"github.com/kurtosis-tech/kurtosis-go/lib/networks"
+	"github.com/kurtosis-tech/kurtosis-go/lib/testsuite"
+	"github.com/palantir/stacktrace"
+	"github.com/sirupsen/logrus"
+
+	caminoNetwork "github.com/chain4travel/camino-testing/camino/networks"
+)
+
+// healthMonitoredTest wraps a test so that the health of every node in its network is polled for the whole duration of
+// the test, failing the test if a node becomes unhealthy outside of a window the test permitted
+type healthMonitoredTest struct {
+	testsuite.Test
+}
+
+func newHealthMonitoredTest(test testsuite.Test) healthMonitoredTest {
+	return healthMonitoredTest{
+		Test: test,
+	}
+}
+
+// Run implements the Kurtosis Test interface
+func (test healthMonitoredTest) Run(network networks.Network, context testsuite.TestContext) {
+	castedNetwork, ok := network.(caminoNetwork.TestCaminoNetwork)
+	if !ok {
+		test.Test.Run(network, context)
+		return
+	}
+
+	monitor := castedNetwork.GetHealthMonitor()
+	monitor.Start()
+	// Stopping on the way out (including when the test fails) keeps the monitor from polling a network being torn down
+	defer monitor.Stop()
+	test.Test.Run(network, context)
+
+	monitor.Stop()
+	logrus.Infof("Observed %v node health transitions during the test", len(monitor.GetTransitions()))
+	if err := monitor.VerifyNoViolations(); err != nil {
+		context.Fatal(stacktrace.Propagate(err, "Nodes became unhealthy during the test"))
+	}
+}
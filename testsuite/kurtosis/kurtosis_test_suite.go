@@ -10,66 +10,317 @@
 package kurtosis
 
 import (
+	"encoding/json"
+	"fmt"
 	"time"
 
+	"github.com/chain4travel/caminogo/utils/units"
 	"github.com/kurtosis-tech/kurtosis-go/lib/testsuite"
+	"github.com/palantir/stacktrace"
 
+	"github.com/chain4travel/camino-testing/camino/byzantine"
+	caminoNetwork "github.com/chain4travel/camino-testing/camino/networks"
+	caminoService "github.com/chain4travel/camino-testing/camino/services"
+	"github.com/chain4travel/camino-testing/testsuite/tests/atomic"
+	"github.com/chain4travel/camino-testing/testsuite/tests/behaviors"
 	"github.com/chain4travel/camino-testing/testsuite/tests/bombard"
+	"github.com/chain4travel/camino-testing/testsuite/tests/clockskew"
+	"github.com/chain4travel/camino-testing/testsuite/tests/compatibility"
 	"github.com/chain4travel/camino-testing/testsuite/tests/conflictvtx"
 	"github.com/chain4travel/camino-testing/testsuite/tests/connected"
+	"github.com/chain4travel/camino-testing/testsuite/tests/diskfaults"
 	"github.com/chain4travel/camino-testing/testsuite/tests/duplicate"
+	"github.com/chain4travel/camino-testing/testsuite/tests/fuzz"
+	"github.com/chain4travel/camino-testing/testsuite/tests/keystore"
+	"github.com/chain4travel/camino-testing/testsuite/tests/latejoin"
+	"github.com/chain4travel/camino-testing/testsuite/tests/propagation"
 	"github.com/chain4travel/camino-testing/testsuite/tests/spamchits"
+	"github.com/chain4travel/camino-testing/testsuite/tests/sweep"
+	"github.com/chain4travel/camino-testing/testsuite/tests/tlscerts"
+	"github.com/chain4travel/camino-testing/testsuite/tests/topology"
+	"github.com/chain4travel/camino-testing/testsuite/tests/uptime"
 	"github.com/chain4travel/camino-testing/testsuite/tests/workflow"
 	"github.com/chain4travel/camino-testing/testsuite/verifier"
+	"github.com/chain4travel/camino-testing/utils/random"
 )
 
 const (
 	// The number of bits to make each test network, which dictates the max number of services a test can spin up
 	// Here we choose 8 bits = 256 max services per test
 	networkWidthBits = 8
+
+	// Prefix of the names of the tests that run a single byzantine behavior, which are suffixed with the behavior ID
+	byzantineBehaviorTestNamePrefix = "stakingNetworkByzantineBehaviorTest-"
+
+	// Format of the names of the compatibility matrix tests, which are identified by the indices of their boot and joining
+	// images in CompatibilityImageNames because image names aren't valid in test names
+	compatibilityMatrixTestNameFormat = "stakingNetworkCompatibilityMatrixTest-%d-%d"
+
+	// Format of the names of the RPC workflow tests run under a tx fee, which are identified by the fee in nAVAX
+	rpcWorkflowFeeTestNameFormat = "stakingNetworkRPCWorkflowFeeTest-%d"
+
+	// Prefix of the names of the consensus sweep tests, which are suffixed with the consensus params they run with
+	consensusSweepTestNamePrefix = "stakingNetworkConsensusSweepTest-"
+
+	// Prefix of the names of the disk fault tests, which are suffixed with the fault they inject
+	diskFaultTestNamePrefix = "stakingNetworkDiskFaultTest-"
 )
 
+// The tx fees, besides none, that the RPC workflow is run under to check that every tx burns the configured fee
+var rpcWorkflowTxFees = []uint64{units.MilliAvax, 10 * units.MilliAvax}
+
+// "Set" of the tests that put the network under load, and so get their nodes profiled
+var profiledTestNames = map[string]bool{
+	"stakingNetworkChitSpammerTest":   true,
+	"stakingNetworkBombardXChainTest": true,
+}
+
 // CaminoTestSuite implements the Kurtosis TestSuite interface
 type CaminoTestSuite struct {
 	ByzantineImageName string
 	NormalImageName    string
+
+	// The images whose compatibility with each other gets tested, one test per ordered pair of distinct images
+	CompatibilityImageNames []string
+
+	// The image whose nodes can have their clocks offset, for the clock skew test; no clock skew test is run without it
+	ClockSkewImageName string
+
+	// Decides which of the tests get run
+	Selector TestSelector
+
+	// Overrides of the params of tests, as parsed by ParseTestParams
+	TestParams map[string]map[string]json.RawMessage
+
+	// The consensus params to sweep, one test per combination, as parsed by ParseConsensusSweepGrid; nil for no sweep
+	ConsensusSweepGrid *sweep.ConsensusSweepGrid
+}
+
+// Validate checks that the test params reference tests and params that exist; it must pass before the suite is run
+func (a CaminoTestSuite) Validate() error {
+	if _, err := a.getTests(); err != nil {
+		return stacktrace.Propagate(err, "Invalid test suite configuration")
+	}
+	return nil
 }
 
 // GetTests implements the Kurtosis TestSuite interface
 func (a CaminoTestSuite) GetTests() map[string]testsuite.Test {
+	result, err := a.getTests()
+	if err != nil {
+		// Validate is called before the suite gets run, so this means a code bug
+		panic(stacktrace.Propagate(err, "An error occurred getting the tests of an unvalidated test suite"))
+	}
+	return result
+}
+
+func (a CaminoTestSuite) GetNetworkWidthBits() uint32 {
+	return networkWidthBits
+}
+
+// ================ Helper functions =========================
+/*
+Gets the selected tests, with their params overridden and wrapped in the suite-wide behaviour
+*/
+func (a CaminoTestSuite) getTests() (map[string]testsuite.Test, error) {
+	allTests := a.getAllTests()
+	for testName := range a.TestParams {
+		if _, found := allTests[testName]; !found {
+			return nil, stacktrace.NewError("Params were given for test '%v', which doesn't exist", testName)
+		}
+	}
+
 	result := make(map[string]testsuite.Test)
+	for testName, registration := range allTests {
+		if !a.Selector.isSelected(testName, registration.tags) {
+			continue
+		}
+		test := registration.test
+		if params, found := a.TestParams[testName]; found {
+			var err error
+			if test, err = applyTestParams(test, params); err != nil {
+				return nil, stacktrace.Propagate(err, "Could not override the params of test '%v'", testName)
+			}
+		}
+
+		wrappedTest := testsuite.Test(newHealthMonitoredTest(test))
+		if profiledTestNames[testName] {
+			wrappedTest = newProfiledTest(testName, wrappedTest)
+		}
+		result[testName] = newArtifactCollectingTest(testName, wrappedTest)
+	}
+	return result, nil
+}
+
+/*
+Gets every test the suite can run with its configuration, before selection
+*/
+func (a CaminoTestSuite) getAllTests() map[string]testRegistration {
+	result := make(map[string]testRegistration)
 
 	if a.ByzantineImageName != "" {
-		result["stakingNetworkChitSpammerTest"] = spamchits.NewStakingNetworkUnrequestedChitSpammerTest(
-			a.ByzantineImageName,
-			a.NormalImageName,
+		result["stakingNetworkChitSpammerTest"] = newTestRegistration(
+			spamchits.NewStakingNetworkUnrequestedChitSpammerTest(
+				a.ByzantineImageName,
+				a.NormalImageName,
+			),
+			Byzantine, Load, Long,
+		)
+		result["conflictingTxsVertexTest"] = newTestRegistration(
+			conflictvtx.StakingNetworkConflictingTxsVertexTest{
+				ByzantineImageName: a.ByzantineImageName,
+				NormalImageName:    a.NormalImageName,
+			},
+			Byzantine,
 		)
-		result["conflictingTxsVertexTest"] = conflictvtx.StakingNetworkConflictingTxsVertexTest{
-			ByzantineImageName: a.ByzantineImageName,
-			NormalImageName:    a.NormalImageName,
+		for _, behavior := range byzantine.GetRegisteredBehaviors() {
+			result[byzantineBehaviorTestNamePrefix+string(behavior.GetID())] = newTestRegistration(
+				behaviors.NewStakingNetworkByzantineBehaviorTest(
+					behavior,
+					a.ByzantineImageName,
+					a.NormalImageName,
+				),
+				Byzantine, Long,
+			)
 		}
 	}
-	result["stakingNetworkBombardXChainTest"] = bombard.StakingNetworkBombardTest{
-		ImageName:         a.NormalImageName,
-		NumTxs:            1000,
-		TxFee:             1000000,
-		AcceptanceTimeout: 10 * time.Second,
+	result["stakingNetworkBombardXChainTest"] = newTestRegistration(
+		bombard.StakingNetworkBombardTest{
+			ImageName:         a.NormalImageName,
+			NumTxs:            1000,
+			TxFee:             1000000,
+			AcceptanceTimeout: 10 * time.Second,
+		},
+		Load,
+	)
+	result["stakingNetworkFullyConnectedTest"] = newTestRegistration(
+		connected.StakingNetworkFullyConnectedTest{
+			ImageName:              a.NormalImageName,
+			Verifier:               verifier.NewNetworkStateVerifier(),
+			FullyConnectedDeadline: 90 * time.Second,
+		},
+		Smoke,
+	)
+	result["stakingNetworkDuplicateNodeIDTest"] = newTestRegistration(
+		duplicate.DuplicateNodeIDTest{
+			ImageName: a.NormalImageName,
+			Verifier:  verifier.NewNetworkStateVerifier(),
+		},
+		Long,
+	)
+	result["stakingNetworkDuplicateValidatorTest"] = newTestRegistration(
+		duplicate.NewDuplicateValidatorSplitBrainTest(a.NormalImageName, false),
+		Long,
+	)
+	result["stakingNetworkDuplicateBootNodeTest"] = newTestRegistration(
+		duplicate.NewDuplicateValidatorSplitBrainTest(a.NormalImageName, true),
+		Long,
+	)
+	result["StakingNetworkRPCWorkflowTest"] = newTestRegistration(
+		workflow.StakingNetworkRPCWorkflowTest{
+			ImageName: a.NormalImageName,
+		},
+		Smoke,
+	)
+	for _, txFee := range rpcWorkflowTxFees {
+		result[fmt.Sprintf(rpcWorkflowFeeTestNameFormat, txFee)] = newTestRegistration(
+			workflow.StakingNetworkRPCWorkflowTest{
+				ImageName: a.NormalImageName,
+				TxFee:     txFee,
+			},
+			Long,
+		)
 	}
-	result["stakingNetworkFullyConnectedTest"] = connected.StakingNetworkFullyConnectedTest{
-		ImageName: a.NormalImageName,
-		Verifier:  verifier.NewNetworkStateVerifier(),
+	result["stakingNetworkKeystoreTest"] = newTestRegistration(
+		keystore.StakingNetworkKeystoreTest{
+			ImageName: a.NormalImageName,
+		},
+		Smoke,
+	)
+	result["stakingNetworkLateJoiningNodeTest"] = newTestRegistration(
+		latejoin.NewStakingNetworkLateJoiningNodeTest(a.NormalImageName, false),
+		Long,
+	)
+	result["stakingNetworkLateJoiningNodeRestartTest"] = newTestRegistration(
+		latejoin.NewStakingNetworkLateJoiningNodeTest(a.NormalImageName, true),
+		Long,
+	)
+	result["stakingNetworkStarTopologyTest"] = newTestRegistration(
+		topology.NewStakingNetworkSparseTopologyTest(a.NormalImageName, caminoNetwork.StarTopology{}),
+		Long,
+	)
+	result["stakingNetworkChainTopologyTest"] = newTestRegistration(
+		topology.NewStakingNetworkSparseTopologyTest(a.NormalImageName, caminoNetwork.ChainTopology{}),
+		Long,
+	)
+	result["stakingNetworkRandomTopologyTest"] = newTestRegistration(
+		topology.NewStakingNetworkSparseTopologyTest(a.NormalImageName, caminoNetwork.NewRandomTopology(2, random.GetSeed())),
+		Long,
+	)
+	result["stakingNetworkCertAcceptanceTest"] = newTestRegistration(
+		tlscerts.NewStakingNetworkCertAcceptanceTest(a.NormalImageName),
+		Long,
+	)
+	result["stakingNetworkValidatorUptimeTest"] = newTestRegistration(
+		uptime.NewStakingNetworkValidatorUptimeTest(a.NormalImageName),
+		Long,
+	)
+	result["stakingNetworkAtomicTransferTest"] = newTestRegistration(
+		atomic.NewStakingNetworkAtomicTransferTest(a.NormalImageName),
+		Long,
+	)
+	result["stakingNetworkTxPropagationTest"] = newTestRegistration(
+		propagation.NewStakingNetworkTxPropagationTest(a.NormalImageName),
+		Load,
+	)
+	result["stakingNetworkRPCFuzzTest"] = newTestRegistration(
+		fuzz.NewStakingNetworkRPCFuzzTest(a.NormalImageName),
+		Load,
+	)
+	for bootIdx, bootImageName := range a.CompatibilityImageNames {
+		for joiningIdx, joiningImageName := range a.CompatibilityImageNames {
+			if bootIdx == joiningIdx {
+				continue
+			}
+			testName := fmt.Sprintf(compatibilityMatrixTestNameFormat, bootIdx, joiningIdx)
+			result[testName] = newTestRegistration(
+				compatibility.NewStakingNetworkCompatibilityMatrixTest(bootImageName, joiningImageName),
+				Long,
+			)
+		}
+	}
+	if a.ClockSkewImageName != "" {
+		result["stakingNetworkClockSkewTest"] = newTestRegistration(
+			clockskew.NewStakingNetworkClockSkewTest(a.NormalImageName, a.ClockSkewImageName),
+			Long,
+		)
 	}
-	result["stakingNetworkDuplicateNodeIDTest"] = duplicate.DuplicateNodeIDTest{
-		ImageName: a.NormalImageName,
-		Verifier:  verifier.NewNetworkStateVerifier(),
+	for _, fault := range caminoService.GetDiskFaults() {
+		result[diskFaultTestNamePrefix+string(fault)] = newTestRegistration(
+			diskfaults.NewStakingNetworkDiskFaultTest(a.NormalImageName, fault),
+			Long,
+		)
 	}
-	result["StakingNetworkRPCWorkflowTest"] = workflow.StakingNetworkRPCWorkflowTest{
-		ImageName: a.NormalImageName,
+	if a.ConsensusSweepGrid != nil {
+		for _, params := range a.ConsensusSweepGrid.GetParams() {
+			result[consensusSweepTestNamePrefix+params.String()] = newTestRegistration(
+				sweep.NewStakingNetworkConsensusSweepTest(a.NormalImageName, params),
+				Load, Long,
+			)
+		}
 	}
-
 	return result
 }
 
-func (a CaminoTestSuite) GetNetworkWidthBits() uint32 {
-	return networkWidthBits
+// testRegistration pairs a test with the tags it can be selected by
+type testRegistration struct {
+	test testsuite.Test
+	tags []TestTag
+}
+
+func newTestRegistration(test testsuite.Test, tags ...TestTag) testRegistration {
+	return testRegistration{
+		test: test,
+		tags: tags,
+	}
 }
@@ -0,0 +1,91 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package kurtosis
+
+import (
+	"fmt"
+
+	"github.com/kurtosis-tech/kurtosis-go/lib/networks"
+	"github.com/kurtosis-tech/kurtosis-go/lib/testsuite"
+	"github.com/sirupsen/logrus"
+
+	caminoNetwork "github.com/chain4travel/camino-testing/camino/networks"
+	"github.com/chain4travel/camino-testing/testsuite/helpers"
+)
+
+// profiledTest wraps a test so that every node in its network is CPU profiled for the whole duration of the test, and
+// memory and lock profiles are taken at its end. The profiles are collected whether or not the test passes, so that
+// performance regressions come with profiles attached.
+// Profiling is best-effort: a node that can't be profiled is logged, but doesn't fail the test.
+type profiledTest struct {
+	testsuite.Test
+
+	testName string
+}
+
+func newProfiledTest(testName string, test testsuite.Test) profiledTest {
+	return profiledTest{
+		Test:     test,
+		testName: testName,
+	}
+}
+
+// Run implements the Kurtosis Test interface
+func (test profiledTest) Run(network networks.Network, context testsuite.TestContext) {
+	castedNetwork, ok := network.(caminoNetwork.TestCaminoNetwork)
+	if !ok {
+		test.Test.Run(network, context)
+		return
+	}
+
+	// "Set" of the services whose CPU profiler is running
+	cpuProfiledServiceIDs := map[networks.ServiceID]bool{}
+	for serviceID, client := range castedNetwork.GetRunningCaminoClients() {
+		if err := helpers.NewAdminWorkFlowRunner(client).StartCPUProfiler(); err != nil {
+			logProfilingError(serviceID, err)
+			continue
+		}
+		cpuProfiledServiceIDs[serviceID] = true
+	}
+	// Deferred so that the profiles of a failed test get collected too
+	defer test.collectProfiles(castedNetwork, cpuProfiledServiceIDs)
+
+	test.Test.Run(network, context)
+}
+
+// ================ Helper functions =========================
+/*
+Writes the final profiles of every node still in the network and archives them
+*/
+func (test profiledTest) collectProfiles(network caminoNetwork.TestCaminoNetwork, cpuProfiledServiceIDs map[networks.ServiceID]bool) {
+	for serviceID, client := range network.GetRunningCaminoClients() {
+		runner := helpers.NewAdminWorkFlowRunner(client)
+		if cpuProfiledServiceIDs[serviceID] {
+			if err := runner.StopCPUProfiler(); err != nil {
+				logProfilingError(serviceID, err)
+			}
+		}
+		if err := runner.WriteMemoryProfile(); err != nil {
+			logProfilingError(serviceID, err)
+		}
+		if err := runner.WriteLockProfile(); err != nil {
+			logProfilingError(serviceID, err)
+		}
+	}
+	if err := network.CollectProfiles(test.testName); err != nil {
+		logrus.Errorf("An error occurred collecting the profiles of test %v:", test.testName)
+		fmt.Fprintln(logrus.StandardLogger().Out, err)
+	}
+}
+
+func logProfilingError(serviceID networks.ServiceID, err error) {
+	logrus.Warnf("An error occurred profiling service with ID %v:", serviceID)
+	fmt.Fprintln(logrus.StandardLogger().Out, err)
+}
@@ -0,0 +1,92 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package kurtosis
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kurtosis-tech/kurtosis-go/lib/testsuite"
+	"github.com/palantir/stacktrace"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// ParseTestParams parses the JSON given to override the parameters of tests, which maps a test name to an object of
+// (exported test field name) -> value; e.g. {"stakingNetworkBombardXChainTest": {"NumTxs": 10000, "AcceptanceTimeout": "30s"}}
+// Durations can be given either as strings in the format time.ParseDuration accepts or as integer nanoseconds.
+func ParseTestParams(paramsJSON string) (map[string]map[string]json.RawMessage, error) {
+	result := map[string]map[string]json.RawMessage{}
+	if strings.TrimSpace(paramsJSON) == "" {
+		return result, nil
+	}
+	if err := json.Unmarshal([]byte(paramsJSON), &result); err != nil {
+		return nil, stacktrace.Propagate(err, "Could not parse test params JSON; it should be an object of test name -> object of param name -> value")
+	}
+	return result, nil
+}
+
+// ================ Helper functions =========================
+/*
+Returns a copy of the given test with its exported fields overridden by the given params
+*/
+func applyTestParams(test testsuite.Test, params map[string]json.RawMessage) (testsuite.Test, error) {
+	testValue := reflect.ValueOf(test)
+	if testValue.Kind() != reflect.Struct {
+		return nil, stacktrace.NewError("Only tests that are structs can have their params overridden, but test is a %v", testValue.Kind())
+	}
+	// The test is held by value, so we work on an addressable copy
+	testCopy := reflect.New(testValue.Type()).Elem()
+	testCopy.Set(testValue)
+
+	// Sorted so that errors are deterministic
+	paramNames := make([]string, 0, len(params))
+	for paramName := range params {
+		paramNames = append(paramNames, paramName)
+	}
+	sort.Strings(paramNames)
+	for _, paramName := range paramNames {
+		field := testCopy.FieldByName(paramName)
+		if !field.IsValid() || !field.CanSet() {
+			return nil, stacktrace.NewError("Test has no exported field '%v' to override", paramName)
+		}
+		if err := setField(field, params[paramName]); err != nil {
+			return nil, stacktrace.Propagate(err, "Could not override field '%v'", paramName)
+		}
+	}
+	return testCopy.Interface().(testsuite.Test), nil
+}
+
+func setField(field reflect.Value, rawValue json.RawMessage) error {
+	if field.Type() == durationType && bytes.HasPrefix(bytes.TrimSpace(rawValue), []byte(`"`)) {
+		var durationStr string
+		if err := json.Unmarshal(rawValue, &durationStr); err != nil {
+			return stacktrace.Propagate(err, "Could not parse duration string from %v", string(rawValue))
+		}
+		duration, err := time.ParseDuration(durationStr)
+		if err != nil {
+			return stacktrace.Propagate(err, "Could not parse duration '%v'", durationStr)
+		}
+		field.SetInt(int64(duration))
+		return nil
+	}
+	newValue := reflect.New(field.Type())
+	decoder := json.NewDecoder(bytes.NewReader(rawValue))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(newValue.Interface()); err != nil {
+		return stacktrace.Propagate(err, "Could not parse %v as a %v", string(rawValue), field.Type())
+	}
+	field.Set(newValue.Elem())
+	return nil
+}
@@ -0,0 +1,72 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package kurtosis
+
+import (
+	"testing"
+	"time"
+
+	"github.com/chain4travel/camino-testing/testsuite/tests/bombard"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	bombardTestName = "stakingNetworkBombardXChainTest"
+)
+
+func TestOverrideBombardParams(t *testing.T) {
+	testParams, err := ParseTestParams(`{"stakingNetworkBombardXChainTest": {"NumTxs": 10000, "AcceptanceTimeout": "30s"}}`)
+	assert.NoError(t, err)
+	suite := CaminoTestSuite{
+		NormalImageName: "normal-image",
+		TestParams:      testParams,
+	}
+	assert.NoError(t, suite.Validate())
+
+	overriddenTest, err := applyTestParams(suite.getAllTests()[bombardTestName].test, testParams[bombardTestName])
+	assert.NoError(t, err)
+	bombardTest := overriddenTest.(bombard.StakingNetworkBombardTest)
+	assert.Equal(t, uint64(10000), bombardTest.NumTxs)
+	assert.Equal(t, 30*time.Second, bombardTest.AcceptanceTimeout)
+	// Params that weren't overridden keep their defaults
+	assert.Equal(t, uint64(1000000), bombardTest.TxFee)
+}
+
+func TestInvalidParams(t *testing.T) {
+	for _, paramsJSON := range []string{
+		`{"nonexistentTest": {"NumTxs": 1}}`,
+		`{"stakingNetworkBombardXChainTest": {"NonexistentParam": 1}}`,
+		`{"stakingNetworkBombardXChainTest": {"NumTxs": "many"}}`,
+	} {
+		testParams, err := ParseTestParams(paramsJSON)
+		assert.NoError(t, err)
+		suite := CaminoTestSuite{
+			TestParams: testParams,
+		}
+		assert.Error(t, suite.Validate(), "Expected params %v to be invalid", paramsJSON)
+	}
+}
+
+func TestSelectByTagAndRegex(t *testing.T) {
+	selector, err := NewTestSelector("smoke, load", "^staking")
+	assert.NoError(t, err)
+	suite := CaminoTestSuite{
+		Selector: selector,
+	}
+	tests := suite.GetTests()
+	assert.Contains(t, tests, bombardTestName)
+	assert.Contains(t, tests, "stakingNetworkFullyConnectedTest")
+	// Tagged smoke, but doesn't match the regex
+	assert.NotContains(t, tests, "StakingNetworkRPCWorkflowTest")
+	assert.NotContains(t, tests, "stakingNetworkDuplicateNodeIDTest")
+
+	_, err = NewTestSelector("nonexistent-tag", "")
+	assert.Error(t, err)
+}
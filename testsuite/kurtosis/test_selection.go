@@ -0,0 +1,108 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package kurtosis
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/palantir/stacktrace"
+)
+
+// TestTag categorizes tests, so that a run can be restricted to the categories it has time for
+type TestTag string
+
+const (
+	// Quick tests of the basic functionality of a network
+	Smoke TestTag = "smoke"
+
+	// Tests that need the byzantine image
+	Byzantine TestTag = "byzantine"
+
+	// Tests that put the network under load
+	Load TestTag = "load"
+
+	// Tests that take several minutes to run
+	Long TestTag = "long"
+)
+
+var allTestTags = map[TestTag]bool{
+	Smoke:     true,
+	Byzantine: true,
+	Load:      true,
+	Long:      true,
+}
+
+// TestSelector decides which of the suite's tests a run includes
+// NOTE: Kurtosis asks the suite for its tests both when listing them and when running each one, so a run must use the same
+// selector throughout
+type TestSelector struct {
+	// "Set" of the tags a test needs at least one of to be selected, or empty to not filter on tags
+	tags map[TestTag]bool
+
+	// The regex a test's name needs to match to be selected, or nil to not filter on names
+	nameRegex *regexp.Regexp
+}
+
+// NewTestSelector creates a selector from the given filters, either of which can be empty to not filter on it
+// Args:
+// 	tagsCSV: Comma-separated list of tags, where a test is selected if it has any of them
+// 	nameRegex: Regex that a test's name must match to be selected
+func NewTestSelector(tagsCSV string, nameRegex string) (TestSelector, error) {
+	tags := map[TestTag]bool{}
+	for _, tagStr := range strings.Split(tagsCSV, ",") {
+		tagStr = strings.TrimSpace(tagStr)
+		if tagStr == "" {
+			continue
+		}
+		tag := TestTag(tagStr)
+		if !allTestTags[tag] {
+			return TestSelector{}, stacktrace.NewError("Unrecognized test tag '%v'; valid tags are %v", tagStr, getAllTestTagStrings())
+		}
+		tags[tag] = true
+	}
+
+	var compiledRegex *regexp.Regexp
+	if nameRegex != "" {
+		var err error
+		if compiledRegex, err = regexp.Compile(nameRegex); err != nil {
+			return TestSelector{}, stacktrace.Propagate(err, "Could not compile test name regex '%v'", nameRegex)
+		}
+	}
+	return TestSelector{
+		tags:      tags,
+		nameRegex: compiledRegex,
+	}, nil
+}
+
+func (selector TestSelector) isSelected(testName string, testTags []TestTag) bool {
+	if selector.nameRegex != nil && !selector.nameRegex.MatchString(testName) {
+		return false
+	}
+	if len(selector.tags) == 0 {
+		return true
+	}
+	for _, tag := range testTags {
+		if selector.tags[tag] {
+			return true
+		}
+	}
+	return false
+}
+
+func getAllTestTagStrings() []string {
+	result := make([]string, 0, len(allTestTags))
+	for tag := range allTestTags {
+		result = append(result, string(tag))
+	}
+	sort.Strings(result)
+	return result
+}
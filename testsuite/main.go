@@ -13,8 +13,12 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	testsuite "github.com/chain4travel/camino-testing/testsuite/kurtosis"
+	"github.com/chain4travel/camino-testing/utils/random"
 	"github.com/kurtosis-tech/kurtosis-go/lib/client"
 	"github.com/sirupsen/logrus"
 )
@@ -57,6 +61,34 @@ func main() {
 		"byzantine-go-image",
 		"",
 		"Name of Byzantine Camino Go Docker image that will be used to launch Camino Go nodes with Byzantine behaviour")
+	caminogoImagesArg := flag.String(
+		"camino-go-images",
+		"",
+		"Comma-separated list of Camino Go Docker images whose compatibility with each other will be tested")
+	clockSkewImageArg := flag.String(
+		"clock-skew-image",
+		"",
+		"Name of a Camino Go Docker image built by scripts/build_clock_skew_image.sh, used to test nodes with skewed clocks (the clock skew test is skipped without it)")
+	testTagsArg := flag.String(
+		"test-tags",
+		"",
+		"Comma-separated list of test tags (smoke, byzantine, load, long); only tests with at least one of them will be run")
+	testNameRegexArg := flag.String(
+		"test-name-regex",
+		"",
+		"Regex that the names of the tests to run must match")
+	testParamsArg := flag.String(
+		"test-params",
+		"",
+		"JSON object of test name -> object of param name -> value, overriding the params that tests are configured with")
+	consensusSweepArg := flag.String(
+		"consensus-sweep",
+		"",
+		"Grid of consensus params to sweep, one test per combination: 'default' or a JSON object of param -> list of values (no sweep by default)")
+	seedArg := flag.String(
+		"seed",
+		"",
+		"Integer that all the suite's randomness (node identities, usernames, topologies, etc.) derives from; a random one is used, and logged, if none is given")
 
 	flag.Parse()
 
@@ -67,10 +99,54 @@ func main() {
 	}
 	logrus.SetLevel(level)
 
+	seed := time.Now().UnixNano()
+	if *seedArg != "" {
+		if seed, err = strconv.ParseInt(*seedArg, 10, 64); err != nil {
+			fmt.Fprintf(os.Stderr, "An error occurred parsing the seed: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	// Set before the tests are created, as creating them already draws randomness
+	random.SetSeed(seed)
+	logrus.Infof("Using seed %v; pass --seed=%v to reproduce this run", seed, seed)
+
+	compatibilityImageNames := []string{}
+	for _, imageName := range strings.Split(*caminogoImagesArg, ",") {
+		if trimmedImageName := strings.TrimSpace(imageName); trimmedImageName != "" {
+			compatibilityImageNames = append(compatibilityImageNames, trimmedImageName)
+		}
+	}
+
+	testSelector, err := testsuite.NewTestSelector(*testTagsArg, *testNameRegexArg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "An error occurred parsing the test selection: %v\n", err)
+		os.Exit(1)
+	}
+	testParams, err := testsuite.ParseTestParams(*testParamsArg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "An error occurred parsing the test params: %v\n", err)
+		os.Exit(1)
+	}
+	consensusSweepGrid, err := testsuite.ParseConsensusSweepGrid(*consensusSweepArg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "An error occurred parsing the consensus sweep grid: %v\n", err)
+		os.Exit(1)
+	}
+
 	logrus.Debugf("Byzantine image name: %s", *byzantineGoImageArg)
+	logrus.Debugf("Compatibility image names: %v", compatibilityImageNames)
 	testSuite := testsuite.CaminoTestSuite{
-		ByzantineImageName: *byzantineGoImageArg,
-		NormalImageName:    *caminogoImageArg,
+		ByzantineImageName:      *byzantineGoImageArg,
+		NormalImageName:         *caminogoImageArg,
+		CompatibilityImageNames: compatibilityImageNames,
+		ClockSkewImageName:      *clockSkewImageArg,
+		Selector:                testSelector,
+		TestParams:              testParams,
+		ConsensusSweepGrid:      consensusSweepGrid,
+	}
+	if err := testSuite.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "An error occurred validating the test suite: %v\n", err)
+		os.Exit(1)
 	}
 	exitCode := client.Run(testSuite, *metadataFilepath, *servicesDirpathArg, *testArg, *kurtosisApiIpArg)
 	os.Exit(exitCode)
@@ -0,0 +1,489 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package atomic
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kurtosis-tech/kurtosis-go/lib/networks"
+	"github.com/kurtosis-tech/kurtosis-go/lib/testsuite"
+
+	caminoNetwork "github.com/chain4travel/camino-testing/camino/networks"
+	caminoService "github.com/chain4travel/camino-testing/camino/services"
+	"github.com/chain4travel/camino-testing/camino_client/apis"
+	"github.com/chain4travel/camino-testing/camino_client/utils/constants"
+	"github.com/chain4travel/camino-testing/testsuite/helpers"
+	"github.com/chain4travel/caminogo/api"
+	"github.com/chain4travel/caminogo/ids"
+	"github.com/palantir/stacktrace"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	importingNodeConfigID           networks.ConfigurationID = "importing-node-config"
+	importingNodeServiceID          networks.ServiceID       = "importing-node"
+	restartedImportingNodeServiceID networks.ServiceID       = "restarted-importing-node"
+	genesisUsername                                          = "atomic_genesis"
+	genesisPassword                                          = "at0mic-g3nesis!pw"
+	exporterUsernamePrefix                                   = "atomic_exporter_"
+	exporterPassword                                         = "at0mic-3xp0rter!pw"
+	importerUsername                                         = "atomic_importer"
+	importerPassword                                         = "at0mic-1mp0rter!pw"
+	seedAmount                                               = uint64(1000000000000)
+	exportAmount                                             = uint64(1000000000)
+
+	networkAcceptanceTimeoutRatio = 0.3
+
+	// How long each of the racing imports gets to commit; the one that loses the race never does
+	raceAcceptanceTimeout = 30 * time.Second
+
+	bootstrapPollInterval = 1 * time.Second
+)
+
+// StakingNetworkAtomicTransferTest moves AVAX between the X and P Chains through atomic memory in the ways that the happy
+// path transfers don't: many exporters exporting at once and importing everything in one batch, two nodes racing to
+// import the same atomic UTXO, imports on the wrong chain, and a node killed between an export and its import. All of it
+// is recorded in a ledger, and every node must agree with it on both the balances and the contents of atomic memory.
+type StakingNetworkAtomicTransferTest struct {
+	ImageName string
+
+	// The number of users exporting at once, spread over the boot nodes
+	NumExporters int
+
+	// The number of exports each exporter makes in each direction before importing them all at once
+	NumExportsPerExporter int
+
+	// How long the restarted importing node may take to bootstrap
+	BootstrapTimeout time.Duration
+}
+
+// NewStakingNetworkAtomicTransferTest creates an atomic transfer test with the default amount of load
+func NewStakingNetworkAtomicTransferTest(imageName string) StakingNetworkAtomicTransferTest {
+	return StakingNetworkAtomicTransferTest{
+		ImageName:             imageName,
+		NumExporters:          4,
+		NumExportsPerExporter: 5,
+		BootstrapTimeout:      3 * time.Minute,
+	}
+}
+
+// Run implements the Kurtosis Test interface
+func (test StakingNetworkAtomicTransferTest) Run(network networks.Network, context testsuite.TestContext) {
+	castedNetwork := network.(caminoNetwork.TestCaminoNetwork)
+	networkAcceptanceTimeout := time.Duration(networkAcceptanceTimeoutRatio * float64(test.GetExecutionTimeout().Nanoseconds()))
+	bootClients, err := getBootClients(castedNetwork)
+	if err != nil {
+		context.Fatal(stacktrace.Propagate(err, "Failed to get the clients of the boot nodes."))
+	}
+	fees, err := helpers.GetLedgerFees(bootClients[0])
+	if err != nil {
+		context.Fatal(stacktrace.Propagate(err, "Failed to get the fees the boot nodes charge."))
+	}
+	ledger := helpers.NewBalanceLedger(fees)
+
+	// ============================= FUND THE EXPORTERS ====================================
+	logrus.Infof("Funding %v exporters...", test.NumExporters)
+	genesisRunner := helpers.NewRPCWorkFlowRunner(
+		bootClients[0],
+		api.UserPass{Username: genesisUsername, Password: genesisPassword},
+		networkAcceptanceTimeout,
+	).WithLedger(ledger)
+	if _, err := genesisRunner.ImportGenesisFunds(); err != nil {
+		context.Fatal(stacktrace.Propagate(err, "Failed to import the genesis funds."))
+	}
+	exporters := make([]*exportingUser, test.NumExporters)
+	exporterXChainAddresses := make([]string, test.NumExporters)
+	for i := range exporters {
+		client := bootClients[i%len(bootClients)]
+		user := api.UserPass{Username: fmt.Sprintf("%v%d", exporterUsernamePrefix, i), Password: exporterPassword}
+		runner := helpers.NewRPCWorkFlowRunner(client, user, networkAcceptanceTimeout).WithLedger(ledger)
+		xChainAddress, pChainAddress, err := runner.CreateDefaultAddresses()
+		if err != nil {
+			context.Fatal(stacktrace.Propagate(err, "Failed to create the addresses of exporter %v.", i))
+		}
+		exporters[i] = &exportingUser{
+			runner:        runner,
+			client:        client,
+			user:          user,
+			xChainAddress: xChainAddress,
+			pChainAddress: pChainAddress,
+		}
+		exporterXChainAddresses[i] = xChainAddress
+	}
+	if err := genesisRunner.FundXChainAddresses(exporterXChainAddresses, seedAmount); err != nil {
+		context.Fatal(stacktrace.Propagate(err, "Failed to fund the exporters."))
+	}
+
+	// ============================= CONCURRENT EXPORTS ====================================
+	logrus.Infof("Exporting from the X Chain to the P Chain with %v exporters at once...", test.NumExporters)
+	if err := runForEachExporter(exporters, func(exporter *exportingUser) error {
+		for i := 0; i < test.NumExportsPerExporter; i++ {
+			if err := exporter.runner.ExportAvaXChainToPChain(exporter.pChainAddress, exportAmount); err != nil {
+				return stacktrace.Propagate(err, "Failed to make export %v to the P Chain", i)
+			}
+		}
+		return nil
+	}); err != nil {
+		context.Fatal(stacktrace.Propagate(err, "The concurrent exports to the P Chain failed."))
+	}
+	if err := waitForAllAgreeWithLedger(castedNetwork, ledger); err != nil {
+		context.Fatal(stacktrace.Propagate(err, "The nodes don't agree on the exports to the P Chain."))
+	}
+	logrus.Infof("Importing each exporter's exports to the P Chain in one batch...")
+	if err := runForEachExporter(exporters, func(exporter *exportingUser) error {
+		return exporter.runner.ImportAvaToPChain(exporter.pChainAddress)
+	}); err != nil {
+		context.Fatal(stacktrace.Propagate(err, "The batch imports to the P Chain failed."))
+	}
+
+	logrus.Infof("Exporting from the P Chain back to the X Chain with %v exporters at once...", test.NumExporters)
+	if err := runForEachExporter(exporters, func(exporter *exportingUser) error {
+		for i := 0; i < test.NumExportsPerExporter; i++ {
+			if err := exporter.runner.ExportAvaPChainToXChain(exporter.xChainAddress, exportAmount/2); err != nil {
+				return stacktrace.Propagate(err, "Failed to make export %v to the X Chain", i)
+			}
+		}
+		return nil
+	}); err != nil {
+		context.Fatal(stacktrace.Propagate(err, "The concurrent exports to the X Chain failed."))
+	}
+	if err := runForEachExporter(exporters, func(exporter *exportingUser) error {
+		return exporter.runner.ImportAvaToXChain(exporter.xChainAddress)
+	}); err != nil {
+		context.Fatal(stacktrace.Propagate(err, "The batch imports to the X Chain failed."))
+	}
+	if err := waitForAllAgreeWithLedger(castedNetwork, ledger); err != nil {
+		context.Fatal(stacktrace.Propagate(err, "The nodes don't agree on the batch imports."))
+	}
+
+	// ============================= DOUBLE IMPORT =========================================
+	logrus.Infof("Racing two nodes to import the same atomic UTXO...")
+	if err := verifyDoubleImportRejected(exporters[0], bootClients[1], ledger); err != nil {
+		context.Fatal(stacktrace.Propagate(err, "The same atomic UTXO wasn't imported exactly once."))
+	}
+
+	// ============================= WRONG CHAIN IMPORTS ===================================
+	logrus.Infof("Importing on the wrong chain...")
+	if err := verifyWrongChainImportsRejected(exporters[1%len(exporters)], ledger); err != nil {
+		context.Fatal(stacktrace.Propagate(err, "An import on the wrong chain wasn't handled correctly."))
+	}
+
+	// ============================= KILL THE IMPORTING NODE ===============================
+	logrus.Infof("Killing the importing node between an export and its import...")
+	if err := test.verifyImportAfterRestart(castedNetwork, exporters[2%len(exporters)], ledger, networkAcceptanceTimeout); err != nil {
+		context.Fatal(stacktrace.Propagate(err, "The export to the killed importing node couldn't be imported after it restarted."))
+	}
+
+	// ============================= VERIFY STATE ==========================================
+	logrus.Infof("Verifying that every node agrees with the ledger...")
+	if err := waitForAllAgreeWithLedger(castedNetwork, ledger); err != nil {
+		context.Fatal(stacktrace.Propagate(err, "The nodes don't agree with the ledger."))
+	}
+	if err := ledger.VerifyBurnedFees(bootClients[0]); err != nil {
+		context.Fatal(stacktrace.Propagate(err, "The atomic txs didn't burn the expected fees."))
+	}
+	logrus.Infof("Every node agrees with the ledger on the balances and the contents of atomic memory.")
+}
+
+// GetNetworkLoader implements the Kurtosis Test interface
+func (test StakingNetworkAtomicTransferTest) GetNetworkLoader() (networks.NetworkLoader, error) {
+	serviceConfigs := map[networks.ConfigurationID]caminoNetwork.TestCaminoNetworkServiceConfig{
+		importingNodeConfigID: *caminoNetwork.NewTestCaminoNetworkServiceConfig(
+			true,
+			caminoService.DEBUG,
+			test.ImageName,
+			2,
+			2,
+			2*time.Second,
+			make(map[string]string),
+		).WithDatabaseType(caminoService.LevelDB),
+	}
+	desiredServices := map[networks.ServiceID]networks.ConfigurationID{
+		importingNodeServiceID: importingNodeConfigID,
+	}
+	return caminoNetwork.NewTestCaminoNetworkLoader(
+		true,
+		test.ImageName,
+		caminoService.DEBUG,
+		2,
+		2,
+		0,
+		2*time.Second,
+		serviceConfigs,
+		desiredServices,
+	)
+}
+
+// GetExecutionTimeout implements the Kurtosis Test interface
+func (test StakingNetworkAtomicTransferTest) GetExecutionTimeout() time.Duration {
+	return 10 * time.Minute
+}
+
+// GetSetupBuffer implements the Kurtosis Test interface
+func (test StakingNetworkAtomicTransferTest) GetSetupBuffer() time.Duration {
+	return 4 * time.Minute
+}
+
+// ================ Helper functions =========================
+// exportingUser is a user that moves funds between the X and P Chains, with a single address on each
+type exportingUser struct {
+	runner        *helpers.RPCWorkFlowRunner
+	client        *apis.Client
+	user          api.UserPass
+	xChainAddress string
+	pChainAddress string
+}
+
+/*
+Gets the clients of the boot nodes, sorted by service ID
+*/
+func getBootClients(network caminoNetwork.TestCaminoNetwork) ([]*apis.Client, error) {
+	bootServiceIDs := []string{}
+	for serviceID := range network.GetAllBootServiceIDs() {
+		bootServiceIDs = append(bootServiceIDs, string(serviceID))
+	}
+	sort.Strings(bootServiceIDs)
+	result := make([]*apis.Client, 0, len(bootServiceIDs))
+	for _, serviceID := range bootServiceIDs {
+		client, err := network.GetCaminoClient(networks.ServiceID(serviceID))
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "Failed to get client for boot node %v", serviceID)
+		}
+		result = append(result, client)
+	}
+	return result, nil
+}
+
+/*
+Runs the workflow for every exporter at once, returning the first error any of them hit
+*/
+func runForEachExporter(exporters []*exportingUser, workflow func(exporter *exportingUser) error) error {
+	errs := make([]error, len(exporters))
+	wg := &sync.WaitGroup{}
+	for i, exporter := range exporters {
+		wg.Add(1)
+		go func(i int, exporter *exportingUser) {
+			defer wg.Done()
+			errs[i] = workflow(exporter)
+		}(i, exporter)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			return stacktrace.Propagate(err, "Exporter %v failed", i)
+		}
+	}
+	return nil
+}
+
+/*
+Exports to the exporter's P Chain address, copies the exporter's user to a second node and issues the import of the
+	export on both nodes at once; exactly one of the imports must commit, and the atomic UTXO can't be imported again after
+*/
+func verifyDoubleImportRejected(exporter *exportingUser, secondClient *apis.Client, ledger *helpers.BalanceLedger) error {
+	ctx := context.Background()
+	exportedUser, err := helpers.NewKeystoreWorkFlowRunner(exporter.client).ExportUser(exporter.user)
+	if err != nil {
+		return stacktrace.Propagate(err, "Failed to export the exporter's user")
+	}
+	if err := helpers.NewKeystoreWorkFlowRunner(secondClient).ImportUser(exporter.user, exportedUser); err != nil {
+		return stacktrace.Propagate(err, "Failed to copy the exporter's user to the second node")
+	}
+	if err := exporter.runner.ExportAvaXChainToPChain(exporter.pChainAddress, exportAmount); err != nil {
+		return stacktrace.Propagate(err, "Failed to make the export that's imported twice")
+	}
+	// Both nodes must have the atomic UTXO before they race to import it
+	if err := helpers.WaitForAgreement(func() error { return ledger.VerifyAtomicUTXOs(secondClient) }); err != nil {
+		return stacktrace.Propagate(err, "The second node never saw the export")
+	}
+
+	racingClients := []*apis.Client{exporter.client, secondClient}
+	txIDs := make([]ids.ID, len(racingClients))
+	issueErrs := make([]error, len(racingClients))
+	wg := &sync.WaitGroup{}
+	for i, client := range racingClients {
+		wg.Add(1)
+		go func(i int, client *apis.Client) {
+			defer wg.Done()
+			txIDs[i], issueErrs[i] = client.PChainAPI().ImportAVAX(
+				ctx,
+				exporter.user,
+				nil,
+				"",
+				exporter.pChainAddress,
+				constants.XChainID.String(),
+			)
+		}(i, client)
+	}
+	wg.Wait()
+
+	// Both nodes may build the very same tx, in which case it's only imported once however often it's issued
+	committedTxIDs := map[ids.ID]bool{}
+	for i, client := range racingClients {
+		if issueErrs[i] != nil {
+			logrus.Infof("Racing import %v was refused when issued: %v", i, issueErrs[i])
+			continue
+		}
+		racingRunner := helpers.NewRPCWorkFlowRunner(client, exporter.user, raceAcceptanceTimeout)
+		if err := racingRunner.AwaitPChainTxs(txIDs[i]); err != nil {
+			logrus.Infof("Racing import %v, tx %v, didn't commit: %v", i, txIDs[i], err)
+			continue
+		}
+		committedTxIDs[txIDs[i]] = true
+	}
+	if len(committedTxIDs) != 1 {
+		return stacktrace.NewError("Expected exactly one of the racing imports to commit, but %v did: %v", len(committedTxIDs), committedTxIDs)
+	}
+	for txID := range committedTxIDs {
+		ledger.RecordImport(helpers.LedgerPChain, exporter.pChainAddress)
+		ledger.RecordTx(helpers.LedgerPChain, helpers.LedgerImportTx, txID)
+	}
+
+	if _, err := exporter.client.PChainAPI().ImportAVAX(
+		ctx,
+		exporter.user,
+		nil,
+		"",
+		exporter.pChainAddress,
+		constants.XChainID.String(),
+	); err == nil {
+		return stacktrace.NewError("The node issued another import of the atomic UTXO that was already imported")
+	}
+	return nil
+}
+
+/*
+Exports to the exporter's P Chain address and then tries importing on the wrong chains, which must all be refused
+	without touching atomic memory; the export must still be importable on the right chain after
+*/
+func verifyWrongChainImportsRejected(exporter *exportingUser, ledger *helpers.BalanceLedger) error {
+	ctx := context.Background()
+	if err := exporter.runner.ExportAvaXChainToPChain(exporter.pChainAddress, exportAmount); err != nil {
+		return stacktrace.Propagate(err, "Failed to make the export that's imported on the wrong chain")
+	}
+	// Nothing was exported to the X Chain, so there's nothing for it to import
+	if _, err := exporter.client.XChainAPI().Import(ctx, exporter.user, exporter.xChainAddress, constants.PlatformChainID.String()); err == nil {
+		return stacktrace.NewError("The X Chain imported from the P Chain although the export went the other way")
+	}
+	// A chain can't import from itself
+	if _, err := exporter.client.XChainAPI().Import(ctx, exporter.user, exporter.xChainAddress, constants.XChainID.String()); err == nil {
+		return stacktrace.NewError("The X Chain imported from itself")
+	}
+	if _, err := exporter.client.PChainAPI().ImportAVAX(
+		ctx,
+		exporter.user,
+		nil,
+		"",
+		exporter.pChainAddress,
+		constants.PlatformChainID.String(),
+	); err == nil {
+		return stacktrace.NewError("The P Chain imported from itself")
+	}
+	if err := ledger.VerifyAtomicUTXOs(exporter.client); err != nil {
+		return stacktrace.Propagate(err, "The imports on the wrong chain changed atomic memory")
+	}
+	if err := exporter.runner.ImportAvaToPChain(exporter.pChainAddress); err != nil {
+		return stacktrace.Propagate(err, "Failed to import the export on the right chain")
+	}
+	return nil
+}
+
+/*
+Exports to a user of the importing node, then kills the node and restarts it from its database before the user imports
+	the export; the restarted node must still have the export in atomic memory and import it
+*/
+func (test StakingNetworkAtomicTransferTest) verifyImportAfterRestart(
+	network caminoNetwork.TestCaminoNetwork,
+	exporter *exportingUser,
+	ledger *helpers.BalanceLedger,
+	networkAcceptanceTimeout time.Duration) error {
+	importingClient, err := network.GetCaminoClient(importingNodeServiceID)
+	if err != nil {
+		return stacktrace.Propagate(err, "Failed to get client for the importing node")
+	}
+	importerUser := api.UserPass{Username: importerUsername, Password: importerPassword}
+	_, importerPChainAddress, err := helpers.NewRPCWorkFlowRunner(importingClient, importerUser, networkAcceptanceTimeout).
+		WithLedger(ledger).
+		CreateDefaultAddresses()
+	if err != nil {
+		return stacktrace.Propagate(err, "Failed to create the importer's addresses")
+	}
+	if err := exporter.runner.ExportAvaXChainToPChain(importerPChainAddress, exportAmount); err != nil {
+		return stacktrace.Propagate(err, "Failed to export to the importer")
+	}
+
+	closeWindow := network.GetHealthMonitor().PermitUnhealthy(importingNodeServiceID, restartedImportingNodeServiceID)
+	defer closeWindow()
+	if err := network.RemoveService(importingNodeServiceID); err != nil {
+		return stacktrace.Propagate(err, "Failed to kill the importing node")
+	}
+	availabilityChecker, err := network.AddServiceFromDatabase(importingNodeConfigID, restartedImportingNodeServiceID, importingNodeServiceID)
+	if err != nil {
+		return stacktrace.Propagate(err, "Failed to restart the importing node from its database")
+	}
+	if err := availabilityChecker.WaitForStartup(); err != nil {
+		return stacktrace.Propagate(err, "The restarted importing node didn't start up")
+	}
+	restartedClient, err := network.GetCaminoClient(restartedImportingNodeServiceID)
+	if err != nil {
+		return stacktrace.Propagate(err, "Failed to get client for the restarted importing node")
+	}
+	if err := test.waitForBootstrap(restartedClient); err != nil {
+		return stacktrace.Propagate(err, "The restarted importing node didn't bootstrap")
+	}
+	if err := helpers.WaitForAgreement(func() error { return ledger.VerifyAtomicUTXOs(restartedClient) }); err != nil {
+		return stacktrace.Propagate(err, "The restarted importing node lost the export")
+	}
+
+	// The importer's keys live in the node's database, so the restarted node still has them
+	restartedImporter := helpers.NewRPCWorkFlowRunner(restartedClient, importerUser, networkAcceptanceTimeout).WithLedger(ledger)
+	if err := restartedImporter.ImportAvaToPChain(importerPChainAddress); err != nil {
+		return stacktrace.Propagate(err, "Failed to import the export on the restarted importing node")
+	}
+	return nil
+}
+
+func (test StakingNetworkAtomicTransferTest) waitForBootstrap(client *apis.Client) error {
+	deadline := time.Now().Add(test.BootstrapTimeout)
+	for _, chainAlias := range []string{"P", "X"} {
+		for {
+			// The node's API isn't up for the first moments, which we treat the same as not being bootstrapped
+			if bootstrapped, err := client.InfoAPI().IsBootstrapped(context.Background(), chainAlias); err == nil && bootstrapped {
+				break
+			}
+			if time.Now().After(deadline) {
+				return stacktrace.NewError("Node didn't bootstrap the %v Chain within %v", chainAlias, test.BootstrapTimeout)
+			}
+			time.Sleep(bootstrapPollInterval)
+		}
+	}
+	return nil
+}
+
+/*
+Waits for every running node to agree with the ledger on both the balances and the contents of atomic memory
+*/
+func waitForAllAgreeWithLedger(network caminoNetwork.TestCaminoNetwork, ledger *helpers.BalanceLedger) error {
+	for serviceID, client := range network.GetRunningCaminoClients() {
+		if err := helpers.WaitForAgreement(func() error {
+			if err := ledger.Verify(client); err != nil {
+				return err
+			}
+			return ledger.VerifyAtomicUTXOs(client)
+		}); err != nil {
+			return stacktrace.Propagate(err, "Node %v doesn't agree with the ledger", serviceID)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package behaviors
+
+import (
+	"context"
+	"time"
+
+	"github.com/kurtosis-tech/kurtosis-go/lib/networks"
+	"github.com/kurtosis-tech/kurtosis-go/lib/testsuite"
+
+	"github.com/chain4travel/camino-testing/camino/byzantine"
+	caminoNetwork "github.com/chain4travel/camino-testing/camino/networks"
+	caminoService "github.com/chain4travel/camino-testing/camino/services"
+	"github.com/chain4travel/camino-testing/testsuite/helpers"
+	"github.com/chain4travel/caminogo/api"
+	"github.com/chain4travel/caminogo/ids"
+	"github.com/palantir/stacktrace"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	normalNodeConfigID  networks.ConfigurationID = "normal-config"
+	stakerUsername                               = "staker_camino"
+	stakerPassword                               = "test34test!23"
+	normalNodeServiceID networks.ServiceID       = "normal-node"
+	seedAmount                                   = uint64(50000000000000)
+	stakeAmount                                  = uint64(30000000000000)
+	transferAmount                               = uint64(1000000)
+
+	// The 5 boot nodes, the byzantine nodes and the normal node
+	expectedNumValidators = 5 + helpers.NumByzantineValidators + 1
+)
+
+// StakingNetworkByzantineBehaviorTest starts from a network where a set of byzantine nodes exhibiting a single behavior of
+// the byzantine image are staked, and then checks that the honest nodes stay healthy and that a new honest node can still join as a validator
+type StakingNetworkByzantineBehaviorTest struct {
+	ctx                context.Context
+	Behavior           byzantine.Behavior
+	ByzantineImageName string
+	NormalImageName    string
+}
+
+func NewStakingNetworkByzantineBehaviorTest(
+	behavior byzantine.Behavior,
+	byzantineImageName string,
+	normalImageName string,
+) StakingNetworkByzantineBehaviorTest {
+	return StakingNetworkByzantineBehaviorTest{
+		ctx:                context.Background(),
+		Behavior:           behavior,
+		ByzantineImageName: byzantineImageName,
+		NormalImageName:    normalImageName,
+	}
+}
+
+// Run implements the Kurtosis Test interface
+func (test StakingNetworkByzantineBehaviorTest) Run(network networks.Network, context testsuite.TestContext) {
+	castedNetwork := network.(caminoNetwork.TestCaminoNetwork)
+	expectedOutcome := test.Behavior.GetExpectedOutcome()
+	networkAcceptanceTimeout := time.Duration(expectedOutcome.AcceptanceTimeoutRatio * float64(test.GetExecutionTimeout().Nanoseconds()))
+	testStartTime := time.Now()
+
+	// We only make claims about the honest nodes, so the byzantine ones may do as they please
+	closeByzantineWindow := castedNetwork.GetHealthMonitor().PermitUnhealthy(helpers.GetByzantineValidatorServiceIDs()...)
+	defer closeByzantineWindow()
+
+	// The byzantine nodes are already validating, as the network is restored from a snapshot taken after they were added
+	// as validators
+	// =================== ADD NORMAL NODE AS A VALIDATOR ON THE NETWORK =======================
+	logrus.Infof("Adding normal node as a staker...")
+	availabilityChecker, err := castedNetwork.AddService(normalNodeConfigID, normalNodeServiceID)
+	if err != nil {
+		context.Fatal(stacktrace.Propagate(err, "Failed to add normal node to network."))
+	}
+	if err = availabilityChecker.WaitForStartup(); err != nil {
+		context.Fatal(stacktrace.Propagate(err, "Failed to wait for startup of normal node."))
+	}
+	normalClient, err := castedNetwork.GetCaminoClient(normalNodeServiceID)
+	if err != nil {
+		context.Fatal(stacktrace.Propagate(err, "Failed to get staker client."))
+	}
+	highLevelNormalClient := helpers.NewRPCWorkFlowRunner(
+		normalClient,
+		api.UserPass{Username: stakerUsername, Password: stakerPassword},
+		networkAcceptanceTimeout)
+	if _, err := highLevelNormalClient.ImportGenesisFundsAndStartValidating(seedAmount, stakeAmount); err != nil {
+		context.Fatal(stacktrace.Propagate(err, "Failed to add normal node as a validator."))
+	}
+
+	// ============= VALIDATE NETWORK STATE DESPITE BYZANTINE BEHAVIOR =========================
+	logrus.Infof("Validating network state...")
+	actualValidators, err := normalClient.PChainAPI().GetCurrentValidators(test.ctx, ids.Empty, []ids.ShortID{})
+	if err != nil {
+		context.Fatal(stacktrace.Propagate(err, "Could not get current validators."))
+	}
+	actualNumValidators := len(actualValidators)
+	logrus.Debugf("Number of current validators: %d, expected number of validators: %d", actualNumValidators, expectedNumValidators)
+	context.AssertTrue(
+		actualNumValidators == expectedNumValidators,
+		stacktrace.NewError("Actual number of validators, %v, != expected number of validators, %v", actualNumValidators, expectedNumValidators))
+
+	logrus.Infof("Verifying that an honest transfer is accepted within %v...", expectedOutcome.MaxTransferLatency)
+	transferAddress, err := normalClient.XChainAPI().CreateAddress(test.ctx, highLevelNormalClient.User())
+	if err != nil {
+		context.Fatal(stacktrace.Propagate(err, "Failed to create X Chain address to transfer to."))
+	}
+	transferStartTime := time.Now()
+	transferTxID, err := highLevelNormalClient.SendAVAX(transferAddress, transferAmount)
+	if err != nil {
+		context.Fatal(stacktrace.Propagate(err, "Failed to issue honest transfer."))
+	}
+	if err := highLevelNormalClient.AwaitXChainTxs(transferTxID); err != nil {
+		context.Fatal(stacktrace.Propagate(err, "Honest transfer %v wasn't accepted.", transferTxID))
+	}
+	transferLatency := time.Since(transferStartTime)
+	logrus.Infof("Honest transfer %v was accepted after %v.", transferTxID, transferLatency)
+	context.AssertTrue(
+		transferLatency <= expectedOutcome.MaxTransferLatency,
+		stacktrace.NewError(
+			"Honest transfer took %v to be accepted against byzantine %v nodes, more than the expected %v",
+			transferLatency,
+			test.Behavior.GetID(),
+			expectedOutcome.MaxTransferLatency))
+
+	logrus.Infof("Verifying that the honest nodes stayed healthy despite the byzantine nodes...")
+	honestServiceIDs := castedNetwork.GetAllBootServiceIDs()
+	honestServiceIDs[normalNodeServiceID] = true
+	if err := castedNetwork.GetHealthMonitor().VerifyStayedHealthy(honestServiceIDs, testStartTime); err != nil {
+		context.Fatal(stacktrace.Propagate(err, "Honest nodes became unhealthy against byzantine %v nodes", test.Behavior.GetID()))
+	}
+}
+
+// GetNetworkLoader implements the Kurtosis Test interface
+func (test StakingNetworkByzantineBehaviorTest) GetNetworkLoader() (networks.NetworkLoader, error) {
+	if err := test.Behavior.Validate(); err != nil {
+		return nil, stacktrace.Propagate(err, "Invalid parameters for byzantine behavior %v", test.Behavior.GetID())
+	}
+	serviceConfigs := map[networks.ConfigurationID]caminoNetwork.TestCaminoNetworkServiceConfig{
+		helpers.ByzantineValidatorsConfigID: *caminoNetwork.NewTestCaminoNetworkServiceConfig(
+			true,
+			caminoService.DEBUG,
+			test.ByzantineImageName,
+			2,
+			2,
+			2*time.Second,
+			test.Behavior.GetCLIArgs(),
+		),
+		normalNodeConfigID: *caminoNetwork.NewTestCaminoNetworkServiceConfig(
+			true,
+			caminoService.DEBUG,
+			test.NormalImageName,
+			6,
+			8,
+			2*time.Second,
+			make(map[string]string),
+		),
+	}
+
+	serviceIDConfigMap := map[networks.ServiceID]networks.ConfigurationID{}
+	for _, byzantineServiceID := range helpers.GetByzantineValidatorServiceIDs() {
+		serviceIDConfigMap[byzantineServiceID] = helpers.ByzantineValidatorsConfigID
+	}
+	logrus.Debugf("Byzantine Image Name: %s, behavior: %v", test.ByzantineImageName, test.Behavior.GetID())
+	logrus.Debugf("Normal Image Name: %s", test.NormalImageName)
+
+	loader, err := caminoNetwork.NewTestCaminoNetworkLoader(
+		true,
+		test.NormalImageName,
+		caminoService.DEBUG,
+		2,
+		2,
+		0,
+		2*time.Second,
+		serviceConfigs,
+		serviceIDConfigMap,
+	)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred creating the network loader")
+	}
+	// The snapshot is shared with the chit spammer test, so whichever byzantine test gets to it first stakes the byzantine
+	// nodes for all of them
+	return loader.WithSnapshot(helpers.NewByzantineValidatorsSnapshot()), nil
+}
+
+// GetExecutionTimeout implements the Kurtosis Test interface
+func (test StakingNetworkByzantineBehaviorTest) GetExecutionTimeout() time.Duration {
+	return 10 * time.Minute
+}
+
+// GetSetupBuffer implements the Kurtosis Test interface
+func (test StakingNetworkByzantineBehaviorTest) GetSetupBuffer() time.Duration {
+	// Covers adding the byzantine nodes as validators, for when this test is the one that takes the snapshot
+	return 10 * time.Minute
+}
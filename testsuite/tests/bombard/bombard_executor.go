@@ -13,55 +13,71 @@ import (
 	"context"
 	"fmt"
 	"math/rand"
-	"strings"
 	"sync"
 	"time"
 
+	"github.com/chain4travel/camino-testing/camino/ipcs"
 	"github.com/chain4travel/camino-testing/camino_client/apis"
 	"github.com/chain4travel/camino-testing/testsuite/helpers"
 	"github.com/chain4travel/camino-testing/testsuite/tester"
+	"github.com/chain4travel/camino-testing/utils/random"
 	"github.com/chain4travel/caminogo/api"
 	"github.com/chain4travel/caminogo/ids"
-	"github.com/chain4travel/caminogo/utils/constants"
-	"github.com/chain4travel/caminogo/utils/crypto"
-	"github.com/chain4travel/caminogo/utils/formatting"
-	"github.com/chain4travel/caminogo/vms/components/avax"
 	"github.com/palantir/stacktrace"
 	"github.com/sirupsen/logrus"
 )
 
-// NewBombardExecutor returns a new bombard test bombardExecutor
-func NewBombardExecutor(clients []*apis.Client, numTxs, txFee uint64, acceptanceTimeout time.Duration) tester.CaminoTester {
+// NewBombardExecutor returns a new bombard test bombardExecutor, which confirms the transactions it issues through the
+// given X Chain acceptance stream of the first client's node
+func NewBombardExecutor(
+	clients []*apis.Client,
+	acceptanceStream *ipcs.AcceptanceStream,
+	numTxs, txFee uint64,
+	acceptanceTimeout time.Duration) tester.CaminoTester {
 	return &bombardExecutor{
 		ctx:               context.Background(),
 		normalClients:     clients,
+		acceptanceStream:  acceptanceStream,
 		numTxs:            numTxs,
 		acceptanceTimeout: acceptanceTimeout,
 		txFee:             txFee,
+		random:            random.NewRand("bombard"),
 	}
 }
 
 type bombardExecutor struct {
 	ctx               context.Context
 	normalClients     []*apis.Client
+	acceptanceStream  *ipcs.AcceptanceStream
 	acceptanceTimeout time.Duration
 	numTxs            uint64
 	txFee             uint64
+
+	// Where the usernames and passwords of the test users come from
+	random *rand.Rand
 }
 
-func createRandomString() string {
-	return fmt.Sprintf("rand:%d", rand.Int())
+func (e *bombardExecutor) createRandomString() string {
+	return fmt.Sprintf("rand:%d", e.random.Int())
 }
 
 // ExecuteTest implements the CaminoTester interface
 func (e *bombardExecutor) ExecuteTest() error {
 	genesisClient := e.normalClients[0]
+	fees, err := helpers.GetLedgerFees(genesisClient)
+	if err != nil {
+		return stacktrace.Propagate(err, "Failed to get the fees the genesis client's node charges.")
+	}
+	// The transactions are built by hand, burning exactly [e.txFee] each
+	if fees.TxFee != e.txFee {
+		return stacktrace.NewError("The bombard transactions burn a fee of %v, but the network charges %v", e.txFee, fees.TxFee)
+	}
 	secondaryClients := make([]*helpers.RPCWorkFlowRunner, len(e.normalClients)-1)
 	xChainAddrs := make([]string, len(e.normalClients)-1)
 	for i, client := range e.normalClients[1:] {
 		secondaryClients[i] = helpers.NewRPCWorkFlowRunner(
 			client,
-			api.UserPass{Username: createRandomString(), Password: createRandomString()},
+			api.UserPass{Username: e.createRandomString(), Password: e.createRandomString()},
 			e.acceptanceTimeout,
 		)
 		xChainAddress, _, err := secondaryClients[i].CreateDefaultAddresses()
@@ -71,7 +87,7 @@ func (e *bombardExecutor) ExecuteTest() error {
 		xChainAddrs[i] = xChainAddress
 	}
 
-	genesisUser := api.UserPass{Username: createRandomString(), Password: createRandomString()}
+	genesisUser := api.UserPass{Username: e.createRandomString(), Password: e.createRandomString()}
 	highLevelGenesisClient := helpers.NewRPCWorkFlowRunner(
 		genesisClient,
 		genesisUser,
@@ -98,62 +114,20 @@ func (e *bombardExecutor) ExecuteTest() error {
 	}
 	logrus.Infof("Funded X Chain Addresses with seedAmount %v.", seedAmount)
 
-	codec, err := createXChainCodec()
-	if err != nil {
-		return stacktrace.Propagate(err, "Failed to initialize codec.")
-	}
-	utxoLists := make([][]*avax.UTXO, len(secondaryClients))
 	for i, client := range secondaryClients {
 		// Each address should have [e.txFee] remaining after sending [numTxs] and paying the fixed fee each time
 		if err := client.VerifyXChainAVABalance(xChainAddrs[i], seedAmount); err != nil {
 			return stacktrace.Propagate(err, "Failed to verify X Chain Balane for Client: %d", i)
 		}
-		formattedUTXOs, _, err := genesisClient.XChainAPI().GetUTXOs(e.ctx, []string{xChainAddrs[i]}, 10, "", "")
-		if err != nil {
-			return err
-		}
-		utxos := make([]*avax.UTXO, len(formattedUTXOs))
-		for i, formattedUTXO := range formattedUTXOs {
-			utxo := &avax.UTXO{}
-			_, err := codec.Unmarshal(formattedUTXO, utxo)
-			if err != nil {
-				return stacktrace.Propagate(err, "Failed to unmarshal utxo bytes.")
-			}
-			utxos[i] = utxo
-		}
-		utxoLists[i] = utxos
-		logrus.Infof("Decoded %d UTXOs", len(utxos))
-
 	}
-	logrus.Infof("Verified X Chain Balances and retrieved UTXOs.")
+	logrus.Infof("Verified X Chain Balances.")
 
 	// Create a string of consecutive transactions for each secondary client to send
-	privateKeys := make([]*crypto.PrivateKeySECP256K1R, len(secondaryClients))
 	txLists := make([][][]byte, len(secondaryClients))
 	txIDLists := make([][]ids.ID, len(secondaryClients))
 	for i, client := range e.normalClients[1:] {
-		utxo := utxoLists[i][0]
-		pkStr, err := client.XChainAPI().ExportKey(e.ctx, secondaryClients[i].User(), xChainAddrs[i])
-		if err != nil {
-			return stacktrace.Propagate(err, "Failed to export key.")
-		}
-
-		if !strings.HasPrefix(pkStr, constants.SecretKeyPrefix) {
-			return fmt.Errorf("private key missing %s prefix", constants.SecretKeyPrefix)
-		}
-		trimmedPrivateKey := strings.TrimPrefix(pkStr, constants.SecretKeyPrefix)
-		formattedPrivateKey, err := formatting.Decode(formatting.CB58, trimmedPrivateKey)
-		if err != nil {
-			return fmt.Errorf("problem parsing private key: %w", err)
-		}
-
-		factory := crypto.FactorySECP256K1R{}
-		skIntf, err := factory.ToPrivateKey(formattedPrivateKey)
-		sk := skIntf.(*crypto.PrivateKeySECP256K1R)
-		privateKeys[i] = sk
-
 		logrus.Infof("Creating string of %d transactions", e.numTxs)
-		txs, txIDs, err := CreateConsecutiveTransactions(utxo, e.numTxs, seedAmount, e.txFee, sk)
+		txs, txIDs, err := CreateConsecutiveTransactionsForAddress(client, secondaryClients[i], xChainAddrs[i], e.numTxs, seedAmount, e.txFee)
 		if err != nil {
 			return stacktrace.Propagate(err, "Failed to create transaction list.")
 		}
@@ -179,13 +153,33 @@ func (e *bombardExecutor) ExecuteTest() error {
 
 	duration := time.Since(startTime)
 	logrus.Infof("Finished issuing transaction lists in %v seconds.", duration.Seconds())
+	allTxIDs := make([]ids.ID, 0, len(txIDLists)*int(e.numTxs))
 	for _, txIDs := range txIDLists {
-		if err := highLevelGenesisClient.AwaitXChainTxs(txIDs...); err != nil {
-			stacktrace.Propagate(err, "Failed to confirm transactions.")
+		allTxIDs = append(allTxIDs, txIDs...)
+	}
+	decided, err := e.acceptanceStream.WaitForDecisions(allTxIDs, e.acceptanceTimeout)
+	if err != nil {
+		return stacktrace.Propagate(err, "Failed to confirm transactions.")
+	}
+	// Each transaction spends the output of the one before it, so they can only be accepted in the order they were created
+	acceptanceIndices := make(map[ids.ID]int, len(decided))
+	for i, container := range decided {
+		acceptanceIndices[container.ID] = i
+	}
+	for i, txIDs := range txIDLists {
+		for j := 1; j < len(txIDs); j++ {
+			if acceptanceIndices[txIDs[j]] < acceptanceIndices[txIDs[j-1]] {
+				return stacktrace.NewError("Transaction %d of client %d was accepted before the transaction it spends from", j, i)
+			}
 		}
 	}
 
-	logrus.Infof("Confirmed all issued transactions.")
+	acceptanceDuration := decided[len(decided)-1].AcceptedAt.Sub(startTime)
+	logrus.Infof(
+		"Confirmed all %d issued transactions, the last of them %v after issuing began (%.1f transactions/second).",
+		len(decided),
+		acceptanceDuration,
+		float64(len(decided))/acceptanceDuration.Seconds())
 
 	return nil
 }
@@ -45,16 +45,24 @@ func (test StakingNetworkBombardTest) Run(network networks.Network, context test
 	castedNetwork := network.(caminoNetwork.TestCaminoNetwork)
 	bootServiceIDs := castedNetwork.GetAllBootServiceIDs()
 	clients := make([]*apis.Client, 0, len(bootServiceIDs))
+	serviceIDs := make([]networks.ServiceID, 0, len(bootServiceIDs))
 	for serviceID := range bootServiceIDs {
 		caminoClient, err := castedNetwork.GetCaminoClient(serviceID)
 		if err != nil {
 			context.Fatal(stacktrace.Propagate(err, "Failed to get Camino Client for boot node with serviceID: %s.", serviceID))
 		}
 		clients = append(clients, caminoClient)
+		serviceIDs = append(serviceIDs, serviceID)
 	}
+	// The transactions are confirmed through the first client's node, which the executor funds them from
+	acceptanceStream, err := castedNetwork.OpenAcceptanceStream(serviceIDs[0], apis.XChain)
+	if err != nil {
+		context.Fatal(stacktrace.Propagate(err, "Failed to open the X Chain acceptance stream of %s.", serviceIDs[0]))
+	}
+	defer acceptanceStream.Close()
 
 	// Execute the bombard test to issue [NumTxs] to each node
-	executor := NewBombardExecutor(clients, test.NumTxs, test.TxFee, test.AcceptanceTimeout)
+	executor := NewBombardExecutor(clients, acceptanceStream, test.NumTxs, test.TxFee, test.AcceptanceTimeout)
 	logrus.Infof("Executing bombard test...")
 	if err := executor.ExecuteTest(); err != nil {
 		context.Fatal(stacktrace.Propagate(err, "Bombard Test Failed."))
@@ -10,14 +10,20 @@
 package bombard
 
 import (
+	"context"
 	"fmt"
 	"math"
+	"strings"
 
+	"github.com/chain4travel/camino-testing/camino_client/apis"
 	"github.com/chain4travel/camino-testing/camino_client/utils/constants"
+	"github.com/chain4travel/camino-testing/testsuite/helpers"
 	"github.com/chain4travel/caminogo/codec"
 	"github.com/chain4travel/caminogo/codec/linearcodec"
 	"github.com/chain4travel/caminogo/ids"
+	nodeconstants "github.com/chain4travel/caminogo/utils/constants"
 	"github.com/chain4travel/caminogo/utils/crypto"
+	"github.com/chain4travel/caminogo/utils/formatting"
 	"github.com/chain4travel/caminogo/utils/wrappers"
 	"github.com/chain4travel/caminogo/vms/avm"
 	"github.com/chain4travel/caminogo/vms/components/avax"
@@ -130,3 +136,47 @@ func CreateConsecutiveTransactions(utxo *avax.UTXO, numTxs, amount, txFee uint64
 
 	return txBytes, txIDs, nil
 }
+
+// CreateConsecutiveTransactionsForAddress returns a string of [numTxs] sending the UTXO of [address] back and forth
+// assumes that [address] belongs to [runner]'s user on [client]'s node and has a single UTXO, holding [amount]
+func CreateConsecutiveTransactionsForAddress(
+	client *apis.Client,
+	runner *helpers.RPCWorkFlowRunner,
+	address string,
+	numTxs, amount, txFee uint64) ([][]byte, []ids.ID, error) {
+	ctx := context.Background()
+	codec, err := createXChainCodec()
+	if err != nil {
+		return nil, nil, fmt.Errorf("problem initializing codec: %w", err)
+	}
+	formattedUTXOs, _, err := client.XChainAPI().GetUTXOs(ctx, []string{address}, 10, "", "")
+	if err != nil {
+		return nil, nil, fmt.Errorf("problem getting UTXOs of %s: %w", address, err)
+	}
+	if len(formattedUTXOs) != 1 {
+		return nil, nil, fmt.Errorf("expected %s to have 1 UTXO, but it has %d", address, len(formattedUTXOs))
+	}
+	utxo := &avax.UTXO{}
+	if _, err := codec.Unmarshal(formattedUTXOs[0], utxo); err != nil {
+		return nil, nil, fmt.Errorf("problem unmarshalling UTXO: %w", err)
+	}
+
+	pkStr, err := client.XChainAPI().ExportKey(ctx, runner.User(), address)
+	if err != nil {
+		return nil, nil, fmt.Errorf("problem exporting key of %s: %w", address, err)
+	}
+	if !strings.HasPrefix(pkStr, nodeconstants.SecretKeyPrefix) {
+		return nil, nil, fmt.Errorf("private key missing %s prefix", nodeconstants.SecretKeyPrefix)
+	}
+	trimmedPrivateKey := strings.TrimPrefix(pkStr, nodeconstants.SecretKeyPrefix)
+	formattedPrivateKey, err := formatting.Decode(formatting.CB58, trimmedPrivateKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("problem parsing private key: %w", err)
+	}
+	factory := crypto.FactorySECP256K1R{}
+	skIntf, err := factory.ToPrivateKey(formattedPrivateKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("problem parsing private key: %w", err)
+	}
+	return CreateConsecutiveTransactions(utxo, numTxs, amount, txFee, skIntf.(*crypto.PrivateKeySECP256K1R))
+}
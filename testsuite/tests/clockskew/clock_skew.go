@@ -0,0 +1,315 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package clockskew
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kurtosis-tech/kurtosis-go/lib/networks"
+	"github.com/kurtosis-tech/kurtosis-go/lib/testsuite"
+
+	caminoNetwork "github.com/chain4travel/camino-testing/camino/networks"
+	caminoService "github.com/chain4travel/camino-testing/camino/services"
+	"github.com/chain4travel/camino-testing/camino_client/apis"
+	"github.com/chain4travel/camino-testing/testsuite/helpers"
+	"github.com/chain4travel/camino-testing/utils/random"
+	"github.com/palantir/stacktrace"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	skewedNodeConfigIDPrefix  = "skewed-node-config-"
+	skewedNodeServiceIDPrefix = "skewed-node-"
+	seedAmount                = uint64(50000000000000)
+	stakeAmount               = uint64(30000000000000)
+
+	// How far ahead of its own clock a node requires staking start times to be (the P Chain's minimum staker delay)
+	minStakingDelay = 20 * time.Second
+
+	// What the P Chain API's error for a start time that isn't far enough ahead of the node's clock starts with
+	startTimeTooSoonErrorPrefix = "start time must be at least"
+
+	networkAcceptanceTimeout = 30 * time.Second
+)
+
+// StakingNetworkClockSkewTest adds nodes whose clocks are offset from the boot nodes' to a staking network, and has each
+// of them stake itself through its own API with start times just either side of the earliest one its clock allows,
+// checking that only the later is accepted. It then checks that every node, skewed or not, agrees on the validator set
+// once the skewed nodes' validation periods have begun.
+type StakingNetworkClockSkewTest struct {
+	ImageName string
+
+	// The image of the skewed nodes, which must have been built by scripts/build_clock_skew_image.sh
+	ClockSkewImageName string
+
+	// How far ahead of the boot nodes' clocks the clock of each skewed node runs, negative to lag behind
+	ClockOffsets []time.Duration
+
+	// How far either side of the earliest start time a skewed node allows the start times it's given are; kept below
+	// the offsets, so that the node's decisions differ from those the boot nodes would make
+	BoundaryMargin time.Duration
+}
+
+// NewStakingNetworkClockSkewTest creates a clock skew test with one node running behind the boot nodes and one ahead of
+// them, a few seconds apart, but within the synchrony bound that the P Chain tolerates between its validators
+func NewStakingNetworkClockSkewTest(imageName string, clockSkewImageName string) StakingNetworkClockSkewTest {
+	return StakingNetworkClockSkewTest{
+		ImageName:          imageName,
+		ClockSkewImageName: clockSkewImageName,
+		ClockOffsets:       []time.Duration{-3 * time.Second, 3 * time.Second},
+		BoundaryMargin:     2 * time.Second,
+	}
+}
+
+// Run implements the Kurtosis Test interface
+func (test StakingNetworkClockSkewTest) Run(network networks.Network, context testsuite.TestContext) {
+	castedNetwork := network.(caminoNetwork.TestCaminoNetwork)
+	funderServiceID := caminoNetwork.GetBootServiceID(0)
+	funderClient, err := castedNetwork.GetCaminoClient(funderServiceID)
+	if err != nil {
+		context.Fatal(stacktrace.Propagate(err, "Failed to get client for boot node %v.", funderServiceID))
+	}
+	userRandom := random.NewRand("clock-skew")
+	funder := helpers.NewRPCWorkFlowRunner(funderClient, helpers.CreateRandomUser(userRandom), networkAcceptanceTimeout)
+	if _, err := funder.ImportGenesisFunds(); err != nil {
+		context.Fatal(stacktrace.Propagate(err, "Failed to import genesis funds on boot node %v.", funderServiceID))
+	}
+
+	// ============================= PROBE THE START TIME BOUNDARIES =======================
+	skewedNodeIDs := make([]string, 0, len(test.ClockOffsets))
+	latestStakingStartTime := time.Now()
+	for i, clockOffset := range test.ClockOffsets {
+		serviceID := getSkewedServiceID(i)
+		skewedClient, err := castedNetwork.GetCaminoClient(serviceID)
+		if err != nil {
+			context.Fatal(stacktrace.Propagate(err, "Failed to get client for skewed node %v.", serviceID))
+		}
+		stakerPrivateKey, err := fundStakerAddress(funder, funderClient)
+		if err != nil {
+			context.Fatal(stacktrace.Propagate(err, "Failed to fund the stake of skewed node %v.", serviceID))
+		}
+		staker := helpers.NewRPCWorkFlowRunner(skewedClient, helpers.CreateRandomUser(userRandom), networkAcceptanceTimeout).
+			WithClockOffset(clockOffset)
+		logrus.Infof("Staking skewed node %v, whose clock is offset by %v...", serviceID, clockOffset)
+		nodeID, stakingStartTime, err := test.stakeAcrossBoundary(staker, skewedClient, stakerPrivateKey, clockOffset)
+		if err != nil {
+			context.Fatal(stacktrace.Propagate(err, "Skewed node %v didn't judge staking start times by its own clock.", serviceID))
+		}
+		skewedNodeIDs = append(skewedNodeIDs, nodeID)
+		if stakingStartTime.After(latestStakingStartTime) {
+			latestStakingStartTime = stakingStartTime
+		}
+	}
+
+	// ============================= VERIFY VALIDATOR SET TRANSITIONS ======================
+	logrus.Infof("Waiting for the validation periods of the skewed nodes to begin...")
+	time.Sleep(time.Until(latestStakingStartTime))
+	serviceIDs := []networks.ServiceID{}
+	for bootServiceID := range castedNetwork.GetAllBootServiceIDs() {
+		serviceIDs = append(serviceIDs, bootServiceID)
+	}
+	for i := range test.ClockOffsets {
+		serviceIDs = append(serviceIDs, getSkewedServiceID(i))
+	}
+	clients := make(map[networks.ServiceID]*apis.Client, len(serviceIDs))
+	for _, serviceID := range serviceIDs {
+		client, err := castedNetwork.GetCaminoClient(serviceID)
+		if err != nil {
+			context.Fatal(stacktrace.Propagate(err, "Failed to get client for node %v.", serviceID))
+		}
+		clients[serviceID] = client
+	}
+	if err := helpers.WaitForAgreement(func() error {
+		return verifySameValidators(clients, skewedNodeIDs)
+	}); err != nil {
+		context.Fatal(stacktrace.Propagate(err, "The nodes don't agree on the validator set."))
+	}
+	logrus.Infof("All nodes agree that the skewed nodes %v are validating.", skewedNodeIDs)
+}
+
+// GetNetworkLoader implements the Kurtosis Test interface
+func (test StakingNetworkClockSkewTest) GetNetworkLoader() (networks.NetworkLoader, error) {
+	serviceConfigs := make(map[networks.ConfigurationID]caminoNetwork.TestCaminoNetworkServiceConfig)
+	desiredServices := make(map[networks.ServiceID]networks.ConfigurationID)
+	for i, clockOffset := range test.ClockOffsets {
+		configID := networks.ConfigurationID(skewedNodeConfigIDPrefix + strconv.Itoa(i))
+		serviceConfigs[configID] = *caminoNetwork.NewTestCaminoNetworkServiceConfig(
+			true,
+			caminoService.DEBUG,
+			test.ClockSkewImageName,
+			2,
+			2,
+			2*time.Second,
+			make(map[string]string),
+		).WithClockOffset(clockOffset)
+		desiredServices[getSkewedServiceID(i)] = configID
+	}
+	return caminoNetwork.NewTestCaminoNetworkLoader(
+		true,
+		test.ImageName,
+		caminoService.DEBUG,
+		2,
+		2,
+		0,
+		2*time.Second,
+		serviceConfigs,
+		desiredServices,
+	)
+}
+
+// GetExecutionTimeout implements the Kurtosis Test interface
+func (test StakingNetworkClockSkewTest) GetExecutionTimeout() time.Duration {
+	return 10 * time.Minute
+}
+
+// GetSetupBuffer implements the Kurtosis Test interface
+func (test StakingNetworkClockSkewTest) GetSetupBuffer() time.Duration {
+	return 4 * time.Minute
+}
+
+// ================ Helper functions =========================
+func getSkewedServiceID(index int) networks.ServiceID {
+	return networks.ServiceID(skewedNodeServiceIDPrefix + strconv.Itoa(index))
+}
+
+/*
+Moves a stake's worth of the funder's funds to a new P Chain address, returning the address's private key so that a
+	skewed node can stake with it through its own keystore
+*/
+func fundStakerAddress(funder *helpers.RPCWorkFlowRunner, funderClient *apis.Client) (string, error) {
+	ctx := context.Background()
+	pChainAddress, err := funderClient.PChainAPI().CreateAddress(ctx, funder.User())
+	if err != nil {
+		return "", stacktrace.Propagate(err, "Failed to create the staker's P Chain address")
+	}
+	if err := funder.TransferAvaXChainToPChain(pChainAddress, seedAmount); err != nil {
+		return "", stacktrace.Propagate(err, "Failed to fund the staker's P Chain address")
+	}
+	privateKey, err := funderClient.PChainAPI().ExportKey(ctx, funder.User(), pChainAddress)
+	if err != nil {
+		return "", stacktrace.Propagate(err, "Failed to export the key of the staker's P Chain address")
+	}
+	return privateKey, nil
+}
+
+/*
+Has the skewed node stake itself with a start time just before the earliest its clock allows, which it must reject, and
+	then with one just after it, which it must accept; returns the node's ID and the start time of its validation period
+*/
+func (test StakingNetworkClockSkewTest) stakeAcrossBoundary(
+	staker *helpers.RPCWorkFlowRunner,
+	skewedClient *apis.Client,
+	stakerPrivateKey string,
+	clockOffset time.Duration) (string, time.Time, error) {
+	ctx := context.Background()
+	if err := helpers.NewKeystoreWorkFlowRunner(skewedClient).CreateUser(staker.User()); err != nil {
+		return "", time.Time{}, stacktrace.Propagate(err, "Failed to create the staker's user")
+	}
+	pChainAddress, err := skewedClient.PChainAPI().ImportKey(ctx, staker.User(), stakerPrivateKey)
+	if err != nil {
+		return "", time.Time{}, stacktrace.Propagate(err, "Failed to import the staker's key")
+	}
+	nodeID, err := skewedClient.InfoAPI().GetNodeID(ctx)
+	if err != nil {
+		return "", time.Time{}, stacktrace.Propagate(err, "Failed to get the node's ID")
+	}
+	// The funds were moved through another node, which the skewed node may not have caught up with yet
+	if err := helpers.WaitForAgreement(func() error {
+		return verifyPChainBalanceAtLeast(skewedClient, pChainAddress, stakeAmount)
+	}); err != nil {
+		return "", time.Time{}, stacktrace.Propagate(err, "The node didn't see the staker's funds")
+	}
+
+	tooSoonStartTime := time.Now().Add(clockOffset).Add(minStakingDelay).Add(-test.BoundaryMargin)
+	_, err = staker.AddValidatorToPrimaryNetworkWithStartTime(nodeID, pChainAddress, stakeAmount, tooSoonStartTime)
+	if err == nil {
+		return "", time.Time{}, stacktrace.NewError(
+			"The node accepted start time %v, which is %v earlier than its clock allows",
+			tooSoonStartTime,
+			test.BoundaryMargin)
+	}
+	if !strings.Contains(err.Error(), startTimeTooSoonErrorPrefix) {
+		return "", time.Time{}, stacktrace.Propagate(err, "The node rejected start time %v for another reason than it being too soon", tooSoonStartTime)
+	}
+
+	stakingStartTime := time.Now().Add(clockOffset).Add(minStakingDelay).Add(test.BoundaryMargin)
+	if _, err := staker.AddValidatorToPrimaryNetworkWithStartTime(nodeID, pChainAddress, stakeAmount, stakingStartTime); err != nil {
+		return "", time.Time{}, stacktrace.Propagate(
+			err,
+			"The node rejected start time %v, which is %v later than its clock allows",
+			stakingStartTime,
+			test.BoundaryMargin)
+	}
+	return nodeID, stakingStartTime, nil
+}
+
+func verifyPChainBalanceAtLeast(client *apis.Client, pChainAddress string, minBalance uint64) error {
+	balance, err := client.PChainAPI().GetBalance(context.Background(), []string{pChainAddress})
+	if err != nil {
+		return stacktrace.Propagate(err, "Could not get the balance of %v", pChainAddress)
+	}
+	if uint64(balance.Unlocked) < minBalance {
+		return stacktrace.NewError("Address %v has an unlocked balance of %v, less than %v", pChainAddress, balance.Unlocked, minBalance)
+	}
+	return nil
+}
+
+/*
+Checks that all the nodes have the same current validators, the same P Chain height and timestamp, and that all the
+	expected validators are among them
+*/
+func verifySameValidators(clients map[networks.ServiceID]*apis.Client, expectedValidatorIDs []string) error {
+	var referenceServiceID networks.ServiceID
+	var referenceValidators []string
+	var referenceHeight uint64
+	var referenceTimestamp time.Time
+	for serviceID, client := range clients {
+		validators, err := helpers.GetCurrentValidatorIDs(client)
+		if err != nil {
+			return stacktrace.Propagate(err, "Could not get the current validators of %v", serviceID)
+		}
+		height, err := client.PChainAPI().GetHeight(context.Background())
+		if err != nil {
+			return stacktrace.Propagate(err, "Could not get the P Chain height of %v", serviceID)
+		}
+		timestamp, err := client.PChainAPI().GetTimestamp(context.Background())
+		if err != nil {
+			return stacktrace.Propagate(err, "Could not get the P Chain timestamp of %v", serviceID)
+		}
+		if referenceValidators == nil {
+			referenceServiceID, referenceValidators, referenceHeight, referenceTimestamp = serviceID, validators, height, timestamp
+			continue
+		}
+		if height != referenceHeight || !timestamp.Equal(referenceTimestamp) {
+			return stacktrace.NewError(
+				"Node %v is at P Chain height %v with timestamp %v, but node %v is at height %v with timestamp %v",
+				serviceID,
+				height,
+				timestamp,
+				referenceServiceID,
+				referenceHeight,
+				referenceTimestamp)
+		}
+		if strings.Join(validators, ",") != strings.Join(referenceValidators, ",") {
+			return stacktrace.NewError("Node %v has current validators %v, but node %v has %v", serviceID, validators, referenceServiceID, referenceValidators)
+		}
+	}
+	for _, expectedValidatorID := range expectedValidatorIDs {
+		idx := sort.SearchStrings(referenceValidators, expectedValidatorID)
+		if idx == len(referenceValidators) || referenceValidators[idx] != expectedValidatorID {
+			return stacktrace.NewError("Node %v isn't among the current validators %v", expectedValidatorID, referenceValidators)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,369 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package compatibility
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kurtosis-tech/kurtosis-go/lib/networks"
+	"github.com/kurtosis-tech/kurtosis-go/lib/testsuite"
+
+	caminoNetwork "github.com/chain4travel/camino-testing/camino/networks"
+	caminoService "github.com/chain4travel/camino-testing/camino/services"
+	"github.com/chain4travel/camino-testing/camino_client/apis"
+	"github.com/chain4travel/camino-testing/testsuite/helpers"
+	"github.com/chain4travel/camino-testing/testsuite/verifier"
+	"github.com/chain4travel/caminogo/api"
+	"github.com/chain4travel/caminogo/ids"
+	"github.com/chain4travel/caminogo/utils/constants"
+	"github.com/palantir/stacktrace"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	joiningNodeConfigID  networks.ConfigurationID = "joining-config"
+	joiningNodeServiceID networks.ServiceID       = "joining-node"
+	genesisUsername                               = "genesis"
+	genesisPassword                               = "MyNameIs!Jeff"
+	joiningUsername                               = "joining_camino"
+	joiningPassword                               = "test34test!23"
+	seedAmount                                    = uint64(50000000000000)
+	stakeAmount                                   = uint64(30000000000000)
+
+	networkAcceptanceTimeoutRatio = 0.3
+)
+
+// Marks a check that couldn't be run because a check it depends on failed
+var errNotChecked = errors.New("not checked, because a check it depends on failed")
+
+// StakingNetworkCompatibilityMatrixTest checks a single cell of the compatibility matrix: it boots a staking network
+// whose boot nodes run one image, joins a node running another image to it, and reports whether the two versions peer,
+// bootstrap, agree on state and stake together, recording the report next to those of the other cells on the suite
+// execution volume. The test fails if any of them doesn't hold.
+type StakingNetworkCompatibilityMatrixTest struct {
+	ctx              context.Context
+	BootImageName    string
+	JoiningImageName string
+}
+
+func NewStakingNetworkCompatibilityMatrixTest(bootImageName string, joiningImageName string) StakingNetworkCompatibilityMatrixTest {
+	return StakingNetworkCompatibilityMatrixTest{
+		ctx:              context.Background(),
+		BootImageName:    bootImageName,
+		JoiningImageName: joiningImageName,
+	}
+}
+
+// Run implements the Kurtosis Test interface
+func (test StakingNetworkCompatibilityMatrixTest) Run(network networks.Network, context testsuite.TestContext) {
+	castedNetwork := network.(caminoNetwork.TestCaminoNetwork)
+	networkAcceptanceTimeout := time.Duration(networkAcceptanceTimeoutRatio * float64(test.GetExecutionTimeout().Nanoseconds()))
+	report := &compatibilityReport{
+		bootImageName:    test.BootImageName,
+		joiningImageName: test.JoiningImageName,
+	}
+
+	// ============================= BOOTSTRAP =============================================
+	logrus.Infof("Joining a node running %v to a network of %v boot nodes...", test.JoiningImageName, test.BootImageName)
+	availabilityChecker, err := castedNetwork.AddService(joiningNodeConfigID, joiningNodeServiceID)
+	if err != nil {
+		context.Fatal(stacktrace.Propagate(err, "Failed to add the joining node to the network."))
+	}
+	report.bootstrapErr = availabilityChecker.WaitForStartup()
+	if report.bootstrapErr != nil {
+		// Nothing else can be checked against a node that's not up
+		report.peerErr = errNotChecked
+		report.stateErr = errNotChecked
+		report.stakeErr = errNotChecked
+		report.log()
+		test.record(castedNetwork, report, context)
+		context.Fatal(stacktrace.Propagate(report.bootstrapErr, "The joining node didn't bootstrap; %v", report))
+	}
+	joiningClient, err := castedNetwork.GetCaminoClient(joiningNodeServiceID)
+	if err != nil {
+		context.Fatal(stacktrace.Propagate(err, "Failed to get the joining node's client."))
+	}
+	joiningNodeID, err := joiningClient.InfoAPI().GetNodeID(test.ctx)
+	if err != nil {
+		context.Fatal(stacktrace.Propagate(err, "Failed to get the joining node's ID."))
+	}
+	bootClients := map[networks.ServiceID]*apis.Client{}
+	bootNodeIDs := map[networks.ServiceID]string{}
+	for bootServiceID := range castedNetwork.GetAllBootServiceIDs() {
+		bootClient, err := castedNetwork.GetCaminoClient(bootServiceID)
+		if err != nil {
+			context.Fatal(stacktrace.Propagate(err, "Failed to get client for boot node %v.", bootServiceID))
+		}
+		bootNodeID, err := bootClient.InfoAPI().GetNodeID(test.ctx)
+		if err != nil {
+			context.Fatal(stacktrace.Propagate(err, "Failed to get the node ID of boot node %v.", bootServiceID))
+		}
+		bootClients[bootServiceID] = bootClient
+		bootNodeIDs[bootServiceID] = bootNodeID
+	}
+
+	// ============================= PEER ==================================================
+	report.peerErr = helpers.WaitForAgreement(func() error {
+		return test.verifyPeered(joiningClient, joiningNodeID, bootClients, bootNodeIDs)
+	})
+
+	// ============================= AGREE ON STATE ========================================
+	genesisServiceID := caminoNetwork.GetBootServiceID(0)
+	genesisClient := helpers.NewRPCWorkFlowRunner(
+		bootClients[genesisServiceID],
+		api.UserPass{Username: genesisUsername, Password: genesisPassword},
+		networkAcceptanceTimeout)
+	joiningRunner := helpers.NewRPCWorkFlowRunner(
+		joiningClient,
+		api.UserPass{Username: joiningUsername, Password: joiningPassword},
+		networkAcceptanceTimeout)
+	joiningXChainAddress, joiningPChainAddress, err := joiningRunner.CreateDefaultAddresses()
+	if err != nil {
+		context.Fatal(stacktrace.Propagate(err, "Could not create addresses on the joining node."))
+	}
+	report.stateErr = test.verifyAgreeOnState(genesisClient, joiningRunner, joiningXChainAddress, bootClients[genesisServiceID], joiningClient)
+
+	// ============================= STAKE TOGETHER ========================================
+	if report.stateErr != nil {
+		report.stakeErr = errNotChecked
+	} else {
+		report.stakeErr = test.verifyStakeTogether(joiningRunner, joiningPChainAddress, joiningNodeID, bootClients)
+	}
+
+	report.log()
+	test.record(castedNetwork, report, context)
+	if !report.isCompatible() {
+		context.Fatal(stacktrace.NewError("Images aren't compatible; %v", report))
+	}
+}
+
+// GetNetworkLoader implements the Kurtosis Test interface
+func (test StakingNetworkCompatibilityMatrixTest) GetNetworkLoader() (networks.NetworkLoader, error) {
+	serviceConfigs := map[networks.ConfigurationID]caminoNetwork.TestCaminoNetworkServiceConfig{
+		joiningNodeConfigID: *caminoNetwork.NewTestCaminoNetworkServiceConfig(
+			true,
+			caminoService.DEBUG,
+			test.JoiningImageName,
+			2,
+			2,
+			2*time.Second,
+			make(map[string]string),
+		),
+	}
+	logrus.Debugf("Boot Image Name: %s", test.BootImageName)
+	logrus.Debugf("Joining Image Name: %s", test.JoiningImageName)
+
+	return caminoNetwork.NewTestCaminoNetworkLoader(
+		true,
+		test.BootImageName,
+		caminoService.DEBUG,
+		2,
+		2,
+		0,
+		2*time.Second,
+		serviceConfigs,
+		make(map[networks.ServiceID]networks.ConfigurationID),
+	)
+}
+
+// GetExecutionTimeout implements the Kurtosis Test interface
+func (test StakingNetworkCompatibilityMatrixTest) GetExecutionTimeout() time.Duration {
+	return 8 * time.Minute
+}
+
+// GetSetupBuffer implements the Kurtosis Test interface
+func (test StakingNetworkCompatibilityMatrixTest) GetSetupBuffer() time.Duration {
+	return 4 * time.Minute
+}
+
+// ================ Helper functions =========================
+/*
+Records the report on the suite execution volume, with the reports of the other cells of the matrix
+*/
+func (test StakingNetworkCompatibilityMatrixTest) record(
+	network caminoNetwork.TestCaminoNetwork,
+	report *compatibilityReport,
+	context testsuite.TestContext) {
+	suiteExecutionDirpath, err := network.GetSuiteExecutionDirpath()
+	if err != nil {
+		context.Fatal(stacktrace.Propagate(err, "Failed to find where to record the report; %v", report))
+	}
+	tableFilepath, err := recordCompatibilityResult(suiteExecutionDirpath, newCompatibilityResult(*report))
+	if err != nil {
+		context.Fatal(stacktrace.Propagate(err, "Failed to record the report; %v", report))
+	}
+	logrus.Infof("Updated the table of the compatibility matrix at %v", tableFilepath)
+}
+
+/*
+Verifies that the joining node and the boot nodes are all peered with each other
+*/
+func (test StakingNetworkCompatibilityMatrixTest) verifyPeered(
+	joiningClient *apis.Client,
+	joiningNodeID string,
+	bootClients map[networks.ServiceID]*apis.Client,
+	bootNodeIDs map[networks.ServiceID]string) error {
+	networkVerifier := verifier.NewNetworkStateVerifier()
+	allNodeIDs := map[string]bool{
+		joiningNodeID: true,
+	}
+	for _, bootNodeID := range bootNodeIDs {
+		allNodeIDs[bootNodeID] = true
+	}
+
+	for bootServiceID, bootClient := range bootClients {
+		if err := networkVerifier.VerifyExpectedPeers(bootServiceID, bootClient, allNodeIDs, len(allNodeIDs)-1, false); err != nil {
+			return stacktrace.Propagate(err, "Boot node %v isn't peered with the rest of the network", bootServiceID)
+		}
+	}
+	if err := networkVerifier.VerifyExpectedPeers(joiningNodeServiceID, joiningClient, allNodeIDs, len(allNodeIDs)-1, false); err != nil {
+		return stacktrace.Propagate(err, "Joining node isn't peered with the rest of the network")
+	}
+	return nil
+}
+
+/*
+Verifies that a transfer accepted by a boot node is seen by the joining node, and that both nodes are at the same P Chain
+	height afterwards
+*/
+func (test StakingNetworkCompatibilityMatrixTest) verifyAgreeOnState(
+	genesisClient *helpers.RPCWorkFlowRunner,
+	joiningRunner *helpers.RPCWorkFlowRunner,
+	joiningXChainAddress string,
+	bootClient *apis.Client,
+	joiningClient *apis.Client) error {
+	if _, err := genesisClient.ImportGenesisFunds(); err != nil {
+		return stacktrace.Propagate(err, "Failed to import genesis funds on the boot node")
+	}
+	if err := genesisClient.FundXChainAddresses([]string{joiningXChainAddress}, seedAmount); err != nil {
+		return stacktrace.Propagate(err, "Failed to fund the joining node's X Chain address through the boot node")
+	}
+	if err := helpers.WaitForAgreement(func() error {
+		return joiningRunner.VerifyXChainAVABalance(joiningXChainAddress, seedAmount)
+	}); err != nil {
+		return stacktrace.Propagate(err, "The joining node doesn't see the transfer the boot node accepted")
+	}
+	if err := helpers.WaitForAgreement(func() error {
+		bootHeight, err := bootClient.PChainAPI().GetHeight(test.ctx)
+		if err != nil {
+			return stacktrace.Propagate(err, "Failed to get the boot node's P Chain height")
+		}
+		joiningHeight, err := joiningClient.PChainAPI().GetHeight(test.ctx)
+		if err != nil {
+			return stacktrace.Propagate(err, "Failed to get the joining node's P Chain height")
+		}
+		if bootHeight != joiningHeight {
+			return stacktrace.NewError("Boot node is at P Chain height %v but the joining node is at %v", bootHeight, joiningHeight)
+		}
+		return nil
+	}); err != nil {
+		return stacktrace.Propagate(err, "The nodes don't agree on the P Chain")
+	}
+	return nil
+}
+
+/*
+Verifies that the joining node can become a validator through its own API, and that every boot node then counts it
+	among the validators
+*/
+func (test StakingNetworkCompatibilityMatrixTest) verifyStakeTogether(
+	joiningRunner *helpers.RPCWorkFlowRunner,
+	joiningPChainAddress string,
+	joiningNodeID string,
+	bootClients map[networks.ServiceID]*apis.Client) error {
+	if err := joiningRunner.TransferAvaXChainToPChain(joiningPChainAddress, seedAmount); err != nil {
+		return stacktrace.Propagate(err, "Failed to transfer the joining node's funds to the P Chain")
+	}
+	if err := joiningRunner.AddValidatorToPrimaryNetwork(joiningNodeID, joiningPChainAddress, stakeAmount); err != nil {
+		return stacktrace.Propagate(err, "Failed to add the joining node as a validator")
+	}
+	for bootServiceID, bootClient := range bootClients {
+		currentValidators, err := bootClient.PChainAPI().GetCurrentValidators(test.ctx, constants.PrimaryNetworkID, []ids.ShortID{})
+		if err != nil {
+			return stacktrace.Propagate(err, "Could not get the current validators of boot node %v", bootServiceID)
+		}
+		found := false
+		for _, iValidator := range currentValidators {
+			validator, err := verifier.ToPrimaryValidator(iValidator)
+			if err != nil {
+				return stacktrace.Propagate(err, "An error occurred reading a current validator of boot node %v", bootServiceID)
+			}
+			if validator.NodeID == joiningNodeID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return stacktrace.NewError("Boot node %v doesn't count the joining node %v among the current validators", bootServiceID, joiningNodeID)
+		}
+	}
+	return nil
+}
+
+// compatibilityReport records the outcome of every check of a cell of the compatibility matrix, where a nil error means
+// the check passed
+type compatibilityReport struct {
+	bootImageName    string
+	joiningImageName string
+
+	peerErr      error
+	bootstrapErr error
+	stateErr     error
+	stakeErr     error
+}
+
+func (report compatibilityReport) isCompatible() bool {
+	return report.peerErr == nil && report.bootstrapErr == nil && report.stateErr == nil && report.stakeErr == nil
+}
+
+func (report compatibilityReport) String() string {
+	return fmt.Sprintf(
+		"boot image %v, joining image %v: peer=%v bootstrap=%v agree-on-state=%v stake-together=%v",
+		report.bootImageName,
+		report.joiningImageName,
+		describeCheck(report.peerErr),
+		describeCheck(report.bootstrapErr),
+		describeCheck(report.stateErr),
+		describeCheck(report.stakeErr))
+}
+
+// getChecks returns the outcome of every check, by check name
+func (report compatibilityReport) getChecks() map[string]error {
+	return map[string]error{
+		"peer":           report.peerErr,
+		"bootstrap":      report.bootstrapErr,
+		"agree-on-state": report.stateErr,
+		"stake-together": report.stakeErr,
+	}
+}
+
+func (report compatibilityReport) log() {
+	logrus.Infof("Compatibility report for %v", report)
+	for name, err := range report.getChecks() {
+		if err != nil {
+			logrus.Infof("Check '%v' failed because: %v", name, strings.SplitN(err.Error(), "\n", 2)[0])
+		}
+	}
+}
+
+func describeCheck(err error) string {
+	switch err {
+	case nil:
+		return "yes"
+	case errNotChecked:
+		return "unknown"
+	default:
+		return "no"
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package compatibility
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/chain4travel/camino-testing/testsuite/helpers"
+	"github.com/palantir/stacktrace"
+)
+
+const (
+	// The directory, at the root of the suite execution volume, where the results of the compatibility matrix are written
+	resultsDirname = "compatibility-matrix"
+
+	// The table of every result in the results directory, rewritten whenever a result is added
+	matrixTableFilename = "matrix.md"
+)
+
+// Characters that can't be part of a result's filename, which is made from the image names
+var unsafeFilenameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// CompatibilityResult is the outcome of every check of a cell of the compatibility matrix, each being "yes", "no" or
+// "unknown" if a check it depends on failed
+type CompatibilityResult struct {
+	BootImageName    string
+	JoiningImageName string
+
+	Peer          string
+	Bootstrap     string
+	AgreeOnState  string
+	StakeTogether string
+
+	// Why each failed check failed, by check name
+	Failures map[string]string
+}
+
+// ================ Helper functions =========================
+func newCompatibilityResult(report compatibilityReport) CompatibilityResult {
+	result := CompatibilityResult{
+		BootImageName:    report.bootImageName,
+		JoiningImageName: report.joiningImageName,
+		Peer:             describeCheck(report.peerErr),
+		Bootstrap:        describeCheck(report.bootstrapErr),
+		AgreeOnState:     describeCheck(report.stateErr),
+		StakeTogether:    describeCheck(report.stakeErr),
+		Failures:         map[string]string{},
+	}
+	for name, err := range report.getChecks() {
+		if err != nil && err != errNotChecked {
+			result.Failures[name] = strings.SplitN(err.Error(), "\n", 2)[0]
+		}
+	}
+	return result
+}
+
+/*
+Writes the result to the results directory at the root of the suite execution volume, and rewrites the table of all the
+	results there so that it includes it; returns the filepath of the table
+*/
+func recordCompatibilityResult(suiteExecutionDirpath string, result CompatibilityResult) (string, error) {
+	resultName := unsafeFilenameChars.ReplaceAllString(result.BootImageName, "_") +
+		"--" +
+		unsafeFilenameChars.ReplaceAllString(result.JoiningImageName, "_")
+	return helpers.RecordSuiteResult(
+		suiteExecutionDirpath,
+		resultsDirname,
+		resultName,
+		result,
+		matrixTableFilename,
+		func(resultsJSON [][]byte) (string, error) {
+			results := make([]CompatibilityResult, 0, len(resultsJSON))
+			for _, resultJSON := range resultsJSON {
+				result := CompatibilityResult{}
+				if err := json.Unmarshal(resultJSON, &result); err != nil {
+					return "", stacktrace.Propagate(err, "An error occurred parsing a result")
+				}
+				results = append(results, result)
+			}
+			return formatMatrixTable(results), nil
+		})
+}
+
+/*
+Formats the results as a Markdown table with a row per ordered pair of images, ordered by boot image and then joining
+	image, so that the pairs sharing a boot image are next to each other
+*/
+func formatMatrixTable(results []CompatibilityResult) string {
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].BootImageName != results[j].BootImageName {
+			return results[i].BootImageName < results[j].BootImageName
+		}
+		return results[i].JoiningImageName < results[j].JoiningImageName
+	})
+
+	builder := strings.Builder{}
+	builder.WriteString("| Boot image | Joining image | Peer | Bootstrap | Agree on state | Stake together |\n")
+	builder.WriteString("|---|---|---|---|---|---|\n")
+	for _, result := range results {
+		fmt.Fprintf(
+			&builder,
+			"| %v | %v | %v | %v | %v | %v |\n",
+			result.BootImageName,
+			result.JoiningImageName,
+			result.Peer,
+			result.Bootstrap,
+			result.AgreeOnState,
+			result.StakeTogether)
+	}
+	return builder.String()
+}
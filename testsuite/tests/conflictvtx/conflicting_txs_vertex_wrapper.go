@@ -15,6 +15,7 @@ import (
 	"github.com/kurtosis-tech/kurtosis-go/lib/networks"
 	"github.com/kurtosis-tech/kurtosis-go/lib/testsuite"
 
+	"github.com/chain4travel/camino-testing/camino/byzantine"
 	caminoNetwork "github.com/chain4travel/camino-testing/camino/networks"
 	caminoService "github.com/chain4travel/camino-testing/camino/services"
 	"github.com/palantir/stacktrace"
@@ -22,18 +23,16 @@ import (
 )
 
 const (
-	normalNodeConfigID          networks.ConfigurationID = "normal-config"
-	byzantineConfigID           networks.ConfigurationID = "byzantine-config"
-	byzantineUsername                                    = "byzantine_camino"
-	byzantinePassword                                    = "byzant1n3!"
-	byzantineBehavior                                    = "byzantine-behavior"
-	conflictingTxVertexBehavior                          = "conflicting-txs-vertex"
-	stakerUsername                                       = "staker_camino"
-	stakerPassword                                       = "test34test!23"
-	byzantineNodeServiceID                               = "byzantine-node"
-	normalNodeServiceID                                  = "virtuous-node"
-	seedAmount                                           = int64(50000000000000)
-	stakeAmount                                          = int64(30000000000000)
+	normalNodeConfigID     networks.ConfigurationID = "normal-config"
+	byzantineConfigID      networks.ConfigurationID = "byzantine-config"
+	byzantineUsername                               = "byzantine_camino"
+	byzantinePassword                               = "byzant1n3!"
+	stakerUsername                                  = "staker_camino"
+	stakerPassword                                  = "test34test!23"
+	byzantineNodeServiceID                          = "byzantine-node"
+	normalNodeServiceID                             = "virtuous-node"
+	seedAmount                                      = int64(50000000000000)
+	stakeAmount                                     = int64(30000000000000)
 )
 
 // StakingNetworkConflictingTxsVertexTest creates a byzantine node to issue conflicting transactions into a single
@@ -107,7 +106,7 @@ func getByzantineNetworkLoader(desiredServices map[networks.ServiceID]networks.C
 			2,
 			2,
 			2*time.Second,
-			map[string]string{byzantineBehavior: conflictingTxVertexBehavior},
+			byzantine.ConflictingTxsVertexBehavior{}.GetCLIArgs(),
 		),
 	}
 	logrus.Debugf("Byzantine Image Name: %s", byzantineImageName)
@@ -119,7 +118,7 @@ func getByzantineNetworkLoader(desiredServices map[networks.ServiceID]networks.C
 		caminoService.DEBUG,
 		2,
 		2,
-		1000000,
+		hardcodedTxsFee,
 		2*time.Second,
 		serviceConfigs,
 		desiredServices,
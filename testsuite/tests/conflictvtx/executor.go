@@ -15,6 +15,7 @@ import (
 	"time"
 
 	"github.com/chain4travel/camino-testing/camino_client/apis"
+	"github.com/chain4travel/camino-testing/testsuite/helpers"
 	"github.com/chain4travel/camino-testing/testsuite/tester"
 	"github.com/chain4travel/caminogo/snow/choices"
 	"github.com/chain4travel/caminogo/utils/formatting"
@@ -22,6 +23,9 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+// The fee, in nAVAX, that the hardcoded transactions burn, which the network has to be started with for them to be valid
+const hardcodedTxsFee = 1000000
+
 type executor struct {
 	ctx             context.Context
 	virtuousClient  *apis.Client
@@ -41,10 +45,18 @@ func NewConflictingTxsVertexExecutor(virtuousClient, byzantineClient *apis.Clien
 func (e *executor) ExecuteTest() error {
 	byzantineXChainAPI := e.byzantineClient.XChainAPI()
 
+	fees, err := helpers.GetLedgerFees(e.virtuousClient)
+	if err != nil {
+		return stacktrace.Propagate(err, "Failed to get the fees the virtuous node charges.")
+	}
+	if fees.TxFee != hardcodedTxsFee {
+		return stacktrace.NewError("The hardcoded transactions burn a fee of %v, but the network charges %v", hardcodedTxsFee, fees.TxFee)
+	}
+
 	// TODO switch to test vectors or come up with method to reliably generate conflicting transactions
 	// how to create a conflicting transaction???
 	// test vector create asset tx and conflicting transactions
-	// Note: these hardcoded transactions are based on everest-deployment with a txFee of 1,000,000 nAVAX
+	// Note: these hardcoded transactions are based on everest-deployment with a txFee of [hardcodedTxsFee]
 
 	createAssetTx, err := formatting.Decode(formatting.CB58, "11111kH8MvvvKhHX48mhBwKNrd4iqNeWsUyXFuuXaaymx3TE5jQEGn4h2mn211eCZwckWeNyMGHMCuHFbSBUF3q6Dz3btR95g17xWe7QraryeR6zKmJi9YuH2cbAoDCVBzqHaHEDVY2mArzpbE2wLLcVRPqnrneB4K1EqjepXSzvLJumGhej6eGCvqfQSTegV9jPjQrcxzWSFDbbvP9e532NzxpP84P4Rhy7oC9R2ngMcz846xsso44YvGorT3gRNHBCVXqKvi1epJ6EsjmskGEFr9xaQm32kkUKf2KUeM9EiKzwn6DDBusAPoLAw97mdjEuoxogue5xvwo4bHDQaL8zsZ68Gm1ETLaFdc1qXwhR6Y6Pd6b4MRgJTEG4cF4dGz18RvheicgGrwQDuASY51xjM1ijeVjmwyXGoo4k248fVY3rLgRirtnGcVfsuAyxdcb4x7ZqcRDNAjhriQCEV9m7R2Bm7XLcL2FkuXNLHFbXgsLEZ8L7LiC5fsmf4")
 	if err != nil {
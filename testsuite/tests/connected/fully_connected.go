@@ -37,13 +37,18 @@ const (
 	networkAcceptanceTimeoutRatio                    = 0.3
 	nonBootValidatorServiceID     networks.ServiceID = "validator-service"
 	nonBootNonValidatorServiceID  networks.ServiceID = "non-validator-service"
+
+	peerPollInterval = 2 * time.Second
 )
 
 // StakingNetworkFullyConnectedTest adds nodes to the network and verifies that the network stays fully connected
 type StakingNetworkFullyConnectedTest struct {
-	ImageName           string
-	FullyConnectedDelay time.Duration
-	Verifier            verifier.NetworkStateVerifier
+	ImageName string
+	Verifier  verifier.NetworkStateVerifier
+
+	// How long the network gets to become fully connected, both at the start of the test and after the new staker is
+	// added, before the test fails
+	FullyConnectedDeadline time.Duration
 }
 
 // Run implements the Kurtosis Test interface
@@ -62,9 +67,12 @@ func (test StakingNetworkFullyConnectedTest) Run(network networks.Network, conte
 
 	allNodeIDs, allCaminoClients := getNodeIDsAndClients(test.Verifier.Ctx(), context, castedNetwork, allServiceIDs)
 	logrus.Infof("Verifying that the network is fully connected...")
-	if err := test.Verifier.VerifyNetworkFullyConnected(allServiceIDs, stakerIDs, allNodeIDs, allCaminoClients); err != nil {
+	initialTracker := verifier.NewFullyConnectedConvergenceTracker(allServiceIDs, stakerIDs, allNodeIDs, allCaminoClients, peerPollInterval)
+	initialReport, err := initialTracker.WaitForConvergence(test.FullyConnectedDeadline)
+	if err != nil {
 		context.Fatal(stacktrace.Propagate(err, "An error occurred verifying the network's state"))
 	}
+	initialReport.LogMetrics()
 	logrus.Infof("Network is fully connected.")
 
 	logrus.Infof("Adding additional staker to the network...")
@@ -77,11 +85,7 @@ func (test StakingNetworkFullyConnectedTest) Run(network networks.Network, conte
 		context.Fatal(stacktrace.Propagate(err, "Failed to add extra staker."))
 	}
 
-	logrus.Infof("Sleeping %v seconds before verifying that the network has fully connected to the new staker...", test.FullyConnectedDelay.Seconds())
-	// Give time for the new validator to propagate via gossip
-	time.Sleep(70 * time.Second)
-
-	logrus.Infof("Verifying that the network is fully connected...")
+	logrus.Infof("Waiting up to %v for the network to fully connect to the new staker...", test.FullyConnectedDeadline)
 	stakerIDs[nonBootValidatorServiceID] = true
 	/*
 		After gossip, we expect the peers list to look like:
@@ -89,9 +93,13 @@ func (test StakingNetworkFullyConnectedTest) Run(network networks.Network, conte
 		2) The validators will have ALL other nodes in the network (propagated via gossip)
 		3) The non-validators will have all the validators in the network (propagated via gossip)
 	*/
-	if err := test.Verifier.VerifyNetworkFullyConnected(allServiceIDs, stakerIDs, allNodeIDs, allCaminoClients); err != nil {
+	// The new validator propagates via gossip, so the peers are polled until they converge
+	gossipTracker := verifier.NewFullyConnectedConvergenceTracker(allServiceIDs, stakerIDs, allNodeIDs, allCaminoClients, peerPollInterval)
+	gossipReport, err := gossipTracker.WaitForConvergence(test.FullyConnectedDeadline)
+	if err != nil {
 		context.Fatal(stacktrace.Propagate(err, "An error occurred verifying that the network is fully connected after gossip"))
 	}
+	gossipReport.LogMetrics()
 	logrus.Infof("The network is fully connected.")
 }
 
@@ -0,0 +1,288 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package diskfaults
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kurtosis-tech/kurtosis-go/lib/networks"
+	"github.com/kurtosis-tech/kurtosis-go/lib/testsuite"
+
+	caminoNetwork "github.com/chain4travel/camino-testing/camino/networks"
+	caminoService "github.com/chain4travel/camino-testing/camino/services"
+	"github.com/chain4travel/camino-testing/camino_client/apis"
+	"github.com/chain4travel/camino-testing/testsuite/helpers"
+	"github.com/chain4travel/camino-testing/utils/random"
+	"github.com/palantir/stacktrace"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	faultyNodeConfigID     networks.ConfigurationID = "faulty-node-config"
+	faultyNodeServiceID    networks.ServiceID       = "faulty-node"
+	restartedNodeServiceID networks.ServiceID       = "restarted-node"
+	transferAmount                                  = uint64(1000000)
+
+	networkAcceptanceTimeout = 30 * time.Second
+
+	healthCallTimeout = 5 * time.Second
+
+	pollInterval = 2 * time.Second
+)
+
+// StakingNetworkDiskFaultTest adds a node that keeps its database on disk to a staking network, injects a fault into that
+// disk while the network accepts transfers, and checks that the node reports unhealthy. The fault is then cleared, and
+// the node must recover, restarted from its own database if it stopped, and agree with the boot nodes on the balances
+// the transfers made, before and after the fault.
+type StakingNetworkDiskFaultTest struct {
+	ImageName string
+	Fault     caminoService.DiskFault
+
+	// The number of transfers made before the fault, and again after the node recovers
+	NumTransfers int
+
+	// How long the node may take to report unhealthy once the fault is injected, while transfers keep being made
+	UnhealthyTimeout time.Duration
+
+	// How long the node may take to report healthy again once the fault is cleared
+	RecoveryTimeout time.Duration
+}
+
+// NewStakingNetworkDiskFaultTest creates a disk fault test of the given fault
+func NewStakingNetworkDiskFaultTest(imageName string, fault caminoService.DiskFault) StakingNetworkDiskFaultTest {
+	return StakingNetworkDiskFaultTest{
+		ImageName:        imageName,
+		Fault:            fault,
+		NumTransfers:     10,
+		UnhealthyTimeout: 2 * time.Minute,
+		RecoveryTimeout:  3 * time.Minute,
+	}
+}
+
+// Run implements the Kurtosis Test interface
+func (test StakingNetworkDiskFaultTest) Run(network networks.Network, context testsuite.TestContext) {
+	castedNetwork := network.(caminoNetwork.TestCaminoNetwork)
+	bootServiceID := caminoNetwork.GetBootServiceID(0)
+	bootClient, err := castedNetwork.GetCaminoClient(bootServiceID)
+	if err != nil {
+		context.Fatal(stacktrace.Propagate(err, "Failed to get client for boot node %v.", bootServiceID))
+	}
+	userRandom := random.NewRand("disk-faults")
+	funder := helpers.NewRPCWorkFlowRunner(bootClient, helpers.CreateRandomUser(userRandom), networkAcceptanceTimeout)
+	funderAddress, err := funder.ImportGenesisFunds()
+	if err != nil {
+		context.Fatal(stacktrace.Propagate(err, "Failed to import genesis funds on boot node %v.", bootServiceID))
+	}
+	recipient := helpers.NewRPCWorkFlowRunner(bootClient, helpers.CreateRandomUser(userRandom), networkAcceptanceTimeout)
+	recipientAddress, _, err := recipient.CreateDefaultAddresses()
+	if err != nil {
+		context.Fatal(stacktrace.Propagate(err, "Failed to create the recipient's address."))
+	}
+	checkedAddresses := []string{funderAddress, recipientAddress}
+
+	faultyClient, err := castedNetwork.GetCaminoClient(faultyNodeServiceID)
+	if err != nil {
+		context.Fatal(stacktrace.Propagate(err, "Failed to get client for the faulty node."))
+	}
+	if err := test.makeTransfers(funder, recipientAddress); err != nil {
+		context.Fatal(stacktrace.Propagate(err, "Failed to make the transfers before the fault."))
+	}
+	if err := helpers.WaitForAgreement(func() error { return verifySameBalances(bootClient, faultyClient, checkedAddresses) }); err != nil {
+		context.Fatal(stacktrace.Propagate(err, "The faulty node didn't agree with the boot node before the fault."))
+	}
+
+	// ============================= INJECT THE FAULT ======================================
+	closeWindow := castedNetwork.GetHealthMonitor().PermitUnhealthy(faultyNodeServiceID, restartedNodeServiceID)
+	defer closeWindow()
+	logrus.Infof("Injecting disk fault '%v' into the faulty node...", test.Fault)
+	if err := castedNetwork.InjectDiskFault(faultyNodeServiceID, test.Fault); err != nil {
+		context.Fatal(stacktrace.Propagate(err, "Failed to inject the disk fault."))
+	}
+	// The node only notices once it has something to write, so the network keeps accepting transfers meanwhile
+	reportedUnhealthy := false
+	deadline := time.Now().Add(test.UnhealthyTimeout)
+	for !reportedUnhealthy && time.Now().Before(deadline) {
+		txID, err := funder.SendAVAX(recipientAddress, transferAmount)
+		if err != nil {
+			context.Fatal(stacktrace.Propagate(err, "Failed to make a transfer while the fault was injected."))
+		}
+		if err := funder.AwaitXChainTxs(txID); err != nil {
+			context.Fatal(stacktrace.Propagate(err, "A transfer made while the fault was injected wasn't accepted."))
+		}
+		if isHealthy, reason := getLiveness(faultyClient); !isHealthy {
+			logrus.Infof("The faulty node reported unhealthy: %v", reason)
+			reportedUnhealthy = true
+		}
+	}
+	if !reportedUnhealthy {
+		context.Fatal(stacktrace.NewError("The faulty node didn't report unhealthy within %v of disk fault '%v'.", test.UnhealthyTimeout, test.Fault))
+	}
+
+	// ============================= CLEAR THE FAULT =======================================
+	logrus.Infof("Clearing the disk fault...")
+	if err := castedNetwork.ClearDiskFaults(faultyNodeServiceID); err != nil {
+		context.Fatal(stacktrace.Propagate(err, "Failed to clear the disk fault."))
+	}
+	recoveredServiceID, err := test.recover(castedNetwork, faultyClient)
+	if err != nil {
+		context.Fatal(stacktrace.Propagate(err, "The faulty node didn't recover from disk fault '%v'.", test.Fault))
+	}
+	recoveredClient, err := castedNetwork.GetCaminoClient(recoveredServiceID)
+	if err != nil {
+		context.Fatal(stacktrace.Propagate(err, "Failed to get client for recovered node %v.", recoveredServiceID))
+	}
+
+	// ============================= VERIFY STATE ==========================================
+	if err := test.makeTransfers(funder, recipientAddress); err != nil {
+		context.Fatal(stacktrace.Propagate(err, "Failed to make the transfers after the fault."))
+	}
+	if err := helpers.WaitForAgreement(func() error { return verifySameBalances(bootClient, recoveredClient, checkedAddresses) }); err != nil {
+		context.Fatal(stacktrace.Propagate(err, "Recovered node %v doesn't agree with the boot node.", recoveredServiceID))
+	}
+	logrus.Infof("Node %v recovered from disk fault '%v' and agrees with the boot node.", recoveredServiceID, test.Fault)
+}
+
+// GetNetworkLoader implements the Kurtosis Test interface
+func (test StakingNetworkDiskFaultTest) GetNetworkLoader() (networks.NetworkLoader, error) {
+	serviceConfigs := map[networks.ConfigurationID]caminoNetwork.TestCaminoNetworkServiceConfig{
+		faultyNodeConfigID: *caminoNetwork.NewTestCaminoNetworkServiceConfig(
+			true,
+			caminoService.DEBUG,
+			test.ImageName,
+			2,
+			2,
+			2*time.Second,
+			make(map[string]string),
+		).WithDatabaseType(caminoService.LevelDB).WithDiskFaultInjection(),
+	}
+	desiredServices := map[networks.ServiceID]networks.ConfigurationID{
+		faultyNodeServiceID: faultyNodeConfigID,
+	}
+	return caminoNetwork.NewTestCaminoNetworkLoader(
+		true,
+		test.ImageName,
+		caminoService.DEBUG,
+		2,
+		2,
+		0,
+		2*time.Second,
+		serviceConfigs,
+		desiredServices,
+	)
+}
+
+// GetExecutionTimeout implements the Kurtosis Test interface
+func (test StakingNetworkDiskFaultTest) GetExecutionTimeout() time.Duration {
+	return 10 * time.Minute
+}
+
+// GetSetupBuffer implements the Kurtosis Test interface
+func (test StakingNetworkDiskFaultTest) GetSetupBuffer() time.Duration {
+	return 4 * time.Minute
+}
+
+// ================ Helper functions =========================
+func (test StakingNetworkDiskFaultTest) makeTransfers(funder *helpers.RPCWorkFlowRunner, recipientAddress string) error {
+	for i := 0; i < test.NumTransfers; i++ {
+		txID, err := funder.SendAVAX(recipientAddress, transferAmount)
+		if err != nil {
+			return stacktrace.Propagate(err, "Failed to make transfer %v", i)
+		}
+		if err := funder.AwaitXChainTxs(txID); err != nil {
+			return stacktrace.Propagate(err, "Transfer %v wasn't accepted", i)
+		}
+	}
+	return nil
+}
+
+/*
+Waits for the faulty node to report healthy again or, if it stopped because of the fault, restarts it from its own
+
+	database and waits for that to; returns the ID of the service that recovered
+*/
+func (test StakingNetworkDiskFaultTest) recover(network caminoNetwork.TestCaminoNetwork, faultyClient *apis.Client) (networks.ServiceID, error) {
+	if _, err := faultyClient.InfoAPI().GetNodeID(context.Background()); err == nil {
+		logrus.Infof("The faulty node kept running; waiting for it to report healthy...")
+		if err := waitForHealthy(faultyClient, test.RecoveryTimeout); err != nil {
+			return "", stacktrace.Propagate(err, "The faulty node didn't report healthy once the fault was cleared")
+		}
+		return faultyNodeServiceID, nil
+	}
+
+	// A node that can't open its database, e.g. because the fault corrupted it, won't become healthy
+	logrus.Infof("The faulty node stopped; restarting it from its database...")
+	if err := network.RemoveService(faultyNodeServiceID); err != nil {
+		return "", stacktrace.Propagate(err, "Failed to remove the stopped node")
+	}
+	availabilityChecker, err := network.AddServiceFromDatabase(faultyNodeConfigID, restartedNodeServiceID, faultyNodeServiceID)
+	if err != nil {
+		return "", stacktrace.Propagate(err, "Failed to restart the node from its database")
+	}
+	if err := availabilityChecker.WaitForStartup(); err != nil {
+		return "", stacktrace.Propagate(err, "The node restarted from its database didn't start up")
+	}
+	restartedClient, err := network.GetCaminoClient(restartedNodeServiceID)
+	if err != nil {
+		return "", stacktrace.Propagate(err, "Failed to get client for the restarted node")
+	}
+	if err := waitForHealthy(restartedClient, test.RecoveryTimeout); err != nil {
+		return "", stacktrace.Propagate(err, "The node restarted from its database didn't report healthy")
+	}
+	return restartedNodeServiceID, nil
+}
+
+/*
+Asks the node whether it's live; a node that can't be reached isn't, and the reason says why
+*/
+func getLiveness(client *apis.Client) (bool, string) {
+	ctx, cancel := context.WithTimeout(context.Background(), healthCallTimeout)
+	defer cancel()
+	reply, err := client.HealthAPI().Liveness(ctx)
+	if err != nil {
+		return false, fmt.Sprintf("unreachable: %v", err)
+	}
+	if !reply.Healthy {
+		return false, fmt.Sprintf("failing checks: %v", reply.Checks)
+	}
+	return true, ""
+}
+
+func waitForHealthy(client *apis.Client, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		isHealthy, reason := getLiveness(client)
+		if isHealthy {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return stacktrace.NewError("The node was still unhealthy after %v: %v", timeout, reason)
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+func verifySameBalances(bootClient *apis.Client, client *apis.Client, addresses []string) error {
+	for _, address := range addresses {
+		bootBalance, err := bootClient.XChainAPI().GetBalance(context.Background(), address, helpers.AvaxAssetID, false)
+		if err != nil {
+			return stacktrace.Propagate(err, "Could not get the boot node's balance of %v", address)
+		}
+		balance, err := client.XChainAPI().GetBalance(context.Background(), address, helpers.AvaxAssetID, false)
+		if err != nil {
+			return stacktrace.Propagate(err, "Could not get the node's balance of %v", address)
+		}
+		if balance.Balance != bootBalance.Balance {
+			return stacktrace.NewError("The node has a balance of %v for %v, but the boot node has %v", balance.Balance, address, bootBalance.Balance)
+		}
+	}
+	return nil
+}
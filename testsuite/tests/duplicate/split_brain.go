@@ -0,0 +1,281 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package duplicate
+
+import (
+	"time"
+
+	"github.com/kurtosis-tech/kurtosis-go/lib/networks"
+	"github.com/kurtosis-tech/kurtosis-go/lib/testsuite"
+
+	caminoNetwork "github.com/chain4travel/camino-testing/camino/networks"
+	caminoService "github.com/chain4travel/camino-testing/camino/services"
+	"github.com/chain4travel/camino-testing/camino_client/apis"
+	"github.com/chain4travel/camino-testing/testsuite/helpers"
+	"github.com/chain4travel/camino-testing/testsuite/verifier"
+	"github.com/chain4travel/caminogo/api"
+	"github.com/palantir/stacktrace"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	bootNodeIdentityConfigID networks.ConfigurationID = "boot-node-identity-config"
+
+	firstDuplicateServiceID  networks.ServiceID = "duplicate-validator-1"
+	secondDuplicateServiceID networks.ServiceID = "duplicate-validator-2"
+	impostorServiceID        networks.ServiceID = "boot-node-impostor"
+
+	// The index of the genesis boot node whose identity is duplicated
+	duplicatedBootNodeIndex = 4
+
+	stakerUsername = "staker"
+	stakerPassword = "test34test!23"
+	funderUsername = "funder"
+	funderPassword = "fund3r!Camino"
+	seedAmount     = uint64(50000000000000)
+	stakeAmount    = uint64(30000000000000)
+	transferAmount = uint64(1000000)
+
+	networkAcceptanceTimeoutRatio = 0.3
+
+	holderPollInterval = 2 * time.Second
+)
+
+// DuplicateValidatorSplitBrainTest starts two nodes with the same node ID where that node ID is a validator, so that the
+// network is split over which of the two it talks to. It records which of the two each other node is peered with over
+// time, checks that consensus keeps progressing meanwhile, and times how long the network takes to settle on the remaining
+// node once the other is stopped.
+// If DuplicateBootNode is set, the duplicated identity is that of a genesis boot node; otherwise it's that of a newly
+// staked validator.
+type DuplicateValidatorSplitBrainTest struct {
+	ImageName string
+	Verifier  verifier.NetworkStateVerifier
+
+	// Whether to duplicate a genesis boot node's identity rather than a newly staked validator's
+	DuplicateBootNode bool
+
+	// How long to record which duplicate each node is peered with while both duplicates are up
+	ObservationPeriod time.Duration
+
+	// The number of X Chain transfers that must be accepted while both duplicates are up, and again after one is stopped
+	NumTransfers int
+
+	// How long every node gets to settle on the remaining duplicate once the other is stopped
+	EvictionDeadline time.Duration
+}
+
+func NewDuplicateValidatorSplitBrainTest(imageName string, duplicateBootNode bool) DuplicateValidatorSplitBrainTest {
+	return DuplicateValidatorSplitBrainTest{
+		ImageName:         imageName,
+		Verifier:          verifier.NewNetworkStateVerifier(),
+		DuplicateBootNode: duplicateBootNode,
+		ObservationPeriod: 90 * time.Second,
+		NumTransfers:      10,
+		EvictionDeadline:  2 * time.Minute,
+	}
+}
+
+// Run implements the Kurtosis Test interface
+func (test DuplicateValidatorSplitBrainTest) Run(network networks.Network, context testsuite.TestContext) {
+	castedNetwork := network.(caminoNetwork.TestCaminoNetwork)
+	networkAcceptanceTimeout := time.Duration(networkAcceptanceTimeoutRatio * float64(test.GetExecutionTimeout().Nanoseconds()))
+
+	var firstDuplicateID, secondDuplicateID networks.ServiceID
+	if test.DuplicateBootNode {
+		firstDuplicateID = caminoNetwork.GetBootServiceID(duplicatedBootNodeIndex)
+		secondDuplicateID = impostorServiceID
+	} else {
+		firstDuplicateID = firstDuplicateServiceID
+		secondDuplicateID = secondDuplicateServiceID
+	}
+
+	// Every node apart from the duplicates observes which duplicate it's peered with
+	observerIDs := map[networks.ServiceID]bool{vanillaNodeServiceID: true}
+	for bootServiceID := range castedNetwork.GetAllBootServiceIDs() {
+		if bootServiceID != firstDuplicateID {
+			observerIDs[bootServiceID] = true
+		}
+	}
+	_, observerClients := getNodeIDsAndClients(test.Verifier.Ctx(), context, castedNetwork, observerIDs)
+
+	if !test.DuplicateBootNode {
+		logrus.Infof("Adding service with ID %v and making it a validator...", firstDuplicateID)
+		firstDuplicateClient := test.addDuplicate(context, castedNetwork, sameCertConfigID, firstDuplicateID)
+		stakerClient := helpers.NewRPCWorkFlowRunner(
+			firstDuplicateClient,
+			api.UserPass{Username: stakerUsername, Password: stakerPassword},
+			networkAcceptanceTimeout)
+		if _, err := stakerClient.ImportGenesisFundsAndStartValidating(seedAmount, stakeAmount); err != nil {
+			context.Fatal(stacktrace.Propagate(err, "Failed to make service with ID %v a validator", firstDuplicateID))
+		}
+	}
+	firstDuplicateClient, err := castedNetwork.GetCaminoClient(firstDuplicateID)
+	if err != nil {
+		context.Fatal(stacktrace.Propagate(err, "An error occurred getting the Camino client for service with ID %v", firstDuplicateID))
+	}
+	duplicateNodeID, err := firstDuplicateClient.InfoAPI().GetNodeID(test.Verifier.Ctx())
+	if err != nil {
+		context.Fatal(stacktrace.Propagate(err, "Could not get node ID from service with ID %v", firstDuplicateID))
+	}
+
+	logrus.Infof("Adding service with ID %v, which duplicates validator node ID %v...", secondDuplicateID, duplicateNodeID)
+	secondDuplicateConfigID := sameCertConfigID
+	if test.DuplicateBootNode {
+		secondDuplicateConfigID = bootNodeIdentityConfigID
+	}
+	secondDuplicateClient := test.addDuplicate(context, castedNetwork, secondDuplicateConfigID, secondDuplicateID)
+	secondNodeID, err := secondDuplicateClient.InfoAPI().GetNodeID(test.Verifier.Ctx())
+	if err != nil {
+		context.Fatal(stacktrace.Propagate(err, "Could not get node ID from service with ID %v", secondDuplicateID))
+	}
+	if secondNodeID != duplicateNodeID {
+		context.Fatal(stacktrace.NewError("Service with ID %v got node ID %v rather than duplicating node ID %v", secondDuplicateID, secondNodeID, duplicateNodeID))
+	}
+
+	duplicateIPs := map[networks.ServiceID]string{}
+	for _, serviceID := range []networks.ServiceID{firstDuplicateID, secondDuplicateID} {
+		ipAddr, err := castedNetwork.GetServiceIPAddress(serviceID)
+		if err != nil {
+			context.Fatal(stacktrace.Propagate(err, "An error occurred getting the IP address of service with ID %v", serviceID))
+		}
+		duplicateIPs[serviceID] = ipAddr
+	}
+	tracker := verifier.NewDuplicatePeerTracker(duplicateNodeID, duplicateIPs, observerClients, holderPollInterval)
+
+	funderClient := helpers.NewRPCWorkFlowRunner(
+		observerClients[vanillaNodeServiceID],
+		api.UserPass{Username: funderUsername, Password: funderPassword},
+		networkAcceptanceTimeout)
+	funderAddress, err := funderClient.ImportGenesisFunds()
+	if err != nil {
+		context.Fatal(stacktrace.Propagate(err, "Failed to import genesis funds on service with ID %v", vanillaNodeServiceID))
+	}
+
+	logrus.Infof("Recording which duplicate each node is peered with for %v, while making %v transfers...", test.ObservationPeriod, test.NumTransfers)
+	timelineChan := make(chan verifier.DuplicatePeerTimeline, 1)
+	observeErrChan := make(chan error, 1)
+	go func() {
+		timeline, err := tracker.Observe(test.ObservationPeriod)
+		timelineChan <- timeline
+		observeErrChan <- err
+	}()
+	if err := funderClient.FundXChainAddresses(repeatAddress(funderAddress, test.NumTransfers), transferAmount); err != nil {
+		context.Fatal(stacktrace.Propagate(err, "Consensus didn't keep progressing while node ID %v was duplicated", duplicateNodeID))
+	}
+	logrus.Infof("Consensus kept progressing while node ID %v was duplicated", duplicateNodeID)
+	timeline := <-timelineChan
+	if err := <-observeErrChan; err != nil {
+		context.Fatal(stacktrace.Propagate(err, "An error occurred recording which duplicate each node is peered with"))
+	}
+	timeline.LogSummary()
+
+	logrus.Infof("Stopping service with ID %v, leaving only service with ID %v...", firstDuplicateID, secondDuplicateID)
+	if err := castedNetwork.RemoveService(firstDuplicateID); err != nil {
+		context.Fatal(stacktrace.Propagate(err, "Could not remove service with ID %v", firstDuplicateID))
+	}
+	if _, err := tracker.WaitForHolder(secondDuplicateID, test.EvictionDeadline); err != nil {
+		context.Fatal(stacktrace.Propagate(err, "The network didn't settle on the remaining duplicate"))
+	}
+	if err := funderClient.FundXChainAddresses(repeatAddress(funderAddress, test.NumTransfers), transferAmount); err != nil {
+		context.Fatal(stacktrace.Propagate(err, "Consensus didn't keep progressing after the duplicate was stopped"))
+	}
+	logrus.Infof("The network settled on service with ID %v and consensus kept progressing", secondDuplicateID)
+}
+
+// GetNetworkLoader implements the Kurtosis Test interface
+func (test DuplicateValidatorSplitBrainTest) GetNetworkLoader() (networks.NetworkLoader, error) {
+	serviceConfigs := map[networks.ConfigurationID]caminoNetwork.TestCaminoNetworkServiceConfig{
+		normalNodeConfigID: *caminoNetwork.NewTestCaminoNetworkServiceConfig(
+			true,
+			caminoService.DEBUG,
+			test.ImageName,
+			2,
+			2,
+			2*time.Second,
+			make(map[string]string),
+		),
+		sameCertConfigID: *caminoNetwork.NewTestCaminoNetworkServiceConfig(
+			false,
+			caminoService.DEBUG,
+			test.ImageName,
+			2,
+			2,
+			2*time.Second,
+			make(map[string]string),
+		),
+		bootNodeIdentityConfigID: *caminoNetwork.NewTestCaminoNetworkServiceConfig(
+			false,
+			caminoService.DEBUG,
+			test.ImageName,
+			2,
+			2,
+			2*time.Second,
+			make(map[string]string),
+		).WithBootNodeIdentity(duplicatedBootNodeIndex),
+	}
+	desiredServices := map[networks.ServiceID]networks.ConfigurationID{
+		vanillaNodeServiceID: normalNodeConfigID,
+	}
+	return caminoNetwork.NewTestCaminoNetworkLoader(
+		true,
+		test.ImageName,
+		caminoService.DEBUG,
+		2,
+		2,
+		0,
+		2*time.Second,
+		serviceConfigs,
+		desiredServices,
+	)
+}
+
+// GetExecutionTimeout implements the Kurtosis Test interface
+func (test DuplicateValidatorSplitBrainTest) GetExecutionTimeout() time.Duration {
+	return 10 * time.Minute
+}
+
+// GetSetupBuffer implements the Kurtosis Test interface
+func (test DuplicateValidatorSplitBrainTest) GetSetupBuffer() time.Duration {
+	return 6 * time.Minute
+}
+
+// ================ Helper functions ==================================
+/*
+Adds a duplicate node to the network, waits for it to start and returns its client
+*/
+func (test DuplicateValidatorSplitBrainTest) addDuplicate(
+	context testsuite.TestContext,
+	network caminoNetwork.TestCaminoNetwork,
+	configID networks.ConfigurationID,
+	serviceID networks.ServiceID) *apis.Client {
+	checker, err := network.AddService(configID, serviceID)
+	if err != nil {
+		context.Fatal(stacktrace.Propagate(err, "Failed to create service with ID %v", serviceID))
+	}
+	if err := checker.WaitForStartup(); err != nil {
+		context.Fatal(stacktrace.Propagate(err, "An error occurred waiting for service with ID %v to start", serviceID))
+	}
+	client, err := network.GetCaminoClient(serviceID)
+	if err != nil {
+		context.Fatal(stacktrace.Propagate(err, "An error occurred getting the Camino client for service with ID %v", serviceID))
+	}
+	return client
+}
+
+/*
+Builds a list of transfer recipients that sends every transfer back to the same address
+*/
+func repeatAddress(address string, numTimes int) []string {
+	result := make([]string, 0, numTimes)
+	for i := 0; i < numTimes; i++ {
+		result = append(result, address)
+	}
+	return result
+}
@@ -0,0 +1,184 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package fuzz
+
+import (
+	"sort"
+	"time"
+
+	"github.com/kurtosis-tech/kurtosis-go/lib/networks"
+	"github.com/kurtosis-tech/kurtosis-go/lib/testsuite"
+
+	caminoNetwork "github.com/chain4travel/camino-testing/camino/networks"
+	caminoService "github.com/chain4travel/camino-testing/camino/services"
+	"github.com/chain4travel/camino-testing/camino_client/apis"
+	"github.com/chain4travel/camino-testing/testsuite/helpers"
+	"github.com/chain4travel/camino-testing/utils/random"
+	"github.com/chain4travel/caminogo/api"
+	"github.com/chain4travel/caminogo/utils/units"
+	"github.com/palantir/stacktrace"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	stakerUsername = "staker"
+	stakerPassword = "test34test!23"
+
+	recipientUsername = "fuzz_recipient"
+	recipientPassword = "f4zz-r3cipient!pw"
+
+	transferAmount = uint64(1000000000)
+
+	networkAcceptanceTimeout = 30 * time.Second
+
+	healthPollInterval = time.Second
+)
+
+// StakingNetworkRPCFuzzTest sends malformed JSON-RPC bodies, params of the wrong type, oversized payloads and invalid tx
+// bytes to every API endpoint of every boot node, checking that each node answers them with errors and stays live and
+// bootstrapped throughout, and that the network still accepts txs afterwards
+type StakingNetworkRPCFuzzTest struct {
+	ImageName string
+
+	// How large the oversized payloads are
+	OversizedPayloadBytes int
+
+	// How long the nodes get to become live and bootstrapped before the fuzzing starts
+	BootstrapTimeout time.Duration
+}
+
+// NewStakingNetworkRPCFuzzTest creates an RPC fuzz test with the default payload size
+func NewStakingNetworkRPCFuzzTest(imageName string) StakingNetworkRPCFuzzTest {
+	return StakingNetworkRPCFuzzTest{
+		ImageName:             imageName,
+		OversizedPayloadBytes: 4 * units.MiB,
+		BootstrapTimeout:      time.Minute,
+	}
+}
+
+// Run implements the Kurtosis Test interface
+func (test StakingNetworkRPCFuzzTest) Run(network networks.Network, context testsuite.TestContext) {
+	castedNetwork := network.(caminoNetwork.TestCaminoNetwork)
+	fuzzRandom := random.NewRand("rpc-fuzz")
+
+	for _, serviceID := range getSortedBootServiceIDs(castedNetwork) {
+		client, err := castedNetwork.GetCaminoClient(serviceID)
+		if err != nil {
+			context.Fatal(stacktrace.Propagate(err, "Failed to get client for %v.", serviceID))
+		}
+		fuzzer := helpers.NewRPCFuzzer(client, fuzzRandom).WithOversizedPayloadBytes(test.OversizedPayloadBytes)
+		if err := waitForNotDegraded(fuzzer, test.BootstrapTimeout); err != nil {
+			context.Fatal(stacktrace.Propagate(err, "%v wasn't live and bootstrapped before the fuzzing.", serviceID))
+		}
+		cases, err := fuzzer.CreateCases(helpers.DefaultRPCFuzzEndpoints)
+		if err != nil {
+			context.Fatal(stacktrace.Propagate(err, "Failed to create the fuzz cases for %v.", serviceID))
+		}
+
+		logrus.Infof("Sending %v fuzz cases to %v...", len(cases), serviceID)
+		report, err := fuzzer.Run(cases)
+		if err != nil {
+			context.Fatal(stacktrace.Propagate(err, "%v didn't withstand the fuzzing.", serviceID))
+		}
+		logrus.Infof("%v rejected %v of %v fuzz cases and stayed live and bootstrapped.", serviceID, report.NumRejected, report.NumCases)
+	}
+
+	// The nodes may be live and bootstrapped yet have stopped making progress, which only a tx being accepted tells
+	logrus.Infof("Verifying that the network still accepts txs...")
+	client, err := castedNetwork.GetCaminoClient(caminoNetwork.GetBootServiceID(0))
+	if err != nil {
+		context.Fatal(stacktrace.Propagate(err, "Failed to get client for the first boot node."))
+	}
+	if err := verifyAcceptsTxs(client); err != nil {
+		context.Fatal(stacktrace.Propagate(err, "The network doesn't accept txs after the fuzzing."))
+	}
+	logrus.Infof("The network still accepts txs after the fuzzing.")
+}
+
+// GetNetworkLoader implements the Kurtosis Test interface
+func (test StakingNetworkRPCFuzzTest) GetNetworkLoader() (networks.NetworkLoader, error) {
+	return caminoNetwork.NewTestCaminoNetworkLoader(
+		true,
+		test.ImageName,
+		caminoService.DEBUG,
+		2,
+		2,
+		0,
+		2*time.Second,
+		make(map[networks.ConfigurationID]caminoNetwork.TestCaminoNetworkServiceConfig),
+		make(map[networks.ServiceID]networks.ConfigurationID),
+	)
+}
+
+// GetExecutionTimeout implements the Kurtosis Test interface
+func (test StakingNetworkRPCFuzzTest) GetExecutionTimeout() time.Duration {
+	return 5 * time.Minute
+}
+
+// GetSetupBuffer implements the Kurtosis Test interface
+func (test StakingNetworkRPCFuzzTest) GetSetupBuffer() time.Duration {
+	return 2 * time.Minute
+}
+
+// ================ Helper functions =========================
+func getSortedBootServiceIDs(network caminoNetwork.TestCaminoNetwork) []networks.ServiceID {
+	serviceIDStrs := []string{}
+	for serviceID := range network.GetAllBootServiceIDs() {
+		serviceIDStrs = append(serviceIDStrs, string(serviceID))
+	}
+	sort.Strings(serviceIDStrs)
+	result := make([]networks.ServiceID, 0, len(serviceIDStrs))
+	for _, serviceID := range serviceIDStrs {
+		result = append(result, networks.ServiceID(serviceID))
+	}
+	return result
+}
+
+/*
+Polls the node until it's live and bootstrapped on the X and P Chains, or until the timeout passes
+*/
+func waitForNotDegraded(fuzzer *helpers.RPCFuzzer, timeout time.Duration) error {
+	startTime := time.Now()
+	for {
+		err := fuzzer.VerifyNotDegraded()
+		if err == nil {
+			return nil
+		}
+		if time.Since(startTime) > timeout {
+			return stacktrace.Propagate(err, "The node still wasn't live and bootstrapped after %v", timeout)
+		}
+		time.Sleep(healthPollInterval)
+	}
+}
+
+/*
+Sends AVAX from the genesis funds to a new user of the node, and waits for the transfer to be accepted
+*/
+func verifyAcceptsTxs(client *apis.Client) error {
+	stakerUser := api.UserPass{Username: stakerUsername, Password: stakerPassword}
+	stakerRunner := helpers.NewRPCWorkFlowRunner(client, stakerUser, networkAcceptanceTimeout)
+	if _, err := stakerRunner.ImportGenesisFunds(); err != nil {
+		return stacktrace.Propagate(err, "Failed to import the genesis funds")
+	}
+	recipientUser := api.UserPass{Username: recipientUsername, Password: recipientPassword}
+	recipientRunner := helpers.NewRPCWorkFlowRunner(client, recipientUser, networkAcceptanceTimeout)
+	recipientAddress, _, err := recipientRunner.CreateDefaultAddresses()
+	if err != nil {
+		return stacktrace.Propagate(err, "Failed to create the recipient's address")
+	}
+	txID, err := stakerRunner.SendAVAX(recipientAddress, transferAmount)
+	if err != nil {
+		return stacktrace.Propagate(err, "Failed to send AVAX to the recipient")
+	}
+	if err := stakerRunner.AwaitXChainTxs(txID); err != nil {
+		return stacktrace.Propagate(err, "The transfer to the recipient wasn't accepted")
+	}
+	return stakerRunner.VerifyXChainAVABalance(recipientAddress, transferAmount)
+}
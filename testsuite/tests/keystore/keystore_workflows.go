@@ -0,0 +1,310 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package keystore
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/kurtosis-tech/kurtosis-go/lib/networks"
+	"github.com/kurtosis-tech/kurtosis-go/lib/testsuite"
+
+	caminoNetwork "github.com/chain4travel/camino-testing/camino/networks"
+	caminoService "github.com/chain4travel/camino-testing/camino/services"
+	"github.com/chain4travel/camino-testing/camino_client/apis"
+	"github.com/chain4travel/camino-testing/testsuite/helpers"
+	"github.com/chain4travel/caminogo/api"
+	"github.com/palantir/stacktrace"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	ownerUsername     = "keystore_owner"
+	ownerPassword     = "k3yst0re-0wner!pw"
+	recipientUsername = "keystore_recipient"
+	recipientPassword = "r3cipient-k3yst0re!pw"
+	wrongPassword     = "n0t-the-right!pw"
+
+	transferAmount = uint64(1000000000)
+
+	networkAcceptanceTimeoutRatio = 0.3
+
+	// The keystore rejects usernames and passwords longer than this
+	maxKeystoreFieldLen = 1024
+)
+
+// StakingNetworkKeystoreTest covers the keystore API of the nodes: creating, listing, exporting, importing and deleting
+// users, the password rules users are created with, and that a user can't spend another user's funds
+type StakingNetworkKeystoreTest struct {
+	ImageName string
+}
+
+// Run implements the Kurtosis Test interface
+func (test StakingNetworkKeystoreTest) Run(network networks.Network, context testsuite.TestContext) {
+	castedNetwork := network.(caminoNetwork.TestCaminoNetwork)
+	networkAcceptanceTimeout := time.Duration(networkAcceptanceTimeoutRatio * float64(test.GetExecutionTimeout().Nanoseconds()))
+	ownerUser := api.UserPass{Username: ownerUsername, Password: ownerPassword}
+	recipientUser := api.UserPass{Username: recipientUsername, Password: recipientPassword}
+
+	sourceClient, err := castedNetwork.GetCaminoClient(caminoNetwork.GetBootServiceID(0))
+	if err != nil {
+		context.Fatal(stacktrace.Propagate(err, "Failed to get the client of the source node"))
+	}
+	destinationClient, err := castedNetwork.GetCaminoClient(caminoNetwork.GetBootServiceID(1))
+	if err != nil {
+		context.Fatal(stacktrace.Propagate(err, "Failed to get the client of the destination node"))
+	}
+	sourceKeystore := helpers.NewKeystoreWorkFlowRunner(sourceClient)
+	destinationKeystore := helpers.NewKeystoreWorkFlowRunner(destinationClient)
+
+	// ==================================== CREATE USERS =======================================
+	logrus.Infof("Creating the owner of the genesis funds and a recipient user...")
+	ownerRunner := helpers.NewRPCWorkFlowRunner(sourceClient, ownerUser, networkAcceptanceTimeout)
+	genesisAddress, err := ownerRunner.ImportGenesisFunds()
+	if err != nil {
+		context.Fatal(stacktrace.Propagate(err, "Failed to create the owner user with the genesis funds"))
+	}
+	recipientRunner := helpers.NewRPCWorkFlowRunner(sourceClient, recipientUser, networkAcceptanceTimeout)
+	recipientAddress, _, err := recipientRunner.CreateDefaultAddresses()
+	if err != nil {
+		context.Fatal(stacktrace.Propagate(err, "Failed to create the recipient user"))
+	}
+	if err := verifyListedUsers(sourceKeystore, map[string]bool{ownerUsername: true, recipientUsername: true}); err != nil {
+		context.Fatal(stacktrace.Propagate(err, "The source node doesn't list the users that were created"))
+	}
+
+	// =================================== USER VALIDATION =====================================
+	logrus.Infof("Verifying that users that break the keystore's rules are rejected...")
+	if err := verifyCreationRejected(sourceClient, sourceKeystore); err != nil {
+		context.Fatal(stacktrace.Propagate(err, "The source node accepted a user that breaks the keystore's rules"))
+	}
+	if err := verifyCollisionRejected(sourceClient, sourceKeystore, ownerUser); err != nil {
+		context.Fatal(stacktrace.Propagate(err, "The source node accepted a user whose username is taken"))
+	}
+
+	// ===================================== ISOLATION =========================================
+	logrus.Infof("Verifying that users can't spend each other's funds...")
+	if err := verifyIsolated(sourceClient, ownerUser, recipientUser, genesisAddress, recipientAddress); err != nil {
+		context.Fatal(stacktrace.Propagate(err, "The users of the source node aren't isolated from each other"))
+	}
+
+	// ================================== EXPORT AND IMPORT ====================================
+	logrus.Infof("Moving the owner to the destination node...")
+	exportedOwner, err := sourceKeystore.ExportUser(ownerUser)
+	if err != nil {
+		context.Fatal(stacktrace.Propagate(err, "Failed to export the owner from the source node"))
+	}
+	wrongPasswordUser := api.UserPass{Username: ownerUsername, Password: wrongPassword}
+	if err := destinationKeystore.ImportUser(wrongPasswordUser, exportedOwner); err == nil {
+		context.Fatal(stacktrace.NewError("The destination node imported the owner with a password other than the one it was exported with"))
+	}
+	if err := destinationKeystore.ImportUser(ownerUser, exportedOwner); err != nil {
+		context.Fatal(stacktrace.Propagate(err, "Failed to import the owner into the destination node"))
+	}
+	if err := destinationKeystore.ImportUser(ownerUser, exportedOwner); err == nil {
+		context.Fatal(stacktrace.NewError("Importing the owner into the destination node a second time should have failed"))
+	}
+	if err := verifyListedUsers(destinationKeystore, map[string]bool{ownerUsername: true}); err != nil {
+		context.Fatal(stacktrace.Propagate(err, "The destination node doesn't list exactly the imported user"))
+	}
+	if err := verifySameAddresses(sourceClient, destinationClient, ownerUser); err != nil {
+		context.Fatal(stacktrace.Propagate(err, "The imported owner doesn't control the addresses it was exported with"))
+	}
+
+	logrus.Infof("Spending the owner's funds from the destination node...")
+	importedOwnerRunner := helpers.NewRPCWorkFlowRunner(destinationClient, ownerUser, networkAcceptanceTimeout)
+	txID, err := importedOwnerRunner.SendAVAX(recipientAddress, transferAmount)
+	if err != nil {
+		context.Fatal(stacktrace.Propagate(err, "Failed to send funds as the imported owner"))
+	}
+	if err := importedOwnerRunner.AwaitXChainTxs(txID); err != nil {
+		context.Fatal(stacktrace.Propagate(err, "The transfer of the imported owner wasn't accepted"))
+	}
+	if err := importedOwnerRunner.VerifyXChainAVABalance(recipientAddress, transferAmount); err != nil {
+		context.Fatal(stacktrace.Propagate(err, "The recipient didn't get the funds the imported owner sent"))
+	}
+
+	// ======================================== DELETION =======================================
+	logrus.Infof("Deleting the owner from the destination node...")
+	if err := destinationKeystore.DeleteUser(wrongPasswordUser); err == nil {
+		context.Fatal(stacktrace.NewError("The destination node deleted the owner although the password was wrong"))
+	}
+	if err := destinationKeystore.DeleteUser(ownerUser); err != nil {
+		context.Fatal(stacktrace.Propagate(err, "Failed to delete the owner from the destination node"))
+	}
+	if _, err := importedOwnerRunner.SendAVAX(recipientAddress, transferAmount); err == nil {
+		context.Fatal(stacktrace.NewError("The destination node spent the funds of the owner after it was deleted"))
+	}
+	// Deleting a user from one node leaves the other nodes' copies alone
+	if err := verifyListedUsers(sourceKeystore, map[string]bool{ownerUsername: true, recipientUsername: true}); err != nil {
+		context.Fatal(stacktrace.Propagate(err, "Deleting the owner from the destination node changed the users of the source node"))
+	}
+	logrus.Infof("The keystore API behaved as expected.")
+}
+
+// GetNetworkLoader implements the Kurtosis Test interface
+func (test StakingNetworkKeystoreTest) GetNetworkLoader() (networks.NetworkLoader, error) {
+	return caminoNetwork.NewTestCaminoNetworkLoader(
+		true,
+		test.ImageName,
+		caminoService.DEBUG,
+		2,
+		2,
+		0,
+		2*time.Second,
+		make(map[networks.ConfigurationID]caminoNetwork.TestCaminoNetworkServiceConfig),
+		make(map[networks.ServiceID]networks.ConfigurationID),
+	)
+}
+
+// GetExecutionTimeout implements the Kurtosis Test interface
+func (test StakingNetworkKeystoreTest) GetExecutionTimeout() time.Duration {
+	return 5 * time.Minute
+}
+
+// GetSetupBuffer implements the Kurtosis Test interface
+func (test StakingNetworkKeystoreTest) GetSetupBuffer() time.Duration {
+	return 2 * time.Minute
+}
+
+// ================ Helper functions =========================
+/*
+Verifies that the node lists exactly the users with the given usernames
+*/
+func verifyListedUsers(keystore *helpers.KeystoreWorkFlowRunner, expectedUsernames map[string]bool) error {
+	usernames, err := keystore.ListUsers()
+	if err != nil {
+		return stacktrace.Propagate(err, "Failed to list the users")
+	}
+	if len(usernames) != len(expectedUsernames) {
+		return stacktrace.NewError("Expected users %v but the node lists %v", expectedUsernames, usernames)
+	}
+	for _, username := range usernames {
+		if !expectedUsernames[username] {
+			return stacktrace.NewError("Expected users %v but the node lists %v", expectedUsernames, usernames)
+		}
+	}
+	return nil
+}
+
+/*
+Verifies that the node refuses to create users with passwords that are empty, too weak or too long, or with usernames
+
+	that are empty or too long, and that it doesn't list any of them after
+*/
+func verifyCreationRejected(client *apis.Client, keystore *helpers.KeystoreWorkFlowRunner) error {
+	invalidUsers := map[string]api.UserPass{
+		"empty password":    {Username: "empty_password", Password: ""},
+		"common password":   {Username: "common_password", Password: "password"},
+		"numeric password":  {Username: "numeric_password", Password: "12345678"},
+		"repeated password": {Username: "repeated_password", Password: "aaaaaaaaaaaa"},
+		"overlong password": {Username: "overlong_password", Password: strings.Repeat("x7!Q", maxKeystoreFieldLen/4+1)},
+		"empty username":    {Username: "", Password: recipientPassword},
+		"overlong username": {Username: strings.Repeat("u", maxKeystoreFieldLen+1), Password: recipientPassword},
+	}
+	for description, user := range invalidUsers {
+		if _, err := client.KeystoreAPI().CreateUser(context.Background(), user); err == nil {
+			return stacktrace.NewError("The node created a user with an invalid %v", description)
+		}
+		logrus.Debugf("The node rejected a user with an invalid %v", description)
+		if user.Username == "" {
+			continue
+		}
+		hasUser, err := keystore.HasUser(user.Username)
+		if err != nil {
+			return err
+		}
+		if hasUser {
+			return stacktrace.NewError("The node rejected a user with an invalid %v but lists it anyway", description)
+		}
+	}
+	return nil
+}
+
+/*
+Verifies that the user can't be created a second time, whatever the password: the keystore runner refuses to reuse its
+
+	username, and so does the node when asked directly
+*/
+func verifyCollisionRejected(client *apis.Client, keystore *helpers.KeystoreWorkFlowRunner, user api.UserPass) error {
+	if err := keystore.CreateUser(user); err == nil {
+		return stacktrace.NewError("Creating user %v a second time should have failed", user.Username)
+	}
+	collidingUser := api.UserPass{Username: user.Username, Password: recipientPassword}
+	if _, err := client.KeystoreAPI().CreateUser(context.Background(), collidingUser); err == nil {
+		return stacktrace.NewError("The node created user %v although it already exists", user.Username)
+	}
+	return nil
+}
+
+/*
+Verifies that neither user can spend from or export the keys of the other's address, and that the owner can't spend
+
+	with the wrong password
+*/
+func verifyIsolated(
+	client *apis.Client,
+	ownerUser api.UserPass,
+	recipientUser api.UserPass,
+	ownerAddress string,
+	recipientAddress string) error {
+	xChain := client.XChainAPI()
+	if _, err := xChain.Send(context.Background(), recipientUser, []string{ownerAddress}, "", transferAmount, helpers.AvaxAssetID, recipientAddress, ""); err == nil {
+		return stacktrace.NewError("The recipient spent funds from the owner's address")
+	}
+	if _, err := xChain.ExportKey(context.Background(), recipientUser, ownerAddress); err == nil {
+		return stacktrace.NewError("The recipient exported the key of the owner's address")
+	}
+	if _, err := xChain.ExportKey(context.Background(), ownerUser, recipientAddress); err == nil {
+		return stacktrace.NewError("The owner exported the key of the recipient's address")
+	}
+	wrongPasswordOwner := api.UserPass{Username: ownerUser.Username, Password: wrongPassword}
+	if _, err := xChain.Send(context.Background(), wrongPasswordOwner, nil, "", transferAmount, helpers.AvaxAssetID, recipientAddress, ""); err == nil {
+		return stacktrace.NewError("Funds were spent from the owner's address with the wrong password")
+	}
+	ownerAddresses, err := xChain.ListAddresses(context.Background(), ownerUser)
+	if err != nil {
+		return stacktrace.Propagate(err, "Failed to list the owner's addresses")
+	}
+	for _, address := range ownerAddresses {
+		if address == recipientAddress {
+			return stacktrace.NewError("The owner's addresses include the recipient's address %v", recipientAddress)
+		}
+	}
+	return nil
+}
+
+/*
+Verifies that the user has the same X Chain addresses on both nodes
+*/
+func verifySameAddresses(sourceClient *apis.Client, destinationClient *apis.Client, user api.UserPass) error {
+	sourceAddresses, err := sourceClient.XChainAPI().ListAddresses(context.Background(), user)
+	if err != nil {
+		return stacktrace.Propagate(err, "Failed to list the user's addresses on the source node")
+	}
+	destinationAddresses, err := destinationClient.XChainAPI().ListAddresses(context.Background(), user)
+	if err != nil {
+		return stacktrace.Propagate(err, "Failed to list the user's addresses on the destination node")
+	}
+	if len(sourceAddresses) != len(destinationAddresses) {
+		return stacktrace.NewError("The user has addresses %v on the source node but %v on the destination node", sourceAddresses, destinationAddresses)
+	}
+	destinationAddressSet := make(map[string]bool, len(destinationAddresses))
+	for _, address := range destinationAddresses {
+		destinationAddressSet[address] = true
+	}
+	for _, address := range sourceAddresses {
+		if !destinationAddressSet[address] {
+			return stacktrace.NewError("The user has addresses %v on the source node but %v on the destination node", sourceAddresses, destinationAddresses)
+		}
+	}
+	return nil
+}
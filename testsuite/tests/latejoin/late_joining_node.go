@@ -0,0 +1,375 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package latejoin
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/kurtosis-tech/kurtosis-go/lib/networks"
+	"github.com/kurtosis-tech/kurtosis-go/lib/testsuite"
+
+	caminoNetwork "github.com/chain4travel/camino-testing/camino/networks"
+	caminoService "github.com/chain4travel/camino-testing/camino/services"
+	"github.com/chain4travel/camino-testing/camino_client/apis"
+	"github.com/chain4travel/camino-testing/testsuite/helpers"
+	"github.com/chain4travel/caminogo/api"
+	"github.com/palantir/stacktrace"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	historyValidatorConfigID networks.ConfigurationID = "history-validator-config"
+	lateJoinerConfigID       networks.ConfigurationID = "late-joiner-config"
+	historyValidatorPrefix   string                   = "history-validator-"
+	lateJoinerServiceID      networks.ServiceID       = "late-joiner"
+	partialJoinerServiceID   networks.ServiceID       = "partial-joiner"
+	restartedJoinerServiceID networks.ServiceID       = "restarted-joiner"
+	funderUsername                                    = "funder_camino"
+	funderPassword                                    = "fund3r!Camino"
+	recipientUsername                                 = "recipient_camino"
+	recipientPassword                                 = "rec1pient!Camino"
+	seedAmount                                        = uint64(50000000000000)
+	stakeAmount                                       = uint64(30000000000000)
+	transferAmount                                    = uint64(1000000)
+	numRecipientAddresses                             = 5
+
+	networkAcceptanceTimeoutRatio = 0.3
+
+	bootstrapPollInterval = 1 * time.Second
+)
+
+// The chains whose bootstrap is timed, in the order a node bootstraps them
+var timedChainAliases = []string{"P", "X", "C"}
+
+// StakingNetworkLateJoiningNodeTest builds up history on the X and P Chains of a staking network, with many transfers and
+// validator additions, and then checks that a node joining afterwards bootstraps all of it and ends up agreeing with the
+// boot nodes on balances and on the validator set. The bootstrap of every chain is timed.
+// If RestartFromPartialSync is set, a joining node is first stopped partway through its bootstrap, and the node that
+// gets checked is restarted from a copy of its partially synced database.
+type StakingNetworkLateJoiningNodeTest struct {
+	ctx       context.Context
+	ImageName string
+
+	// The number of X Chain transfers to make before the node joins
+	NumTransfers int
+
+	// The number of nodes to add as validators before the node joins
+	NumValidatorAdditions int
+
+	// How long the joining node may take to bootstrap every chain
+	BootstrapTimeout time.Duration
+
+	// Whether to restart the bootstrap from a partially synced database rather than start it from scratch
+	RestartFromPartialSync bool
+}
+
+func NewStakingNetworkLateJoiningNodeTest(imageName string, restartFromPartialSync bool) StakingNetworkLateJoiningNodeTest {
+	return StakingNetworkLateJoiningNodeTest{
+		ctx:                    context.Background(),
+		ImageName:              imageName,
+		NumTransfers:           50,
+		NumValidatorAdditions:  2,
+		BootstrapTimeout:       3 * time.Minute,
+		RestartFromPartialSync: restartFromPartialSync,
+	}
+}
+
+// Run implements the Kurtosis Test interface
+func (test StakingNetworkLateJoiningNodeTest) Run(network networks.Network, context testsuite.TestContext) {
+	castedNetwork := network.(caminoNetwork.TestCaminoNetwork)
+	networkAcceptanceTimeout := time.Duration(networkAcceptanceTimeoutRatio * float64(test.GetExecutionTimeout().Nanoseconds()))
+
+	funderServiceID := caminoNetwork.GetBootServiceID(0)
+	funderClient, err := castedNetwork.GetCaminoClient(funderServiceID)
+	if err != nil {
+		context.Fatal(stacktrace.Propagate(err, "Failed to get client for boot node %v.", funderServiceID))
+	}
+	highLevelFunderClient := helpers.NewRPCWorkFlowRunner(
+		funderClient,
+		api.UserPass{Username: funderUsername, Password: funderPassword},
+		networkAcceptanceTimeout)
+	funderXChainAddress, err := highLevelFunderClient.ImportGenesisFunds()
+	if err != nil {
+		context.Fatal(stacktrace.Propagate(err, "Failed to import genesis funds on boot node %v.", funderServiceID))
+	}
+
+	// ============================= BUILD X CHAIN HISTORY =================================
+	logrus.Infof("Making %v X Chain transfers...", test.NumTransfers)
+	recipientAddresses, err := test.createRecipientAddresses(funderClient)
+	if err != nil {
+		context.Fatal(stacktrace.Propagate(err, "Failed to create the recipient addresses."))
+	}
+	for i := 0; i < test.NumTransfers; i++ {
+		recipientAddress := recipientAddresses[i%len(recipientAddresses)]
+		if err := highLevelFunderClient.FundXChainAddresses([]string{recipientAddress}, transferAmount); err != nil {
+			context.Fatal(stacktrace.Propagate(err, "Failed to make X Chain transfer %v.", i))
+		}
+	}
+
+	// ============================= BUILD P CHAIN HISTORY =================================
+	logrus.Infof("Adding %v validators...", test.NumValidatorAdditions)
+	funderPChainAddress, err := funderClient.PChainAPI().CreateAddress(test.ctx, highLevelFunderClient.User())
+	if err != nil {
+		context.Fatal(stacktrace.Propagate(err, "Failed to create funder P Chain address."))
+	}
+	if test.NumValidatorAdditions > 0 {
+		if err := highLevelFunderClient.TransferAvaXChainToPChain(funderPChainAddress, uint64(test.NumValidatorAdditions)*seedAmount); err != nil {
+			context.Fatal(stacktrace.Propagate(err, "Failed to fund the validator stakes."))
+		}
+	}
+	for i := 0; i < test.NumValidatorAdditions; i++ {
+		serviceID := networks.ServiceID(historyValidatorPrefix + strconv.Itoa(i))
+		availabilityChecker, err := castedNetwork.AddService(historyValidatorConfigID, serviceID)
+		if err != nil {
+			context.Fatal(stacktrace.Propagate(err, "Failed to add validator %v to the network.", serviceID))
+		}
+		if err := availabilityChecker.WaitForStartup(); err != nil {
+			context.Fatal(stacktrace.Propagate(err, "Failed to wait for startup of validator %v.", serviceID))
+		}
+		validatorClient, err := castedNetwork.GetCaminoClient(serviceID)
+		if err != nil {
+			context.Fatal(stacktrace.Propagate(err, "Failed to get client for validator %v.", serviceID))
+		}
+		validatorNodeID, err := validatorClient.InfoAPI().GetNodeID(test.ctx)
+		if err != nil {
+			context.Fatal(stacktrace.Propagate(err, "Could not get node ID of validator %v.", serviceID))
+		}
+		if err := highLevelFunderClient.AddValidatorToPrimaryNetwork(validatorNodeID, funderPChainAddress, stakeAmount); err != nil {
+			context.Fatal(stacktrace.Propagate(err, "Failed to add %v as a validator.", serviceID))
+		}
+		logrus.Infof("Added %v as a validator.", serviceID)
+	}
+
+	// ============================= JOIN LATE =============================================
+	joinerServiceID := lateJoinerServiceID
+	if test.RestartFromPartialSync {
+		logrus.Infof("Starting a node to stop partway through its bootstrap...")
+		if _, err := castedNetwork.AddService(lateJoinerConfigID, partialJoinerServiceID); err != nil {
+			context.Fatal(stacktrace.Propagate(err, "Failed to add the partially syncing node to the network."))
+		}
+		partialClient, err := castedNetwork.GetCaminoClient(partialJoinerServiceID)
+		if err != nil {
+			context.Fatal(stacktrace.Propagate(err, "Failed to get the partially syncing node's client."))
+		}
+		if err := test.waitForPartialSync(partialClient); err != nil {
+			context.Fatal(stacktrace.Propagate(err, "The partially syncing node didn't get partway through its bootstrap."))
+		}
+		if err := castedNetwork.RemoveService(partialJoinerServiceID); err != nil {
+			context.Fatal(stacktrace.Propagate(err, "Failed to stop the partially syncing node."))
+		}
+
+		logrus.Infof("Restarting the bootstrap from the partially synced database...")
+		if _, err := castedNetwork.AddServiceFromDatabase(lateJoinerConfigID, restartedJoinerServiceID, partialJoinerServiceID); err != nil {
+			context.Fatal(stacktrace.Propagate(err, "Failed to add a node from the partially synced database."))
+		}
+		joinerServiceID = restartedJoinerServiceID
+	} else {
+		logrus.Infof("Joining a node to the network...")
+		if _, err := castedNetwork.AddService(lateJoinerConfigID, lateJoinerServiceID); err != nil {
+			context.Fatal(stacktrace.Propagate(err, "Failed to add the late joining node to the network."))
+		}
+	}
+	joinerClient, err := castedNetwork.GetCaminoClient(joinerServiceID)
+	if err != nil {
+		context.Fatal(stacktrace.Propagate(err, "Failed to get the late joining node's client."))
+	}
+	bootstrapDurations, err := test.timeBootstrap(joinerClient)
+	if err != nil {
+		context.Fatal(stacktrace.Propagate(err, "The late joining node didn't bootstrap."))
+	}
+	for _, chainAlias := range timedChainAliases {
+		logrus.Infof("Late joining node bootstrapped the %v Chain after %v", chainAlias, bootstrapDurations[chainAlias])
+	}
+
+	// ============================= VERIFY STATE ==========================================
+	logrus.Infof("Verifying that the late joining node agrees with the boot nodes...")
+	checkedAddresses := append([]string{funderXChainAddress}, recipientAddresses...)
+	for bootServiceID := range castedNetwork.GetAllBootServiceIDs() {
+		bootClient, err := castedNetwork.GetCaminoClient(bootServiceID)
+		if err != nil {
+			context.Fatal(stacktrace.Propagate(err, "Failed to get client for boot node %v.", bootServiceID))
+		}
+		if err := helpers.WaitForAgreement(func() error {
+			return test.verifySameState(bootClient, joinerClient, checkedAddresses, funderPChainAddress)
+		}); err != nil {
+			context.Fatal(stacktrace.Propagate(err, "The late joining node doesn't agree with boot node %v.", bootServiceID))
+		}
+	}
+}
+
+// GetNetworkLoader implements the Kurtosis Test interface
+func (test StakingNetworkLateJoiningNodeTest) GetNetworkLoader() (networks.NetworkLoader, error) {
+	serviceConfigs := map[networks.ConfigurationID]caminoNetwork.TestCaminoNetworkServiceConfig{
+		historyValidatorConfigID: *caminoNetwork.NewTestCaminoNetworkServiceConfig(
+			true,
+			caminoService.DEBUG,
+			test.ImageName,
+			2,
+			2,
+			2*time.Second,
+			make(map[string]string),
+		),
+		lateJoinerConfigID: *caminoNetwork.NewTestCaminoNetworkServiceConfig(
+			true,
+			caminoService.DEBUG,
+			test.ImageName,
+			2,
+			2,
+			2*time.Second,
+			make(map[string]string),
+		),
+	}
+	return caminoNetwork.NewTestCaminoNetworkLoader(
+		true,
+		test.ImageName,
+		caminoService.DEBUG,
+		2,
+		2,
+		0,
+		2*time.Second,
+		serviceConfigs,
+		make(map[networks.ServiceID]networks.ConfigurationID),
+	)
+}
+
+// GetExecutionTimeout implements the Kurtosis Test interface
+func (test StakingNetworkLateJoiningNodeTest) GetExecutionTimeout() time.Duration {
+	return 15 * time.Minute
+}
+
+// GetSetupBuffer implements the Kurtosis Test interface
+func (test StakingNetworkLateJoiningNodeTest) GetSetupBuffer() time.Duration {
+	return 4 * time.Minute
+}
+
+// ================ Helper functions =========================
+/*
+Creates the X Chain addresses the history's transfers are sent to, under a user of their own so that their balances are
+	only changed by those transfers
+*/
+func (test StakingNetworkLateJoiningNodeTest) createRecipientAddresses(client *apis.Client) ([]string, error) {
+	recipientUser := api.UserPass{Username: recipientUsername, Password: recipientPassword}
+	if err := helpers.NewKeystoreWorkFlowRunner(client).CreateUser(recipientUser); err != nil {
+		return nil, stacktrace.Propagate(err, "Could not create the recipient user")
+	}
+	result := make([]string, 0, numRecipientAddresses)
+	for i := 0; i < numRecipientAddresses; i++ {
+		address, err := client.XChainAPI().CreateAddress(test.ctx, recipientUser)
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "Could not create recipient address %v", i)
+		}
+		result = append(result, address)
+	}
+	return result, nil
+}
+
+/*
+Polls the node until it has bootstrapped every timed chain, returning how long after the start of polling each chain
+	reported being bootstrapped
+*/
+func (test StakingNetworkLateJoiningNodeTest) timeBootstrap(client *apis.Client) (map[string]time.Duration, error) {
+	startTime := time.Now()
+	deadline := startTime.Add(test.BootstrapTimeout)
+	result := map[string]time.Duration{}
+	for len(result) < len(timedChainAliases) {
+		if time.Now().After(deadline) {
+			return nil, stacktrace.NewError("Node didn't bootstrap every chain within %v; bootstrapped chains: %v", test.BootstrapTimeout, result)
+		}
+		for _, chainAlias := range timedChainAliases {
+			if _, found := result[chainAlias]; found {
+				continue
+			}
+			// The node's API isn't up for the first moments, which we treat the same as not being bootstrapped
+			if bootstrapped, err := client.InfoAPI().IsBootstrapped(test.ctx, chainAlias); err == nil && bootstrapped {
+				result[chainAlias] = time.Since(startTime)
+			}
+		}
+		time.Sleep(bootstrapPollInterval)
+	}
+	return result, nil
+}
+
+/*
+Waits until the node is partway through its bootstrap, which is once it has bootstrapped the P Chain. If the node
+	bootstraps every chain before that's observed, its database is still a valid one to restart from, so that only gets
+	logged.
+*/
+func (test StakingNetworkLateJoiningNodeTest) waitForPartialSync(client *apis.Client) error {
+	deadline := time.Now().Add(test.BootstrapTimeout)
+	for {
+		if time.Now().After(deadline) {
+			return stacktrace.NewError("Node didn't bootstrap the P Chain within %v", test.BootstrapTimeout)
+		}
+		if bootstrapped, err := client.InfoAPI().IsBootstrapped(test.ctx, "P"); err == nil && bootstrapped {
+			break
+		}
+		time.Sleep(bootstrapPollInterval)
+	}
+	if bootstrapped, err := client.InfoAPI().IsBootstrapped(test.ctx, "X"); err == nil && bootstrapped {
+		logrus.Infof("Node had already bootstrapped the X Chain when it was stopped, so it will restart from a fully synced database")
+	}
+	return nil
+}
+
+/*
+Verifies that both nodes report the same X Chain balances for the given addresses, the same P Chain balance for the given
+	address and the same current validators
+*/
+func (test StakingNetworkLateJoiningNodeTest) verifySameState(
+	bootClient *apis.Client,
+	joinerClient *apis.Client,
+	xChainAddresses []string,
+	pChainAddress string) error {
+	for _, address := range xChainAddresses {
+		bootBalance, err := bootClient.XChainAPI().GetBalance(test.ctx, address, helpers.AvaxAssetID, false)
+		if err != nil {
+			return stacktrace.Propagate(err, "Failed to get the boot node's X Chain balance of %v", address)
+		}
+		joinerBalance, err := joinerClient.XChainAPI().GetBalance(test.ctx, address, helpers.AvaxAssetID, false)
+		if err != nil {
+			return stacktrace.Propagate(err, "Failed to get the joining node's X Chain balance of %v", address)
+		}
+		if bootBalance.Balance != joinerBalance.Balance {
+			return stacktrace.NewError("Boot node has an X Chain balance of %v for %v but the joining node has %v", bootBalance.Balance, address, joinerBalance.Balance)
+		}
+	}
+
+	bootPBalance, err := bootClient.PChainAPI().GetBalance(test.ctx, []string{pChainAddress})
+	if err != nil {
+		return stacktrace.Propagate(err, "Failed to get the boot node's P Chain balance of %v", pChainAddress)
+	}
+	joinerPBalance, err := joinerClient.PChainAPI().GetBalance(test.ctx, []string{pChainAddress})
+	if err != nil {
+		return stacktrace.Propagate(err, "Failed to get the joining node's P Chain balance of %v", pChainAddress)
+	}
+	if bootPBalance.Balance != joinerPBalance.Balance {
+		return stacktrace.NewError("Boot node has a P Chain balance of %v for %v but the joining node has %v", bootPBalance.Balance, pChainAddress, joinerPBalance.Balance)
+	}
+
+	bootValidators, err := helpers.GetCurrentValidatorIDs(bootClient)
+	if err != nil {
+		return stacktrace.Propagate(err, "Failed to get the boot node's current validators")
+	}
+	joinerValidators, err := helpers.GetCurrentValidatorIDs(joinerClient)
+	if err != nil {
+		return stacktrace.Propagate(err, "Failed to get the joining node's current validators")
+	}
+	if len(bootValidators) != len(joinerValidators) {
+		return stacktrace.NewError("Boot node has current validators %v but the joining node has %v", bootValidators, joinerValidators)
+	}
+	for i, nodeID := range bootValidators {
+		if joinerValidators[i] != nodeID {
+			return stacktrace.NewError("Boot node has current validators %v but the joining node has %v", bootValidators, joinerValidators)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package propagation
+
+import (
+	"sort"
+	"time"
+
+	"github.com/kurtosis-tech/kurtosis-go/lib/networks"
+	"github.com/kurtosis-tech/kurtosis-go/lib/testsuite"
+
+	caminoNetwork "github.com/chain4travel/camino-testing/camino/networks"
+	caminoService "github.com/chain4travel/camino-testing/camino/services"
+	"github.com/chain4travel/camino-testing/camino_client/apis"
+	"github.com/chain4travel/camino-testing/testsuite/helpers"
+	"github.com/chain4travel/camino-testing/testsuite/tests/bombard"
+	"github.com/chain4travel/camino-testing/testsuite/verifier"
+	"github.com/chain4travel/camino-testing/utils/random"
+	"github.com/chain4travel/caminogo/ids"
+	"github.com/palantir/stacktrace"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// What the output of the last tx is left with, so that the txs are valid even on a network that charges no fee
+	remainingAmount = uint64(1000000)
+
+	networkAcceptanceTimeout = 30 * time.Second
+
+	txStatusPollInterval = 50 * time.Millisecond
+)
+
+// StakingNetworkTxPropagationTest issues X Chain txs to one boot node and measures how long each takes to reach every
+// other boot node, logging the distribution of the propagation latencies. Once the txs are accepted, it issues some of
+// them again to every node and checks that no node takes them for new txs.
+type StakingNetworkTxPropagationTest struct {
+	ImageName string
+
+	// The number of txs issued, one after the other
+	NumTxs uint64
+
+	// How long each tx may take to reach every node
+	PropagationTimeout time.Duration
+}
+
+// NewStakingNetworkTxPropagationTest creates a tx propagation test with the default number of txs
+func NewStakingNetworkTxPropagationTest(imageName string) StakingNetworkTxPropagationTest {
+	return StakingNetworkTxPropagationTest{
+		ImageName:          imageName,
+		NumTxs:             20,
+		PropagationTimeout: 10 * time.Second,
+	}
+}
+
+// Run implements the Kurtosis Test interface
+func (test StakingNetworkTxPropagationTest) Run(network networks.Network, context testsuite.TestContext) {
+	castedNetwork := network.(caminoNetwork.TestCaminoNetwork)
+	issuerServiceID := caminoNetwork.GetBootServiceID(0)
+	issuerClient, err := castedNetwork.GetCaminoClient(issuerServiceID)
+	if err != nil {
+		context.Fatal(stacktrace.Propagate(err, "Failed to get client for issuing node %v.", issuerServiceID))
+	}
+
+	// ============================= CREATE THE TXS ========================================
+	workloadRunner, txs, txIDs, err := test.createWorkload(issuerClient)
+	if err != nil {
+		context.Fatal(stacktrace.Propagate(err, "Failed to create the txs to propagate."))
+	}
+
+	// ============================= MEASURE PROPAGATION ===================================
+	logrus.Infof("Issuing %v txs to %v and waiting for each to reach every other node...", len(txs), issuerServiceID)
+	tracker := verifier.NewTxPropagationTracker(issuerServiceID, castedNetwork.GetRunningCaminoClients(), txStatusPollInterval)
+	report, err := tracker.TrackTxs(txs, test.PropagationTimeout)
+	report.LogMetrics()
+	logSlowestNodes(report)
+	if err != nil {
+		context.Fatal(stacktrace.Propagate(err, "The txs didn't reach every node."))
+	}
+	for i, propagation := range report.Txs {
+		if propagation.TxID != txIDs[i] {
+			context.Fatal(stacktrace.NewError("Tx %v was issued as %v, but it was created as %v.", i, propagation.TxID, txIDs[i]))
+		}
+	}
+
+	// ============================= REISSUE ===============================================
+	if err := workloadRunner.AwaitXChainTxs(txIDs...); err != nil {
+		context.Fatal(stacktrace.Propagate(err, "The propagated txs weren't accepted."))
+	}
+	logrus.Infof("Reissuing accepted txs to every node...")
+	for _, i := range []int{0, len(txs) - 1} {
+		if err := tracker.VerifyReissueIgnored(txs[i], txIDs[i]); err != nil {
+			context.Fatal(stacktrace.Propagate(err, "A node took reissued tx %v for a new tx.", i))
+		}
+	}
+}
+
+// GetNetworkLoader implements the Kurtosis Test interface
+func (test StakingNetworkTxPropagationTest) GetNetworkLoader() (networks.NetworkLoader, error) {
+	return caminoNetwork.NewTestCaminoNetworkLoader(
+		true,
+		test.ImageName,
+		caminoService.DEBUG,
+		2,
+		2,
+		0,
+		2*time.Second,
+		make(map[networks.ConfigurationID]caminoNetwork.TestCaminoNetworkServiceConfig),
+		make(map[networks.ServiceID]networks.ConfigurationID),
+	)
+}
+
+// GetExecutionTimeout implements the Kurtosis Test interface
+func (test StakingNetworkTxPropagationTest) GetExecutionTimeout() time.Duration {
+	return 5 * time.Minute
+}
+
+// GetSetupBuffer implements the Kurtosis Test interface
+func (test StakingNetworkTxPropagationTest) GetSetupBuffer() time.Duration {
+	return 2 * time.Minute
+}
+
+// ================ Helper functions =========================
+/*
+Funds a new address on the issuing node with enough to send the txs, and creates them
+
+Returns:
+	A runner of the user that owns the address, and the txs with their IDs
+*/
+func (test StakingNetworkTxPropagationTest) createWorkload(client *apis.Client) (*helpers.RPCWorkFlowRunner, [][]byte, []ids.ID, error) {
+	userRandom := random.NewRand("tx-propagation")
+	fees, err := helpers.GetLedgerFees(client)
+	if err != nil {
+		return nil, nil, nil, stacktrace.Propagate(err, "Failed to get the fees the issuing node charges")
+	}
+	genesisRunner := helpers.NewRPCWorkFlowRunner(client, helpers.CreateRandomUser(userRandom), networkAcceptanceTimeout)
+	if _, err := genesisRunner.ImportGenesisFunds(); err != nil {
+		return nil, nil, nil, stacktrace.Propagate(err, "Failed to import the genesis funds")
+	}
+	workloadRunner := helpers.NewRPCWorkFlowRunner(client, helpers.CreateRandomUser(userRandom), networkAcceptanceTimeout)
+	workloadAddress, _, err := workloadRunner.CreateDefaultAddresses()
+	if err != nil {
+		return nil, nil, nil, stacktrace.Propagate(err, "Failed to create the workload's address")
+	}
+	amount := test.NumTxs*fees.TxFee + remainingAmount
+	if err := genesisRunner.FundXChainAddresses([]string{workloadAddress}, amount); err != nil {
+		return nil, nil, nil, stacktrace.Propagate(err, "Failed to fund the workload's address")
+	}
+	txs, txIDs, err := bombard.CreateConsecutiveTransactionsForAddress(client, workloadRunner, workloadAddress, test.NumTxs, amount, fees.TxFee)
+	if err != nil {
+		return nil, nil, nil, stacktrace.Propagate(err, "Failed to create the workload's transactions")
+	}
+	return workloadRunner, txs, txIDs, nil
+}
+
+/*
+Logs, per node, the longest any tx took to reach it, so that a node that's slow to get txs stands out
+*/
+func logSlowestNodes(report verifier.TxPropagationReport) {
+	slowestNodes := report.GetSlowestNodes()
+	serviceIDs := make([]string, 0, len(slowestNodes))
+	for serviceID := range slowestNodes {
+		serviceIDs = append(serviceIDs, string(serviceID))
+	}
+	sort.Strings(serviceIDs)
+	for _, serviceID := range serviceIDs {
+		logrus.Infof("The slowest tx reached %v after %v", serviceID, slowestNodes[networks.ServiceID(serviceID)])
+	}
+}
@@ -11,38 +11,31 @@ package spamchits
 
 import (
 	"context"
-	"strconv"
 	"time"
 
 	"github.com/kurtosis-tech/kurtosis-go/lib/networks"
 	"github.com/kurtosis-tech/kurtosis-go/lib/testsuite"
 
+	"github.com/chain4travel/camino-testing/camino/byzantine"
 	caminoNetwork "github.com/chain4travel/camino-testing/camino/networks"
 	caminoService "github.com/chain4travel/camino-testing/camino/services"
 	"github.com/chain4travel/camino-testing/testsuite/helpers"
+	"github.com/chain4travel/camino-testing/testsuite/verifier"
 	"github.com/chain4travel/caminogo/api"
 	"github.com/chain4travel/caminogo/ids"
-	"github.com/chain4travel/caminogo/vms/platformvm"
 	"github.com/palantir/stacktrace"
 	"github.com/sirupsen/logrus"
 )
 
 const (
-	normalNodeConfigID     networks.ConfigurationID = "normal-config"
-	byzantineConfigID      networks.ConfigurationID = "byzantine-config"
-	byzantineUsername                               = "byzantine_camino"
-	byzantinePassword                               = "byzant1n3!"
-	stakerUsername                                  = "staker_camino"
-	stakerPassword                                  = "test34test!23"
-	normalNodeServiceID    networks.ServiceID       = "normal-node"
-	byzantineNodePrefix    string                   = "byzantine-node-"
-	numberOfByzantineNodes                          = 4
-	seedAmount                                      = uint64(50000000000000)
-	stakeAmount                                     = uint64(30000000000000)
+	normalNodeConfigID  networks.ConfigurationID = "normal-config"
+	stakerUsername                               = "staker_camino"
+	stakerPassword                               = "test34test!23"
+	normalNodeServiceID networks.ServiceID       = "normal-node"
+	seedAmount                                   = uint64(50000000000000)
+	stakeAmount                                  = uint64(30000000000000)
 
 	networkAcceptanceTimeoutRatio = 0.3
-	byzantineBehavior             = "byzantine-behavior"
-	chitSpammerBehavior           = "chit-spammer"
 )
 
 // StakingNetworkUnrequestedChitSpammerTest tests that a node is able to continue to work normally
@@ -68,37 +61,14 @@ func NewStakingNetworkUnrequestedChitSpammerTest(
 func (test StakingNetworkUnrequestedChitSpammerTest) Run(network networks.Network, context testsuite.TestContext) {
 	castedNetwork := network.(caminoNetwork.TestCaminoNetwork)
 	networkAcceptanceTimeout := time.Duration(networkAcceptanceTimeoutRatio * float64(test.GetExecutionTimeout().Nanoseconds()))
+	testStartTime := time.Now()
 
-	// ============= ADD SET OF BYZANTINE NODES AS VALIDATORS ON THE NETWORK ===================
-	logrus.Infof("Adding byzantine chit spammer nodes as validators...")
-	for i := 0; i < numberOfByzantineNodes; i++ {
-		byzClient, err := castedNetwork.GetCaminoClient(networks.ServiceID(byzantineNodePrefix + strconv.Itoa(i)))
-		if err != nil {
-			context.Fatal(stacktrace.Propagate(err, "Failed to get byzantine client."))
-		}
-		highLevelByzClient := helpers.NewRPCWorkFlowRunner(
-			byzClient,
-			api.UserPass{Username: byzantineUsername, Password: byzantinePassword},
-			networkAcceptanceTimeout)
-		_, err = highLevelByzClient.ImportGenesisFundsAndStartValidating(seedAmount, stakeAmount)
-		if err != nil {
-			context.Fatal(stacktrace.Propagate(err, "Failed add client as a validator."))
-		}
-		currentValidators, err := byzClient.PChainAPI().GetCurrentValidators(test.ctx, ids.Empty, []ids.ShortID{})
-		if err != nil {
-			context.Fatal(stacktrace.Propagate(err, "Could not get current validators."))
-		}
-		currentNumDelegators := 0
-		for _, iValidator := range currentValidators {
-			if validator, ok := iValidator.(platformvm.APIPrimaryValidator); !ok {
-				context.Fatal(stacktrace.Propagate(err, "Could not convert validator."))
-			} else {
-				currentNumDelegators += len(validator.Delegators)
-			}
-		}
-		logrus.Infof("Current Validators: %d, Delegators: %d", len(currentValidators), currentNumDelegators)
-	}
+	// We only make claims about the health of the honest nodes, so the byzantine ones may do as they please
+	closeByzantineWindow := castedNetwork.GetHealthMonitor().PermitUnhealthy(helpers.GetByzantineValidatorServiceIDs()...)
+	defer closeByzantineWindow()
 
+	// The byzantine nodes are already validating, as the network is restored from a snapshot taken after they were added
+	// as validators
 	// =================== ADD NORMAL NODE AS A VALIDATOR ON THE NETWORK =======================
 	logrus.Infof("Adding normal node as a staker...")
 	availabilityChecker, err := castedNetwork.AddService(normalNodeConfigID, normalNodeServiceID)
@@ -138,33 +108,38 @@ func (test StakingNetworkUnrequestedChitSpammerTest) Run(network networks.Networ
 	}
 	actualNumDelegators := 0
 	for _, iValidator := range actualValidators {
-		if validator, ok := iValidator.(platformvm.APIPrimaryValidator); !ok {
+		validator, err := verifier.ToPrimaryValidator(iValidator)
+		if err != nil {
 			context.Fatal(stacktrace.Propagate(err, "Could not convert validator."))
-		} else {
-			actualNumDelegators += len(validator.Delegators)
 		}
+		actualNumDelegators += len(validator.Delegators)
 	}
 	expectedNumDelegators := 0
 	logrus.Debugf("Number of current delegators: %d, expected number of delegators: %d", actualNumDelegators, expectedNumDelegators)
 	if actualNumDelegators != expectedNumDelegators {
 		context.AssertTrue(actualNumDelegators == expectedNumDelegators, stacktrace.NewError("Actual number of delegators, %v, != expected number of delegators, %v", actualNumDelegators, expectedNumDelegators))
 	}
+
+	logrus.Infof("Verifying that the honest nodes stayed healthy despite the byzantine nodes...")
+	honestServiceIDs := castedNetwork.GetAllBootServiceIDs()
+	honestServiceIDs[normalNodeServiceID] = true
+	if err := castedNetwork.GetHealthMonitor().VerifyStayedHealthy(honestServiceIDs, testStartTime); err != nil {
+		context.Fatal(stacktrace.Propagate(err, "Honest nodes became unhealthy while the network was spammed with chits"))
+	}
 }
 
 // GetNetworkLoader implements the Kurtosis Test interface
 func (test StakingNetworkUnrequestedChitSpammerTest) GetNetworkLoader() (networks.NetworkLoader, error) {
 	// Define normal node and byzantine node configurations
 	serviceConfigs := map[networks.ConfigurationID]caminoNetwork.TestCaminoNetworkServiceConfig{
-		byzantineConfigID: *caminoNetwork.NewTestCaminoNetworkServiceConfig(
+		helpers.ByzantineValidatorsConfigID: *caminoNetwork.NewTestCaminoNetworkServiceConfig(
 			true,
 			caminoService.DEBUG,
 			test.ByzantineImageName,
 			2,
 			2,
 			2*time.Second,
-			map[string]string{
-				byzantineBehavior: chitSpammerBehavior,
-			},
+			byzantine.ChitSpammerBehavior{}.GetCLIArgs(),
 		),
 		normalNodeConfigID: *caminoNetwork.NewTestCaminoNetworkServiceConfig(
 			true,
@@ -179,13 +154,13 @@ func (test StakingNetworkUnrequestedChitSpammerTest) GetNetworkLoader() (network
 
 	// Define the map from service->configuration for the network
 	serviceIDConfigMap := map[networks.ServiceID]networks.ConfigurationID{}
-	for i := 0; i < numberOfByzantineNodes; i++ {
-		serviceIDConfigMap[networks.ServiceID(byzantineNodePrefix+strconv.Itoa(i))] = byzantineConfigID
+	for _, byzantineServiceID := range helpers.GetByzantineValidatorServiceIDs() {
+		serviceIDConfigMap[byzantineServiceID] = helpers.ByzantineValidatorsConfigID
 	}
 	logrus.Debugf("Byzantine Image Name: %s", test.ByzantineImageName)
 	logrus.Debugf("Normal Image Name: %s", test.NormalImageName)
 
-	return caminoNetwork.NewTestCaminoNetworkLoader(
+	loader, err := caminoNetwork.NewTestCaminoNetworkLoader(
 		true,
 		test.NormalImageName,
 		caminoService.DEBUG,
@@ -196,16 +171,19 @@ func (test StakingNetworkUnrequestedChitSpammerTest) GetNetworkLoader() (network
 		serviceConfigs,
 		serviceIDConfigMap,
 	)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred creating the network loader")
+	}
+	return loader.WithSnapshot(helpers.NewByzantineValidatorsSnapshot()), nil
 }
 
 // GetExecutionTimeout implements the Kurtosis Test interface
 func (test StakingNetworkUnrequestedChitSpammerTest) GetExecutionTimeout() time.Duration {
-	// TODO drop this when the availabilityChecker doesn't have a sleep, because we spin up a *bunch* of byzantine
-	// nodes during test execution
-	return 10 * time.Minute
+	return 6 * time.Minute
 }
 
 // GetSetupBuffer implements the Kurtosis Test interface
 func (test StakingNetworkUnrequestedChitSpammerTest) GetSetupBuffer() time.Duration {
-	return 4 * time.Minute
+	// Covers adding the byzantine nodes as validators, for when this test is the one that takes the snapshot
+	return 10 * time.Minute
 }
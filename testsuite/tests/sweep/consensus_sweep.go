@@ -0,0 +1,251 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package sweep
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/kurtosis-tech/kurtosis-go/lib/networks"
+	"github.com/kurtosis-tech/kurtosis-go/lib/testsuite"
+
+	caminoNetwork "github.com/chain4travel/camino-testing/camino/networks"
+	caminoService "github.com/chain4travel/camino-testing/camino/services"
+	"github.com/chain4travel/camino-testing/camino_client/apis"
+	"github.com/chain4travel/camino-testing/testsuite/helpers"
+	"github.com/chain4travel/camino-testing/testsuite/tests/bombard"
+	"github.com/chain4travel/camino-testing/utils/random"
+	"github.com/chain4travel/caminogo/ids"
+	"github.com/palantir/stacktrace"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	sweepNodeConfigID          networks.ConfigurationID = "sweep-node-config"
+	sweepNodeServiceIDPrefix                            = "sweep-node-"
+	virtuousCommitThresholdArg                          = "snow-virtuous-commit-threshold"
+	rogueCommitThresholdArg                             = "snow-rogue-commit-threshold"
+	concurrentRepollsArg                                = "snow-concurrent-repolls"
+
+	// The number of polls the nodes run concurrently by default, which can't exceed the rogue commit threshold
+	defaultConcurrentRepolls = 4
+)
+
+// ConsensusParams are the consensus parameters that a StakingNetworkConsensusSweepTest runs its network with
+type ConsensusParams struct {
+	// The number of nodes in the network, of which the genesis stakers are the validators and the rest only follow
+	NumNodes int
+
+	// The Snow sample size (k)
+	SnowSampleSize int
+
+	// The Snow quorum size (alpha)
+	SnowQuorumSize int
+
+	// The number of consecutive successful polls that finalize a virtuous tx (beta1)
+	BetaVirtuous int
+
+	// The number of consecutive successful polls that finalize a rogue tx (beta2)
+	BetaRogue int
+}
+
+// Validate checks that a network can be started with the params, in the same way the nodes check them at startup
+func (params ConsensusParams) Validate() error {
+	numBootNodes := len(caminoNetwork.DefaultLocalNetGenesisConfig.Stakers)
+	switch {
+	case params.NumNodes < numBootNodes:
+		return stacktrace.NewError("The network can't have %v nodes, as it has %v boot nodes", params.NumNodes, numBootNodes)
+	case params.SnowQuorumSize <= params.SnowSampleSize/2 || params.SnowQuorumSize > params.SnowSampleSize:
+		return stacktrace.NewError("Quorum size %v must be more than half of sample size %v, and at most the sample size", params.SnowQuorumSize, params.SnowSampleSize)
+	case params.BetaVirtuous <= 0 || params.BetaRogue < params.BetaVirtuous:
+		return stacktrace.NewError("Beta virtuous %v must be positive, and at most beta rogue %v", params.BetaVirtuous, params.BetaRogue)
+	}
+	return nil
+}
+
+// String names the params compactly, e.g. for test names
+func (params ConsensusParams) String() string {
+	return fmt.Sprintf(
+		"n%d-k%d-alpha%d-beta%d-%d",
+		params.NumNodes,
+		params.SnowSampleSize,
+		params.SnowQuorumSize,
+		params.BetaVirtuous,
+		params.BetaRogue)
+}
+
+// StakingNetworkConsensusSweepTest starts a network with the given consensus params, sends a fixed string of consecutive
+// X Chain transactions through one of its nodes and records how long each took to be finalized and the resulting
+// throughput, adding them to the comparison table of all the params swept so far
+type StakingNetworkConsensusSweepTest struct {
+	ImageName string
+	Params    ConsensusParams
+
+	// The number of consecutive transactions in the workload
+	NumTxs uint64
+
+	TxFee uint64
+
+	// How long to wait for the next transaction of the workload to be finalized
+	AcceptanceTimeout time.Duration
+}
+
+// NewStakingNetworkConsensusSweepTest creates a sweep test of the given params with the default workload
+func NewStakingNetworkConsensusSweepTest(imageName string, params ConsensusParams) StakingNetworkConsensusSweepTest {
+	return StakingNetworkConsensusSweepTest{
+		ImageName:         imageName,
+		Params:            params,
+		NumTxs:            200,
+		TxFee:             1000000,
+		AcceptanceTimeout: 30 * time.Second,
+	}
+}
+
+// Run implements the Kurtosis Test interface
+func (test StakingNetworkConsensusSweepTest) Run(network networks.Network, context testsuite.TestContext) {
+	castedNetwork := network.(caminoNetwork.TestCaminoNetwork)
+	workloadServiceID := caminoNetwork.GetBootServiceID(0)
+	client, err := castedNetwork.GetCaminoClient(workloadServiceID)
+	if err != nil {
+		context.Fatal(stacktrace.Propagate(err, "Failed to get the client of %v", workloadServiceID))
+	}
+	acceptanceStream, err := castedNetwork.OpenAcceptanceStream(workloadServiceID, apis.XChain)
+	if err != nil {
+		context.Fatal(stacktrace.Propagate(err, "Failed to open the X Chain acceptance stream of %v", workloadServiceID))
+	}
+	defer acceptanceStream.Close()
+
+	txs, txIDs, err := test.createWorkload(client)
+	if err != nil {
+		context.Fatal(stacktrace.Propagate(err, "Failed to create the workload"))
+	}
+
+	logrus.Infof("Issuing %d consecutive transactions with consensus params %v...", len(txs), test.Params)
+	issueTimes, err := issueWorkload(client, txs, txIDs)
+	if err != nil {
+		context.Fatal(stacktrace.Propagate(err, "Failed to issue the workload"))
+	}
+	decided, err := acceptanceStream.WaitForDecisions(txIDs, test.AcceptanceTimeout)
+	if err != nil {
+		context.Fatal(stacktrace.Propagate(err, "Failed to wait for the transactions to be finalized"))
+	}
+
+	result := newConsensusSweepResult(test.Params, issueTimes, decided)
+	logrus.Infof("Consensus params %v: %v", test.Params, result)
+	suiteExecutionDirpath, err := castedNetwork.GetSuiteExecutionDirpath()
+	if err != nil {
+		context.Fatal(stacktrace.Propagate(err, "Failed to find where to record the result"))
+	}
+	tableFilepath, err := recordConsensusSweepResult(suiteExecutionDirpath, result)
+	if err != nil {
+		context.Fatal(stacktrace.Propagate(err, "Failed to record the result"))
+	}
+	logrus.Infof("Updated the comparison table of the consensus sweep at %v", tableFilepath)
+}
+
+// GetNetworkLoader implements the Kurtosis Test interface
+func (test StakingNetworkConsensusSweepTest) GetNetworkLoader() (networks.NetworkLoader, error) {
+	if err := test.Params.Validate(); err != nil {
+		return nil, stacktrace.Propagate(err, "Invalid consensus params %v", test.Params)
+	}
+	cliArgs := map[string]string{
+		virtuousCommitThresholdArg: strconv.Itoa(test.Params.BetaVirtuous),
+		rogueCommitThresholdArg:    strconv.Itoa(test.Params.BetaRogue),
+	}
+	if test.Params.BetaRogue < defaultConcurrentRepolls {
+		cliArgs[concurrentRepollsArg] = strconv.Itoa(test.Params.BetaRogue)
+	}
+
+	serviceConfigs := map[networks.ConfigurationID]caminoNetwork.TestCaminoNetworkServiceConfig{
+		sweepNodeConfigID: *caminoNetwork.NewTestCaminoNetworkServiceConfig(
+			true,
+			caminoService.INFO,
+			test.ImageName,
+			test.Params.SnowQuorumSize,
+			test.Params.SnowSampleSize,
+			2*time.Second,
+			cliArgs,
+		),
+	}
+	desiredServices := make(map[networks.ServiceID]networks.ConfigurationID)
+	numBootNodes := len(caminoNetwork.DefaultLocalNetGenesisConfig.Stakers)
+	for i := 0; i < test.Params.NumNodes-numBootNodes; i++ {
+		desiredServices[networks.ServiceID(sweepNodeServiceIDPrefix+strconv.Itoa(i))] = sweepNodeConfigID
+	}
+
+	loader, err := caminoNetwork.NewTestCaminoNetworkLoader(
+		true,
+		test.ImageName,
+		caminoService.INFO,
+		test.Params.SnowQuorumSize,
+		test.Params.SnowSampleSize,
+		test.TxFee,
+		2*time.Second,
+		serviceConfigs,
+		desiredServices,
+	)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "Failed to create the network loader")
+	}
+	return loader.WithBootNodeCLIArgs(cliArgs), nil
+}
+
+// GetExecutionTimeout implements the Kurtosis Test interface
+func (test StakingNetworkConsensusSweepTest) GetExecutionTimeout() time.Duration {
+	return 10 * time.Minute
+}
+
+// GetSetupBuffer implements the Kurtosis Test interface
+func (test StakingNetworkConsensusSweepTest) GetSetupBuffer() time.Duration {
+	// Setting up the nodes that aren't boot nodes takes longer the more there are
+	return 2*time.Minute + time.Duration(test.Params.NumNodes)*15*time.Second
+}
+
+// ================ Helper functions =========================
+/*
+Funds a new address on the workload node with enough to send the workload's transactions, and creates them
+*/
+func (test StakingNetworkConsensusSweepTest) createWorkload(client *apis.Client) ([][]byte, []ids.ID, error) {
+	userRandom := random.NewRand("consensus-sweep")
+	genesisRunner := helpers.NewRPCWorkFlowRunner(client, helpers.CreateRandomUser(userRandom), test.AcceptanceTimeout)
+	if _, err := genesisRunner.ImportGenesisFunds(); err != nil {
+		return nil, nil, stacktrace.Propagate(err, "Failed to import the genesis funds")
+	}
+	workloadRunner := helpers.NewRPCWorkFlowRunner(client, helpers.CreateRandomUser(userRandom), test.AcceptanceTimeout)
+	workloadAddress, _, err := workloadRunner.CreateDefaultAddresses()
+	if err != nil {
+		return nil, nil, stacktrace.Propagate(err, "Failed to create the workload's address")
+	}
+	amount := (test.NumTxs + 1) * test.TxFee
+	if err := genesisRunner.FundXChainAddresses([]string{workloadAddress}, amount); err != nil {
+		return nil, nil, stacktrace.Propagate(err, "Failed to fund the workload's address")
+	}
+	txs, txIDs, err := bombard.CreateConsecutiveTransactionsForAddress(client, workloadRunner, workloadAddress, test.NumTxs, amount, test.TxFee)
+	if err != nil {
+		return nil, nil, stacktrace.Propagate(err, "Failed to create the workload's transactions")
+	}
+	return txs, txIDs, nil
+}
+
+/*
+Issues the workload's transactions one after the other, returning when each was issued
+*/
+func issueWorkload(client *apis.Client, txs [][]byte, txIDs []ids.ID) (map[ids.ID]time.Time, error) {
+	issueTimes := make(map[ids.ID]time.Time, len(txs))
+	for i, tx := range txs {
+		issueTimes[txIDs[i]] = time.Now()
+		if _, err := client.XChainAPI().IssueTx(context.Background(), tx); err != nil {
+			return nil, stacktrace.Propagate(err, "Failed to issue transaction %d", i)
+		}
+	}
+	return issueTimes, nil
+}
@@ -0,0 +1,57 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package sweep
+
+// ConsensusSweepGrid is the values that a consensus sweep tries for each of the consensus params; every combination of
+// them that a network can be started with gets swept
+type ConsensusSweepGrid struct {
+	NumNodes        []int
+	SnowSampleSizes []int
+	SnowQuorumSizes []int
+	BetaVirtuous    []int
+	BetaRogue       []int
+}
+
+// DefaultConsensusSweepGrid spans the small sample sizes the other tests use up to the whole validator set, with betas
+// from well below to the defaults of the nodes
+var DefaultConsensusSweepGrid = ConsensusSweepGrid{
+	NumNodes:        []int{5, 8},
+	SnowSampleSizes: []int{2, 4},
+	SnowQuorumSizes: []int{2, 3, 4},
+	BetaVirtuous:    []int{5, 15},
+	BetaRogue:       []int{10, 20},
+}
+
+// GetParams returns every combination of the grid's values that a network can be started with, ordered by the values'
+// positions in the grid
+func (grid ConsensusSweepGrid) GetParams() []ConsensusParams {
+	result := []ConsensusParams{}
+	for _, numNodes := range grid.NumNodes {
+		for _, sampleSize := range grid.SnowSampleSizes {
+			for _, quorumSize := range grid.SnowQuorumSizes {
+				for _, betaVirtuous := range grid.BetaVirtuous {
+					for _, betaRogue := range grid.BetaRogue {
+						params := ConsensusParams{
+							NumNodes:       numNodes,
+							SnowSampleSize: sampleSize,
+							SnowQuorumSize: quorumSize,
+							BetaVirtuous:   betaVirtuous,
+							BetaRogue:      betaRogue,
+						}
+						if params.Validate() == nil {
+							result = append(result, params)
+						}
+					}
+				}
+			}
+		}
+	}
+	return result
+}
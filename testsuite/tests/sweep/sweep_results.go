@@ -0,0 +1,145 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package sweep
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/chain4travel/camino-testing/camino/ipcs"
+	"github.com/chain4travel/camino-testing/testsuite/helpers"
+	"github.com/chain4travel/caminogo/ids"
+	"github.com/palantir/stacktrace"
+)
+
+const (
+	// The directory, at the root of the suite execution volume, where the results of the sweep are written
+	resultsDirname = "consensus-sweep"
+
+	// The comparison table of every result in the results directory, rewritten whenever a result is added
+	comparisonTableFilename = "comparison.md"
+)
+
+// ConsensusSweepResult is how a network with the given consensus params performed on the sweep's workload
+type ConsensusSweepResult struct {
+	Params ConsensusParams
+
+	NumTxs int
+
+	// Statistics of the time from each transaction being issued to it being finalized
+	MedianLatency time.Duration
+	P95Latency    time.Duration
+	MaxLatency    time.Duration
+
+	// Transactions finalized per second, from the first being issued to the last being finalized
+	Throughput float64
+}
+
+func (result ConsensusSweepResult) String() string {
+	return fmt.Sprintf(
+		"%d txs finalized with median latency %v, p95 latency %v and max latency %v, at %.1f txs/second",
+		result.NumTxs,
+		result.MedianLatency,
+		result.P95Latency,
+		result.MaxLatency,
+		result.Throughput)
+}
+
+// ================ Helper functions =========================
+func newConsensusSweepResult(params ConsensusParams, issueTimes map[ids.ID]time.Time, decided []ipcs.AcceptedContainer) ConsensusSweepResult {
+	result := ConsensusSweepResult{
+		Params: params,
+		NumTxs: len(decided),
+	}
+	if len(decided) == 0 {
+		return result
+	}
+	latencies := make([]time.Duration, 0, len(decided))
+	firstIssueTime := decided[0].AcceptedAt
+	for _, container := range decided {
+		issueTime := issueTimes[container.ID]
+		latencies = append(latencies, container.AcceptedAt.Sub(issueTime))
+		if issueTime.Before(firstIssueTime) {
+			firstIssueTime = issueTime
+		}
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	result.MedianLatency = latencies[len(latencies)/2]
+	result.P95Latency = latencies[(len(latencies)*95)/100]
+	result.MaxLatency = latencies[len(latencies)-1]
+	if duration := decided[len(decided)-1].AcceptedAt.Sub(firstIssueTime); duration > 0 {
+		result.Throughput = float64(len(decided)) / duration.Seconds()
+	}
+	return result
+}
+
+/*
+Writes the result to the results directory at the root of the suite execution volume, and rewrites the comparison table
+	of all the results there so that it includes it; returns the filepath of the table
+*/
+func recordConsensusSweepResult(suiteExecutionDirpath string, result ConsensusSweepResult) (string, error) {
+	return helpers.RecordSuiteResult(
+		suiteExecutionDirpath,
+		resultsDirname,
+		result.Params.String(),
+		result,
+		comparisonTableFilename,
+		func(resultsJSON [][]byte) (string, error) {
+			results := make([]ConsensusSweepResult, 0, len(resultsJSON))
+			for _, resultJSON := range resultsJSON {
+				result := ConsensusSweepResult{}
+				if err := json.Unmarshal(resultJSON, &result); err != nil {
+					return "", stacktrace.Propagate(err, "An error occurred parsing a result")
+				}
+				results = append(results, result)
+			}
+			return formatComparisonTable(results), nil
+		})
+}
+
+/*
+Formats the results as a Markdown table, ordered by the params so that neighbouring rows differ in the fewest of them
+*/
+func formatComparisonTable(results []ConsensusSweepResult) string {
+	sort.Slice(results, func(i, j int) bool {
+		paramsI, paramsJ := results[i].Params, results[j].Params
+		keyI := []int{paramsI.NumNodes, paramsI.SnowSampleSize, paramsI.SnowQuorumSize, paramsI.BetaVirtuous, paramsI.BetaRogue}
+		keyJ := []int{paramsJ.NumNodes, paramsJ.SnowSampleSize, paramsJ.SnowQuorumSize, paramsJ.BetaVirtuous, paramsJ.BetaRogue}
+		for k := range keyI {
+			if keyI[k] != keyJ[k] {
+				return keyI[k] < keyJ[k]
+			}
+		}
+		return false
+	})
+
+	builder := strings.Builder{}
+	builder.WriteString("| Nodes | k | alpha | beta virtuous | beta rogue | Txs | Median latency | p95 latency | Max latency | Throughput (txs/s) |\n")
+	builder.WriteString("|---|---|---|---|---|---|---|---|---|---|\n")
+	for _, result := range results {
+		fmt.Fprintf(
+			&builder,
+			"| %d | %d | %d | %d | %d | %d | %v | %v | %v | %.1f |\n",
+			result.Params.NumNodes,
+			result.Params.SnowSampleSize,
+			result.Params.SnowQuorumSize,
+			result.Params.BetaVirtuous,
+			result.Params.BetaRogue,
+			result.NumTxs,
+			result.MedianLatency.Round(time.Millisecond),
+			result.P95Latency.Round(time.Millisecond),
+			result.MaxLatency.Round(time.Millisecond),
+			result.Throughput)
+	}
+	return builder.String()
+}
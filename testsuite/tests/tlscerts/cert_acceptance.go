@@ -0,0 +1,257 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package tlscerts
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kurtosis-tech/kurtosis-go/lib/networks"
+	"github.com/kurtosis-tech/kurtosis-go/lib/services"
+	"github.com/kurtosis-tech/kurtosis-go/lib/testsuite"
+
+	caminoNetwork "github.com/chain4travel/camino-testing/camino/networks"
+	caminoService "github.com/chain4travel/camino-testing/camino/services"
+	"github.com/chain4travel/camino-testing/camino/services/certs"
+	"github.com/chain4travel/camino-testing/camino_client/apis"
+	"github.com/chain4travel/camino-testing/utils/random"
+	"github.com/palantir/stacktrace"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	certCaseConfigIDPrefix  = "cert-config-"
+	certCaseServiceIDPrefix = "cert-node-"
+
+	peerPollInterval = 2 * time.Second
+)
+
+// CertExpectation is how the network is expected to treat a node started with a kind of cert
+type CertExpectation struct {
+	// Whether the node starts, i.e. bootstraps every chain
+	Starts bool
+
+	// Whether the honest boot nodes end up peered with the node
+	Accepted bool
+}
+
+// StakingNetworkCertAcceptanceTest starts a node with each kind of cert that the cert providers can produce, and checks
+// whether the node starts and whether the honest boot nodes accept its connections
+type StakingNetworkCertAcceptanceTest struct {
+	ctx       context.Context
+	ImageName string
+
+	// Mapping of cert case name -> the provider of the cert that the case's node is started with
+	providers map[string]certs.CaminoCertProvider
+
+	// Mapping of cert case name -> how the network is expected to treat the case's node
+	// The defaults follow from nodes skipping CA verification of their peers' certs: only certs that a node can't load, or
+	// whose key can't sign the node's IP, are refused
+	Expectations map[string]CertExpectation
+
+	// How long the honest boot nodes get to accept, or keep rejecting, the nodes that were started
+	AcceptanceDeadline time.Duration
+}
+
+func NewStakingNetworkCertAcceptanceTest(imageName string) StakingNetworkCertAcceptanceTest {
+	return StakingNetworkCertAcceptanceTest{
+		ctx:       context.Background(),
+		ImageName: imageName,
+		providers: map[string]certs.CaminoCertProvider{
+			"ecdsa":          certs.NewKeyTypeCaminoCertProvider(certs.ECDSA, newCertCaseRand("ecdsa")),
+			"ed25519":        certs.NewKeyTypeCaminoCertProvider(certs.Ed25519, newCertCaseRand("ed25519")),
+			"expired":        certs.NewExpiredCaminoCertProvider(newCertCaseRand("expired")),
+			"not-yet-valid":  certs.NewNotYetValidCaminoCertProvider(newCertCaseRand("not-yet-valid")),
+			"self-signed":    certs.NewSelfSignedCaminoCertProvider(newCertCaseRand("self-signed")),
+			"truncated-pem":  certs.NewMalformedCaminoCertProvider(certs.TruncatedPEM, newCertCaseRand("truncated-pem")),
+			"corrupt-der":    certs.NewMalformedCaminoCertProvider(certs.CorruptDER, newCertCaseRand("corrupt-der")),
+			"mismatched-key": certs.NewMalformedCaminoCertProvider(certs.MismatchedKey, newCertCaseRand("mismatched-key")),
+		},
+		Expectations: map[string]CertExpectation{
+			"ecdsa": {Starts: true, Accepted: true},
+			// Nodes sign their IP with SHA-256 as the hash, which Ed25519 keys don't support
+			"ed25519":        {Starts: false, Accepted: false},
+			"expired":        {Starts: true, Accepted: true},
+			"not-yet-valid":  {Starts: true, Accepted: true},
+			"self-signed":    {Starts: true, Accepted: true},
+			"truncated-pem":  {Starts: false, Accepted: false},
+			"corrupt-der":    {Starts: false, Accepted: false},
+			"mismatched-key": {Starts: false, Accepted: false},
+		},
+		AcceptanceDeadline: 60 * time.Second,
+	}
+}
+
+// Run implements the Kurtosis Test interface
+func (test StakingNetworkCertAcceptanceTest) Run(network networks.Network, context testsuite.TestContext) {
+	castedNetwork := network.(caminoNetwork.TestCaminoNetwork)
+
+	bootClients := map[networks.ServiceID]*apis.Client{}
+	for bootServiceID := range castedNetwork.GetAllBootServiceIDs() {
+		client, err := castedNetwork.GetCaminoClient(bootServiceID)
+		if err != nil {
+			context.Fatal(stacktrace.Propagate(err, "An error occurred getting the Camino client for service with ID %v", bootServiceID))
+		}
+		bootClients[bootServiceID] = client
+	}
+
+	caseNames := test.getSortedCaseNames()
+	checkers := map[string]*services.ServiceAvailabilityChecker{}
+	caseIPs := map[string]string{}
+	for _, caseName := range caseNames {
+		serviceID := networks.ServiceID(certCaseServiceIDPrefix + caseName)
+		logrus.Infof("Starting service with ID %v with a %v cert...", serviceID, caseName)
+		checker, err := castedNetwork.AddService(networks.ConfigurationID(certCaseConfigIDPrefix+caseName), serviceID)
+		if err != nil {
+			context.Fatal(stacktrace.Propagate(err, "An error occurred adding service with ID %v", serviceID))
+		}
+		ipAddr, err := castedNetwork.GetServiceIPAddress(serviceID)
+		if err != nil {
+			context.Fatal(stacktrace.Propagate(err, "An error occurred getting the IP address of service with ID %v", serviceID))
+		}
+		checkers[caseName] = checker
+		caseIPs[caseName] = ipAddr
+	}
+
+	// The nodes that don't start take the whole startup timeout to show it, so they're all waited for at once
+	startupResults := make(chan startupResult, len(checkers))
+	for caseName, checker := range checkers {
+		go func(caseName string, checker *services.ServiceAvailabilityChecker) {
+			startupResults <- startupResult{caseName: caseName, started: checker.WaitForStartup() == nil}
+		}(caseName, checker)
+	}
+	started := map[string]bool{}
+	for range checkers {
+		result := <-startupResults
+		started[result.caseName] = result.started
+	}
+
+	logrus.Infof("Giving the boot nodes %v to accept or reject the nodes...", test.AcceptanceDeadline)
+	time.Sleep(test.AcceptanceDeadline)
+	accepted := map[string]bool{}
+	for _, caseName := range caseNames {
+		numHolders, err := test.countPeeredBootNodes(bootClients, caseIPs[caseName])
+		if err != nil {
+			context.Fatal(stacktrace.Propagate(err, "An error occurred checking which boot nodes are peered with the %v cert node", caseName))
+		}
+		accepted[caseName] = numHolders == len(bootClients)
+		if numHolders != 0 && numHolders != len(bootClients) {
+			logrus.Warnf("Only %v of %v boot nodes are peered with the %v cert node", numHolders, len(bootClients), caseName)
+		}
+	}
+
+	mismatches := []string{}
+	for _, caseName := range caseNames {
+		expectation := test.Expectations[caseName]
+		logrus.Infof("Node with %v cert: started = %v, accepted = %v", caseName, started[caseName], accepted[caseName])
+		if started[caseName] != expectation.Starts || accepted[caseName] != expectation.Accepted {
+			mismatches = append(mismatches, fmt.Sprintf(
+				"\t%v: expected started = %v and accepted = %v, but got started = %v and accepted = %v",
+				caseName,
+				expectation.Starts,
+				expectation.Accepted,
+				started[caseName],
+				accepted[caseName]))
+		}
+	}
+	if len(mismatches) > 0 {
+		context.Fatal(stacktrace.NewError("The network didn't treat these certs as expected:\n%v", strings.Join(mismatches, "\n")))
+	}
+}
+
+// GetNetworkLoader implements the Kurtosis Test interface
+func (test StakingNetworkCertAcceptanceTest) GetNetworkLoader() (networks.NetworkLoader, error) {
+	serviceConfigs := map[networks.ConfigurationID]caminoNetwork.TestCaminoNetworkServiceConfig{}
+	for caseName, provider := range test.providers {
+		if _, found := test.Expectations[caseName]; !found {
+			return nil, stacktrace.NewError("No expectation was given for the %v cert", caseName)
+		}
+		serviceConfigs[networks.ConfigurationID(certCaseConfigIDPrefix+caseName)] = *caminoNetwork.NewTestCaminoNetworkServiceConfig(
+			true,
+			caminoService.DEBUG,
+			test.ImageName,
+			2,
+			2,
+			2*time.Second,
+			make(map[string]string),
+		).WithCertProvider(provider)
+	}
+	return caminoNetwork.NewTestCaminoNetworkLoader(
+		true,
+		test.ImageName,
+		caminoService.DEBUG,
+		2,
+		2,
+		0,
+		2*time.Second,
+		serviceConfigs,
+		map[networks.ServiceID]networks.ConfigurationID{},
+	)
+}
+
+// GetExecutionTimeout implements the Kurtosis Test interface
+func (test StakingNetworkCertAcceptanceTest) GetExecutionTimeout() time.Duration {
+	return 6 * time.Minute
+}
+
+// GetSetupBuffer implements the Kurtosis Test interface
+func (test StakingNetworkCertAcceptanceTest) GetSetupBuffer() time.Duration {
+	return 6 * time.Minute
+}
+
+// ================ Helper functions =========================
+func newCertCaseRand(caseName string) *rand.Rand {
+	return random.NewRand("tlscerts/" + caseName)
+}
+
+// startupResult records whether the node of a cert case started
+type startupResult struct {
+	caseName string
+	started  bool
+}
+
+func (test StakingNetworkCertAcceptanceTest) getSortedCaseNames() []string {
+	result := make([]string, 0, len(test.providers))
+	for caseName := range test.providers {
+		result = append(result, caseName)
+	}
+	sort.Strings(result)
+	return result
+}
+
+/*
+Counts the boot nodes that have a peer at the given IP address, which identifies the node even if its cert is too broken
+	for its node ID to be known
+*/
+func (test StakingNetworkCertAcceptanceTest) countPeeredBootNodes(bootClients map[networks.ServiceID]*apis.Client, ipAddr string) (int, error) {
+	numHolders := 0
+	for bootServiceID, client := range bootClients {
+		peers, err := client.InfoAPI().Peers(test.ctx)
+		if err != nil {
+			return 0, stacktrace.Propagate(err, "Failed to get peers from service with ID %v", bootServiceID)
+		}
+		for _, peer := range peers {
+			host, _, err := net.SplitHostPort(peer.IP)
+			if err != nil {
+				host = peer.IP
+			}
+			if host == ipAddr {
+				numHolders++
+				break
+			}
+		}
+	}
+	return numHolders, nil
+}
@@ -0,0 +1,138 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package topology
+
+import (
+	"time"
+
+	"github.com/kurtosis-tech/kurtosis-go/lib/networks"
+	"github.com/kurtosis-tech/kurtosis-go/lib/testsuite"
+
+	caminoNetwork "github.com/chain4travel/camino-testing/camino/networks"
+	caminoService "github.com/chain4travel/camino-testing/camino/services"
+	"github.com/chain4travel/camino-testing/camino_client/apis"
+	"github.com/chain4travel/camino-testing/testsuite/verifier"
+	"github.com/palantir/stacktrace"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	normalNodeConfigID networks.ConfigurationID = "normal-config"
+	normalNodePrefix   string                   = "node-"
+
+	peerPollInterval = 2 * time.Second
+)
+
+// StakingNetworkSparseTopologyTest starts a network whose nodes only bootstrap from the few nodes that the topology gives
+// them, and verifies that gossip still connects the network fully: every validator ends up peered with every node, and
+// every other node with every validator
+type StakingNetworkSparseTopologyTest struct {
+	ImageName string
+	Topology  caminoNetwork.Topology
+	Verifier  verifier.NetworkStateVerifier
+
+	// The number of nodes to start on top of the boot nodes
+	NumNodes int
+
+	// How long gossip gets to connect the network fully
+	ConvergenceTimeout time.Duration
+}
+
+func NewStakingNetworkSparseTopologyTest(imageName string, topology caminoNetwork.Topology) StakingNetworkSparseTopologyTest {
+	return StakingNetworkSparseTopologyTest{
+		ImageName:          imageName,
+		Topology:           topology,
+		Verifier:           verifier.NewNetworkStateVerifier(),
+		NumNodes:           5,
+		ConvergenceTimeout: 2 * time.Minute,
+	}
+}
+
+// Run implements the Kurtosis Test interface
+func (test StakingNetworkSparseTopologyTest) Run(network networks.Network, context testsuite.TestContext) {
+	castedNetwork := network.(caminoNetwork.TestCaminoNetwork)
+
+	stakerIDs := castedNetwork.GetAllBootServiceIDs()
+	allServiceIDs := make(map[networks.ServiceID]bool)
+	for stakerID := range stakerIDs {
+		allServiceIDs[stakerID] = true
+	}
+	for _, serviceID := range caminoNetwork.GetNonBootServiceIDs(normalNodePrefix, test.NumNodes) {
+		allServiceIDs[serviceID] = true
+	}
+
+	allNodeIDs := make(map[networks.ServiceID]string)
+	allCaminoClients := make(map[networks.ServiceID]*apis.Client)
+	for serviceID := range allServiceIDs {
+		client, err := castedNetwork.GetCaminoClient(serviceID)
+		if err != nil {
+			context.Fatal(stacktrace.Propagate(err, "An error occurred getting the Camino client for service with ID %v", serviceID))
+		}
+		nodeID, err := client.InfoAPI().GetNodeID(test.Verifier.Ctx())
+		if err != nil {
+			context.Fatal(stacktrace.Propagate(err, "An error occurred getting the Camino node ID for service with ID %v", serviceID))
+		}
+		allCaminoClients[serviceID] = client
+		allNodeIDs[serviceID] = nodeID
+	}
+
+	logrus.Infof("Waiting up to %v for gossip to connect the network fully...", test.ConvergenceTimeout)
+	tracker := verifier.NewFullyConnectedConvergenceTracker(allServiceIDs, stakerIDs, allNodeIDs, allCaminoClients, peerPollInterval)
+	report, err := tracker.WaitForConvergence(test.ConvergenceTimeout)
+	if err != nil {
+		context.Fatal(stacktrace.Propagate(err, "Gossip didn't connect the network fully"))
+	}
+	report.LogMetrics()
+}
+
+// GetNetworkLoader implements the Kurtosis Test interface
+func (test StakingNetworkSparseTopologyTest) GetNetworkLoader() (networks.NetworkLoader, error) {
+	serviceConfigs := map[networks.ConfigurationID]caminoNetwork.TestCaminoNetworkServiceConfig{
+		normalNodeConfigID: *caminoNetwork.NewTestCaminoNetworkServiceConfig(
+			true,
+			caminoService.DEBUG,
+			test.ImageName,
+			2,
+			2,
+			2*time.Second,
+			make(map[string]string),
+		),
+	}
+	desiredServices := map[networks.ServiceID]networks.ConfigurationID{}
+	for _, serviceID := range caminoNetwork.GetNonBootServiceIDs(normalNodePrefix, test.NumNodes) {
+		desiredServices[serviceID] = normalNodeConfigID
+	}
+	loader, err := caminoNetwork.NewTestCaminoNetworkLoader(
+		true,
+		test.ImageName,
+		caminoService.DEBUG,
+		2,
+		2,
+		0,
+		2*time.Second,
+		serviceConfigs,
+		desiredServices,
+	)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "An error occurred creating the network loader")
+	}
+	return loader.WithTopology(test.Topology), nil
+}
+
+// GetExecutionTimeout implements the Kurtosis Test interface
+func (test StakingNetworkSparseTopologyTest) GetExecutionTimeout() time.Duration {
+	return 5 * time.Minute
+}
+
+// GetSetupBuffer implements the Kurtosis Test interface
+func (test StakingNetworkSparseTopologyTest) GetSetupBuffer() time.Duration {
+	// The availability checker waits for every node to bootstrap before the test runs
+	return 8 * time.Minute
+}
@@ -0,0 +1,178 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package uptime
+
+import (
+	"time"
+
+	"github.com/kurtosis-tech/kurtosis-go/lib/networks"
+	"github.com/kurtosis-tech/kurtosis-go/lib/testsuite"
+
+	caminoNetwork "github.com/chain4travel/camino-testing/camino/networks"
+	caminoService "github.com/chain4travel/camino-testing/camino/services"
+	"github.com/chain4travel/camino-testing/camino_client/apis"
+	"github.com/chain4travel/camino-testing/testsuite/helpers"
+	"github.com/chain4travel/camino-testing/testsuite/verifier"
+	"github.com/chain4travel/caminogo/api"
+	"github.com/palantir/stacktrace"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	stakerUsername = "staker"
+	stakerPassword = "test34test!23"
+	seedAmount     = uint64(50000000000000)
+	stakeAmount    = uint64(30000000000000)
+
+	normalNodeConfigID networks.ConfigurationID = "normal-config"
+	stoppedValidatorID networks.ServiceID       = "stopped-validator"
+
+	networkAcceptanceTimeoutRatio = 0.3
+)
+
+// StakingNetworkValidatorUptimeTest adds a validator to the network, verifies that all the validators report each other as
+// connected with a high uptime, then stops the new validator and verifies that the others report it as disconnected and
+// that the uptime they report for it falls
+type StakingNetworkValidatorUptimeTest struct {
+	ImageName string
+	Verifier  verifier.NetworkStateVerifier
+
+	// The lowest uptime, as a fraction between 0 and 1, that the validators may report for each other while all are up
+	MinUptime float32
+
+	// How long the validators get to report each other as connected with at least the minimum uptime
+	ConnectedDeadline time.Duration
+
+	// How long the stopped validator is down before the others' reports on it are checked
+	DisconnectedWait time.Duration
+}
+
+func NewStakingNetworkValidatorUptimeTest(imageName string) StakingNetworkValidatorUptimeTest {
+	return StakingNetworkValidatorUptimeTest{
+		ImageName:         imageName,
+		Verifier:          verifier.NewNetworkStateVerifier(),
+		MinUptime:         0.8,
+		ConnectedDeadline: 90 * time.Second,
+		DisconnectedWait:  90 * time.Second,
+	}
+}
+
+// Run implements the Kurtosis Test interface
+func (test StakingNetworkValidatorUptimeTest) Run(network networks.Network, context testsuite.TestContext) {
+	castedNetwork := network.(caminoNetwork.TestCaminoNetwork)
+	networkAcceptanceTimeout := time.Duration(networkAcceptanceTimeoutRatio * float64(test.GetExecutionTimeout().Nanoseconds()))
+
+	bootServiceIDs := castedNetwork.GetAllBootServiceIDs()
+	validatorIDs := make(map[networks.ServiceID]bool)
+	for bootServiceID := range bootServiceIDs {
+		validatorIDs[bootServiceID] = true
+	}
+	validatorIDs[stoppedValidatorID] = true
+
+	allNodeIDs := make(map[networks.ServiceID]string)
+	allCaminoClients := make(map[networks.ServiceID]*apis.Client)
+	for serviceID := range validatorIDs {
+		client, err := castedNetwork.GetCaminoClient(serviceID)
+		if err != nil {
+			context.Fatal(stacktrace.Propagate(err, "An error occurred getting the Camino client for service with ID %v", serviceID))
+		}
+		nodeID, err := client.InfoAPI().GetNodeID(test.Verifier.Ctx())
+		if err != nil {
+			context.Fatal(stacktrace.Propagate(err, "An error occurred getting the Camino node ID for service with ID %v", serviceID))
+		}
+		allCaminoClients[serviceID] = client
+		allNodeIDs[serviceID] = nodeID
+	}
+
+	logrus.Infof("Adding service with ID %v as a validator...", stoppedValidatorID)
+	stakerClient := helpers.NewRPCWorkFlowRunner(
+		allCaminoClients[stoppedValidatorID],
+		api.UserPass{Username: stakerUsername, Password: stakerPassword},
+		networkAcceptanceTimeout)
+	if _, err := stakerClient.ImportGenesisFundsAndStartValidating(seedAmount, stakeAmount); err != nil {
+		context.Fatal(stacktrace.Propagate(err, "Failed to add service with ID %v as a validator", stoppedValidatorID))
+	}
+
+	logrus.Infof("Waiting up to %v for the validators to report each other as connected with an uptime of at least %v...", test.ConnectedDeadline, test.MinUptime)
+	if err := helpers.WaitFor(test.ConnectedDeadline, func() error {
+		return test.Verifier.VerifyValidatorsConnected(validatorIDs, allNodeIDs, allCaminoClients, test.MinUptime)
+	}); err != nil {
+		context.Fatal(stacktrace.Propagate(err, "The validators didn't report each other as connected"))
+	}
+	uptimesBeforeStop, err := test.Verifier.GetReportedUptimes(stoppedValidatorID, bootServiceIDs, allNodeIDs, allCaminoClients)
+	if err != nil {
+		context.Fatal(stacktrace.Propagate(err, "An error occurred getting the uptimes reported for service with ID %v", stoppedValidatorID))
+	}
+	logrus.Infof("The validators report each other as connected; uptimes reported for service with ID %v: %v", stoppedValidatorID, uptimesBeforeStop)
+
+	logrus.Infof("Stopping service with ID %v and waiting %v...", stoppedValidatorID, test.DisconnectedWait)
+	if err := castedNetwork.RemoveService(stoppedValidatorID); err != nil {
+		context.Fatal(stacktrace.Propagate(err, "An error occurred stopping service with ID %v", stoppedValidatorID))
+	}
+	time.Sleep(test.DisconnectedWait)
+
+	if err := test.Verifier.VerifyValidatorDisconnected(stoppedValidatorID, bootServiceIDs, allNodeIDs, allCaminoClients); err != nil {
+		context.Fatal(stacktrace.Propagate(err, "The remaining validators don't all report service with ID %v as disconnected", stoppedValidatorID))
+	}
+	uptimesAfterStop, err := test.Verifier.GetReportedUptimes(stoppedValidatorID, bootServiceIDs, allNodeIDs, allCaminoClients)
+	if err != nil {
+		context.Fatal(stacktrace.Propagate(err, "An error occurred getting the uptimes reported for service with ID %v", stoppedValidatorID))
+	}
+	for reporterID, uptimeAfterStop := range uptimesAfterStop {
+		if uptimeBeforeStop := uptimesBeforeStop[reporterID]; uptimeAfterStop >= uptimeBeforeStop {
+			context.Fatal(stacktrace.NewError(
+				"Service with ID %v reported an uptime of %v for stopped service with ID %v, which didn't fall from the %v it reported before the stop",
+				reporterID,
+				uptimeAfterStop,
+				stoppedValidatorID,
+				uptimeBeforeStop))
+		}
+	}
+	logrus.Infof("The remaining validators report service with ID %v as disconnected, with falling uptimes: %v", stoppedValidatorID, uptimesAfterStop)
+}
+
+// GetNetworkLoader implements the Kurtosis Test interface
+func (test StakingNetworkValidatorUptimeTest) GetNetworkLoader() (networks.NetworkLoader, error) {
+	serviceConfigs := map[networks.ConfigurationID]caminoNetwork.TestCaminoNetworkServiceConfig{
+		normalNodeConfigID: *caminoNetwork.NewTestCaminoNetworkServiceConfig(
+			true,
+			caminoService.DEBUG,
+			test.ImageName,
+			2,
+			2,
+			2*time.Second,
+			make(map[string]string),
+		),
+	}
+	desiredServices := map[networks.ServiceID]networks.ConfigurationID{
+		stoppedValidatorID: normalNodeConfigID,
+	}
+	return caminoNetwork.NewTestCaminoNetworkLoader(
+		true,
+		test.ImageName,
+		caminoService.DEBUG,
+		2,
+		2,
+		0,
+		2*time.Second,
+		serviceConfigs,
+		desiredServices,
+	)
+}
+
+// GetExecutionTimeout implements the Kurtosis Test interface
+func (test StakingNetworkValidatorUptimeTest) GetExecutionTimeout() time.Duration {
+	return 6 * time.Minute
+}
+
+// GetSetupBuffer implements the Kurtosis Test interface
+func (test StakingNetworkValidatorUptimeTest) GetSetupBuffer() time.Duration {
+	return 6 * time.Minute
+}
@@ -16,11 +16,12 @@ import (
 	"github.com/chain4travel/camino-testing/camino_client/apis"
 	"github.com/chain4travel/camino-testing/testsuite/helpers"
 	"github.com/chain4travel/camino-testing/testsuite/tester"
+	"github.com/chain4travel/camino-testing/testsuite/verifier"
 	"github.com/chain4travel/caminogo/api"
 	"github.com/chain4travel/caminogo/ids"
 	"github.com/chain4travel/caminogo/utils/constants"
+	"github.com/chain4travel/caminogo/utils/logging"
 	"github.com/chain4travel/caminogo/utils/units"
-	"github.com/chain4travel/caminogo/vms/platformvm"
 	"github.com/palantir/stacktrace"
 	"github.com/sirupsen/logrus"
 )
@@ -35,35 +36,36 @@ const (
 	seedAmount        = 5 * units.KiloAvax
 	stakeAmount       = 3 * units.KiloAvax
 	delegatorAmount   = 3 * units.KiloAvax
+	xChainAlias       = "exchange"
 )
 
 type executor struct {
 	ctx                           context.Context
 	stakerClient, delegatorClient *apis.Client
+	txFee                         uint64
 	acceptanceTimeout             time.Duration
 }
 
-// NewRPCWorkflowTestExecutor ...
-func NewRPCWorkflowTestExecutor(stakerClient, delegatorClient *apis.Client, acceptanceTimeout time.Duration) tester.CaminoTester {
+// NewRPCWorkflowTestExecutor creates an executor of the workflow on a network whose nodes were started with [txFee]
+func NewRPCWorkflowTestExecutor(stakerClient, delegatorClient *apis.Client, txFee uint64, acceptanceTimeout time.Duration) tester.CaminoTester {
 	return &executor{
 		stakerClient:      stakerClient,
 		delegatorClient:   delegatorClient,
+		txFee:             txFee,
 		acceptanceTimeout: acceptanceTimeout,
 	}
 }
 
 // ExecuteTest ...
 func (e *executor) ExecuteTest() error {
-	genesisClient := helpers.NewRPCWorkFlowRunner(
-		e.stakerClient,
-		api.UserPass{Username: genesisUsername, Password: genesisPassword},
-		e.acceptanceTimeout,
-	)
-
-	if _, err := genesisClient.ImportGenesisFunds(); err != nil {
-		return stacktrace.Propagate(err, "Failed to fund genesis client.")
+	fees, err := helpers.GetLedgerFees(e.stakerClient)
+	if err != nil {
+		return stacktrace.Propagate(err, "Could not get the fees the staker node charges.")
 	}
-	logrus.Debugf("Funded genesis client...")
+	if fees.TxFee != e.txFee {
+		return stacktrace.NewError("The staker node charges a tx fee of %v, but the network was started with %v", fees.TxFee, e.txFee)
+	}
+	ledger := helpers.NewBalanceLedger(fees)
 
 	stakerNodeID, err := e.stakerClient.InfoAPI().GetNodeID(e.ctx)
 	if err != nil {
@@ -73,50 +75,40 @@ func (e *executor) ExecuteTest() error {
 	if err != nil {
 		return stacktrace.Propagate(err, "Could not get delegator node ID.")
 	}
-	highLevelStakerClient := helpers.NewRPCWorkFlowRunner(
-		e.stakerClient,
-		api.UserPass{Username: stakerUsername, Password: stakerPassword},
-		e.acceptanceTimeout,
-	)
-	highLevelDelegatorClient := helpers.NewRPCWorkFlowRunner(
-		e.delegatorClient,
-		api.UserPass{Username: delegatorUsername, Password: delegatorPassword},
-		e.acceptanceTimeout,
-	)
-
-	// ====================================== CREATE FUNDED ACCOUNTS ===============================
-	stakerXChainAddress, stakerPChainAddress, err := highLevelStakerClient.CreateDefaultAddresses()
+	stakerUser := api.UserPass{Username: stakerUsername, Password: stakerPassword}
+	delegatorUser := api.UserPass{Username: delegatorUsername, Password: delegatorPassword}
+	highLevelStakerClient := helpers.NewRPCWorkFlowRunner(e.stakerClient, stakerUser, e.acceptanceTimeout).WithLedger(ledger)
+	highLevelDelegatorClient := helpers.NewRPCWorkFlowRunner(e.delegatorClient, delegatorUser, e.acceptanceTimeout).WithLedger(ledger)
+
+	// ====================================== CHECK FUNDED ACCOUNTS ===============================
+	// The staker and delegator users were created and funded by the snapshot the network started from
+	stakerXChainAddress, stakerPChainAddress, err := getFundedAddresses(e.ctx, e.stakerClient, stakerUser)
 	if err != nil {
-		return stacktrace.Propagate(err, "Could not create default addresses for staker client.")
+		return stacktrace.Propagate(err, "Could not get the funded addresses of the staker client.")
 	}
-	delegatorXChainAddress, delegatorPChainAddress, err := highLevelDelegatorClient.CreateDefaultAddresses()
+	delegatorXChainAddress, delegatorPChainAddress, err := getFundedAddresses(e.ctx, e.delegatorClient, delegatorUser)
 	if err != nil {
-		return stacktrace.Propagate(err, "Could not create default addresses for delegator client.")
+		return stacktrace.Propagate(err, "Could not get the funded addresses of the delegator client.")
 	}
-	logrus.Infof("Created addresses for staker and delegator clients.")
-
-	if err := genesisClient.FundXChainAddresses([]string{stakerXChainAddress, delegatorXChainAddress}, seedAmount); err != nil {
-		return stacktrace.Propagate(err, "Failed to fund X Chain Addresses from genesis client.")
+	for _, xChainAddress := range []string{stakerXChainAddress, delegatorXChainAddress} {
+		ledger.Track(helpers.LedgerXChain, xChainAddress, seedAmount)
 	}
-
-	if err := highLevelStakerClient.VerifyXChainAVABalance(stakerXChainAddress, seedAmount); err != nil {
-		return stacktrace.Propagate(err, "Unexpected X Chain balance for staker client.")
+	for _, pChainAddress := range []string{stakerPChainAddress, delegatorPChainAddress} {
+		ledger.Track(helpers.LedgerPChain, pChainAddress, 0)
 	}
-	if err := highLevelDelegatorClient.VerifyXChainAVABalance(delegatorXChainAddress, seedAmount); err != nil {
-		return stacktrace.Propagate(err, "Unexpected X Chain Balance for delegator client.")
+	if err := ledger.Verify(e.stakerClient); err != nil {
+		return stacktrace.Propagate(err, "Unexpected balances for the staker and delegator clients funded by the snapshot.")
 	}
-	logrus.Infof("Funded X Chain Addresses for staker and delegator clients.")
+	logrus.Infof("Verified the funded X Chain Addresses of the staker and delegator clients.")
 
 	//  ====================================== ADD VALIDATOR ===============================
-	err = highLevelStakerClient.TransferAvaXChainToPChain(stakerPChainAddress, seedAmount)
+	// Everything but the export fee is moved to the P Chain
+	err = highLevelStakerClient.TransferAvaXChainToPChain(stakerPChainAddress, seedAmount-fees.TxFee)
 	if err != nil {
 		return stacktrace.Propagate(err, "Could not transfer AVAX from XChain to PChain account information")
 	}
-	if err := highLevelStakerClient.VerifyPChainBalance(stakerPChainAddress, seedAmount); err != nil {
-		return stacktrace.Propagate(err, "Unexpected P Chain balance after X -> P Transfer.")
-	}
-	if err := highLevelStakerClient.VerifyXChainAVABalance(stakerXChainAddress, 0); err != nil {
-		return stacktrace.Propagate(err, "X Chain Balance not updated correctly after X -> P Transfer for validator")
+	if err := ledger.Verify(e.stakerClient); err != nil {
+		return stacktrace.Propagate(err, "Unexpected balances after X -> P Transfer for validator.")
 	}
 	err = highLevelStakerClient.AddValidatorToPrimaryNetwork(stakerNodeID, stakerPChainAddress, stakeAmount)
 	if err != nil {
@@ -138,11 +130,11 @@ func (e *executor) ExecuteTest() error {
 	}
 	actualNumDelegators := 0
 	for _, iValidator := range currentValidators {
-		if validator, ok := iValidator.(platformvm.APIPrimaryValidator); !ok {
-			return stacktrace.Propagate(nil, "Could not convert validator.")
-		} else {
-			actualNumDelegators += len(validator.Delegators)
+		validator, err := verifier.ToPrimaryValidator(iValidator)
+		if err != nil {
+			return stacktrace.Propagate(err, "Could not convert validator.")
 		}
+		actualNumDelegators += len(validator.Delegators)
 	}
 
 	logrus.Debugf("Number of current delegators: %d", actualNumDelegators)
@@ -150,58 +142,68 @@ func (e *executor) ExecuteTest() error {
 	if actualNumDelegators != expectedNumDelegators {
 		return stacktrace.NewError("Actual number of delegators, %v, != expected number of delegators, %v", actualNumDelegators, expectedNumDelegators)
 	}
-	expectedStakerBalance := seedAmount - stakeAmount
-	if err := highLevelStakerClient.VerifyPChainBalance(stakerPChainAddress, expectedStakerBalance); err != nil {
-		return stacktrace.Propagate(err, "Unexpected P Chain Balance after adding  validator to the primary network")
+	if err := ledger.Verify(e.stakerClient); err != nil {
+		return stacktrace.Propagate(err, "Unexpected balances after adding validator to the primary network")
 	}
 	logrus.Infof("Verified the staker was added to current validators and has the expected P Chain balance.")
 
 	// ====================================== ADD DELEGATOR ======================================
-	err = highLevelDelegatorClient.TransferAvaXChainToPChain(delegatorPChainAddress, seedAmount)
+	err = highLevelDelegatorClient.TransferAvaXChainToPChain(delegatorPChainAddress, seedAmount-fees.TxFee)
 	if err != nil {
 		return stacktrace.Propagate(err, "Could not transfer AVAX from X Chain to P Chain account.")
 	}
-	if err := highLevelDelegatorClient.VerifyPChainBalance(delegatorPChainAddress, seedAmount); err != nil {
-		return stacktrace.Propagate(err, "Unexpected P Chain balance after X -> P Transfer for Delegator.")
-	}
-	if err := highLevelDelegatorClient.VerifyXChainAVABalance(delegatorXChainAddress, 0); err != nil {
-		return stacktrace.Propagate(err, "Unexpected X Chain Balance after X -> P Transfer for Delegator")
+	if err := ledger.Verify(e.stakerClient); err != nil {
+		return stacktrace.Propagate(err, "Unexpected balances after X -> P Transfer for Delegator.")
 	}
 
 	err = highLevelDelegatorClient.AddDelegatorToPrimaryNetwork(stakerNodeID, delegatorPChainAddress, delegatorAmount)
 	if err != nil {
 		return stacktrace.Propagate(err, "Could not add delegator %s to the primary network.", delegatorNodeID)
 	}
-	expectedDelegatorBalance := seedAmount - delegatorAmount
-	if err := highLevelDelegatorClient.VerifyPChainBalance(delegatorPChainAddress, expectedDelegatorBalance); err != nil {
-		return stacktrace.Propagate(err, "Unexpected P Chain Balance after adding a new delegator to the network.")
+	if err := ledger.Verify(e.stakerClient); err != nil {
+		return stacktrace.Propagate(err, "Unexpected balances after adding a new delegator to the network.")
 	}
 	logrus.Infof("Added delegator to subnet and verified the expected P Chain balance.")
 
 	// ====================================== TRANSFER TO X CHAIN ================================
-	err = highLevelStakerClient.TransferAvaPChainToXChain(stakerXChainAddress, expectedStakerBalance)
-	if err != nil {
-		return stacktrace.Propagate(err, "Failed to transfer AvaX from P Chain to X Chain.")
-	}
-	if err := highLevelStakerClient.VerifyPChainBalance(stakerPChainAddress, 0); err != nil {
-		return stacktrace.Propagate(err, "Unexpected P Chain Balance after P -> X Transfer.")
+	for _, leftover := range []struct {
+		runner        *helpers.RPCWorkFlowRunner
+		pChainAddress string
+		xChainAddress string
+	}{
+		{highLevelStakerClient, stakerPChainAddress, stakerXChainAddress},
+		{highLevelDelegatorClient, delegatorPChainAddress, delegatorXChainAddress},
+	} {
+		pChainBalance, _ := ledger.GetExpectedBalance(helpers.LedgerPChain, leftover.pChainAddress)
+		// Everything but the export fee is moved back to the X Chain
+		err = leftover.runner.TransferAvaPChainToXChain(leftover.xChainAddress, pChainBalance-fees.TxFee)
+		if err != nil {
+			return stacktrace.Propagate(err, "Failed to transfer AVAX from P Chain address %s to X Chain.", leftover.pChainAddress)
+		}
+		if err := ledger.Verify(e.stakerClient); err != nil {
+			return stacktrace.Propagate(err, "Unexpected balances after P -> X Transfer from %s.", leftover.pChainAddress)
+		}
 	}
-	if err := highLevelStakerClient.VerifyXChainAVABalance(stakerXChainAddress, expectedStakerBalance); err != nil {
-		return stacktrace.Propagate(err, "Unexpected X Chain Balance after P -> X Transfer.")
+	logrus.Infof("Transferred leftover staker and delegator funds back to X Chain and verified X and P balances.")
+
+	if err := ledger.VerifyBurnedFees(e.stakerClient); err != nil {
+		return stacktrace.Propagate(err, "Not every tx burned the fee the network was started with.")
 	}
-	logrus.Infof("Transferred leftover staker funds back to X Chain and verified X and P balances.")
+	logrus.Infof("Verified that every tx burned a fee of %v.", fees.TxFee)
 
-	err = highLevelDelegatorClient.TransferAvaPChainToXChain(delegatorXChainAddress, expectedStakerBalance)
-	if err != nil {
-		return stacktrace.Propagate(err, "Failed to transfer AVAX from P Chain to X Chain.")
+	// ====================================== ADMIN ======================================
+	adminClient := helpers.NewAdminWorkFlowRunner(e.stakerClient)
+	if err := adminClient.SetLogLevel("", logging.Debug); err != nil {
+		return stacktrace.Propagate(err, "Failed to raise the log level of the staker node.")
 	}
-	if err := highLevelDelegatorClient.VerifyPChainBalance(delegatorPChainAddress, 0); err != nil {
-		return stacktrace.Propagate(err, "Unexpected P Chain Balance after P -> X Transfer.")
+	xChainID, err := e.stakerClient.InfoAPI().GetBlockchainID(e.ctx, apis.XChain)
+	if err != nil {
+		return stacktrace.Propagate(err, "Could not get X Chain ID.")
 	}
-	if err := highLevelDelegatorClient.VerifyXChainAVABalance(delegatorXChainAddress, expectedDelegatorBalance); err != nil {
-		return stacktrace.Propagate(err, "Unexpected X Chain Balance after P -> X Transfer.")
+	if err := adminClient.AliasChain(xChainID.String(), xChainAlias); err != nil {
+		return stacktrace.Propagate(err, "Failed to alias the X Chain on the staker node.")
 	}
-	logrus.Infof("Transferred leftover delegator funds back to X Chain and verified X and P balances.")
+	logrus.Infof("Changed the log level and aliased the X Chain through the staker's admin API.")
 
 	return nil
 }
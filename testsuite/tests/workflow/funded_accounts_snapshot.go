@@ -0,0 +1,108 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	caminoNetwork "github.com/chain4travel/camino-testing/camino/networks"
+	"github.com/chain4travel/camino-testing/camino_client/apis"
+	"github.com/chain4travel/camino-testing/testsuite/helpers"
+	"github.com/chain4travel/caminogo/api"
+	"github.com/palantir/stacktrace"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// The snapshot of the workflow's network after the staker and delegator users have been funded, of which there's one
+	// per tx fee the network is started with
+	fundedAccountsSnapshotNameFormat = "rpcWorkflowFundedAccounts-%d"
+
+	// How long the snapshot's setup waits for the network to accept each tx
+	fundedAccountsAcceptanceTimeout = 3 * time.Minute
+)
+
+/*
+Creates the snapshot of the workflow's network in which the staker and delegator users each have a single X Chain and P
+Chain address, and have been sent seedAmount on the X Chain from the genesis funds on the staker node, for a network
+started with the given tx fee
+*/
+func newFundedAccountsSnapshot(txFee uint64) *caminoNetwork.NetworkSnapshot {
+	snapshotName := fmt.Sprintf(fundedAccountsSnapshotNameFormat, txFee)
+	return caminoNetwork.NewNetworkSnapshot(snapshotName, func(network caminoNetwork.TestCaminoNetwork) error {
+		stakerClient, err := network.GetCaminoClient(regularNodeServiceID)
+		if err != nil {
+			return stacktrace.Propagate(err, "Could not get staker client")
+		}
+		delegatorClient, err := network.GetCaminoClient(delegatorNodeServiceID)
+		if err != nil {
+			return stacktrace.Propagate(err, "Could not get delegator client")
+		}
+		return fundAccounts(stakerClient, delegatorClient)
+	})
+}
+
+func fundAccounts(stakerClient, delegatorClient *apis.Client) error {
+	genesisClient := helpers.NewRPCWorkFlowRunner(
+		stakerClient,
+		api.UserPass{Username: genesisUsername, Password: genesisPassword},
+		fundedAccountsAcceptanceTimeout,
+	)
+	if _, err := genesisClient.ImportGenesisFunds(); err != nil {
+		return stacktrace.Propagate(err, "Failed to fund genesis client.")
+	}
+
+	stakerXChainAddress, _, err := helpers.NewRPCWorkFlowRunner(
+		stakerClient,
+		api.UserPass{Username: stakerUsername, Password: stakerPassword},
+		fundedAccountsAcceptanceTimeout,
+	).CreateDefaultAddresses()
+	if err != nil {
+		return stacktrace.Propagate(err, "Could not create default addresses for staker client.")
+	}
+	delegatorXChainAddress, _, err := helpers.NewRPCWorkFlowRunner(
+		delegatorClient,
+		api.UserPass{Username: delegatorUsername, Password: delegatorPassword},
+		fundedAccountsAcceptanceTimeout,
+	).CreateDefaultAddresses()
+	if err != nil {
+		return stacktrace.Propagate(err, "Could not create default addresses for delegator client.")
+	}
+
+	if err := genesisClient.FundXChainAddresses([]string{stakerXChainAddress, delegatorXChainAddress}, seedAmount); err != nil {
+		return stacktrace.Propagate(err, "Failed to fund X Chain Addresses from genesis client.")
+	}
+	logrus.Infof("Funded X Chain Addresses for staker and delegator clients.")
+	return nil
+}
+
+/*
+Gets the only X Chain and P Chain addresses of a user funded by the snapshot's setup
+*/
+func getFundedAddresses(ctx context.Context, client *apis.Client, user api.UserPass) (string, string, error) {
+	xChainAddresses, err := client.XChainAPI().ListAddresses(ctx, user)
+	if err != nil {
+		return "", "", stacktrace.Propagate(err, "Failed to list the X Chain addresses of user %s", user.Username)
+	}
+	pChainAddresses, err := client.PChainAPI().ListAddresses(ctx, user)
+	if err != nil {
+		return "", "", stacktrace.Propagate(err, "Failed to list the P Chain addresses of user %s", user.Username)
+	}
+	if len(xChainAddresses) != 1 || len(pChainAddresses) != 1 {
+		return "", "", stacktrace.NewError(
+			"User %s has %v X Chain and %v P Chain addresses, but the snapshot should have given it one of each",
+			user.Username,
+			len(xChainAddresses),
+			len(pChainAddresses))
+	}
+	return xChainAddresses[0], pChainAddresses[0], nil
+}
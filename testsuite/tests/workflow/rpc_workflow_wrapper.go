@@ -32,6 +32,9 @@ const (
 // StakingNetworkRPCWorkflowTest ...
 type StakingNetworkRPCWorkflowTest struct {
 	ImageName string
+
+	// The fee that the network's nodes are started with, which every tx of the workflow is checked to burn
+	TxFee uint64
 }
 
 // Run implements the Kurtosis Test interface
@@ -49,7 +52,7 @@ func (test StakingNetworkRPCWorkflowTest) Run(network networks.Network, context
 		context.Fatal(stacktrace.Propagate(err, "Could not get delegator client"))
 	}
 
-	executor := NewRPCWorkflowTestExecutor(stakerClient, delegatorClient, networkAcceptanceTimeout)
+	executor := NewRPCWorkflowTestExecutor(stakerClient, delegatorClient, test.TxFee, networkAcceptanceTimeout)
 
 	logrus.Infof("Set up RPCWorkFlowTest. Executing...")
 	if err := executor.ExecuteTest(); err != nil {
@@ -76,18 +79,23 @@ func (test StakingNetworkRPCWorkflowTest) GetNetworkLoader() (networks.NetworkLo
 		regularNodeServiceID:   normalNodeConfigID,
 		delegatorNodeServiceID: normalNodeConfigID,
 	}
-	// Return an Camino Test Network with this service:configuration mapping.
-	return caminoNetwork.NewTestCaminoNetworkLoader(
+	// Return an Camino Test Network with this service:configuration mapping, which starts with the staker and delegator
+	// users already funded
+	loader, err := caminoNetwork.NewTestCaminoNetworkLoader(
 		true,
 		test.ImageName,
 		caminoService.DEBUG,
 		2,
 		2,
-		0,
+		test.TxFee,
 		2*time.Second,
 		serviceConfigs,
 		desiredServices,
 	)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "Could not create network loader")
+	}
+	return loader.WithSnapshot(newFundedAccountsSnapshot(test.TxFee)), nil
 }
 
 // GetExecutionTimeout implements the Kurtosis Test interface
@@ -0,0 +1,229 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package verifier
+
+import (
+	"context"
+	"net"
+	"sort"
+	"time"
+
+	"github.com/chain4travel/camino-testing/camino_client/apis"
+	"github.com/kurtosis-tech/kurtosis-go/lib/networks"
+	"github.com/palantir/stacktrace"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// The holder recorded when an observer isn't peered with the duplicated node ID at all
+	NoDuplicateHolder networks.ServiceID = ""
+
+	// The name the time it took every observer to settle on the remaining duplicate is reported under
+	duplicateEvictionTimeMetric = "duplicate_eviction_seconds"
+)
+
+// DuplicatePeerTracker polls the peers of a set of observer nodes and records which of several nodes sharing one node ID
+// each observer is peered with - i.e. which IP each observer holds for the duplicated node ID - over time
+type DuplicatePeerTracker struct {
+	ctx context.Context
+
+	// The node ID that the duplicates share
+	duplicateNodeID string
+
+	// Mapping of IP address -> service ID of the nodes sharing the node ID
+	duplicateServiceIDsByIP map[string]networks.ServiceID
+
+	observerClients map[networks.ServiceID]*apis.Client
+
+	pollInterval time.Duration
+}
+
+// NewDuplicatePeerTracker creates a tracker of which duplicate each observer is peered with
+// Args:
+// 	duplicateNodeID: The node ID that the duplicates share
+// 	duplicateIPs: Mapping of service ID -> IP address of the nodes sharing the node ID
+// 	observerClients: The clients of the nodes whose peers are polled
+// 	pollInterval: How often the peers of every observer are polled
+func NewDuplicatePeerTracker(
+	duplicateNodeID string,
+	duplicateIPs map[networks.ServiceID]string,
+	observerClients map[networks.ServiceID]*apis.Client,
+	pollInterval time.Duration) DuplicatePeerTracker {
+	duplicateServiceIDsByIP := make(map[string]networks.ServiceID, len(duplicateIPs))
+	for serviceID, ipAddr := range duplicateIPs {
+		duplicateServiceIDsByIP[ipAddr] = serviceID
+	}
+	return DuplicatePeerTracker{
+		ctx:                     context.Background(),
+		duplicateNodeID:         duplicateNodeID,
+		duplicateServiceIDsByIP: duplicateServiceIDsByIP,
+		observerClients:         observerClients,
+		pollInterval:            pollInterval,
+	}
+}
+
+// Observe polls the observers' peers for the given duration, recording which duplicate each observer holds at each poll
+func (tracker DuplicatePeerTracker) Observe(duration time.Duration) (DuplicatePeerTimeline, error) {
+	startTime := time.Now()
+	timeline := DuplicatePeerTimeline{}
+	for {
+		pollTime := time.Since(startTime)
+		holders, err := tracker.pollHolders()
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "An error occurred polling which duplicate the observers hold")
+		}
+		for observerID, holderID := range holders {
+			timeline[observerID] = append(timeline[observerID], DuplicatePeerSample{
+				Time:   pollTime,
+				Holder: holderID,
+			})
+		}
+		if time.Since(startTime) > duration {
+			return timeline, nil
+		}
+		time.Sleep(tracker.pollInterval)
+	}
+}
+
+// WaitForHolder polls the observers' peers until every observer holds the given duplicate, or until the deadline passes
+// Returns:
+// 	How long it took every observer to hold the given duplicate
+func (tracker DuplicatePeerTracker) WaitForHolder(serviceID networks.ServiceID, deadline time.Duration) (time.Duration, error) {
+	startTime := time.Now()
+	for {
+		pollTime := time.Since(startTime)
+		holders, err := tracker.pollHolders()
+		if err != nil {
+			return 0, stacktrace.Propagate(err, "An error occurred polling which duplicate the observers hold")
+		}
+		otherHolders := map[networks.ServiceID]networks.ServiceID{}
+		for observerID, holderID := range holders {
+			if holderID != serviceID {
+				otherHolders[observerID] = holderID
+			}
+		}
+		if len(otherHolders) == 0 {
+			logrus.WithFields(logrus.Fields{
+				"metric": duplicateEvictionTimeMetric,
+				"value":  pollTime.Seconds(),
+			}).Infof("Every observer held duplicate service with ID %v after %v", serviceID, pollTime)
+			return pollTime, nil
+		}
+		if time.Since(startTime) > deadline {
+			return 0, stacktrace.NewError(
+				"Not every observer held duplicate service with ID %v within %v; at the last poll, these observers held other duplicates: %v",
+				serviceID,
+				deadline,
+				otherHolders)
+		}
+		time.Sleep(tracker.pollInterval)
+	}
+}
+
+// DuplicatePeerSample records which duplicate an observer held at one poll
+type DuplicatePeerSample struct {
+	// How long after tracking started the poll was
+	Time time.Duration
+
+	// The service ID of the duplicate the observer was peered with, NoDuplicateHolder if it wasn't peered with the
+	// duplicated node ID, or the peer's IP address if it didn't belong to any of the duplicates
+	Holder networks.ServiceID
+}
+
+// DuplicatePeerTimeline maps each observer's service ID to the samples of which duplicate it held, in poll order
+type DuplicatePeerTimeline map[networks.ServiceID][]DuplicatePeerSample
+
+// GetNumSwitches returns how many times the observer changed which duplicate it held, counting dropping and regaining the
+// duplicated node ID as changes
+func (timeline DuplicatePeerTimeline) GetNumSwitches(observerID networks.ServiceID) int {
+	numSwitches := 0
+	samples := timeline[observerID]
+	for i := 1; i < len(samples); i++ {
+		if samples[i].Holder != samples[i-1].Holder {
+			numSwitches++
+		}
+	}
+	return numSwitches
+}
+
+// GetFinalHolders returns the duplicate that each observer held at the last poll
+func (timeline DuplicatePeerTimeline) GetFinalHolders() map[networks.ServiceID]networks.ServiceID {
+	result := make(map[networks.ServiceID]networks.ServiceID, len(timeline))
+	for observerID, samples := range timeline {
+		if len(samples) > 0 {
+			result[observerID] = samples[len(samples)-1].Holder
+		}
+	}
+	return result
+}
+
+// LogSummary logs, for every observer, the duplicate it ended up holding, how often it switched and how long it held each
+// duplicate
+func (timeline DuplicatePeerTimeline) LogSummary() {
+	observerIDs := make([]string, 0, len(timeline))
+	for observerID := range timeline {
+		observerIDs = append(observerIDs, string(observerID))
+	}
+	sort.Strings(observerIDs)
+	for _, observerIDStr := range observerIDs {
+		observerID := networks.ServiceID(observerIDStr)
+		samples := timeline[observerID]
+		holdTimes := map[networks.ServiceID]time.Duration{}
+		for i := 1; i < len(samples); i++ {
+			holdTimes[samples[i-1].Holder] += samples[i].Time - samples[i-1].Time
+		}
+		logrus.WithFields(logrus.Fields{
+			"observer":     observerID,
+			"num_switches": timeline.GetNumSwitches(observerID),
+		}).Infof(
+			"Observer %v ended up holding %q after %v switches; time held per duplicate: %v",
+			observerID,
+			timeline.GetFinalHolders()[observerID],
+			timeline.GetNumSwitches(observerID),
+			holdTimes)
+	}
+}
+
+// ================ Helper functions =========================
+/*
+Gets the duplicate that each observer is currently peered with
+*/
+func (tracker DuplicatePeerTracker) pollHolders() (map[networks.ServiceID]networks.ServiceID, error) {
+	result := make(map[networks.ServiceID]networks.ServiceID, len(tracker.observerClients))
+	for observerID, client := range tracker.observerClients {
+		peers, err := client.InfoAPI().Peers(tracker.ctx)
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "Failed to get peers from service with ID %v", observerID)
+		}
+		holderID := NoDuplicateHolder
+		for _, peer := range peers {
+			if peer.ID != tracker.duplicateNodeID {
+				continue
+			}
+			holderID = tracker.getHolderID(peer.IP)
+		}
+		result[observerID] = holderID
+	}
+	return result, nil
+}
+
+/*
+Resolves a peer's IP, which may include the port, to the duplicate it belongs to
+*/
+func (tracker DuplicatePeerTracker) getHolderID(peerIP string) networks.ServiceID {
+	host := peerIP
+	if splitHost, _, err := net.SplitHostPort(peerIP); err == nil {
+		host = splitHost
+	}
+	if serviceID, found := tracker.duplicateServiceIDsByIP[host]; found {
+		return serviceID
+	}
+	return networks.ServiceID(peerIP)
+}
@@ -0,0 +1,97 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package verifier
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/chain4travel/camino-testing/camino_client/apis"
+	"github.com/chain4travel/camino-testing/camino_client/apis/apistest"
+	"github.com/kurtosis-tech/kurtosis-go/lib/networks"
+	"github.com/stretchr/testify/assert"
+)
+
+const testDuplicateNodeID = "NodeID-dupe"
+
+var testDuplicateIPs = map[networks.ServiceID]string{
+	"dupe-1": "10.0.0.1",
+	"dupe-2": "10.0.0.2",
+}
+
+func TestObserveRecordsHolderSwitches(t *testing.T) {
+	tracker := NewDuplicatePeerTracker(
+		testDuplicateNodeID,
+		testDuplicateIPs,
+		map[networks.ServiceID]*apis.Client{
+			// Switches from the first duplicate to the second, via dropping the node ID altogether
+			"observer-a": newDuplicatePeerClient(t, "10.0.0.1:9651", "", "10.0.0.2:9651"),
+			"observer-b": newDuplicatePeerClient(t, "10.0.0.1:9651"),
+		},
+		testPollInterval)
+
+	timeline, err := tracker.Observe(5 * testPollInterval)
+	assert.NoError(t, err)
+	assert.Equal(t, networks.ServiceID("dupe-1"), timeline["observer-a"][0].Holder)
+	assert.Equal(t, NoDuplicateHolder, timeline["observer-a"][1].Holder)
+	assert.Equal(t, 2, timeline.GetNumSwitches("observer-a"))
+	assert.Equal(t, 0, timeline.GetNumSwitches("observer-b"))
+	assert.Equal(
+		t,
+		map[networks.ServiceID]networks.ServiceID{"observer-a": "dupe-2", "observer-b": "dupe-1"},
+		timeline.GetFinalHolders())
+}
+
+func TestWaitForHolder(t *testing.T) {
+	tracker := NewDuplicatePeerTracker(
+		testDuplicateNodeID,
+		testDuplicateIPs,
+		map[networks.ServiceID]*apis.Client{
+			"observer-a": newDuplicatePeerClient(t, "10.0.0.1:9651", "10.0.0.2:9651"),
+			"observer-b": newDuplicatePeerClient(t, "10.0.0.2:9651"),
+		},
+		testPollInterval)
+	evictionTime, err := tracker.WaitForHolder("dupe-2", time.Second)
+	assert.NoError(t, err)
+	assert.True(t, evictionTime >= testPollInterval, "observer-a should only have switched on a later poll")
+
+	_, err = tracker.WaitForHolder("dupe-1", 5*testPollInterval)
+	assert.Error(t, err)
+}
+
+func TestUnknownDuplicateIPIsRecorded(t *testing.T) {
+	tracker := NewDuplicatePeerTracker(
+		testDuplicateNodeID,
+		testDuplicateIPs,
+		map[networks.ServiceID]*apis.Client{
+			"observer-a": newDuplicatePeerClient(t, "10.0.0.3:9651"),
+		},
+		testPollInterval)
+	timeline, err := tracker.Observe(0)
+	assert.NoError(t, err)
+	assert.Equal(t, networks.ServiceID("10.0.0.3:9651"), timeline["observer-a"][0].Holder)
+}
+
+/*
+Creates a client for a node that's peered with a normal node and, on each call, with the duplicated node ID at the next of
+	the given IPs (none if the IP is empty), repeating the last one
+*/
+func newDuplicatePeerClient(t *testing.T, duplicateIPs ...string) *apis.Client {
+	responses := make([]apistest.Response, 0, len(duplicateIPs))
+	for _, duplicateIP := range duplicateIPs {
+		peers := `{"nodeID": "NodeID-normal", "ip": "10.0.0.9:9651"}`
+		if duplicateIP != "" {
+			peers += fmt.Sprintf(`, {"nodeID": "%v", "ip": "%v"}`, testDuplicateNodeID, duplicateIP)
+		}
+		responses = append(responses, apistest.Resultf(`{"numPeers": "2", "peers": [%v]}`, peers))
+	}
+	return apistest.NewFakeNode(t).On("info.peers", responses...).GetClient()
+}
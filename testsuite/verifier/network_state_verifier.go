@@ -11,8 +11,12 @@ package verifier
 
 import (
 	"context"
+	"encoding/json"
 
 	"github.com/chain4travel/camino-testing/camino_client/apis"
+	"github.com/chain4travel/caminogo/ids"
+	"github.com/chain4travel/caminogo/utils/constants"
+	"github.com/chain4travel/caminogo/vms/platformvm"
 	"github.com/kurtosis-tech/kurtosis-go/lib/networks"
 	"github.com/palantir/stacktrace"
 	"github.com/sirupsen/logrus"
@@ -48,25 +52,7 @@ func (verifier NetworkStateVerifier) VerifyNetworkFullyConnected(
 	allAvalalancheClients map[networks.ServiceID]*apis.Client,
 ) error {
 	logrus.Tracef("All node IDs in network being verified: %v", allNodeIDs)
-	for serviceID := range allServiceIDs {
-		_, isStaker := stakerServiceIDs[serviceID]
-
-		acceptableNodeIDs := make(map[string]bool)
-		for comparisonID := range allServiceIDs {
-			// Nodes will never have themselves in their peer list
-			if serviceID == comparisonID {
-				continue
-			}
-			_, isComparisonStaker := stakerServiceIDs[comparisonID]
-
-			// Staker nodes will have all other nodes in their peer list
-			// Non-stakers will only have the stakers
-			if isStaker || (!isStaker && isComparisonStaker) {
-				comparisonNodeID := allNodeIDs[comparisonID]
-				acceptableNodeIDs[comparisonNodeID] = true
-			}
-		}
-
+	for serviceID, acceptableNodeIDs := range getFullyConnectedPeers(allServiceIDs, stakerServiceIDs, allNodeIDs) {
 		logrus.Infof("Expecting serviceID %v to have the following peer node IDs, %v", serviceID, acceptableNodeIDs)
 		if err := verifier.VerifyExpectedPeers(serviceID, allAvalalancheClients[serviceID], acceptableNodeIDs, len(acceptableNodeIDs), false); err != nil {
 			return stacktrace.Propagate(err, "An error occurred verifying the expected peers list")
@@ -123,3 +109,156 @@ func (verifier NetworkStateVerifier) VerifyExpectedPeers(
 	}
 	return nil
 }
+
+// VerifyValidatorsConnected asserts that every validator reports every other validator as connected, with an uptime of at
+// least the given minimum, in its P Chain current validator set
+// Args:
+// 	validatorServiceIDs: The service IDs of the validators to check, each of which reports on all the others
+// 	allNodeIDs: The mapping of service_id -> node_id
+// 	allCaminoClients: The mapping of service_id -> Camino client
+// 	minUptime: The lowest acceptable uptime, as a fraction between 0 and 1
+func (verifier NetworkStateVerifier) VerifyValidatorsConnected(
+	validatorServiceIDs map[networks.ServiceID]bool,
+	allNodeIDs map[networks.ServiceID]string,
+	allCaminoClients map[networks.ServiceID]*apis.Client,
+	minUptime float32) error {
+	for reporterID := range validatorServiceIDs {
+		reportedValidators, err := verifier.getReportedValidators(allCaminoClients[reporterID])
+		if err != nil {
+			return stacktrace.Propagate(err, "An error occurred getting the current validators reported by service with ID %v", reporterID)
+		}
+		for validatorID := range validatorServiceIDs {
+			// Nodes don't report on their own connection
+			if validatorID == reporterID {
+				continue
+			}
+			validator, found := reportedValidators[allNodeIDs[validatorID]]
+			if !found {
+				return stacktrace.NewError("Service ID %v doesn't have service ID %v in its current validators", reporterID, validatorID)
+			}
+			if validator.Connected == nil || !*validator.Connected {
+				return stacktrace.NewError("Service ID %v reports validator service ID %v as disconnected", reporterID, validatorID)
+			}
+			if validator.Uptime == nil {
+				return stacktrace.NewError("Service ID %v doesn't report an uptime for validator service ID %v", reporterID, validatorID)
+			}
+			if uptime := float32(*validator.Uptime); uptime < minUptime {
+				return stacktrace.NewError(
+					"Service ID %v reports validator service ID %v with uptime %v, which is below the minimum of %v",
+					reporterID,
+					validatorID,
+					uptime,
+					minUptime)
+			}
+		}
+	}
+	return nil
+}
+
+// VerifyValidatorDisconnected asserts that every one of the given reporters reports the validator as disconnected
+// Args:
+// 	validatorServiceID: The service ID of the validator that's expected to be disconnected
+// 	reporterServiceIDs: The service IDs of the validators that report on it
+// 	allNodeIDs: The mapping of service_id -> node_id
+// 	allCaminoClients: The mapping of service_id -> Camino client
+func (verifier NetworkStateVerifier) VerifyValidatorDisconnected(
+	validatorServiceID networks.ServiceID,
+	reporterServiceIDs map[networks.ServiceID]bool,
+	allNodeIDs map[networks.ServiceID]string,
+	allCaminoClients map[networks.ServiceID]*apis.Client) error {
+	for reporterID := range reporterServiceIDs {
+		validator, err := verifier.getReportedValidator(validatorServiceID, reporterID, allNodeIDs, allCaminoClients)
+		if err != nil {
+			return stacktrace.Propagate(err, "An error occurred getting validator service ID %v as reported by service ID %v", validatorServiceID, reporterID)
+		}
+		if validator.Connected != nil && *validator.Connected {
+			return stacktrace.NewError("Service ID %v reports validator service ID %v as connected", reporterID, validatorServiceID)
+		}
+	}
+	return nil
+}
+
+// GetReportedUptimes gets the uptime that each of the given reporters reports for the validator
+// Args:
+// 	validatorServiceID: The service ID of the validator whose uptime is reported
+// 	reporterServiceIDs: The service IDs of the validators that report on it
+// 	allNodeIDs: The mapping of service_id -> node_id
+// 	allCaminoClients: The mapping of service_id -> Camino client
+// Returns:
+// 	Mapping of reporter service ID -> the uptime it reports, as a fraction between 0 and 1
+func (verifier NetworkStateVerifier) GetReportedUptimes(
+	validatorServiceID networks.ServiceID,
+	reporterServiceIDs map[networks.ServiceID]bool,
+	allNodeIDs map[networks.ServiceID]string,
+	allCaminoClients map[networks.ServiceID]*apis.Client) (map[networks.ServiceID]float32, error) {
+	result := make(map[networks.ServiceID]float32, len(reporterServiceIDs))
+	for reporterID := range reporterServiceIDs {
+		validator, err := verifier.getReportedValidator(validatorServiceID, reporterID, allNodeIDs, allCaminoClients)
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "An error occurred getting validator service ID %v as reported by service ID %v", validatorServiceID, reporterID)
+		}
+		if validator.Uptime == nil {
+			return nil, stacktrace.NewError("Service ID %v doesn't report an uptime for validator service ID %v", reporterID, validatorServiceID)
+		}
+		result[reporterID] = float32(*validator.Uptime)
+	}
+	return result, nil
+}
+
+// ================ Helper functions =========================
+/*
+Gets a single validator from the reporter's current primary network validators
+*/
+func (verifier NetworkStateVerifier) getReportedValidator(
+	validatorServiceID networks.ServiceID,
+	reporterServiceID networks.ServiceID,
+	allNodeIDs map[networks.ServiceID]string,
+	allCaminoClients map[networks.ServiceID]*apis.Client) (platformvm.APIPrimaryValidator, error) {
+	reportedValidators, err := verifier.getReportedValidators(allCaminoClients[reporterServiceID])
+	if err != nil {
+		return platformvm.APIPrimaryValidator{}, stacktrace.Propagate(err, "An error occurred getting the current validators")
+	}
+	validator, found := reportedValidators[allNodeIDs[validatorServiceID]]
+	if !found {
+		return platformvm.APIPrimaryValidator{}, stacktrace.NewError("Service ID %v isn't in the current validators", validatorServiceID)
+	}
+	return validator, nil
+}
+
+/*
+Gets the node's current primary network validators, keyed by node ID
+*/
+func (verifier NetworkStateVerifier) getReportedValidators(client *apis.Client) (map[string]platformvm.APIPrimaryValidator, error) {
+	currentValidators, err := client.PChainAPI().GetCurrentValidators(verifier.ctx, constants.PrimaryNetworkID, []ids.ShortID{})
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "Could not get the current validators")
+	}
+	result := make(map[string]platformvm.APIPrimaryValidator, len(currentValidators))
+	for _, iValidator := range currentValidators {
+		validator, err := ToPrimaryValidator(iValidator)
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "An error occurred reading a current validator")
+		}
+		result[validator.NodeID] = validator
+	}
+	return result, nil
+}
+
+/*
+The client hands back the validators as they were decoded from JSON, i.e. as generic maps, so they're re-encoded into the
+	validator type
+*/
+func ToPrimaryValidator(iValidator interface{}) (platformvm.APIPrimaryValidator, error) {
+	if validator, ok := iValidator.(platformvm.APIPrimaryValidator); ok {
+		return validator, nil
+	}
+	validatorBytes, err := json.Marshal(iValidator)
+	if err != nil {
+		return platformvm.APIPrimaryValidator{}, stacktrace.Propagate(err, "Could not encode validator of type %T", iValidator)
+	}
+	validator := platformvm.APIPrimaryValidator{}
+	if err := json.Unmarshal(validatorBytes, &validator); err != nil {
+		return platformvm.APIPrimaryValidator{}, stacktrace.Propagate(err, "Could not decode validator %s", validatorBytes)
+	}
+	return validator, nil
+}
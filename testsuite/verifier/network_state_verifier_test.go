@@ -0,0 +1,82 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package verifier
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/chain4travel/camino-testing/camino_client/apis"
+	"github.com/chain4travel/camino-testing/camino_client/apis/apistest"
+	"github.com/kurtosis-tech/kurtosis-go/lib/networks"
+	"github.com/stretchr/testify/assert"
+)
+
+var testValidatorServiceIDs = map[networks.ServiceID]bool{
+	"node-a": true,
+	"node-b": true,
+}
+
+func TestValidatorsConnected(t *testing.T) {
+	clients := map[networks.ServiceID]*apis.Client{
+		"node-a": newValidatorsClient(t, testValidator{"NodeID-a", "1.0000", true}, testValidator{"NodeID-b", "0.9500", true}),
+		"node-b": newValidatorsClient(t, testValidator{"NodeID-a", "0.9900", true}, testValidator{"NodeID-b", "1.0000", true}),
+	}
+	verifier := NewNetworkStateVerifier()
+	assert.NoError(t, verifier.VerifyValidatorsConnected(testValidatorServiceIDs, testNodeIDs, clients, 0.9))
+
+	err := verifier.VerifyValidatorsConnected(testValidatorServiceIDs, testNodeIDs, clients, 0.96)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "reports validator service ID node-b with uptime 0.95")
+}
+
+func TestValidatorReportedDisconnected(t *testing.T) {
+	clients := map[networks.ServiceID]*apis.Client{
+		"node-a": newValidatorsClient(t, testValidator{"NodeID-a", "1.0000", true}, testValidator{"NodeID-b", "0.5000", false}),
+		"node-b": newValidatorsClient(t, testValidator{"NodeID-a", "1.0000", true}, testValidator{"NodeID-b", "1.0000", true}),
+	}
+	verifier := NewNetworkStateVerifier()
+	err := verifier.VerifyValidatorsConnected(testValidatorServiceIDs, testNodeIDs, clients, 0.4)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Service ID node-a reports validator service ID node-b as disconnected")
+
+	reporterIDs := map[networks.ServiceID]bool{"node-a": true}
+	assert.NoError(t, verifier.VerifyValidatorDisconnected("node-b", reporterIDs, testNodeIDs, clients))
+	assert.Error(t, verifier.VerifyValidatorDisconnected("node-a", map[networks.ServiceID]bool{"node-b": true}, testNodeIDs, clients))
+
+	uptimes, err := verifier.GetReportedUptimes("node-b", testValidatorServiceIDs, testNodeIDs, clients)
+	assert.NoError(t, err)
+	assert.Equal(t, map[networks.ServiceID]float32{"node-a": 0.5, "node-b": 1}, uptimes)
+}
+
+// testValidator is a current validator as the P Chain API reports it
+type testValidator struct {
+	nodeID    string
+	uptime    string
+	connected bool
+}
+
+/*
+Creates a client for a node whose P Chain API always reports the given current validators
+*/
+func newValidatorsClient(t *testing.T, validators ...testValidator) *apis.Client {
+	validatorJSONs := make([]string, 0, len(validators))
+	for _, validator := range validators {
+		validatorJSONs = append(validatorJSONs, fmt.Sprintf(
+			`{"nodeID": "%v", "startTime": "0", "endTime": "1", "uptime": "%v", "connected": %v, "delegationFee": "2.0000", "delegators": null}`,
+			validator.nodeID,
+			validator.uptime,
+			validator.connected))
+	}
+	return apistest.NewFakeNode(t).
+		On("platform.getCurrentValidators", apistest.Resultf(`{"validators": [%v]}`, strings.Join(validatorJSONs, ", "))).
+		GetClient()
+}
@@ -0,0 +1,291 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package verifier
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/chain4travel/camino-testing/camino_client/apis"
+	"github.com/kurtosis-tech/kurtosis-go/lib/networks"
+	"github.com/palantir/stacktrace"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// The name the convergence time is reported under by PeerConvergenceReport.LogMetrics
+	peerConvergenceTimeMetric = "peer_convergence_seconds"
+
+	// The name the time the last expected peer link appeared is reported under by PeerConvergenceReport.LogMetrics
+	lastPeerLinkMetric = "last_peer_link_seconds"
+)
+
+// PeerConvergenceTracker polls the peers of every node of a network until each node has exactly the peers it's expected
+// to have, recording when each expected peer link first appears
+type PeerConvergenceTracker struct {
+	ctx context.Context
+
+	clients map[networks.ServiceID]*apis.Client
+
+	// Mapping of service ID -> "set" of the node IDs the service is expected to end up peered with
+	expectedPeers map[networks.ServiceID]map[string]bool
+
+	// Mapping of node ID -> service ID, used to make the reported peers readable
+	serviceIDsByNodeID map[string]networks.ServiceID
+
+	pollInterval time.Duration
+}
+
+// NewPeerConvergenceTracker creates a tracker that waits for every node to be peered with exactly the given nodes
+// Args:
+// 	clients: The clients of the nodes whose peers are polled
+// 	expectedPeers: Mapping of service ID -> "set" of the node IDs that the service is expected to end up peered with
+// 	allNodeIDs: The mapping of service_id -> node_id, used to report peers by their service ID
+// 	pollInterval: How often the peers of every node are polled
+func NewPeerConvergenceTracker(
+	clients map[networks.ServiceID]*apis.Client,
+	expectedPeers map[networks.ServiceID]map[string]bool,
+	allNodeIDs map[networks.ServiceID]string,
+	pollInterval time.Duration) PeerConvergenceTracker {
+	serviceIDsByNodeID := make(map[string]networks.ServiceID, len(allNodeIDs))
+	for serviceID, nodeID := range allNodeIDs {
+		serviceIDsByNodeID[nodeID] = serviceID
+	}
+	return PeerConvergenceTracker{
+		ctx:                context.Background(),
+		clients:            clients,
+		expectedPeers:      expectedPeers,
+		serviceIDsByNodeID: serviceIDsByNodeID,
+		pollInterval:       pollInterval,
+	}
+}
+
+// NewFullyConnectedConvergenceTracker creates a tracker that waits for the network to be fully connected, in the sense
+// of NetworkStateVerifier.VerifyNetworkFullyConnected
+func NewFullyConnectedConvergenceTracker(
+	allServiceIDs map[networks.ServiceID]bool,
+	stakerServiceIDs map[networks.ServiceID]bool,
+	allNodeIDs map[networks.ServiceID]string,
+	allCaminoClients map[networks.ServiceID]*apis.Client,
+	pollInterval time.Duration) PeerConvergenceTracker {
+	return NewPeerConvergenceTracker(
+		allCaminoClients,
+		getFullyConnectedPeers(allServiceIDs, stakerServiceIDs, allNodeIDs),
+		allNodeIDs,
+		pollInterval)
+}
+
+// WaitForConvergence polls the peers of every node until each has exactly its expected peers, or until the deadline
+// passes, in which case the error describes, per node, the peers that were missing and unexpected at the last poll
+// Args:
+// 	deadline: How long the nodes get to converge
+// Returns:
+// 	A report of when each expected peer link appeared, which is filled in even if the nodes didn't converge
+func (tracker PeerConvergenceTracker) WaitForConvergence(deadline time.Duration) (PeerConvergenceReport, error) {
+	startTime := time.Now()
+	report := PeerConvergenceReport{
+		LinkAppearances: make(map[networks.ServiceID]map[string]time.Duration, len(tracker.expectedPeers)),
+	}
+	for serviceID := range tracker.expectedPeers {
+		report.LinkAppearances[serviceID] = map[string]time.Duration{}
+	}
+
+	for {
+		pollTime := time.Since(startTime)
+		diffs := map[networks.ServiceID]peerDiff{}
+		for serviceID, expectedPeers := range tracker.expectedPeers {
+			diff := tracker.pollPeers(serviceID, expectedPeers)
+			for nodeID := range diff.present {
+				if _, found := report.LinkAppearances[serviceID][nodeID]; !found {
+					report.LinkAppearances[serviceID][nodeID] = pollTime
+				}
+			}
+			if !diff.isConverged() {
+				diffs[serviceID] = diff
+			}
+		}
+		if len(diffs) == 0 {
+			report.Converged = true
+			report.ConvergenceTime = pollTime
+			return report, nil
+		}
+		if time.Since(startTime) > deadline {
+			return report, stacktrace.NewError(
+				"The nodes' peers didn't converge within %v; at the last poll:\n%v",
+				deadline,
+				tracker.describeDiffs(diffs))
+		}
+		time.Sleep(tracker.pollInterval)
+	}
+}
+
+// PeerConvergenceReport records how the peers of a network's nodes converged
+type PeerConvergenceReport struct {
+	// Mapping of service ID -> node ID of an expected peer -> how long after tracking started the two were first seen to
+	// be peered
+	LinkAppearances map[networks.ServiceID]map[string]time.Duration
+
+	// Whether every node ended up with exactly its expected peers
+	Converged bool
+
+	// How long after tracking started every node had exactly its expected peers; only set if the nodes converged
+	ConvergenceTime time.Duration
+}
+
+// LogMetrics logs the convergence time, and the time the last expected peer link appeared, as metrics
+func (report PeerConvergenceReport) LogMetrics() {
+	var lastLinkTime time.Duration
+	numLinks := 0
+	for _, linkAppearances := range report.LinkAppearances {
+		for _, appearanceTime := range linkAppearances {
+			numLinks++
+			if appearanceTime > lastLinkTime {
+				lastLinkTime = appearanceTime
+			}
+		}
+	}
+	logrus.WithFields(logrus.Fields{
+		"metric":    lastPeerLinkMetric,
+		"value":     lastLinkTime.Seconds(),
+		"num_links": numLinks,
+	}).Infof("The last expected peer link of %v appeared after %v", numLinks, lastLinkTime)
+	if report.Converged {
+		logrus.WithFields(logrus.Fields{
+			"metric": peerConvergenceTimeMetric,
+			"value":  report.ConvergenceTime.Seconds(),
+		}).Infof("The nodes' peers converged after %v", report.ConvergenceTime)
+	}
+}
+
+// ================ Helper functions =========================
+/*
+Gets the peers every node of a fully connected network has: stakers are peered with every other node, and non-stakers
+	with every staker
+*/
+func getFullyConnectedPeers(
+	allServiceIDs map[networks.ServiceID]bool,
+	stakerServiceIDs map[networks.ServiceID]bool,
+	allNodeIDs map[networks.ServiceID]string) map[networks.ServiceID]map[string]bool {
+	result := make(map[networks.ServiceID]map[string]bool, len(allServiceIDs))
+	for serviceID := range allServiceIDs {
+		_, isStaker := stakerServiceIDs[serviceID]
+
+		acceptableNodeIDs := make(map[string]bool)
+		for comparisonID := range allServiceIDs {
+			// Nodes will never have themselves in their peer list
+			if serviceID == comparisonID {
+				continue
+			}
+			_, isComparisonStaker := stakerServiceIDs[comparisonID]
+
+			// Staker nodes will have all other nodes in their peer list
+			// Non-stakers will only have the stakers
+			if isStaker || (!isStaker && isComparisonStaker) {
+				comparisonNodeID := allNodeIDs[comparisonID]
+				acceptableNodeIDs[comparisonNodeID] = true
+			}
+		}
+		result[serviceID] = acceptableNodeIDs
+	}
+	return result
+}
+
+/*
+Compares the node's current peers with its expected ones
+*/
+func (tracker PeerConvergenceTracker) pollPeers(serviceID networks.ServiceID, expectedPeers map[string]bool) peerDiff {
+	diff := peerDiff{
+		present:    map[string]bool{},
+		missing:    map[string]bool{},
+		unexpected: map[string]bool{},
+	}
+	peers, err := tracker.clients[serviceID].InfoAPI().Peers(tracker.ctx)
+	if err != nil {
+		diff.pollErr = err
+		for nodeID := range expectedPeers {
+			diff.missing[nodeID] = true
+		}
+		return diff
+	}
+	for _, peer := range peers {
+		if expectedPeers[peer.ID] {
+			diff.present[peer.ID] = true
+		} else {
+			diff.unexpected[peer.ID] = true
+		}
+	}
+	for nodeID := range expectedPeers {
+		if !diff.present[nodeID] {
+			diff.missing[nodeID] = true
+		}
+	}
+	return diff
+}
+
+func (tracker PeerConvergenceTracker) describeDiffs(diffs map[networks.ServiceID]peerDiff) string {
+	serviceIDs := make([]string, 0, len(diffs))
+	for serviceID := range diffs {
+		serviceIDs = append(serviceIDs, string(serviceID))
+	}
+	sort.Strings(serviceIDs)
+
+	lines := make([]string, 0, len(diffs))
+	for _, serviceID := range serviceIDs {
+		diff := diffs[networks.ServiceID(serviceID)]
+		line := fmt.Sprintf(
+			"\t%v: missing %v, unexpected %v",
+			serviceID,
+			tracker.describePeers(diff.missing),
+			tracker.describePeers(diff.unexpected))
+		if diff.pollErr != nil {
+			line += fmt.Sprintf(" (couldn't get its peers: %v)", strings.SplitN(diff.pollErr.Error(), "\n", 2)[0])
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+/*
+Describes the given peers by their service ID where known, and by their node ID otherwise
+*/
+func (tracker PeerConvergenceTracker) describePeers(nodeIDs map[string]bool) string {
+	result := make([]string, 0, len(nodeIDs))
+	for nodeID := range nodeIDs {
+		if serviceID, found := tracker.serviceIDsByNodeID[nodeID]; found {
+			result = append(result, string(serviceID))
+		} else {
+			result = append(result, nodeID)
+		}
+	}
+	sort.Strings(result)
+	return "[" + strings.Join(result, ", ") + "]"
+}
+
+// peerDiff compares the peers a node has with the peers it's expected to have
+type peerDiff struct {
+	// Node IDs of the expected peers the node has
+	present map[string]bool
+
+	// Node IDs of the expected peers the node doesn't have
+	missing map[string]bool
+
+	// Node IDs of the peers the node has but isn't expected to
+	unexpected map[string]bool
+
+	// The error the node's peers couldn't be polled because of, if any
+	pollErr error
+}
+
+func (diff peerDiff) isConverged() bool {
+	return diff.pollErr == nil && len(diff.missing) == 0 && len(diff.unexpected) == 0
+}
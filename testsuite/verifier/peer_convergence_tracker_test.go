@@ -0,0 +1,90 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package verifier
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chain4travel/camino-testing/camino_client/apis"
+	"github.com/chain4travel/camino-testing/camino_client/apis/apistest"
+	"github.com/kurtosis-tech/kurtosis-go/lib/networks"
+	"github.com/stretchr/testify/assert"
+)
+
+const testPollInterval = 10 * time.Millisecond
+
+var testNodeIDs = map[networks.ServiceID]string{
+	"node-a": "NodeID-a",
+	"node-b": "NodeID-b",
+}
+
+func TestConvergenceRecordsLinkAppearances(t *testing.T) {
+	// node-a only finds node-b on its second poll
+	peersA := newPeersClient(t, []string{}, []string{"NodeID-b"})
+	peersB := newPeersClient(t, []string{"NodeID-a"})
+	tracker := NewFullyConnectedConvergenceTracker(
+		map[networks.ServiceID]bool{"node-a": true, "node-b": true},
+		map[networks.ServiceID]bool{"node-a": true, "node-b": true},
+		testNodeIDs,
+		map[networks.ServiceID]*apis.Client{
+			"node-a": peersA,
+			"node-b": peersB,
+		},
+		testPollInterval)
+
+	report, err := tracker.WaitForConvergence(time.Second)
+	assert.NoError(t, err)
+	assert.True(t, report.Converged)
+	assert.True(t, report.LinkAppearances["node-b"]["NodeID-a"] < testPollInterval, "node-b's link should have appeared on the first poll")
+	assert.True(t, report.LinkAppearances["node-a"]["NodeID-b"] >= testPollInterval, "node-a's link should have appeared on a later poll")
+	assert.Equal(t, report.LinkAppearances["node-a"]["NodeID-b"], report.ConvergenceTime)
+}
+
+func TestNonConvergenceReportsDiffPerNode(t *testing.T) {
+	peersA := newPeersClient(t, []string{"NodeID-unknown"})
+	peersB := newPeersClient(t, []string{"NodeID-a"})
+	tracker := NewFullyConnectedConvergenceTracker(
+		map[networks.ServiceID]bool{"node-a": true, "node-b": true},
+		map[networks.ServiceID]bool{"node-a": true, "node-b": true},
+		testNodeIDs,
+		map[networks.ServiceID]*apis.Client{
+			"node-a": peersA,
+			"node-b": peersB,
+		},
+		testPollInterval)
+
+	report, err := tracker.WaitForConvergence(50 * time.Millisecond)
+	assert.Error(t, err)
+	assert.False(t, report.Converged)
+	assert.Contains(t, err.Error(), "node-a: missing [node-b], unexpected [NodeID-unknown]")
+	assert.NotContains(t, err.Error(), "node-b:")
+}
+
+/*
+Creates a client for a node whose info API answers each peers call with the next of the given peer lists, repeating the
+	last one
+*/
+func newPeersClient(t *testing.T, peerLists ...[]string) *apis.Client {
+	responses := make([]apistest.Response, 0, len(peerLists))
+	for _, peerList := range peerLists {
+		peers := make([]string, 0, len(peerList))
+		for _, nodeID := range peerList {
+			peers = append(peers, fmt.Sprintf(`{"nodeID": "%v"}`, nodeID))
+		}
+		responses = append(responses, apistest.Resultf(
+			`{"numPeers": "%d", "peers": [%v]}`,
+			len(peers),
+			strings.Join(peers, ", ")))
+	}
+	return apistest.NewFakeNode(t).On("info.peers", responses...).GetClient()
+}
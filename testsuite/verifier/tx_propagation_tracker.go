@@ -0,0 +1,264 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package verifier
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/chain4travel/camino-testing/camino_client/apis"
+	"github.com/chain4travel/caminogo/ids"
+	"github.com/chain4travel/caminogo/snow/choices"
+	"github.com/kurtosis-tech/kurtosis-go/lib/networks"
+	"github.com/palantir/stacktrace"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// The name the propagation latency distribution is reported under by TxPropagationReport.LogMetrics
+	txPropagationLatencyMetric = "tx_propagation_seconds"
+
+	// The error the X Chain refuses to issue a tx it has rejected with
+	rejectedTxError = "transaction is rejected"
+)
+
+// TxPropagationTracker issues X Chain txs to one node of a network and polls the status of each tx on every other node
+// until each of them knows it, i.e. reports any status but unknown, recording how long after the issue each node got it
+type TxPropagationTracker struct {
+	ctx context.Context
+
+	// The node the txs are issued to
+	issuerServiceID networks.ServiceID
+
+	// The clients of every node, including the issuer
+	clients map[networks.ServiceID]*apis.Client
+
+	pollInterval time.Duration
+}
+
+// NewTxPropagationTracker creates a tracker that issues txs to one node and waits for them to reach the others
+// Args:
+// 	issuerServiceID: The ID of the service the txs are issued to
+// 	clients: The clients of every node, including the issuer; the txs must reach all of them
+// 	pollInterval: How often the tx status is polled on the nodes that don't know the tx yet
+func NewTxPropagationTracker(
+	issuerServiceID networks.ServiceID,
+	clients map[networks.ServiceID]*apis.Client,
+	pollInterval time.Duration) TxPropagationTracker {
+	return TxPropagationTracker{
+		ctx:             context.Background(),
+		issuerServiceID: issuerServiceID,
+		clients:         clients,
+		pollInterval:    pollInterval,
+	}
+}
+
+// TrackTxs issues each tx to the issuing node in order, and waits for each to reach every other node before issuing the
+// next, so that the propagation of one tx isn't slowed down by the next
+// Args:
+// 	txList: The txs to issue
+// 	deadline: How long each tx gets to reach every node
+// Returns:
+// 	A report of how long each tx took to reach each node, which is filled in up to the tx that didn't reach every node, if
+// 		any
+func (tracker TxPropagationTracker) TrackTxs(txList [][]byte, deadline time.Duration) (TxPropagationReport, error) {
+	report := TxPropagationReport{}
+	for i, txBytes := range txList {
+		propagation, err := tracker.TrackTx(txBytes, deadline)
+		report.Txs = append(report.Txs, propagation)
+		if err != nil {
+			return report, stacktrace.Propagate(err, "Tx %v didn't reach every node", i)
+		}
+	}
+	return report, nil
+}
+
+// TrackTx issues the tx to the issuing node and polls the other nodes until each knows it, or until the deadline passes,
+// in which case the error lists the nodes that didn't know the tx at the last poll
+// Returns:
+// 	How long the tx took to reach each node, which is filled in for the nodes it reached even if it didn't reach all
+func (tracker TxPropagationTracker) TrackTx(txBytes []byte, deadline time.Duration) (TxPropagation, error) {
+	txID, err := tracker.clients[tracker.issuerServiceID].XChainAPI().IssueTx(tracker.ctx, txBytes)
+	if err != nil {
+		return TxPropagation{}, stacktrace.Propagate(err, "Failed to issue the tx to %v", tracker.issuerServiceID)
+	}
+	issueTime := time.Now()
+	propagation := TxPropagation{
+		TxID:      txID,
+		Latencies: make(map[networks.ServiceID]time.Duration, len(tracker.clients)-1),
+	}
+
+	for {
+		unawareServiceIDs := []string{}
+		for serviceID, client := range tracker.clients {
+			if _, found := propagation.Latencies[serviceID]; found || serviceID == tracker.issuerServiceID {
+				continue
+			}
+			// A node that can't be asked is treated like one that doesn't know the tx yet
+			status, err := client.XChainAPI().GetTxStatus(tracker.ctx, txID)
+			if err == nil && status != choices.Unknown {
+				propagation.Latencies[serviceID] = time.Since(issueTime)
+			} else {
+				unawareServiceIDs = append(unawareServiceIDs, string(serviceID))
+			}
+		}
+		if len(unawareServiceIDs) == 0 {
+			return propagation, nil
+		}
+		if time.Since(issueTime) > deadline {
+			sort.Strings(unawareServiceIDs)
+			return propagation, stacktrace.NewError(
+				"Tx %v, issued to %v, didn't reach %v within %v",
+				txID,
+				tracker.issuerServiceID,
+				strings.Join(unawareServiceIDs, ", "),
+				deadline)
+		}
+		time.Sleep(tracker.pollInterval)
+	}
+}
+
+// VerifyReissueIgnored issues a tx that every node already knows to each node again, and checks that no node treats it
+// as a new tx: each must answer as the X Chain does for the status the tx had on it, and report the same status for it
+// afterwards as before. The X Chain refuses a tx it has rejected with rejectedTxError, and answers with the ID of a tx it
+// has accepted or is still deciding on, as it considers both verified.
+func (tracker TxPropagationTracker) VerifyReissueIgnored(txBytes []byte, txID ids.ID) error {
+	problems := []string{}
+	for _, serviceID := range tracker.getSortedServiceIDs() {
+		client := tracker.clients[serviceID]
+		statusBefore, err := client.XChainAPI().GetTxStatus(tracker.ctx, txID)
+		if err != nil {
+			return stacktrace.Propagate(err, "Failed to get the status of tx %v on %v", txID, serviceID)
+		}
+		if statusBefore == choices.Unknown {
+			return stacktrace.NewError("Tx %v can't be reissued to %v, which doesn't know it yet", txID, serviceID)
+		}
+		reissuedTxID, reissueErr := client.XChainAPI().IssueTx(tracker.ctx, txBytes)
+		statusAfter, err := client.XChainAPI().GetTxStatus(tracker.ctx, txID)
+		if err != nil {
+			return stacktrace.Propagate(err, "Failed to get the status of tx %v on %v", txID, serviceID)
+		}
+		if problem := checkReissueAnswer(statusBefore, reissuedTxID, reissueErr, txID); problem != "" {
+			problems = append(problems, fmt.Sprintf("%v: %v", serviceID, problem))
+		}
+		// A tx the node was still deciding on may get decided meanwhile, but a decided tx must stay decided the same way
+		if statusBefore.Decided() && statusAfter != statusBefore {
+			problems = append(problems, fmt.Sprintf("%v: the tx's status went from %v to %v", serviceID, statusBefore, statusAfter))
+		}
+	}
+	if len(problems) > 0 {
+		return stacktrace.NewError("Nodes treated reissued tx %v as a new tx:\n\t%v", txID, strings.Join(problems, "\n\t"))
+	}
+	return nil
+}
+
+// TxPropagation records how long a tx took to reach each node after it was issued
+type TxPropagation struct {
+	TxID ids.ID
+
+	// Mapping of service ID -> how long after the tx was issued the node was first seen to know it; the issuing node isn't
+	// included
+	Latencies map[networks.ServiceID]time.Duration
+}
+
+// TxPropagationReport records how the txs a TxPropagationTracker issued propagated through the network
+type TxPropagationReport struct {
+	Txs []TxPropagation
+}
+
+// GetLatencies returns the time every tx took to reach every node it reached, sorted from shortest to longest
+func (report TxPropagationReport) GetLatencies() []time.Duration {
+	result := []time.Duration{}
+	for _, propagation := range report.Txs {
+		for _, latency := range propagation.Latencies {
+			result = append(result, latency)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i] < result[j] })
+	return result
+}
+
+// GetSlowestNodes returns, for each node, the longest any of the txs took to reach it
+func (report TxPropagationReport) GetSlowestNodes() map[networks.ServiceID]time.Duration {
+	result := map[networks.ServiceID]time.Duration{}
+	for _, propagation := range report.Txs {
+		for serviceID, latency := range propagation.Latencies {
+			if latency > result[serviceID] {
+				result[serviceID] = latency
+			}
+		}
+	}
+	return result
+}
+
+// LogMetrics logs the median, 95th percentile and maximum propagation latency as metrics
+func (report TxPropagationReport) LogMetrics() {
+	latencies := report.GetLatencies()
+	if len(latencies) == 0 {
+		logrus.Infof("No tx reached any node besides the one it was issued to")
+		return
+	}
+	medianLatency := latencies[len(latencies)/2]
+	p95Latency := latencies[(len(latencies)*95)/100]
+	maxLatency := latencies[len(latencies)-1]
+	logrus.WithFields(logrus.Fields{
+		"metric":        txPropagationLatencyMetric,
+		"value":         medianLatency.Seconds(),
+		"p95":           p95Latency.Seconds(),
+		"max":           maxLatency.Seconds(),
+		"num_txs":       len(report.Txs),
+		"num_latencies": len(latencies),
+	}).Infof(
+		"%v txs reached the other nodes with median latency %v, p95 latency %v and max latency %v",
+		len(report.Txs),
+		medianLatency,
+		p95Latency,
+		maxLatency)
+}
+
+// ================ Helper functions =========================
+func (tracker TxPropagationTracker) getSortedServiceIDs() []networks.ServiceID {
+	serviceIDStrs := make([]string, 0, len(tracker.clients))
+	for serviceID := range tracker.clients {
+		serviceIDStrs = append(serviceIDStrs, string(serviceID))
+	}
+	sort.Strings(serviceIDStrs)
+	result := make([]networks.ServiceID, 0, len(serviceIDStrs))
+	for _, serviceID := range serviceIDStrs {
+		result = append(result, networks.ServiceID(serviceID))
+	}
+	return result
+}
+
+/*
+Describes how the answer of a node to a reissued tx differs from what the X Chain answers for the status the tx had on it,
+	or returns the empty string if it doesn't
+*/
+func checkReissueAnswer(status choices.Status, reissuedTxID ids.ID, reissueErr error, txID ids.ID) string {
+	if status == choices.Rejected {
+		if reissueErr == nil {
+			return fmt.Sprintf("issued the rejected tx again as %v instead of refusing it", reissuedTxID)
+		}
+		if !strings.Contains(reissueErr.Error(), rejectedTxError) {
+			return fmt.Sprintf("refused the rejected tx with '%v' instead of '%v'", reissueErr, rejectedTxError)
+		}
+		return ""
+	}
+	if reissueErr != nil {
+		return fmt.Sprintf("refused the %v tx: %v", strings.ToLower(status.String()), reissueErr)
+	}
+	if reissuedTxID != txID {
+		return fmt.Sprintf("answered with a different tx ID, %v", reissuedTxID)
+	}
+	return ""
+}
@@ -0,0 +1,126 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package verifier
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/chain4travel/camino-testing/camino_client/apis"
+	"github.com/chain4travel/camino-testing/camino_client/apis/apistest"
+	"github.com/chain4travel/caminogo/ids"
+	"github.com/kurtosis-tech/kurtosis-go/lib/networks"
+	"github.com/stretchr/testify/assert"
+)
+
+var testTxID = ids.ID{1}
+
+func TestPropagationRecordsLatencyPerNode(t *testing.T) {
+	// node-b only knows the tx from its third poll on
+	tracker := NewTxPropagationTracker(
+		"node-a",
+		map[networks.ServiceID]*apis.Client{
+			"node-a": newTxServerClient(t, testTxID, nil, "Processing"),
+			"node-b": newTxServerClient(t, testTxID, nil, "Unknown", "Unknown", "Processing"),
+			"node-c": newTxServerClient(t, testTxID, nil, "Processing"),
+		},
+		testPollInterval)
+
+	report, err := tracker.TrackTxs([][]byte{{0}}, time.Second)
+	assert.NoError(t, err)
+	assert.Len(t, report.Txs, 1)
+	latencies := report.Txs[0].Latencies
+	assert.NotContains(t, latencies, networks.ServiceID("node-a"), "the issuing node shouldn't be tracked")
+	assert.True(t, latencies["node-c"] < testPollInterval, "node-c should have known the tx on the first poll")
+	assert.True(t, latencies["node-b"] >= 2*testPollInterval, "node-b should only have known the tx on the third poll")
+	assert.Equal(t, []time.Duration{latencies["node-c"], latencies["node-b"]}, report.GetLatencies())
+	assert.Equal(t, latencies["node-b"], report.GetSlowestNodes()["node-b"])
+}
+
+func TestPropagationReportsUnawareNodes(t *testing.T) {
+	tracker := NewTxPropagationTracker(
+		"node-a",
+		map[networks.ServiceID]*apis.Client{
+			"node-a": newTxServerClient(t, testTxID, nil, "Processing"),
+			"node-b": newTxServerClient(t, testTxID, nil, "Unknown"),
+			"node-c": newTxServerClient(t, testTxID, nil, "Accepted"),
+		},
+		testPollInterval)
+
+	propagation, err := tracker.TrackTx([]byte{0}, 50*time.Millisecond)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "didn't reach node-b within")
+	assert.Contains(t, propagation.Latencies, networks.ServiceID("node-c"))
+	assert.NotContains(t, propagation.Latencies, networks.ServiceID("node-b"))
+}
+
+func TestReissueIgnored(t *testing.T) {
+	tracker := NewTxPropagationTracker(
+		"node-a",
+		map[networks.ServiceID]*apis.Client{
+			"node-a": newTxServerClient(t, testTxID, nil, "Accepted"),
+			"node-b": newTxServerClient(t, testTxID, fmt.Errorf("problem issuing transaction: transaction is rejected"), "Rejected"),
+			"node-c": newTxServerClient(t, testTxID, nil, "Processing"),
+		},
+		testPollInterval)
+	assert.NoError(t, tracker.VerifyReissueIgnored([]byte{0}, testTxID))
+
+	// node-b answers with another tx's ID, and node-c forgets the tx it had accepted
+	tracker = NewTxPropagationTracker(
+		"node-a",
+		map[networks.ServiceID]*apis.Client{
+			"node-a": newTxServerClient(t, testTxID, nil, "Accepted"),
+			"node-b": newTxServerClient(t, ids.ID{2}, nil, "Accepted"),
+			"node-c": newTxServerClient(t, testTxID, nil, "Accepted", "Unknown"),
+		},
+		testPollInterval)
+	err := tracker.VerifyReissueIgnored([]byte{0}, testTxID)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), fmt.Sprintf("node-b: answered with a different tx ID, %v", ids.ID{2}))
+	assert.Contains(t, err.Error(), "node-c: the tx's status went from Accepted to Unknown")
+	assert.NotContains(t, err.Error(), "node-a:")
+}
+
+func TestReissueAnsweredAsForStatus(t *testing.T) {
+	// node-a issues its rejected tx again, node-b refuses its rejected tx for another reason, and node-c refuses a tx it
+	// accepted
+	tracker := NewTxPropagationTracker(
+		"node-a",
+		map[networks.ServiceID]*apis.Client{
+			"node-a": newTxServerClient(t, testTxID, nil, "Rejected"),
+			"node-b": newTxServerClient(t, testTxID, fmt.Errorf("missing utxo"), "Rejected"),
+			"node-c": newTxServerClient(t, testTxID, fmt.Errorf("missing utxo"), "Accepted"),
+		},
+		testPollInterval)
+	err := tracker.VerifyReissueIgnored([]byte{0}, testTxID)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), fmt.Sprintf("node-a: issued the rejected tx again as %v instead of refusing it", testTxID))
+	assert.Contains(t, err.Error(), "node-b: refused the rejected tx with")
+	assert.Contains(t, err.Error(), "node-c: refused the accepted tx")
+}
+
+/*
+Creates a client for a node whose X Chain answers every issued tx with the given tx ID or error, and each status call
+	with the next of the given statuses, repeating the last one
+*/
+func newTxServerClient(t *testing.T, issuedTxID ids.ID, issueErr error, statuses ...string) *apis.Client {
+	node := apistest.NewFakeNode(t)
+	if issueErr != nil {
+		node.On("avm.issueTx", apistest.Error(issueErr.Error()))
+	} else {
+		node.On("avm.issueTx", apistest.Resultf(`{"txID": "%v"}`, issuedTxID))
+	}
+	statusResponses := make([]apistest.Response, 0, len(statuses))
+	for _, status := range statuses {
+		statusResponses = append(statusResponses, apistest.Resultf(`{"status": "%v"}`, status))
+	}
+	return node.On("avm.getTxStatus", statusResponses...).GetClient()
+}
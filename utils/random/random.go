@@ -0,0 +1,49 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package random
+
+import (
+	"hash/fnv"
+	"math/rand"
+	"sync"
+)
+
+var (
+	seedMutex = &sync.RWMutex{}
+
+	// The seed that all of the suite's randomness derives from
+	seed int64
+)
+
+// SetSeed sets the seed that all of the suite's randomness derives from; it must be called before any randomness is drawn
+// for a run to be reproducible from the seed
+func SetSeed(newSeed int64) {
+	seedMutex.Lock()
+	defer seedMutex.Unlock()
+	seed = newSeed
+}
+
+// GetSeed returns the seed that all of the suite's randomness derives from
+func GetSeed() int64 {
+	seedMutex.RLock()
+	defer seedMutex.RUnlock()
+	return seed
+}
+
+// NewRand creates a source of randomness that only depends on the suite seed and the given scope, so that what one
+// component draws doesn't depend on what other components drew before it, or in which order they were created
+// Args:
+// 	scope: The name of the component that the randomness is for, which should be unique within a test
+func NewRand(scope string) *rand.Rand {
+	hash := fnv.New64a()
+	// Writes to a hash never fail
+	hash.Write([]byte(scope))
+	return rand.New(rand.NewSource(GetSeed() ^ int64(hash.Sum64())))
+}
@@ -0,0 +1,42 @@
+// Copyright (C) 2022, Chain4Travel AG. All rights reserved.
+//
+// This file is a derived work, based on ava-labs code
+//
+// It is distributed under the same license conditions as the
+// original code from which it is derived.
+//
+// Much love to the original authors for their work.
+
+package random
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSameSeedAndScopeGiveSameSequence(t *testing.T) {
+	originalSeed := GetSeed()
+	defer SetSeed(originalSeed)
+
+	SetSeed(1234)
+	first := NewRand("scope")
+	// Drawing from another scope in between doesn't affect the sequence
+	NewRand("other-scope").Int63()
+	second := NewRand("scope")
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, first.Int63(), second.Int63())
+	}
+}
+
+func TestScopesAndSeedsDiffer(t *testing.T) {
+	originalSeed := GetSeed()
+	defer SetSeed(originalSeed)
+
+	SetSeed(1234)
+	firstScopeValue := NewRand("scope").Int63()
+	assert.NotEqual(t, firstScopeValue, NewRand("other-scope").Int63())
+
+	SetSeed(5678)
+	assert.NotEqual(t, firstScopeValue, NewRand("scope").Int63())
+}